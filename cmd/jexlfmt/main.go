@@ -0,0 +1,124 @@
+// Команда jexlfmt форматирует скрипты JEXL, как gofmt - исходники Go.
+//
+//	jexlfmt [flags] [path ...]
+//
+// Без путей читает стандартный ввод и пишет результат в стандартный вывод.
+// Каталоги обходятся рекурсивно, форматируются файлы *.jexl.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/mentatxx/jexl-golang/jexl/format"
+)
+
+var (
+	write     = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	list      = flag.Bool("l", false, "list files whose formatting differs from jexlfmt's")
+	indent    = flag.Int("indent", 4, "indent width in spaces")
+	tabs      = flag.Bool("tabs", false, "indent with tabs")
+	brace     = flag.String("brace", "same", "opening brace placement: same or next")
+	minParens = flag.Bool("minparens", false, "remove parentheses that do not affect parsing")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: jexlfmt [flags] [path ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	opts := &format.Options{IndentWidth: *indent, UseTabs: *tabs, MinimizeParens: *minParens}
+	switch *brace {
+	case "same":
+		opts.Braces = format.BraceSameLine
+	case "next":
+		opts.Braces = format.BraceNextLine
+	default:
+		fmt.Fprintf(os.Stderr, "jexlfmt: unknown brace style %q\n", *brace)
+		os.Exit(2)
+	}
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "jexlfmt: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := process("<standard input>", os.Stdin, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Явно указанный файл форматируется независимо от расширения
+			if entry.IsDir() || file != path && filepath.Ext(file) != ".jexl" {
+				return nil
+			}
+			if err := processFile(file, opts); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+			}
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(2)
+	}
+}
+
+// processFile форматирует файл.
+func processFile(path string, opts *format.Options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return process(path, f, opts)
+}
+
+// process форматирует содержимое in и выводит результат согласно флагам.
+func process(name string, in io.Reader, opts *format.Options) error {
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	out, err := format.Source(string(src), opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	changed := !bytes.Equal(src, []byte(out))
+	if *list && changed {
+		fmt.Println(name)
+	}
+	if *write {
+		if !changed {
+			return nil
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, []byte(out), info.Mode().Perm())
+	}
+	if !*list {
+		_, err = io.WriteString(os.Stdout, out)
+	}
+	return err
+}
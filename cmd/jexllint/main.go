@@ -0,0 +1,153 @@
+// Команда jexllint проверяет скрипты JEXL правилами пакета lint.
+//
+//	jexllint [flags] [path ...]
+//
+// Без путей читает стандартный ввод. Каталоги обходятся рекурсивно,
+// проверяются файлы *.jexl. Код выхода 1 означает найденные сообщения,
+// 2 - ошибку чтения или неверные флаги.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/lint"
+)
+
+var (
+	jsonOut = flag.Bool("json", false, "print issues as a JSON array")
+	disable = flag.String("disable", "", "comma-separated rule IDs to disable")
+	deny    = flag.String("deny", "", "comma-separated features disabled by policy: "+strings.Join(featureNames(), ", "))
+	rules   = flag.Bool("rules", false, "list available rules and exit")
+)
+
+// features - возможности, которые можно запретить флагом -deny.
+var features = []struct {
+	name    string
+	feature jexl.Feature
+}{
+	{"loop", jexl.FeatureLoop},
+	{"lambda", jexl.FeatureLambda},
+	{"local-var", jexl.FeatureLocalVar},
+	{"side-effect", jexl.FeatureSideEffect},
+	{"side-effect-global", jexl.FeatureSideEffectGlobal},
+	{"method-call", jexl.FeatureMethodCall},
+	{"structured-literal", jexl.FeatureStructuredLiteral},
+	{"pragma", jexl.FeaturePragma},
+}
+
+// featureNames возвращает имена возможностей для -deny.
+func featureNames() []string {
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = f.name
+	}
+	return names
+}
+
+// featureByName находит возможность по имени.
+func featureByName(name string) (jexl.Feature, bool) {
+	for _, f := range features {
+		if f.name == name {
+			return f.feature, true
+		}
+	}
+	return 0, false
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: jexllint [flags] [path ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *rules {
+		for _, rule := range lint.DefaultRules() {
+			fmt.Printf("%-22s %-8v %s\n", rule.ID, rule.Severity, rule.Description)
+		}
+		return
+	}
+
+	config := &lint.Config{}
+	if *disable != "" {
+		config.Disabled = strings.Split(*disable, ",")
+	}
+	if *deny != "" {
+		config.Policy = jexl.FeaturesDefault()
+		for _, name := range strings.Split(*deny, ",") {
+			feature, ok := featureByName(strings.TrimSpace(name))
+			if !ok {
+				fmt.Fprintf(os.Stderr, "jexllint: unknown feature %q\n", name)
+				os.Exit(2)
+			}
+			config.Policy = config.Policy.Without(feature)
+		}
+	}
+
+	var issues []lint.Issue
+	failed := false
+	if flag.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		issues = check("<standard input>", string(src), config)
+	}
+	for _, path := range flag.Args() {
+		err := filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Явно указанный файл проверяется независимо от расширения
+			if entry.IsDir() || file != path && filepath.Ext(file) != ".jexl" {
+				return nil
+			}
+			src, err := os.ReadFile(file)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				failed = true
+				return nil
+			}
+			issues = append(issues, check(file, string(src), config)...)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+	}
+
+	if *jsonOut {
+		if err := lint.WriteJSON(os.Stdout, issues); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+	}
+	switch {
+	case failed:
+		os.Exit(2)
+	case len(issues) > 0:
+		os.Exit(1)
+	}
+}
+
+// check проверяет исходный текст и подписывает сообщения именем файла.
+func check(name, src string, config *lint.Config) []lint.Issue {
+	issues := lint.Source(src, config)
+	for i := range issues {
+		issues[i].File = name
+	}
+	return issues
+}
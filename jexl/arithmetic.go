@@ -2,10 +2,12 @@ package jexl
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // Arithmetic определяет операции, используемые движком.
@@ -18,7 +20,9 @@ type Arithmetic interface {
 	Subtract(a, b any) (any, error)
 	Multiply(a, b any) (any, error)
 	Divide(a, b any) (any, error)
+	IntegerDivide(a, b any) (any, error)
 	Modulo(a, b any) (any, error)
+	Power(a, b any) (any, error)
 	Negate(value any) (any, error)
 	ToBoolean(value any) (bool, error)
 	// Битовые операции
@@ -36,6 +40,9 @@ type Arithmetic interface {
 	EndsWith(a, b any) (any, error)
 	// Range операция
 	CreateRange(left, right any) (any, error)
+	// Операторы принадлежности и проверки типа
+	In(value, container any) (any, error)
+	InstanceOf(value, typ any) (bool, error)
 }
 
 // BaseArithmetic предоставляет простую реализацию с ограниченным функционалом.
@@ -82,6 +89,37 @@ func (a *BaseArithmetic) Compare(lhs, rhs any) (int, error) {
 		return 1, nil // любое не-null значение больше null
 	}
 	
+	// Коллекции JEXL сравниваются только на равенство
+	switch lhs.(type) {
+	case *Set, *Map, *Range:
+		if equals(lhs, rhs) {
+			return 0, nil
+		}
+		return 0, ErrUnordered
+	}
+	switch rhs.(type) {
+	case *Set, *Map:
+		return 0, ErrUnordered
+	case *Range:
+		if equals(lhs, rhs) {
+			return 0, nil
+		}
+		return 0, ErrUnordered
+	}
+	// Списки и мапы Go сравниваются поэлементно, как в equals
+	if lv, rv := reflect.ValueOf(lhs), reflect.ValueOf(rhs); isListValue(lv) || isListValue(rv) ||
+		lv.Kind() == reflect.Map || rv.Kind() == reflect.Map {
+		if equals(lhs, rhs) {
+			return 0, nil
+		}
+		return 0, ErrUnordered
+	}
+
+	// Время и длительности сравниваются между собой
+	if cmp, ok := compareTemporal(lhs, rhs); ok {
+		return cmp, nil
+	}
+
 	// Специальная обработка для bool
 	if lb, ok := lhs.(bool); ok {
 		if rb, ok := rhs.(bool); ok {
@@ -198,12 +236,18 @@ func (a *BaseArithmetic) Add(lhs, rhs any) (any, error) {
 		rs := formatNumber(rhs, hasFloat)
 		return ls + rs, nil
 	}
+	if result, ok, err := a.addCollections(lhs, rhs); ok {
+		return result, err
+	}
 	if rs, ok := rhs.(string); ok {
 		// Проверяем, был ли lhs результатом операции с float
 		hasFloat := isFloatNumber(lhs) || isResultFromFloat(lhs)
 		ls := formatNumber(lhs, hasFloat)
 		return ls + rs, nil
 	}
+	if result, ok, err := addTemporal(lhs, rhs); ok {
+		return result, err
+	}
 	
 	al, ok := toBig(lhs)
 	if !ok {
@@ -218,6 +262,13 @@ func (a *BaseArithmetic) Add(lhs, rhs any) (any, error) {
 
 // Subtract вычитает значения.
 func (a *BaseArithmetic) Subtract(lhs, rhs any) (any, error) {
+	if result, ok, err := subtractTemporal(lhs, rhs); ok {
+		return result, err
+	}
+	if result, ok, err := a.subtractCollections(lhs, rhs); ok {
+		return result, err
+	}
+
 	// Проверяем, является ли один из операндов float
 	hasFloat := isFloatNumber(lhs) || isFloatNumber(rhs)
 	
@@ -277,6 +328,9 @@ func isResultFromFloat(value any) bool {
 
 // Multiply умножает значения.
 func (a *BaseArithmetic) Multiply(lhs, rhs any) (any, error) {
+	if result, ok, err := multiplyTemporal(lhs, rhs); ok {
+		return result, err
+	}
 	al, ok := toBig(lhs)
 	if !ok {
 		return nil, ErrUnsupportedOperand
@@ -290,6 +344,9 @@ func (a *BaseArithmetic) Multiply(lhs, rhs any) (any, error) {
 
 // Divide делит значения.
 func (a *BaseArithmetic) Divide(lhs, rhs any) (any, error) {
+	if result, ok, err := divideTemporal(lhs, rhs); ok {
+		return result, err
+	}
 	al, ok := toBig(lhs)
 	if !ok {
 		return nil, ErrUnsupportedOperand
@@ -305,6 +362,24 @@ func (a *BaseArithmetic) Divide(lhs, rhs any) (any, error) {
 	return new(big.Rat).Quo(al, ar), nil
 }
 
+// IntegerDivide делит значения нацело (оператор div). Частное округляется
+// к нулю, как и остаток в Modulo: (a div b) * b + a % b == a.
+func (a *BaseArithmetic) IntegerDivide(lhs, rhs any) (any, error) {
+	al, ok := toBig(lhs)
+	if !ok {
+		return nil, ErrUnsupportedOperand
+	}
+	ar, ok := toBig(rhs)
+	if !ok {
+		return nil, ErrUnsupportedOperand
+	}
+	if ar.Sign() == 0 {
+		return nil, NewError("division by zero")
+	}
+	q := new(big.Rat).Quo(al, ar)
+	return new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom())), nil
+}
+
 // Modulo вычисляет остаток.
 func (a *BaseArithmetic) Modulo(lhs, rhs any) (any, error) {
 	al, ok := toBig(lhs)
@@ -325,8 +400,54 @@ func (a *BaseArithmetic) Modulo(lhs, rhs any) (any, error) {
 	return big.NewRat(alInt%arInt, 1), nil
 }
 
+// Power возводит lhs в степень rhs (оператор **).
+func (a *BaseArithmetic) Power(lhs, rhs any) (any, error) {
+	al, ok := toBig(lhs)
+	if !ok {
+		return nil, ErrUnsupportedOperand
+	}
+	ar, ok := toBig(rhs)
+	if !ok {
+		return nil, ErrUnsupportedOperand
+	}
+	return powerRat(al, ar)
+}
+
+// maxExactBits ограничивает размер в битах числителя и знаменателя степени,
+// которая считается точно.
+const maxExactBits = 1 << 20
+
+// powerRat возводит x в степень y. Целая степень считается точно в big.Rat,
+// если результат не больше maxExactBits, иначе - через float64.
+func powerRat(x, y *big.Rat) (*big.Rat, error) {
+	bits := int64(max(x.Num().BitLen(), x.Denom().BitLen()))
+	if y.IsInt() && y.Num().IsInt64() && (bits <= 1 || new(big.Int).Abs(y.Num()).Cmp(big.NewInt(maxExactBits/bits)) <= 0) {
+		n := y.Num().Int64()
+		if n < 0 && x.Sign() == 0 {
+			return nil, NewError("division by zero")
+		}
+		exp := new(big.Int).Abs(y.Num())
+		num := new(big.Int).Exp(x.Num(), exp, nil)
+		den := new(big.Int).Exp(x.Denom(), exp, nil)
+		if n < 0 {
+			num, den = den, num
+		}
+		return new(big.Rat).SetFrac(num, den), nil
+	}
+	xf, _ := x.Float64()
+	yf, _ := y.Float64()
+	result := math.Pow(xf, yf)
+	if math.IsNaN(result) || math.IsInf(result, 0) {
+		return nil, NewError(fmt.Sprintf("power %s ** %s is not a finite number", x.RatString(), y.RatString()))
+	}
+	return new(big.Rat).SetFloat64(result), nil
+}
+
 // Negate возвращает противоположное значение.
 func (a *BaseArithmetic) Negate(value any) (any, error) {
+	if d, ok := asDuration(value); ok {
+		return -d, nil
+	}
 	v, ok := toBig(value)
 	if !ok {
 		return nil, ErrUnsupportedOperand
@@ -360,6 +481,17 @@ func (a *BaseArithmetic) ToBoolean(value any) (bool, error) {
 		return len(v) > 0, nil
 	case map[string]any:
 		return len(v) > 0, nil
+	case *Map:
+		return !v.IsEmpty(), nil
+	case *Set:
+		return !v.IsEmpty(), nil
+	case *Range:
+		return !v.IsEmpty(), nil
+	case time.Time:
+		// Нулевое время (time.Time{}) ложно и пусто
+		return !v.IsZero(), nil
+	case time.Duration:
+		return v != 0, nil
 	default:
 		// Для неизвестных типов пробуем преобразовать через toBig
 		if rat, ok := toBig(v); ok {
@@ -375,10 +507,16 @@ var (
 	ErrUnsupportedOperand = NewError("unsupported operand type")
 	// ErrUnsupportedOperation сигнализирует о неподдерживаемой операции.
 	ErrUnsupportedOperation = NewError("unsupported operation")
+	// ErrUnordered сигнализирует, что неравные значения не упорядочены: их можно
+	// сравнить только на равенство.
+	ErrUnordered = NewError("values are not ordered")
 )
 
 // BitwiseAnd выполняет побитовую операцию AND.
 func (a *BaseArithmetic) BitwiseAnd(lhs, rhs any) (any, error) {
+	if result, ok, err := a.intersectSets(lhs, rhs); ok {
+		return result, err
+	}
 	left, ok := toInt64(lhs)
 	if !ok {
 		return nil, ErrUnsupportedOperand
@@ -605,28 +743,48 @@ func toUint64(value any) (uint64, bool) {
 // Для строк: проверяет соответствие регулярному выражению или подстроке
 // Для коллекций: проверяет наличие элемента
 func (a *BaseArithmetic) Contains(lhs, rhs any) (any, error) {
-	// Если lhs - строка, проверяем соответствие rhs (регулярное выражение или подстрока)
-	if ls, ok := lhs.(string); ok {
-		rs := fmt.Sprintf("%v", rhs)
-		// Пробуем как регулярное выражение
-		if matched, err := regexp.MatchString(rs, ls); err == nil && matched {
+	// Скомпилированное регулярное выражение с любой стороны
+	if re, ok := rhs.(*regexp.Regexp); ok && re != nil {
+		if lhs == nil {
+			return false, nil
+		}
+		return re.MatchString(fmt.Sprintf("%v", lhs)), nil
+	}
+	if re, ok := lhs.(*regexp.Regexp); ok && re != nil {
+		if rhs == nil {
+			return false, nil
+		}
+		return re.MatchString(fmt.Sprintf("%v", rhs)), nil
+	}
+
+	// Множество содержит элемент или все элементы другого множества, мапа - ключ
+	switch c := lhs.(type) {
+	case *Set:
+		if other, ok := rhs.(*Set); ok {
+			for _, item := range other.items {
+				if !c.Contains(item) {
+					return false, nil
+				}
+			}
 			return true, nil
 		}
-		// Если не регулярное выражение, проверяем как подстроку
-		return strings.Contains(ls, rs), nil
+		return c.Contains(rhs), nil
+	case *Map:
+		return c.Has(rhs), nil
+	case *Range:
+		return c.Contains(rhs), nil
 	}
-	
+
+	// Если lhs - строка, проверяем соответствие rhs (регулярное выражение или подстрока)
+	if ls, ok := lhs.(string); ok {
+		return a.matchPattern(ls, fmt.Sprintf("%v", rhs))
+	}
+
 	// Если rhs - строка, а lhs - нет, пробуем обратный порядок
 	if rs, ok := rhs.(string); ok {
-		ls := fmt.Sprintf("%v", lhs)
-		// Пробуем как регулярное выражение
-		if matched, err := regexp.MatchString(rs, ls); err == nil && matched {
-			return true, nil
-		}
-		// Если не регулярное выражение, проверяем как подстроку
-		return strings.Contains(ls, rs), nil
+		return a.matchPattern(fmt.Sprintf("%v", lhs), rs)
 	}
-	
+
 	// Для коллекций проверяем наличие элемента
 	rv := reflect.ValueOf(lhs)
 	switch rv.Kind() {
@@ -663,6 +821,16 @@ func (a *BaseArithmetic) Contains(lhs, rhs any) (any, error) {
 	return strings.Contains(ls, rs), nil
 }
 
+// matchPattern проверяет строку на соответствие регулярному выражению.
+// Шаблоны компилируются один раз; некорректный шаблон - ошибка.
+func (a *BaseArithmetic) matchPattern(s, pattern string) (any, error) {
+	re, err := CompileRegex(pattern)
+	if err != nil {
+		return nil, WrapError("invalid regex '"+pattern+"'", err, nil)
+	}
+	return re.MatchString(s), nil
+}
+
 // ContainsAll проверяет, содержится ли каждый элемент elements в collection.
 // Используется для проверки, является ли один массив подмножеством другого.
 func (a *BaseArithmetic) ContainsAll(collection, elements any) (any, error) {
@@ -708,7 +876,7 @@ func (a *BaseArithmetic) EndsWith(lhs, rhs any) (any, error) {
 }
 
 // CreateRange создаёт range от left до right включительно.
-// Возвращает слайс чисел от left до right.
+// Возвращает ленивый *Range, убывающий при left > right.
 func (a *BaseArithmetic) CreateRange(left, right any) (any, error) {
 	// Преобразуем left и right в числа
 	// Сначала пробуем через toBig для поддержки всех числовых типов
@@ -738,24 +906,12 @@ func (a *BaseArithmetic) CreateRange(left, right any) (any, error) {
 	if !rightRat.IsInt() {
 		return nil, NewError("range right operand must be an integer")
 	}
-	leftNum := leftRat.Num().Int64()
-	rightNum := rightRat.Num().Int64()
-
-	// Создаём range
-	var result []int64
-	if leftNum <= rightNum {
-		// Возрастающий range
-		for i := leftNum; i <= rightNum; i++ {
-			result = append(result, i)
-		}
-	} else {
-		// Убывающий range
-		for i := leftNum; i >= rightNum; i-- {
-			result = append(result, i)
-		}
+	if !leftRat.Num().IsInt64() || !rightRat.Num().IsInt64() {
+		return nil, NewError("range operands must fit in int64")
 	}
 
-	return result, nil
+	// Диапазон ленивый: элементы не материализуются
+	return NewRange(leftRat.Num().Int64(), rightRat.Num().Int64()), nil
 }
 
 // toInteger преобразует значение в int64.
@@ -832,8 +988,49 @@ func equals(a, b any) bool {
 		return false
 	}
 	
-	// Прямое сравнение
-	if a == b {
+	// Прямое сравнение; срезы и мапы Go несравнимы через ==
+	if reflect.TypeOf(a).Comparable() && reflect.TypeOf(b).Comparable() && a == b {
+		return true
+	}
+
+	// Диапазон равен диапазону или списку с теми же элементами
+	if r, ok := a.(*Range); ok {
+		return rangeEquals(r, b)
+	}
+	if r, ok := b.(*Range); ok {
+		return rangeEquals(r, a)
+	}
+
+	// Моменты времени равны независимо от часового пояса
+	if ta, ok := asTime(a); ok {
+		tb, ok := asTime(b)
+		return ok && ta.Equal(tb)
+	}
+
+	// Множества и мапы JEXL равны по содержимому, независимо от порядка
+	switch ca := a.(type) {
+	case *Set:
+		cb, ok := b.(*Set)
+		if !ok || ca.Size() != cb.Size() {
+			return false
+		}
+		for _, item := range ca.items {
+			if !cb.Contains(item) {
+				return false
+			}
+		}
+		return true
+	case *Map:
+		cb, ok := b.(*Map)
+		if !ok || ca.Size() != cb.Size() {
+			return false
+		}
+		for i, key := range ca.keys {
+			value, ok := cb.Get(key)
+			if !ok || !equals(ca.values[i], value) {
+				return false
+			}
+		}
 		return true
 	}
 	
@@ -859,16 +1056,46 @@ func equals(a, b any) bool {
 	// Для других типов пробуем прямое сравнение через reflection
 	va := reflect.ValueOf(a)
 	vb := reflect.ValueOf(b)
+
+	// Списки и мапы Go сравниваются поэлементно
+	if isListValue(va) && isListValue(vb) {
+		if va.Len() != vb.Len() {
+			return false
+		}
+		for i := 0; i < va.Len(); i++ {
+			if !equals(va.Index(i).Interface(), vb.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	}
+	if va.Kind() == reflect.Map && vb.Kind() == reflect.Map {
+		if va.Len() != vb.Len() || !va.Type().Key().AssignableTo(vb.Type().Key()) {
+			return false
+		}
+		for _, key := range va.MapKeys() {
+			value := vb.MapIndex(key)
+			if !value.IsValid() || !equals(va.MapIndex(key).Interface(), value.Interface()) {
+				return false
+			}
+		}
+		return true
+	}
 	
 	// Если типы совпадают, используем прямое сравнение
 	if va.Type() == vb.Type() {
-		return va.Interface() == vb.Interface()
+		return va.Type().Comparable() && va.Interface() == vb.Interface()
 	}
 	
 	// Пробуем преобразовать оба в строки и сравнить
 	return fmt.Sprintf("%v", a) == fmt.Sprintf("%v", b)
 }
 
+// isListValue проверяет, является ли значение срезом или массивом.
+func isListValue(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
 func toBig(value any) (*big.Rat, bool) {
 	// Проверяем, не является ли это floatResult
 	if fr, ok := value.(*floatResult); ok {
@@ -14,11 +14,14 @@ type Node interface {
 	String() string
 	// SourceText возвращает исходный текст узла.
 	SourceText() string
+	// Position возвращает положение узла в исходном тексте.
+	Position() Position
 }
 
 // ScriptNode представляет корневой узел скрипта или выражения.
 // Аналог org.apache.commons.jexl3.parser.ASTJexlScript.
 type ScriptNode struct {
+	span
 	info       *Info
 	children   []Node
 	source     string
@@ -26,6 +29,8 @@ type ScriptNode struct {
 	features   *Features
 	variables  []string
 	parameters []string
+	defaults   []Node
+	rest       bool
 }
 
 // NewScriptNode создаёт новый ScriptNode.
@@ -111,8 +116,30 @@ func (s *ScriptNode) SetParameters(params []string) {
 	s.parameters = params
 }
 
+// ParameterDefaults возвращает выражения значений по умолчанию, параллельные Parameters.
+// nil означает обязательный параметр.
+func (s *ScriptNode) ParameterDefaults() []Node {
+	return s.defaults
+}
+
+// SetParameterDefaults устанавливает выражения значений по умолчанию.
+func (s *ScriptNode) SetParameterDefaults(defaults []Node) {
+	s.defaults = defaults
+}
+
+// HasRestParameter проверяет, собирает ли последний параметр оставшиеся аргументы (...rest).
+func (s *ScriptNode) HasRestParameter() bool {
+	return s.rest
+}
+
+// SetRestParameter отмечает последний параметр как ...rest.
+func (s *ScriptNode) SetRestParameter(rest bool) {
+	s.rest = rest
+}
+
 // LiteralNode представляет литерал (число, строка, bool, null).
 type LiteralNode struct {
+	span
 	value  any
 	source string
 }
@@ -147,6 +174,7 @@ func (l *LiteralNode) Value() any {
 
 // IdentifierNode представляет идентификатор (переменную).
 type IdentifierNode struct {
+	span
 	name   string
 	source string
 }
@@ -161,7 +189,7 @@ func NewIdentifierNode(name, source string) *IdentifierNode {
 
 // Info возвращает информацию об узле (для ошибок).
 func (i *IdentifierNode) Info() *Info {
-	return NewInfoAt(i.source, 1, 1)
+	return NewInfoAt(i.source, i.pos.Line, i.pos.Column)
 }
 
 // Children возвращает пустой список.
@@ -186,6 +214,7 @@ func (i *IdentifierNode) Name() string {
 
 // BinaryOpNode представляет бинарную операцию.
 type BinaryOpNode struct {
+	span
 	op     string
 	left   Node
 	right  Node
@@ -234,6 +263,7 @@ func (b *BinaryOpNode) Right() Node {
 
 // UnaryOpNode представляет унарную операцию.
 type UnaryOpNode struct {
+	span
 	op      string
 	operand Node
 	source  string
@@ -275,6 +305,7 @@ func (u *UnaryOpNode) Operand() Node {
 
 // PropertyAccessNode представляет доступ к свойству объекта (obj.prop).
 type PropertyAccessNode struct {
+	span
 	object   Node
 	property Node
 	source   string
@@ -316,6 +347,7 @@ func (p *PropertyAccessNode) Property() Node {
 
 // IndexAccessNode представляет доступ к элементу массива/мапы (arr[index]).
 type IndexAccessNode struct {
+	span
 	object Node
 	index  Node
 	source string
@@ -357,10 +389,12 @@ func (i *IndexAccessNode) Index() Node {
 
 // MethodCallNode представляет вызов метода или функции (obj.method(args) или func(args)).
 type MethodCallNode struct {
-	target Node // может быть nil для функций верхнего уровня
-	method Node // имя метода или функции
-	args   []Node
-	source string
+	span
+	target    Node   // может быть nil для функций верхнего уровня
+	namespace string // пространство имён для вызова ns:func(args)
+	method    Node   // имя метода или функции
+	args      []Node
+	source    string
 }
 
 // NewMethodCallNode создаёт новый MethodCallNode.
@@ -373,6 +407,16 @@ func NewMethodCallNode(target, method Node, args []Node, source string) *MethodC
 	}
 }
 
+// NewNamespaceCallNode создаёт MethodCallNode для вызова функции пространства имён ns:func(args).
+func NewNamespaceCallNode(namespace string, method Node, args []Node, source string) *MethodCallNode {
+	return &MethodCallNode{
+		namespace: namespace,
+		method:    method,
+		args:      args,
+		source:    source,
+	}
+}
+
 // Children возвращает дочерние узлы.
 func (m *MethodCallNode) Children() []Node {
 	children := []Node{}
@@ -399,6 +443,11 @@ func (m *MethodCallNode) Target() Node {
 	return m.target
 }
 
+// Namespace возвращает пространство имён вызова ns:func(args) или пустую строку.
+func (m *MethodCallNode) Namespace() string {
+	return m.namespace
+}
+
 // Method возвращает имя метода или функции.
 func (m *MethodCallNode) Method() Node {
 	return m.method
@@ -411,6 +460,7 @@ func (m *MethodCallNode) Args() []Node {
 
 // AssignmentNode представляет присваивание (target = value).
 type AssignmentNode struct {
+	span
 	target Node
 	value  Node
 	source string
@@ -452,6 +502,7 @@ func (a *AssignmentNode) Value() Node {
 
 // TernaryNode представляет тернарный оператор (condition ? trueExpr : falseExpr).
 type TernaryNode struct {
+	span
 	condition Node
 	trueExpr  Node
 	falseExpr Node
@@ -498,10 +549,12 @@ func (t *TernaryNode) FalseExpr() Node {
 	return t.falseExpr
 }
 
-// RangeNode представляет range оператор (left .. right).
+// RangeNode представляет range оператор (left .. right или left .. right step n).
 type RangeNode struct {
+	span
 	left   Node
 	right  Node
+	step   Node
 	source string
 }
 
@@ -514,8 +567,21 @@ func NewRangeNode(left, right Node, source string) *RangeNode {
 	}
 }
 
+// NewRangeStepNode создаёт RangeNode с явным шагом.
+func NewRangeStepNode(left, right, step Node, source string) *RangeNode {
+	return &RangeNode{
+		left:   left,
+		right:  right,
+		step:   step,
+		source: source,
+	}
+}
+
 // Children возвращает дочерние узлы.
 func (r *RangeNode) Children() []Node {
+	if r.step != nil {
+		return []Node{r.left, r.right, r.step}
+	}
 	return []Node{r.left, r.right}
 }
 
@@ -539,8 +605,14 @@ func (r *RangeNode) Right() Node {
 	return r.right
 }
 
+// Step возвращает выражение шага или nil.
+func (r *RangeNode) Step() Node {
+	return r.step
+}
+
 // ElvisNode представляет Elvis оператор (expr ?: defaultExpr).
 type ElvisNode struct {
+	span
 	expr        Node
 	defaultExpr Node
 	source      string
@@ -580,8 +652,53 @@ func (e *ElvisNode) DefaultExpr() Node {
 	return e.defaultExpr
 }
 
+// PipeNode представляет оператор конвейера (value |> target).
+// Значение передаётся первым аргументом вызову target или единственным аргументом
+// функции, лямбде или функции пространства имён, записанной без скобок (str:trim).
+type PipeNode struct {
+	span
+	value  Node
+	target Node
+	source string
+}
+
+// NewPipeNode создаёт новый PipeNode.
+func NewPipeNode(value, target Node, source string) *PipeNode {
+	return &PipeNode{
+		value:  value,
+		target: target,
+		source: source,
+	}
+}
+
+// Children возвращает дочерние узлы.
+func (p *PipeNode) Children() []Node {
+	return []Node{p.value, p.target}
+}
+
+// String возвращает строковое представление.
+func (p *PipeNode) String() string {
+	return p.source
+}
+
+// SourceText возвращает исходный текст.
+func (p *PipeNode) SourceText() string {
+	return p.source
+}
+
+// Value возвращает передаваемое значение.
+func (p *PipeNode) Value() Node {
+	return p.value
+}
+
+// Target возвращает получателя значения.
+func (p *PipeNode) Target() Node {
+	return p.target
+}
+
 // ArrayLiteralNode представляет литерал массива [1, 2, 3].
 type ArrayLiteralNode struct {
+	span
 	elements []Node
 	source   string
 }
@@ -622,6 +739,7 @@ type MapEntry struct {
 
 // MapLiteralNode представляет литерал мапы {key: value, ...}.
 type MapLiteralNode struct {
+	span
 	entries []MapEntry
 	source  string
 }
@@ -660,6 +778,7 @@ func (m *MapLiteralNode) Entries() []MapEntry {
 
 // SetLiteralNode представляет литерал множества {1, 2, 3}.
 type SetLiteralNode struct {
+	span
 	elements []Node
 	source   string
 }
@@ -694,6 +813,7 @@ func (s *SetLiteralNode) Elements() []Node {
 
 // IfNode представляет условный оператор if/else.
 type IfNode struct {
+	span
 	condition Node
 	thenBranch Node
 	elseBranch Node
@@ -746,6 +866,7 @@ func (i *IfNode) ElseBranch() Node {
 
 // ForNode представляет цикл for (init; condition; step) body.
 type ForNode struct {
+	span
 	init      Node
 	condition Node
 	step      Node
@@ -810,8 +931,10 @@ func (f *ForNode) Body() Node {
 	return f.body
 }
 
-// ForeachNode представляет цикл foreach (var x : items) body.
+// ForeachNode представляет цикл foreach (var x : items) body или (var k, v : items) body.
 type ForeachNode struct {
+	span
+	key      Node
 	variable Node
 	items    Node
 	body     Node
@@ -828,8 +951,22 @@ func NewForeachNode(variable, items, body Node, source string) *ForeachNode {
 	}
 }
 
+// NewForeachKeyValueNode создаёт ForeachNode с переменными ключа и значения: for (var k, v : items).
+func NewForeachKeyValueNode(key, variable, items, body Node, source string) *ForeachNode {
+	return &ForeachNode{
+		key:      key,
+		variable: variable,
+		items:    items,
+		body:     body,
+		source:   source,
+	}
+}
+
 // Children возвращает дочерние узлы.
 func (f *ForeachNode) Children() []Node {
+	if f.key != nil {
+		return []Node{f.key, f.variable, f.items, f.body}
+	}
 	return []Node{f.variable, f.items, f.body}
 }
 
@@ -843,11 +980,16 @@ func (f *ForeachNode) SourceText() string {
 	return f.source
 }
 
-// Variable возвращает переменную цикла.
+// Variable возвращает переменную цикла (значение элемента).
 func (f *ForeachNode) Variable() Node {
 	return f.variable
 }
 
+// Key возвращает переменную ключа (индекса) или nil для формы с одной переменной.
+func (f *ForeachNode) Key() Node {
+	return f.key
+}
+
 // Items возвращает коллекцию для итерации.
 func (f *ForeachNode) Items() Node {
 	return f.items
@@ -860,6 +1002,7 @@ func (f *ForeachNode) Body() Node {
 
 // WhileNode представляет цикл while (condition) body.
 type WhileNode struct {
+	span
 	condition Node
 	body      Node
 	source    string
@@ -901,6 +1044,7 @@ func (w *WhileNode) Body() Node {
 
 // DoWhileNode представляет цикл do body while (condition).
 type DoWhileNode struct {
+	span
 	condition Node
 	body      Node
 	source    string
@@ -942,6 +1086,7 @@ func (d *DoWhileNode) Body() Node {
 
 // BlockNode представляет блок кода { statements }.
 type BlockNode struct {
+	span
 	statements []Node
 	source     string
 }
@@ -976,6 +1121,7 @@ func (b *BlockNode) Statements() []Node {
 
 // BreakNode представляет оператор break.
 type BreakNode struct {
+	span
 	source string
 }
 
@@ -1001,6 +1147,7 @@ func (b *BreakNode) SourceText() string {
 
 // ContinueNode представляет оператор continue.
 type ContinueNode struct {
+	span
 	source string
 }
 
@@ -1026,6 +1173,7 @@ func (c *ContinueNode) SourceText() string {
 
 // ReturnNode представляет оператор return.
 type ReturnNode struct {
+	span
 	value  Node
 	source string
 }
@@ -1063,6 +1211,7 @@ func (r *ReturnNode) Value() Node {
 
 // VarNode представляет объявление переменной var x или var x = value.
 type VarNode struct {
+	span
 	name   *IdentifierNode
 	value  Node
 	source string
@@ -1105,10 +1254,187 @@ func (v *VarNode) Value() Node {
 	return v.value
 }
 
+// ArrayPatternNode представляет шаблон деструктуризации массива [a, b = 1, , ...rest].
+type ArrayPatternNode struct {
+	span
+	elements []Node // IdentifierNode, вложенный шаблон или nil (пропуск элемента)
+	defaults []Node // значения по умолчанию, параллельные elements
+	rest     *IdentifierNode
+	source   string
+}
+
+// NewArrayPatternNode создаёт новый ArrayPatternNode.
+func NewArrayPatternNode(elements, defaults []Node, rest *IdentifierNode, source string) *ArrayPatternNode {
+	return &ArrayPatternNode{
+		elements: elements,
+		defaults: defaults,
+		rest:     rest,
+		source:   source,
+	}
+}
+
+// Children возвращает дочерние узлы (элементы, значения по умолчанию и rest).
+func (a *ArrayPatternNode) Children() []Node {
+	return patternChildren(a.elements, a.defaults, a.rest)
+}
+
+// String возвращает строковое представление.
+func (a *ArrayPatternNode) String() string {
+	return a.source
+}
+
+// SourceText возвращает исходный текст.
+func (a *ArrayPatternNode) SourceText() string {
+	return a.source
+}
+
+// Elements возвращает цели элементов (nil - пропущенный элемент).
+func (a *ArrayPatternNode) Elements() []Node {
+	return a.elements
+}
+
+// Defaults возвращает значения по умолчанию (nil - без значения по умолчанию).
+func (a *ArrayPatternNode) Defaults() []Node {
+	return a.defaults
+}
+
+// Rest возвращает переменную для оставшихся элементов (может быть nil).
+func (a *ArrayPatternNode) Rest() *IdentifierNode {
+	return a.rest
+}
+
+// MapPatternNode представляет шаблон деструктуризации мапы или объекта {name, age: years = 0, ...others}.
+type MapPatternNode struct {
+	span
+	keys     []string
+	targets  []Node // IdentifierNode или вложенный шаблон
+	defaults []Node // значения по умолчанию, параллельные keys
+	rest     *IdentifierNode
+	source   string
+}
+
+// NewMapPatternNode создаёт новый MapPatternNode.
+func NewMapPatternNode(keys []string, targets, defaults []Node, rest *IdentifierNode, source string) *MapPatternNode {
+	return &MapPatternNode{
+		keys:     keys,
+		targets:  targets,
+		defaults: defaults,
+		rest:     rest,
+		source:   source,
+	}
+}
+
+// Children возвращает дочерние узлы (цели, значения по умолчанию и rest).
+func (m *MapPatternNode) Children() []Node {
+	return patternChildren(m.targets, m.defaults, m.rest)
+}
+
+// String возвращает строковое представление.
+func (m *MapPatternNode) String() string {
+	return m.source
+}
+
+// SourceText возвращает исходный текст.
+func (m *MapPatternNode) SourceText() string {
+	return m.source
+}
+
+// Keys возвращает ключи (имена свойств).
+func (m *MapPatternNode) Keys() []string {
+	return m.keys
+}
+
+// Targets возвращает цели, параллельные ключам.
+func (m *MapPatternNode) Targets() []Node {
+	return m.targets
+}
+
+// Defaults возвращает значения по умолчанию (nil - без значения по умолчанию).
+func (m *MapPatternNode) Defaults() []Node {
+	return m.defaults
+}
+
+// Rest возвращает переменную для оставшихся свойств (может быть nil).
+func (m *MapPatternNode) Rest() *IdentifierNode {
+	return m.rest
+}
+
+// patternChildren собирает дочерние узлы шаблона деструктуризации.
+func patternChildren(targets, defaults []Node, rest *IdentifierNode) []Node {
+	children := make([]Node, 0, len(targets)+len(defaults)+1)
+	for _, target := range targets {
+		if target != nil {
+			children = append(children, target)
+		}
+	}
+	for _, def := range defaults {
+		if def != nil {
+			children = append(children, def)
+		}
+	}
+	if rest != nil {
+		children = append(children, rest)
+	}
+	return children
+}
+
+// DestructuringNode представляет деструктурирующее объявление или присваивание:
+// var [lo, hi] = expr или {name, age} = expr.
+type DestructuringNode struct {
+	span
+	pattern     Node
+	value       Node
+	declaration bool
+	source      string
+}
+
+// NewDestructuringNode создаёт новый DestructuringNode.
+func NewDestructuringNode(pattern, value Node, declaration bool, source string) *DestructuringNode {
+	return &DestructuringNode{
+		pattern:     pattern,
+		value:       value,
+		declaration: declaration,
+		source:      source,
+	}
+}
+
+// Children возвращает дочерние узлы (шаблон и значение).
+func (d *DestructuringNode) Children() []Node {
+	return []Node{d.pattern, d.value}
+}
+
+// String возвращает строковое представление.
+func (d *DestructuringNode) String() string {
+	return d.source
+}
+
+// SourceText возвращает исходный текст.
+func (d *DestructuringNode) SourceText() string {
+	return d.source
+}
+
+// Pattern возвращает шаблон (ArrayPatternNode или MapPatternNode).
+func (d *DestructuringNode) Pattern() Node {
+	return d.pattern
+}
+
+// Value возвращает деструктурируемое выражение.
+func (d *DestructuringNode) Value() Node {
+	return d.value
+}
+
+// IsDeclaration проверяет, является ли узел объявлением (var).
+func (d *DestructuringNode) IsDeclaration() bool {
+	return d.declaration
+}
+
 // LambdaNode представляет lambda функцию (x, y) -> x + y или (x, y) => x + y.
 // Аналог org.apache.commons.jexl3.parser.ASTJexlLambda.
 type LambdaNode struct {
+	span
 	parameters []*IdentifierNode
+	defaults   []Node
+	rest       bool
 	body       Node
 	source     string
 }
@@ -1122,12 +1448,29 @@ func NewLambdaNode(parameters []*IdentifierNode, body Node, source string) *Lamb
 	}
 }
 
-// Children возвращает дочерние узлы (параметры и тело).
+// NewLambdaNodeWithSignature создаёт LambdaNode с значениями по умолчанию и ...rest параметром.
+// defaults параллелен parameters, nil означает обязательный параметр.
+func NewLambdaNodeWithSignature(parameters []*IdentifierNode, defaults []Node, rest bool, body Node, source string) *LambdaNode {
+	return &LambdaNode{
+		parameters: parameters,
+		defaults:   defaults,
+		rest:       rest,
+		body:       body,
+		source:     source,
+	}
+}
+
+// Children возвращает дочерние узлы (параметры, значения по умолчанию и тело).
 func (l *LambdaNode) Children() []Node {
 	children := make([]Node, 0, len(l.parameters)+1)
 	for _, param := range l.parameters {
 		children = append(children, param)
 	}
+	for _, def := range l.defaults {
+		if def != nil {
+			children = append(children, def)
+		}
+	}
 	if l.body != nil {
 		children = append(children, l.body)
 	}
@@ -1149,14 +1492,68 @@ func (l *LambdaNode) Parameters() []*IdentifierNode {
 	return l.parameters
 }
 
+// Defaults возвращает значения параметров по умолчанию (nil - обязательный параметр).
+func (l *LambdaNode) Defaults() []Node {
+	return l.defaults
+}
+
+// HasRest проверяет, собирает ли последний параметр оставшиеся аргументы.
+func (l *LambdaNode) HasRest() bool {
+	return l.rest
+}
+
 // Body возвращает тело lambda функции.
 func (l *LambdaNode) Body() Node {
 	return l.body
 }
 
+// FunctionNode представляет объявление именованной функции function name(x, y) { ... }.
+// Объявления поднимаются (hoisting) в пределах скрипта или блока.
+type FunctionNode struct {
+	span
+	name   *IdentifierNode
+	lambda *LambdaNode
+	source string
+}
+
+// NewFunctionNode создаёт новый FunctionNode.
+func NewFunctionNode(name *IdentifierNode, lambda *LambdaNode, source string) *FunctionNode {
+	return &FunctionNode{
+		name:   name,
+		lambda: lambda,
+		source: source,
+	}
+}
+
+// Children возвращает дочерние узлы (имя и lambda).
+func (f *FunctionNode) Children() []Node {
+	return []Node{f.name, f.lambda}
+}
+
+// String возвращает строковое представление.
+func (f *FunctionNode) String() string {
+	return f.source
+}
+
+// SourceText возвращает исходный текст.
+func (f *FunctionNode) SourceText() string {
+	return f.source
+}
+
+// Name возвращает имя функции.
+func (f *FunctionNode) Name() *IdentifierNode {
+	return f.name
+}
+
+// Lambda возвращает lambda с параметрами и телом функции.
+func (f *FunctionNode) Lambda() *LambdaNode {
+	return f.lambda
+}
+
 // SwitchNode представляет switch statement или expression.
 // Аналог org.apache.commons.jexl3.parser.ASTSwitchStatement.
 type SwitchNode struct {
+	span
 	expression   Node
 	cases        []*CaseNode
 	isStatement  bool
@@ -1293,6 +1690,7 @@ func (s *SwitchNode) SwitchIndex(value any, arithmetic Arithmetic) int {
 // CaseNode представляет case в switch statement или expression.
 // Аналог org.apache.commons.jexl3.parser.ASTCaseStatement и ASTCaseExpression.
 type CaseNode struct {
+	span
 	values []any // Значения для case (пустой список означает default)
 	body   Node  // Тело case
 	source string
@@ -1343,11 +1741,13 @@ func (c *CaseNode) Body() Node {
 // TryNode представляет try/catch/finally statement.
 // Аналог org.apache.commons.jexl3.parser.ASTTryStatement.
 type TryNode struct {
-	tryBlock    Node   // Блок try
-	catchVar    string // Имя переменной для catch (может быть пустым)
-	catchBlock  Node   // Блок catch (может быть nil)
-	finallyBlock Node  // Блок finally (может быть nil)
-	source      string
+	span
+	resources    []Node // Ресурсы try (var x = expr или идентификатор), закрываются в обратном порядке
+	tryBlock     Node   // Блок try
+	catchVar     string // Имя переменной для catch (может быть пустым)
+	catchBlock   Node   // Блок catch (может быть nil)
+	finallyBlock Node   // Блок finally (может быть nil)
+	source       string
 }
 
 // NewTryNode создаёт новый TryNode.
@@ -1361,9 +1761,18 @@ func NewTryNode(tryBlock Node, catchVar string, catchBlock, finallyBlock Node, s
 	}
 }
 
-// Children возвращает дочерние узлы (try, catch, finally блоки).
+// NewTryResourcesNode создаёт TryNode с ресурсами: try (var r = expr; ...) { ... }.
+// Аналог org.apache.commons.jexl3.parser.ASTTryResources.
+func NewTryResourcesNode(resources []Node, tryBlock Node, catchVar string, catchBlock, finallyBlock Node, source string) *TryNode {
+	node := NewTryNode(tryBlock, catchVar, catchBlock, finallyBlock, source)
+	node.resources = resources
+	return node
+}
+
+// Children возвращает дочерние узлы (ресурсы, try, catch, finally блоки).
 func (t *TryNode) Children() []Node {
-	children := make([]Node, 0, 3)
+	children := make([]Node, 0, len(t.resources)+3)
+	children = append(children, t.resources...)
 	if t.tryBlock != nil {
 		children = append(children, t.tryBlock)
 	}
@@ -1386,6 +1795,16 @@ func (t *TryNode) SourceText() string {
 	return t.source
 }
 
+// Resources возвращает ресурсы try (может быть пустым).
+func (t *TryNode) Resources() []Node {
+	return t.resources
+}
+
+// HasResources возвращает true, если try объявляет ресурсы.
+func (t *TryNode) HasResources() bool {
+	return len(t.resources) > 0
+}
+
 // TryBlock возвращает блок try.
 func (t *TryNode) TryBlock() Node {
 	return t.tryBlock
@@ -0,0 +1,532 @@
+package jexl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+)
+
+// Теги значений двоичного формата.
+const (
+	astValueNil byte = iota
+	astValueFalse
+	astValueTrue
+	astValueString
+	astValueInt
+	astValueInt64
+	astValueFloat64
+	astValueBigInt
+	astValueRat
+	astValueRegex
+	astValueList
+	astValueMap
+)
+
+// MarshalASTBinary сериализует AST скрипта в компактный двоичный формат:
+// сигнатура "JXAST", версия формата и узлы в порядке обхода. Повторяющиеся
+// строки (имена, операторы, исходный текст) записываются ссылками на первое
+// вхождение. Данные читает UnmarshalAST.
+func MarshalASTBinary(ast *ScriptNode) ([]byte, error) {
+	if ast == nil {
+		return nil, errors.New("script AST is nil")
+	}
+	e := &astBinaryEncoder{strings: map[string]uint64{}}
+	e.buf = append(e.buf, astBinaryMagic...)
+	e.uint(ASTFormatVersion)
+	e.node(ast)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf, nil
+}
+
+// astBinaryEncoder пишет узлы в двоичном формате.
+type astBinaryEncoder struct {
+	buf     []byte
+	strings map[string]uint64 // номера уже записанных строк, с 1
+	err     error
+}
+
+func (e *astBinaryEncoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *astBinaryEncoder) decoding() bool { return false }
+
+func (e *astBinaryEncoder) uint(n uint64) {
+	e.buf = binary.AppendUvarint(e.buf, n)
+}
+
+func (e *astBinaryEncoder) int(n int) {
+	e.uint(uint64(n))
+}
+
+// string пишет ссылку на строку: 0 и текст для новой строки, иначе её номер.
+func (e *astBinaryEncoder) string(s string) {
+	if ref, ok := e.strings[s]; ok {
+		e.uint(ref)
+		return
+	}
+	e.strings[s] = uint64(len(e.strings) + 1)
+	e.uint(0)
+	e.int(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+// length пишет длину списка; 0 означает nil.
+func (e *astBinaryEncoder) length(n int, isNil bool) {
+	if isNil {
+		e.uint(0)
+		return
+	}
+	e.int(n + 1)
+}
+
+// node пишет код вида узла (0 - nil), поля и положение.
+func (e *astBinaryEncoder) node(node Node) {
+	if isNilNode(node) {
+		e.uint(0)
+		return
+	}
+	kind := slices.Index(astKinds, nodeKind(node))
+	if kind < 0 {
+		e.fail(fmt.Errorf("unsupported node type %T", node))
+		return
+	}
+	e.int(kind + 1)
+	describeNode(node, e)
+	pos := nodePosition(node)
+	if !pos.IsValid() {
+		e.uint(0)
+		return
+	}
+	for _, n := range []int{pos.Line, pos.Column, pos.EndLine, pos.EndColumn, pos.Offset, pos.EndOffset} {
+		e.int(n)
+	}
+}
+
+func (e *astBinaryEncoder) child(_ string, n *Node, _ bool) {
+	e.node(*n)
+}
+
+func (e *astBinaryEncoder) children(_ string, ns *[]Node) {
+	e.length(len(*ns), *ns == nil)
+	for _, node := range *ns {
+		e.node(node)
+	}
+}
+
+func (e *astBinaryEncoder) str(_ string, s *string) {
+	e.string(*s)
+}
+
+func (e *astBinaryEncoder) strs(_ string, s *[]string) {
+	e.stringList(*s)
+}
+
+func (e *astBinaryEncoder) stringList(s []string) {
+	e.length(len(s), s == nil)
+	for _, item := range s {
+		e.string(item)
+	}
+}
+
+func (e *astBinaryEncoder) flag(_ string, b *bool) {
+	if *b {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *astBinaryEncoder) value(name string, v *any) {
+	if err := checkASTValue(*v); err != nil {
+		e.fail(fmt.Errorf("%s: %w", name, err))
+		return
+	}
+	e.literal(*v)
+}
+
+// literal пишет тег и проверенное checkASTValue значение.
+func (e *astBinaryEncoder) literal(value any) {
+	switch v := value.(type) {
+	case nil:
+		e.buf = append(e.buf, astValueNil)
+	case bool:
+		if v {
+			e.buf = append(e.buf, astValueTrue)
+		} else {
+			e.buf = append(e.buf, astValueFalse)
+		}
+	case string:
+		e.buf = append(e.buf, astValueString)
+		e.string(v)
+	case int:
+		e.buf = append(e.buf, astValueInt)
+		e.buf = binary.AppendVarint(e.buf, int64(v))
+	case int64:
+		e.buf = append(e.buf, astValueInt64)
+		e.buf = binary.AppendVarint(e.buf, v)
+	case float64:
+		e.buf = append(e.buf, astValueFloat64)
+		e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+	case *big.Int:
+		e.buf = append(e.buf, astValueBigInt)
+		e.string(v.String())
+	case *big.Rat:
+		e.buf = append(e.buf, astValueRat)
+		e.string(v.String())
+	case *regexp.Regexp:
+		e.buf = append(e.buf, astValueRegex)
+		e.string(v.String())
+	case []any:
+		e.buf = append(e.buf, astValueList)
+		e.int(len(v))
+		for _, item := range v {
+			e.literal(item)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		e.buf = append(e.buf, astValueMap)
+		e.int(len(keys))
+		for _, key := range keys {
+			e.string(key)
+			e.literal(v[key])
+		}
+	}
+}
+
+func (e *astBinaryEncoder) info(_ string, info **Info) {
+	if *info == nil {
+		e.buf = append(e.buf, 0)
+		return
+	}
+	e.buf = append(e.buf, 1)
+	e.string((*info).name)
+	e.int((*info).line)
+	e.int((*info).column)
+}
+
+func (e *astBinaryEncoder) features(_ string, features **Features) {
+	f := *features
+	if f == nil {
+		e.buf = append(e.buf, 0)
+		return
+	}
+	e.buf = append(e.buf, 1)
+	e.uint(f.flags)
+	e.stringList(f.reserved)
+	e.stringList(f.namespaceSet)
+}
+
+// unmarshalASTBinary восстанавливает AST из данных MarshalASTBinary без сигнатуры.
+func unmarshalASTBinary(data []byte) (*ScriptNode, error) {
+	d := &astBinaryDecoder{data: data}
+	version := d.uint()
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid AST data: %w", d.err)
+	}
+	if err := checkASTVersion(version); err != nil {
+		return nil, err
+	}
+	node := d.node()
+	if d.err == nil && len(d.data) > 0 {
+		d.fail(errors.New("unexpected data after AST"))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid AST data: %w", d.err)
+	}
+	script, ok := node.(*ScriptNode)
+	if !ok {
+		return nil, fmt.Errorf("invalid AST data: root is %s, not Script", nodeKind(node))
+	}
+	return script, nil
+}
+
+// astBinaryDecoder читает узлы двоичного формата. Размеры списков и строк
+// проверяются по остатку данных, поэтому повреждённые данные не приводят
+// к чрезмерному выделению памяти.
+type astBinaryDecoder struct {
+	data    []byte
+	strings []string
+	depth   int
+	err     error
+}
+
+var errASTTruncated = errors.New("unexpected end of data")
+
+func (d *astBinaryDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *astBinaryDecoder) decoding() bool { return true }
+
+func (d *astBinaryDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.data) == 0 {
+		d.fail(errASTTruncated)
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *astBinaryDecoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	n, size := binary.Uvarint(d.data)
+	if size <= 0 {
+		d.fail(errors.New("invalid varint"))
+		return 0
+	}
+	d.data = d.data[size:]
+	return n
+}
+
+func (d *astBinaryDecoder) int() int {
+	n := d.uint()
+	if n > math.MaxInt {
+		d.fail(errors.New("number is out of range"))
+		return 0
+	}
+	return int(n)
+}
+
+// count читает размер, каждый элемент которого занимает хотя бы один байт.
+func (d *astBinaryDecoder) count(n uint64) int {
+	if n > uint64(len(d.data)) {
+		d.fail(errASTTruncated)
+		return 0
+	}
+	return int(n)
+}
+
+// length читает длину списка; ok = false означает nil.
+func (d *astBinaryDecoder) length() (n int, ok bool) {
+	encoded := d.uint()
+	if encoded == 0 || d.err != nil {
+		return 0, false
+	}
+	return d.count(encoded - 1), d.err == nil
+}
+
+func (d *astBinaryDecoder) string() string {
+	ref := d.uint()
+	if d.err != nil {
+		return ""
+	}
+	if ref > 0 {
+		if ref > uint64(len(d.strings)) {
+			d.fail(fmt.Errorf("invalid string reference %d", ref))
+			return ""
+		}
+		return d.strings[ref-1]
+	}
+	n := d.count(d.uint())
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	d.strings = append(d.strings, s)
+	return s
+}
+
+func (d *astBinaryDecoder) node() Node {
+	code := d.uint()
+	if code == 0 || d.err != nil {
+		return nil
+	}
+	if code > uint64(len(astKinds)) {
+		d.fail(fmt.Errorf("unknown node kind code %d", code))
+		return nil
+	}
+	node, _ := newASTNode(astKinds[code-1])
+	if d.depth++; d.depth > maxASTDepth {
+		d.fail(errors.New("AST is nested too deeply"))
+		return nil
+	}
+	describeNode(node, d)
+	if line := d.int(); line > 0 {
+		pos := nodePosition(node)
+		pos.Line = line
+		pos.Column = d.int()
+		pos.EndLine = d.int()
+		pos.EndColumn = d.int()
+		pos.Offset = d.int()
+		pos.EndOffset = d.int()
+	}
+	d.depth--
+	if d.err != nil {
+		return nil
+	}
+	return node
+}
+
+func (d *astBinaryDecoder) child(name string, n *Node, required bool) {
+	*n = d.node()
+	if *n == nil && required && d.err == nil {
+		d.fail(fmt.Errorf("%s: node is required", name))
+	}
+}
+
+func (d *astBinaryDecoder) children(_ string, ns *[]Node) {
+	*ns = nil
+	n, ok := d.length()
+	if !ok {
+		return
+	}
+	*ns = make([]Node, 0, n)
+	for k := 0; k < n && d.err == nil; k++ {
+		*ns = append(*ns, d.node())
+	}
+}
+
+func (d *astBinaryDecoder) str(_ string, s *string) {
+	*s = d.string()
+}
+
+func (d *astBinaryDecoder) strs(_ string, s *[]string) {
+	*s = d.stringList()
+}
+
+func (d *astBinaryDecoder) stringList() []string {
+	n, ok := d.length()
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, n)
+	for k := 0; k < n && d.err == nil; k++ {
+		result = append(result, d.string())
+	}
+	return result
+}
+
+func (d *astBinaryDecoder) flag(name string, b *bool) {
+	switch d.byte() {
+	case 0:
+		*b = false
+	case 1:
+		*b = true
+	default:
+		d.fail(fmt.Errorf("%s: invalid boolean", name))
+	}
+}
+
+func (d *astBinaryDecoder) value(name string, v *any) {
+	*v = d.literal(0)
+	if d.err != nil {
+		d.err = fmt.Errorf("%s: %w", name, d.err)
+	}
+}
+
+func (d *astBinaryDecoder) literal(depth int) any {
+	if depth > maxASTDepth {
+		d.fail(errors.New("value is nested too deeply"))
+		return nil
+	}
+	tag := d.byte()
+	if d.err != nil {
+		return nil
+	}
+	switch tag {
+	case astValueNil:
+		return nil
+	case astValueFalse:
+		return false
+	case astValueTrue:
+		return true
+	case astValueString:
+		return d.string()
+	case astValueInt, astValueInt64:
+		n, size := binary.Varint(d.data)
+		if size <= 0 {
+			d.fail(errors.New("invalid varint"))
+			return nil
+		}
+		d.data = d.data[size:]
+		if tag == astValueInt64 {
+			return n
+		}
+		if n != int64(int(n)) {
+			d.fail(errors.New("number is out of range"))
+			return nil
+		}
+		return int(n)
+	case astValueFloat64:
+		if len(d.data) < 8 {
+			d.fail(errASTTruncated)
+			return nil
+		}
+		bits := binary.LittleEndian.Uint64(d.data)
+		d.data = d.data[8:]
+		return math.Float64frombits(bits)
+	case astValueBigInt, astValueRat, astValueRegex:
+		text := d.string()
+		if d.err != nil {
+			return nil
+		}
+		names := map[byte]string{astValueBigInt: "bigint", astValueRat: "rat", astValueRegex: "regex"}
+		value, err := parseASTScalar(names[tag], text)
+		if err != nil {
+			d.fail(err)
+			return nil
+		}
+		return value
+	case astValueList:
+		n := d.count(d.uint())
+		result := make([]any, 0, n)
+		for k := 0; k < n && d.err == nil; k++ {
+			result = append(result, d.literal(depth+1))
+		}
+		return result
+	case astValueMap:
+		n := d.count(d.uint())
+		result := make(map[string]any, n)
+		for k := 0; k < n && d.err == nil; k++ {
+			key := d.string()
+			result[key] = d.literal(depth + 1)
+		}
+		return result
+	}
+	d.fail(fmt.Errorf("unknown value tag %d", tag))
+	return nil
+}
+
+func (d *astBinaryDecoder) info(_ string, info **Info) {
+	*info = nil
+	if d.byte() == 0 {
+		return
+	}
+	name := d.string()
+	line := d.int()
+	column := d.int()
+	*info = &Info{name: name, line: line, column: column}
+}
+
+func (d *astBinaryDecoder) features(_ string, features **Features) {
+	*features = nil
+	if d.byte() == 0 {
+		return
+	}
+	f := &Features{flags: d.uint()}
+	f.reserved = d.stringList()
+	f.namespaceSet = d.stringList()
+	*features = f
+}
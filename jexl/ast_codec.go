@@ -0,0 +1,478 @@
+package jexl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// ASTFormatVersion - версия форматов сериализации AST (JSON и двоичного).
+// Данные другой версии UnmarshalAST отвергает.
+const ASTFormatVersion = 1
+
+// maxASTDepth ограничивает вложенность узлов при декодировании.
+const maxASTDepth = 10000
+
+// astKinds - имена видов узлов. Индекс в срезе - код вида в двоичном формате,
+// поэтому новые виды добавляются только в конец.
+var astKinds = []string{
+	"Script", "Literal", "Identifier", "BinaryOp", "UnaryOp", "PropertyAccess",
+	"IndexAccess", "MethodCall", "Assignment", "Ternary", "Range", "Elvis", "Pipe",
+	"ArrayLiteral", "MapLiteral", "SetLiteral", "If", "For", "Foreach", "While",
+	"DoWhile", "Block", "Break", "Continue", "Return", "Var", "ArrayPattern",
+	"MapPattern", "Destructuring", "Lambda", "Function", "Switch", "Case", "Try",
+}
+
+// newASTNode создаёт пустой узел вида kind.
+func newASTNode(kind string) (Node, error) {
+	switch kind {
+	case "Script":
+		return &ScriptNode{pragmas: map[string]any{}}, nil
+	case "Literal":
+		return &LiteralNode{}, nil
+	case "Identifier":
+		return &IdentifierNode{}, nil
+	case "BinaryOp":
+		return &BinaryOpNode{}, nil
+	case "UnaryOp":
+		return &UnaryOpNode{}, nil
+	case "PropertyAccess":
+		return &PropertyAccessNode{}, nil
+	case "IndexAccess":
+		return &IndexAccessNode{}, nil
+	case "MethodCall":
+		return &MethodCallNode{}, nil
+	case "Assignment":
+		return &AssignmentNode{}, nil
+	case "Ternary":
+		return &TernaryNode{}, nil
+	case "Range":
+		return &RangeNode{}, nil
+	case "Elvis":
+		return &ElvisNode{}, nil
+	case "Pipe":
+		return &PipeNode{}, nil
+	case "ArrayLiteral":
+		return &ArrayLiteralNode{}, nil
+	case "MapLiteral":
+		return &MapLiteralNode{}, nil
+	case "SetLiteral":
+		return &SetLiteralNode{}, nil
+	case "If":
+		return &IfNode{}, nil
+	case "For":
+		return &ForNode{}, nil
+	case "Foreach":
+		return &ForeachNode{}, nil
+	case "While":
+		return &WhileNode{}, nil
+	case "DoWhile":
+		return &DoWhileNode{}, nil
+	case "Block":
+		return &BlockNode{}, nil
+	case "Break":
+		return &BreakNode{}, nil
+	case "Continue":
+		return &ContinueNode{}, nil
+	case "Return":
+		return &ReturnNode{}, nil
+	case "Var":
+		return &VarNode{}, nil
+	case "ArrayPattern":
+		return &ArrayPatternNode{}, nil
+	case "MapPattern":
+		return &MapPatternNode{}, nil
+	case "Destructuring":
+		return &DestructuringNode{}, nil
+	case "Lambda":
+		return &LambdaNode{}, nil
+	case "Function":
+		return &FunctionNode{}, nil
+	case "Switch":
+		return &SwitchNode{}, nil
+	case "Case":
+		return &CaseNode{}, nil
+	case "Try":
+		return &TryNode{}, nil
+	}
+	return nil, fmt.Errorf("unknown node kind %q", kind)
+}
+
+// astFields - кодек полей узла. describeNode перечисляет поля узла через указатели:
+// кодировщик читает значения, декодировщик записывает их.
+type astFields interface {
+	// child - дочерний узел; required запрещает nil.
+	child(name string, n *Node, required bool)
+	// children - список дочерних узлов, элементы могут быть nil.
+	children(name string, ns *[]Node)
+	str(name string, s *string)
+	strs(name string, s *[]string)
+	flag(name string, b *bool)
+	// value - значение литерала, case или pragma (см. checkASTValue).
+	value(name string, v *any)
+	info(name string, info **Info)
+	features(name string, features **Features)
+	fail(err error)
+	// decoding сообщает, что поля записываются: при кодировании узлы не изменяются.
+	decoding() bool
+}
+
+// describeNode перечисляет поля узла в порядке, общем для всех форматов.
+func describeNode(node Node, f astFields) {
+	switch n := node.(type) {
+	case *ScriptNode:
+		f.str("source", &n.source)
+		f.info("info", &n.info)
+		f.features("features", &n.features)
+		var pragmas any = n.pragmas
+		f.value("pragmas", &pragmas)
+		f.strs("variables", &n.variables)
+		f.strs("parameters", &n.parameters)
+		f.children("defaults", &n.defaults)
+		f.flag("rest", &n.rest)
+		f.children("children", &n.children)
+		if f.decoding() {
+			var ok bool
+			if n.pragmas, ok = pragmas.(map[string]any); !ok || n.pragmas == nil {
+				n.pragmas = map[string]any{}
+				if !ok && pragmas != nil {
+					f.fail(errors.New("pragmas must be a map"))
+				}
+			}
+			if n.children == nil {
+				n.children = []Node{}
+			}
+		}
+	case *LiteralNode:
+		f.str("source", &n.source)
+		f.value("value", &n.value)
+	case *IdentifierNode:
+		f.str("source", &n.source)
+		f.str("name", &n.name)
+	case *BinaryOpNode:
+		f.str("source", &n.source)
+		f.str("op", &n.op)
+		f.child("left", &n.left, true)
+		f.child("right", &n.right, true)
+	case *UnaryOpNode:
+		f.str("source", &n.source)
+		f.str("op", &n.op)
+		f.child("operand", &n.operand, true)
+	case *PropertyAccessNode:
+		f.str("source", &n.source)
+		f.child("object", &n.object, true)
+		f.child("property", &n.property, true)
+	case *IndexAccessNode:
+		f.str("source", &n.source)
+		f.child("object", &n.object, true)
+		f.child("index", &n.index, true)
+	case *MethodCallNode:
+		f.str("source", &n.source)
+		f.child("target", &n.target, false)
+		f.str("namespace", &n.namespace)
+		f.child("method", &n.method, true)
+		f.children("args", &n.args)
+	case *AssignmentNode:
+		f.str("source", &n.source)
+		f.child("target", &n.target, true)
+		f.child("value", &n.value, true)
+	case *TernaryNode:
+		f.str("source", &n.source)
+		f.child("condition", &n.condition, true)
+		f.child("true", &n.trueExpr, true)
+		f.child("false", &n.falseExpr, true)
+	case *RangeNode:
+		f.str("source", &n.source)
+		f.child("left", &n.left, true)
+		f.child("right", &n.right, true)
+		f.child("step", &n.step, false)
+	case *ElvisNode:
+		f.str("source", &n.source)
+		f.child("expr", &n.expr, true)
+		f.child("default", &n.defaultExpr, true)
+	case *PipeNode:
+		f.str("source", &n.source)
+		f.child("value", &n.value, true)
+		f.child("target", &n.target, true)
+	case *ArrayLiteralNode:
+		f.str("source", &n.source)
+		f.children("elements", &n.elements)
+	case *MapLiteralNode:
+		f.str("source", &n.source)
+		keys := make([]Node, len(n.entries))
+		values := make([]Node, len(n.entries))
+		for k, entry := range n.entries {
+			keys[k], values[k] = entry.Key, entry.Value
+		}
+		f.children("keys", &keys)
+		f.children("values", &values)
+		if !f.decoding() {
+			return
+		}
+		if len(keys) != len(values) {
+			f.fail(errors.New("map literal keys and values differ in length"))
+			return
+		}
+		n.entries = make([]MapEntry, len(keys))
+		for k := range keys {
+			n.entries[k] = MapEntry{Key: keys[k], Value: values[k]}
+		}
+	case *SetLiteralNode:
+		f.str("source", &n.source)
+		f.children("elements", &n.elements)
+	case *IfNode:
+		f.str("source", &n.source)
+		f.child("condition", &n.condition, true)
+		f.child("then", &n.thenBranch, false)
+		f.child("else", &n.elseBranch, false)
+	case *ForNode:
+		f.str("source", &n.source)
+		f.child("init", &n.init, false)
+		f.child("condition", &n.condition, false)
+		f.child("step", &n.step, false)
+		f.child("body", &n.body, false)
+	case *ForeachNode:
+		f.str("source", &n.source)
+		f.child("key", &n.key, false)
+		f.child("variable", &n.variable, true)
+		f.child("items", &n.items, true)
+		f.child("body", &n.body, false)
+	case *WhileNode:
+		f.str("source", &n.source)
+		f.child("condition", &n.condition, true)
+		f.child("body", &n.body, false)
+	case *DoWhileNode:
+		f.str("source", &n.source)
+		f.child("condition", &n.condition, true)
+		f.child("body", &n.body, false)
+	case *BlockNode:
+		f.str("source", &n.source)
+		f.children("statements", &n.statements)
+	case *BreakNode:
+		f.str("source", &n.source)
+	case *ContinueNode:
+		f.str("source", &n.source)
+	case *ReturnNode:
+		f.str("source", &n.source)
+		f.child("value", &n.value, false)
+	case *VarNode:
+		f.str("source", &n.source)
+		typedField(f, "name", &n.name, true)
+		f.child("value", &n.value, false)
+	case *ArrayPatternNode:
+		f.str("source", &n.source)
+		f.children("elements", &n.elements)
+		f.children("defaults", &n.defaults)
+		typedField(f, "rest", &n.rest, false)
+	case *MapPatternNode:
+		f.str("source", &n.source)
+		f.strs("keys", &n.keys)
+		f.children("targets", &n.targets)
+		f.children("defaults", &n.defaults)
+		typedField(f, "rest", &n.rest, false)
+	case *DestructuringNode:
+		f.str("source", &n.source)
+		f.child("pattern", &n.pattern, true)
+		f.child("value", &n.value, true)
+		f.flag("declaration", &n.declaration)
+	case *LambdaNode:
+		f.str("source", &n.source)
+		typedList(f, "parameters", &n.parameters)
+		f.children("defaults", &n.defaults)
+		f.flag("rest", &n.rest)
+		f.child("body", &n.body, true)
+	case *FunctionNode:
+		f.str("source", &n.source)
+		typedField(f, "name", &n.name, true)
+		typedField(f, "lambda", &n.lambda, true)
+	case *SwitchNode:
+		f.str("source", &n.source)
+		f.child("expression", &n.expression, true)
+		typedList(f, "cases", &n.cases)
+		f.flag("statement", &n.isStatement)
+		if f.decoding() {
+			// Индекс значений case производный, его восстанавливает конструктор
+			rebuilt := NewSwitchNode(n.expression, n.cases, n.isStatement, n.source)
+			n.caseMap, n.defaultIndex = rebuilt.caseMap, rebuilt.defaultIndex
+		}
+	case *CaseNode:
+		f.str("source", &n.source)
+		var values any = n.values
+		f.value("values", &values)
+		if f.decoding() {
+			switch v := values.(type) {
+			case []any:
+				// Значения case - ключи индекса switch, списки и словари недопустимы
+				for _, value := range v {
+					switch value.(type) {
+					case []any, map[string]any:
+						f.fail(errors.New("case value must be a scalar"))
+						return
+					}
+				}
+				n.values = v
+			case nil:
+				n.values = nil
+			default:
+				f.fail(errors.New("case values must be a list"))
+			}
+		}
+		f.child("body", &n.body, false)
+	case *TryNode:
+		f.str("source", &n.source)
+		f.children("resources", &n.resources)
+		f.child("try", &n.tryBlock, true)
+		f.str("catchVar", &n.catchVar)
+		f.child("catch", &n.catchBlock, false)
+		f.child("finally", &n.finallyBlock, false)
+	default:
+		f.fail(fmt.Errorf("unsupported node type %T", node))
+	}
+}
+
+// typedField описывает дочерний узел конкретного типа.
+func typedField[T Node](f astFields, name string, field *T, required bool) {
+	var node Node
+	if !isNilNode(*field) {
+		node = *field
+	}
+	f.child(name, &node, required)
+	if !f.decoding() {
+		return
+	}
+	var zero T
+	*field = zero
+	if node == nil {
+		return
+	}
+	typed, ok := node.(T)
+	if !ok {
+		f.fail(fmt.Errorf("%s: unexpected node %s", name, nodeKind(node)))
+		return
+	}
+	*field = typed
+}
+
+// typedList описывает список дочерних узлов конкретного типа без nil.
+func typedList[T Node](f astFields, name string, field *[]T) {
+	nodes := make([]Node, len(*field))
+	for k, node := range *field {
+		nodes[k] = node
+	}
+	f.children(name, &nodes)
+	if !f.decoding() {
+		return
+	}
+	*field = make([]T, 0, len(nodes))
+	for _, node := range nodes {
+		typed, ok := node.(T)
+		if !ok || isNilNode(node) {
+			f.fail(fmt.Errorf("%s: unexpected node %s", name, nodeKind(node)))
+			return
+		}
+		*field = append(*field, typed)
+	}
+}
+
+// isNilNode проверяет, что узел - nil или nil-указатель.
+func isNilNode(node Node) bool {
+	if node == nil {
+		return true
+	}
+	switch n := node.(type) {
+	case *IdentifierNode:
+		return n == nil
+	case *LambdaNode:
+		return n == nil
+	case *CaseNode:
+		return n == nil
+	}
+	return false
+}
+
+// nodeKind возвращает имя вида узла.
+func nodeKind(node Node) string {
+	if isNilNode(node) {
+		return "null"
+	}
+	kind := fmt.Sprintf("%T", node)
+	kind = kind[len("*jexl."):]
+	return kind[:len(kind)-len("Node")]
+}
+
+// positionRef возвращает указатель на положение узла для кодеков.
+func (s *span) positionRef() *Position {
+	return &s.pos
+}
+
+// nodePosition возвращает указатель на положение узла.
+func nodePosition(node Node) *Position {
+	if positioned, ok := node.(interface{ positionRef() *Position }); ok {
+		return positioned.positionRef()
+	}
+	return new(Position)
+}
+
+// checkASTValue проверяет, что значение сериализуемо: nil, bool, string, int,
+// int64, float64, *big.Int, *big.Rat, *regexp.Regexp, []any или map[string]any из них.
+func checkASTValue(value any) error {
+	switch v := value.(type) {
+	case nil, bool, string, int, int64, float64:
+		return nil
+	case *big.Int, *big.Rat, *regexp.Regexp:
+		if isNilPointer(v) {
+			return fmt.Errorf("unsupported value %T(nil)", v)
+		}
+		return nil
+	case []any:
+		for _, item := range v {
+			if err := checkASTValue(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		for _, item := range v {
+			if err := checkASTValue(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported value type %T", value)
+}
+
+func isNilPointer(value any) bool {
+	switch v := value.(type) {
+	case *big.Int:
+		return v == nil
+	case *big.Rat:
+		return v == nil
+	case *regexp.Regexp:
+		return v == nil
+	}
+	return false
+}
+
+// astBinaryMagic - сигнатура двоичного формата AST.
+var astBinaryMagic = []byte("JXAST")
+
+// UnmarshalAST восстанавливает AST из данных MarshalAST (JSON) или
+// MarshalASTBinary; формат определяется по содержимому. Данные другой версии
+// формата и некорректные данные отвергаются с ошибкой.
+func UnmarshalAST(data []byte) (*ScriptNode, error) {
+	if bytes.HasPrefix(data, astBinaryMagic) {
+		return unmarshalASTBinary(data[len(astBinaryMagic):])
+	}
+	return unmarshalASTJSON(data)
+}
+
+// checkASTVersion проверяет версию формата.
+func checkASTVersion(version uint64) error {
+	if version != ASTFormatVersion {
+		return fmt.Errorf("unsupported AST format version %d (supported: %d)", version, ASTFormatVersion)
+	}
+	return nil
+}
@@ -0,0 +1,561 @@
+package jexl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// astJSONFormat - значение поля "format" JSON-документа AST.
+const astJSONFormat = "jexl-ast"
+
+// MarshalAST сериализует AST скрипта в JSON со стабильной схемой:
+//
+//	{"format":"jexl-ast","version":1,"script":{"kind":"Script",...}}
+//
+// Каждый узел - объект с полем "kind", полями узла и положением "pos".
+// Значения литералов, кроме null, bool и строк, записываются объектами с тегом
+// типа: {"int64":"42"}, {"float64":"1.5"}, {"rat":"1/3"}, {"regex":"a+"} и т.п.
+func MarshalAST(ast *ScriptNode) ([]byte, error) {
+	if ast == nil {
+		return nil, errors.New("script AST is nil")
+	}
+	e := &astJSONEncoder{}
+	e.buf.WriteString(`{"format":`)
+	e.string(astJSONFormat)
+	fmt.Fprintf(&e.buf, `,"version":%d,"script":`, ASTFormatVersion)
+	e.node(ast)
+	e.buf.WriteByte('}')
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf.Bytes(), nil
+}
+
+// astJSONEncoder пишет JSON, сохраняя порядок полей узлов.
+type astJSONEncoder struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (e *astJSONEncoder) fail(err error) {
+	if e.err == nil {
+		e.err = err
+	}
+}
+
+func (e *astJSONEncoder) decoding() bool { return false }
+
+func (e *astJSONEncoder) string(s string) {
+	data, _ := json.Marshal(s)
+	e.buf.Write(data)
+}
+
+func (e *astJSONEncoder) key(name string) {
+	e.buf.WriteByte(',')
+	e.string(name)
+	e.buf.WriteByte(':')
+}
+
+// node пишет узел или null.
+func (e *astJSONEncoder) node(node Node) {
+	if isNilNode(node) {
+		e.buf.WriteString("null")
+		return
+	}
+	e.buf.WriteString(`{"kind":`)
+	e.string(nodeKind(node))
+	describeNode(node, e)
+	if pos := nodePosition(node); pos.IsValid() {
+		e.key("pos")
+		fmt.Fprintf(&e.buf, `{"offset":%d,"end":%d,"line":%d,"column":%d,"endLine":%d,"endColumn":%d}`,
+			pos.Offset, pos.EndOffset, pos.Line, pos.Column, pos.EndLine, pos.EndColumn)
+	}
+	e.buf.WriteByte('}')
+}
+
+func (e *astJSONEncoder) list(n int, item func(k int)) {
+	e.buf.WriteByte('[')
+	for k := 0; k < n; k++ {
+		if k > 0 {
+			e.buf.WriteByte(',')
+		}
+		item(k)
+	}
+	e.buf.WriteByte(']')
+}
+
+func (e *astJSONEncoder) child(name string, n *Node, _ bool) {
+	e.key(name)
+	e.node(*n)
+}
+
+func (e *astJSONEncoder) children(name string, ns *[]Node) {
+	e.key(name)
+	if *ns == nil {
+		e.buf.WriteString("null")
+		return
+	}
+	e.list(len(*ns), func(k int) { e.node((*ns)[k]) })
+}
+
+func (e *astJSONEncoder) str(name string, s *string) {
+	e.key(name)
+	e.string(*s)
+}
+
+func (e *astJSONEncoder) strs(name string, s *[]string) {
+	e.key(name)
+	e.strings(*s)
+}
+
+func (e *astJSONEncoder) strings(s []string) {
+	if s == nil {
+		e.buf.WriteString("null")
+		return
+	}
+	e.list(len(s), func(k int) { e.string(s[k]) })
+}
+
+func (e *astJSONEncoder) flag(name string, b *bool) {
+	e.key(name)
+	e.buf.WriteString(strconv.FormatBool(*b))
+}
+
+func (e *astJSONEncoder) value(name string, v *any) {
+	e.key(name)
+	if err := checkASTValue(*v); err != nil {
+		e.fail(fmt.Errorf("%s: %w", name, err))
+		e.buf.WriteString("null")
+		return
+	}
+	e.literal(*v)
+}
+
+// literal пишет проверенное checkASTValue значение.
+func (e *astJSONEncoder) literal(value any) {
+	tagged := func(tag, text string) {
+		e.buf.WriteByte('{')
+		e.string(tag)
+		e.buf.WriteByte(':')
+		e.string(text)
+		e.buf.WriteByte('}')
+	}
+	switch v := value.(type) {
+	case nil:
+		e.buf.WriteString("null")
+	case bool:
+		e.buf.WriteString(strconv.FormatBool(v))
+	case string:
+		e.string(v)
+	case int:
+		tagged("int", strconv.Itoa(v))
+	case int64:
+		tagged("int64", strconv.FormatInt(v, 10))
+	case float64:
+		tagged("float64", strconv.FormatFloat(v, 'g', -1, 64))
+	case *big.Int:
+		tagged("bigint", v.String())
+	case *big.Rat:
+		tagged("rat", v.String())
+	case *regexp.Regexp:
+		tagged("regex", v.String())
+	case []any:
+		e.buf.WriteString(`{"list":`)
+		e.list(len(v), func(k int) { e.literal(v[k]) })
+		e.buf.WriteByte('}')
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		e.buf.WriteString(`{"map":{`)
+		for k, key := range keys {
+			if k > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.string(key)
+			e.buf.WriteByte(':')
+			e.literal(v[key])
+		}
+		e.buf.WriteString("}}")
+	}
+}
+
+func (e *astJSONEncoder) info(name string, info **Info) {
+	e.key(name)
+	if *info == nil {
+		e.buf.WriteString("null")
+		return
+	}
+	e.buf.WriteString(`{"name":`)
+	e.string((*info).name)
+	fmt.Fprintf(&e.buf, `,"line":%d,"column":%d}`, (*info).line, (*info).column)
+}
+
+func (e *astJSONEncoder) features(name string, features **Features) {
+	e.key(name)
+	f := *features
+	if f == nil {
+		e.buf.WriteString("null")
+		return
+	}
+	fmt.Fprintf(&e.buf, `{"flags":%d,"reserved":`, f.flags)
+	e.strings(f.reserved)
+	e.buf.WriteString(`,"namespaces":`)
+	e.strings(f.namespaceSet)
+	e.buf.WriteByte('}')
+}
+
+// unmarshalASTJSON восстанавливает AST из JSON-документа MarshalAST.
+func unmarshalASTJSON(data []byte) (*ScriptNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var document any
+	if err := dec.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid AST data: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid AST data: unexpected data after document")
+	}
+	header, ok := document.(map[string]any)
+	if !ok || header["format"] != astJSONFormat {
+		return nil, errors.New("invalid AST data: not a jexl-ast document")
+	}
+	version, err := jsonUint(header["version"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid AST data: version: %w", err)
+	}
+	if err := checkASTVersion(version); err != nil {
+		return nil, err
+	}
+	d := &astJSONDecoder{}
+	node := d.node(header["script"])
+	if d.err != nil {
+		return nil, fmt.Errorf("invalid AST data: %w", d.err)
+	}
+	script, ok := node.(*ScriptNode)
+	if !ok {
+		return nil, fmt.Errorf("invalid AST data: root is %s, not Script", nodeKind(node))
+	}
+	return script, nil
+}
+
+// astJSONDecoder заполняет узлы из разобранного JSON.
+type astJSONDecoder struct {
+	fields map[string]any // поля текущего узла
+	depth  int
+	err    error
+}
+
+func (d *astJSONDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *astJSONDecoder) decoding() bool { return true }
+
+// node создаёт узел из JSON-объекта; null даёт nil.
+func (d *astJSONDecoder) node(value any) Node {
+	if value == nil || d.err != nil {
+		return nil
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		d.fail(errors.New("node must be an object"))
+		return nil
+	}
+	kind, _ := object["kind"].(string)
+	node, err := newASTNode(kind)
+	if err != nil {
+		d.fail(err)
+		return nil
+	}
+	if d.depth++; d.depth > maxASTDepth {
+		d.fail(errors.New("AST is nested too deeply"))
+		return nil
+	}
+	parent := d.fields
+	d.fields = object
+	describeNode(node, d)
+	if pos, ok := object["pos"]; ok && pos != nil {
+		*nodePosition(node) = d.position(pos)
+	}
+	d.fields = parent
+	d.depth--
+	if d.err != nil {
+		return nil
+	}
+	return node
+}
+
+func (d *astJSONDecoder) position(value any) Position {
+	object, ok := value.(map[string]any)
+	if !ok {
+		d.fail(errors.New("pos must be an object"))
+		return Position{}
+	}
+	get := func(name string) int {
+		n, err := jsonInt(object[name])
+		if err != nil {
+			d.fail(fmt.Errorf("pos.%s: %w", name, err))
+		}
+		return n
+	}
+	return Position{
+		Offset:    get("offset"),
+		EndOffset: get("end"),
+		Line:      get("line"),
+		Column:    get("column"),
+		EndLine:   get("endLine"),
+		EndColumn: get("endColumn"),
+	}
+}
+
+func (d *astJSONDecoder) child(name string, n *Node, required bool) {
+	*n = d.node(d.fields[name])
+	if *n == nil && required && d.err == nil {
+		d.fail(fmt.Errorf("%s: node is required", name))
+	}
+}
+
+func (d *astJSONDecoder) children(name string, ns *[]Node) {
+	*ns = nil
+	value := d.fields[name]
+	if value == nil {
+		return
+	}
+	items, ok := value.([]any)
+	if !ok {
+		d.fail(fmt.Errorf("%s: expected a list of nodes", name))
+		return
+	}
+	*ns = make([]Node, 0, len(items))
+	for _, item := range items {
+		*ns = append(*ns, d.node(item))
+	}
+}
+
+func (d *astJSONDecoder) str(name string, s *string) {
+	*s = ""
+	if value, ok := d.fields[name]; ok {
+		if *s, ok = value.(string); !ok {
+			d.fail(fmt.Errorf("%s: expected a string", name))
+		}
+	}
+}
+
+func (d *astJSONDecoder) strs(name string, s *[]string) {
+	var err error
+	if *s, err = jsonStrings(d.fields[name]); err != nil {
+		d.fail(fmt.Errorf("%s: %w", name, err))
+	}
+}
+
+func (d *astJSONDecoder) flag(name string, b *bool) {
+	*b = false
+	if value, ok := d.fields[name]; ok {
+		if *b, ok = value.(bool); !ok {
+			d.fail(fmt.Errorf("%s: expected a boolean", name))
+		}
+	}
+}
+
+func (d *astJSONDecoder) value(name string, v *any) {
+	var err error
+	if *v, err = jsonLiteral(d.fields[name], 0); err != nil {
+		d.fail(fmt.Errorf("%s: %w", name, err))
+	}
+}
+
+// jsonLiteral восстанавливает значение, записанное astJSONEncoder.literal.
+func jsonLiteral(value any, depth int) (any, error) {
+	if depth > maxASTDepth {
+		return nil, errors.New("value is nested too deeply")
+	}
+	switch v := value.(type) {
+	case nil, bool, string:
+		return v, nil
+	case map[string]any:
+		if len(v) != 1 {
+			break
+		}
+		for tag, payload := range v {
+			switch tag {
+			case "list":
+				items, ok := payload.([]any)
+				if !ok {
+					return nil, errors.New("list value must be an array")
+				}
+				result := make([]any, len(items))
+				for k, item := range items {
+					var err error
+					if result[k], err = jsonLiteral(item, depth+1); err != nil {
+						return nil, err
+					}
+				}
+				return result, nil
+			case "map":
+				entries, ok := payload.(map[string]any)
+				if !ok {
+					return nil, errors.New("map value must be an object")
+				}
+				result := make(map[string]any, len(entries))
+				for key, item := range entries {
+					var err error
+					if result[key], err = jsonLiteral(item, depth+1); err != nil {
+						return nil, err
+					}
+				}
+				return result, nil
+			}
+			text, ok := payload.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s value must be a string", tag)
+			}
+			return parseASTScalar(tag, text)
+		}
+	}
+	return nil, fmt.Errorf("unsupported value %v", value)
+}
+
+// parseASTScalar разбирает текстовое представление значения с тегом типа.
+func parseASTScalar(tag, text string) (any, error) {
+	switch tag {
+	case "int":
+		n, err := strconv.ParseInt(text, 10, strconv.IntSize)
+		return int(n), err
+	case "int64":
+		return strconv.ParseInt(text, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(text, 64)
+	case "bigint":
+		if n, ok := new(big.Int).SetString(text, 10); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("invalid bigint %q", text)
+	case "rat":
+		// SetString принимает и экспоненты вида 1e1000000000, поэтому только a/b
+		if r, ok := parseRat(text); ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("invalid rat %q", text)
+	case "regex":
+		return regexp.Compile(text)
+	}
+	return nil, fmt.Errorf("unknown value type %q", tag)
+}
+
+// parseRat разбирает дробь вида a/b, записанную big.Rat.String.
+func parseRat(text string) (*big.Rat, bool) {
+	num, den, found := bytes.Cut([]byte(text), []byte("/"))
+	if !found {
+		return nil, false
+	}
+	a, ok := new(big.Int).SetString(string(num), 10)
+	if !ok {
+		return nil, false
+	}
+	b, ok := new(big.Int).SetString(string(den), 10)
+	if !ok || b.Sign() <= 0 {
+		return nil, false
+	}
+	return new(big.Rat).SetFrac(a, b), true
+}
+
+func (d *astJSONDecoder) info(name string, info **Info) {
+	*info = nil
+	value := d.fields[name]
+	if value == nil {
+		return
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		d.fail(fmt.Errorf("%s: expected an object", name))
+		return
+	}
+	infoName, _ := object["name"].(string)
+	line, err := jsonInt(object["line"])
+	if err == nil {
+		var column int
+		if column, err = jsonInt(object["column"]); err == nil {
+			*info = &Info{name: infoName, line: line, column: column}
+			return
+		}
+	}
+	d.fail(fmt.Errorf("%s: %w", name, err))
+}
+
+func (d *astJSONDecoder) features(name string, features **Features) {
+	*features = nil
+	value := d.fields[name]
+	if value == nil {
+		return
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		d.fail(fmt.Errorf("%s: expected an object", name))
+		return
+	}
+	flags, err := jsonUint(object["flags"])
+	if err != nil {
+		d.fail(fmt.Errorf("%s.flags: %w", name, err))
+		return
+	}
+	f := &Features{flags: flags}
+	if f.reserved, err = jsonStrings(object["reserved"]); err != nil {
+		d.fail(fmt.Errorf("%s.reserved: %w", name, err))
+	}
+	if f.namespaceSet, err = jsonStrings(object["namespaces"]); err != nil {
+		d.fail(fmt.Errorf("%s.namespaces: %w", name, err))
+	}
+	*features = f
+}
+
+// jsonUint читает неотрицательное целое JSON-число.
+func jsonUint(value any) (uint64, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, errors.New("expected a number")
+	}
+	return strconv.ParseUint(number.String(), 10, 64)
+}
+
+// jsonInt читает неотрицательное целое JSON-число, помещающееся в int.
+func jsonInt(value any) (int, error) {
+	n, err := jsonUint(value)
+	if err != nil {
+		return 0, err
+	}
+	if n > math.MaxInt {
+		return 0, errors.New("number is out of range")
+	}
+	return int(n), nil
+}
+
+// jsonStrings читает список строк; null даёт nil.
+func jsonStrings(value any) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, errors.New("expected a list of strings")
+	}
+	result := make([]string, len(items))
+	for k, item := range items {
+		if result[k], ok = item.(string); !ok {
+			return nil, errors.New("expected a list of strings")
+		}
+	}
+	return result, nil
+}
@@ -2,6 +2,8 @@ package jexl
 
 import (
 	"math"
+	"reflect"
+	"slices"
 )
 
 const (
@@ -29,6 +31,7 @@ type Builder struct {
 	cacheThreshold int
 	charset        string
 	features       *Features
+	stdNamespaces  []string
 }
 
 // NewBuilder создаёт Builder с настройками по умолчанию.
@@ -158,6 +161,25 @@ func (b *Builder) Namespaces(namespaces map[string]any) *Builder {
 	return b
 }
 
+// Types регистрирует типы, доступные оператору instanceof по имени:
+// x instanceof Person проверяет x на соответствие типу, зарегистрированному как "Person".
+func (b *Builder) Types(types map[string]reflect.Type) *Builder {
+	b.options.SetTypes(types)
+	return b
+}
+
+// StandardNamespaces подключает стандартные пространства имён (NamespaceMath, NamespaceTime и т.д.).
+// Функции, запрещённые Permissions, не подключаются; пространства, заданные через
+// Namespaces, имеют приоритет над стандартными с тем же именем.
+func (b *Builder) StandardNamespaces(names ...string) *Builder {
+	for _, name := range names {
+		if !slices.Contains(b.stdNamespaces, name) {
+			b.stdNamespaces = append(b.stdNamespaces, name)
+		}
+	}
+	return b
+}
+
 // Safe управляет безопасной навигацией (safe navigation).
 func (b *Builder) Safe(flag bool) *Builder {
 	b.options.SetSafe(flag)
@@ -170,12 +192,33 @@ func (b *Builder) Silent(flag bool) *Builder {
 	return b
 }
 
+// Compiled управляет компиляцией скриптов в дерево замыканий: скрипт компилируется
+// при первом выполнении, повторные выполнения не разбирают AST заново.
+func (b *Builder) Compiled(flag bool) *Builder {
+	b.options.SetCompiled(flag)
+	return b
+}
+
+// Optimize управляет оптимизацией AST после разбора: свёрткой операций над литералами
+// через Arithmetic движка, упрощением true && x, слиянием вложенных блоков и удалением
+// недостижимых ветвей if (false). ParsedText показывает исходный, а не оптимизированный текст.
+func (b *Builder) Optimize(flag bool) *Builder {
+	b.options.SetOptimize(flag)
+	return b
+}
+
 // Strict управляет strict режимом.
 func (b *Builder) Strict(flag bool) *Builder {
 	b.options.SetStrict(flag)
 	return b
 }
 
+// SortedKeys включает итерацию foreach по мапам в порядке сортировки ключей.
+func (b *Builder) SortedKeys(flag bool) *Builder {
+	b.options.SetSortedKeys(flag)
+	return b
+}
+
 // Lexical управляет лексической областью видимости.
 func (b *Builder) Lexical(flag bool) *Builder {
 	b.options.SetLexical(flag)
@@ -340,3 +383,7 @@ func (b *Builder) CharsetValue() string {
 func (b *Builder) FeaturesValue() *Features {
 	return b.features
 }
+
+func (b *Builder) StandardNamespacesValue() []string {
+	return slices.Clone(b.stdNamespaces)
+}
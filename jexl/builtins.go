@@ -0,0 +1,16 @@
+package jexl
+
+// builtinFunctions - функции, которые интерпретатор вызывает без обращения к контексту.
+// Значение true - функцию переопределяет одноимённая функция контекста или скрипта.
+var builtinFunctions = map[string]bool{
+	"empty": false, "size": false,
+	"matches": true, "groups": true,
+	"now": true, "duration": true,
+}
+
+// Builtin сообщает, является ли name встроенной функцией, и может ли её
+// переопределить одноимённая функция контекста.
+func Builtin(name string) (overridable, ok bool) {
+	overridable, ok = builtinFunctions[name]
+	return overridable, ok
+}
@@ -0,0 +1,178 @@
+package jexl
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// collectionKind - вид коллекции для операторов над коллекциями.
+type collectionKind int
+
+const (
+	kindNone collectionKind = iota
+	kindList                // слайс, массив или Range
+	kindSet                 // *Set
+	kindMap                 // *Map или Go-мапа
+)
+
+// kindOf определяет вид коллекции значения.
+func kindOf(value any) collectionKind {
+	switch value.(type) {
+	case nil, string:
+		return kindNone
+	case *Set:
+		return kindSet
+	case *Map:
+		return kindMap
+	case *Range:
+		return kindList
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Slice, reflect.Array:
+		return kindList
+	case reflect.Map:
+		return kindMap
+	}
+	return kindNone
+}
+
+// toMap приводит мапу-операнд к *Map.
+func toMap(value any) *Map {
+	if m, ok := value.(*Map); ok {
+		return m
+	}
+	return NewMapFrom(value)
+}
+
+// mixedCollections возвращает ошибку оператора для операндов разных видов.
+func mixedCollections(symbol string, lhs, rhs any) error {
+	return NewOperatorError(symbol, nil, fmt.Errorf("cannot apply to %T and %T", lhs, rhs))
+}
+
+// addCollections выполняет + над коллекциями: конкатенацию списков, объединение множеств
+// и слияние мап (значения правой мапы перекрывают левую).
+// В нестрогом режиме к списку и множеству можно добавить коллекцию другого вида
+// или отдельный элемент. Второй результат false, если левый операнд не коллекция.
+func (a *BaseArithmetic) addCollections(lhs, rhs any) (any, bool, error) {
+	left, right := kindOf(lhs), kindOf(rhs)
+	if left == kindNone {
+		if a.strict && right != kindNone {
+			return nil, true, mixedCollections("+", lhs, rhs)
+		}
+		return nil, false, nil
+	}
+	if left != right && (a.strict || left == kindMap || right == kindMap) {
+		return nil, true, mixedCollections("+", lhs, rhs)
+	}
+	switch left {
+	case kindList:
+		items, _, err := stdElements(lhs)
+		if err != nil {
+			return nil, true, err
+		}
+		result := append([]any{}, items...)
+		if right == kindNone {
+			return append(result, rhs), true, nil
+		}
+		more, _, err := stdElements(rhs)
+		if err != nil {
+			return nil, true, err
+		}
+		return append(result, more...), true, nil
+	case kindSet:
+		result := NewSet(lhs.(*Set).Values()...)
+		if right == kindNone {
+			result.Add(rhs)
+			return result, true, nil
+		}
+		more, _, err := stdElements(rhs)
+		if err != nil {
+			return nil, true, err
+		}
+		for _, item := range more {
+			result.Add(item)
+		}
+		return result, true, nil
+	default:
+		result := NewMap()
+		for key, value := range toMap(lhs).Iterator() {
+			result.Put(key, value)
+		}
+		for key, value := range toMap(rhs).Iterator() {
+			result.Put(key, value)
+		}
+		return result, true, nil
+	}
+}
+
+// subtractCollections выполняет - над коллекциями: разность множеств и удаление
+// из списка элементов правого операнда. В нестрогом режиме правый операнд может
+// быть коллекцией другого вида или отдельным элементом.
+func (a *BaseArithmetic) subtractCollections(lhs, rhs any) (any, bool, error) {
+	left, right := kindOf(lhs), kindOf(rhs)
+	if left != kindList && left != kindSet {
+		if a.strict && right != kindNone {
+			return nil, true, mixedCollections("-", lhs, rhs)
+		}
+		return nil, false, nil
+	}
+	if left != right && (a.strict || right == kindMap) {
+		return nil, true, mixedCollections("-", lhs, rhs)
+	}
+	removed := NewSet(rhs)
+	if right != kindNone {
+		more, _, err := stdElements(rhs)
+		if err != nil {
+			return nil, true, err
+		}
+		removed = NewSet(more...)
+	}
+	if left == kindSet {
+		result := NewSet()
+		for _, item := range lhs.(*Set).Values() {
+			if !removed.Contains(item) {
+				result.Add(item)
+			}
+		}
+		return result, true, nil
+	}
+	items, _, err := stdElements(lhs)
+	if err != nil {
+		return nil, true, err
+	}
+	result := []any{}
+	for _, item := range items {
+		if !removed.Contains(item) {
+			result = append(result, item)
+		}
+	}
+	return result, true, nil
+}
+
+// intersectSets выполняет & над множествами. В нестрогом режиме правый
+// операнд может быть списком. Второй результат false, если левый операнд не множество.
+func (a *BaseArithmetic) intersectSets(lhs, rhs any) (any, bool, error) {
+	set, ok := lhs.(*Set)
+	right := kindOf(rhs)
+	if !ok {
+		if a.strict && right == kindSet {
+			return nil, true, mixedCollections("&", lhs, rhs)
+		}
+		return nil, false, nil
+	}
+	if right != kindSet && (a.strict || right != kindList) {
+		return nil, true, mixedCollections("&", lhs, rhs)
+	}
+	more, _, err := stdElements(rhs)
+	if err != nil {
+		return nil, true, err
+	}
+	other := NewSet(more...)
+	result := NewSet()
+	for _, item := range set.Values() {
+		if other.Contains(item) {
+			result.Add(item)
+		}
+	}
+	return result, true, nil
+}
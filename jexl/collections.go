@@ -0,0 +1,474 @@
+package jexl
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// numberKey - ключ числа, нормализованный по значению.
+type numberKey string
+
+// compositeKey - ключ несравнимого значения или коллекции, построенный по содержимому.
+type compositeKey string
+
+// progressionKey - ключ целочисленной арифметической прогрессии: диапазона или
+// списка с теми же элементами. Прогрессия задаётся крайними элементами, а не
+// размером: в диапазоне на весь int64 элементов больше, чем вмещает int64.
+// У последовательности из одного элемента шаг равен нулю.
+type progressionKey struct {
+	first, last, step int64
+	empty             bool
+}
+
+// timeKey - ключ момента времени независимо от часового пояса.
+type timeKey struct {
+	sec  int64
+	nsec int
+}
+
+// HashKey возвращает ключ, по которому Set и Map отождествляют элементы.
+// Числа равны по значению, как в Arithmetic (1, 1.0 и big.Rat 1 - один ключ),
+// но строки и логические значения с числами не смешиваются: 1 и '1' - разные ключи.
+// Коллекции отождествляются по содержимому, как в ==: списки и диапазоны с теми же
+// элементами, множества и мапы независимо от порядка.
+func HashKey(value any) any {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string, bool:
+		return v
+	case float32:
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return v
+		}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return v
+		}
+	case *Range:
+		return v.hashKey()
+	case *Set:
+		keys := make([]string, len(v.items))
+		for i, item := range v.items {
+			keys[i] = fmt.Sprintf("%#v", HashKey(item))
+		}
+		sort.Strings(keys)
+		return compositeKey("{" + strings.Join(keys, ",") + "}")
+	case *Map:
+		entries := make([]string, len(v.keys))
+		for i, key := range v.keys {
+			entries[i] = fmt.Sprintf("%#v:%#v", HashKey(key), HashKey(v.values[i]))
+		}
+		sort.Strings(entries)
+		return compositeKey("{" + strings.Join(entries, ",") + "}:map")
+	}
+	if t, ok := asTime(value); ok {
+		return timeKey{sec: t.Unix(), nsec: t.Nanosecond()}
+	}
+	if r, ok := toBig(value); ok {
+		return numberKey(r.RatString())
+	}
+	rv := reflect.ValueOf(value)
+	if isListValue(rv) {
+		return listKey(rv)
+	}
+	if rv.Comparable() {
+		return value
+	}
+	return compositeKey(compositeRepr(rv))
+}
+
+// hashKey возвращает ключ диапазона, совпадающий с ключом списка тех же элементов.
+func (r *Range) hashKey() progressionKey {
+	steps, ok := r.steps()
+	switch {
+	case !ok:
+		return progressionKey{empty: true}
+	case steps == 0:
+		return progressionKey{first: r.first, last: r.first}
+	default:
+		return progressionKey{first: r.first, last: r.lastElement(steps), step: r.step}
+	}
+}
+
+// listKey возвращает ключ слайса или массива. Список целых чисел, образующих
+// арифметическую прогрессию, получает ключ равного ему диапазона.
+func listKey(rv reflect.Value) any {
+	if key, ok := progressionOf(rv); ok {
+		return key
+	}
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, "%#v", HashKey(rv.Index(i).Interface()))
+	}
+	sb.WriteString("]")
+	return compositeKey(sb.String())
+}
+
+// progressionOf проверяет, образуют ли элементы списка целочисленную прогрессию
+// с ненулевым шагом, и возвращает её ключ.
+func progressionOf(rv reflect.Value) (progressionKey, bool) {
+	var key progressionKey
+	var prev int64
+	for i := 0; i < rv.Len(); i++ {
+		n, ok := integerElement(rv.Index(i).Interface())
+		if !ok {
+			return key, false
+		}
+		switch i {
+		case 0:
+			key.first = n
+		case 1:
+			key.step = n - prev
+			if key.step == 0 || (n > prev) != (key.step > 0) {
+				return key, false
+			}
+		default:
+			if diff := n - prev; diff != key.step || (n > prev) != (key.step > 0) {
+				return key, false
+			}
+		}
+		prev = n
+	}
+	key.last = prev
+	key.empty = rv.Len() == 0
+	return key, true
+}
+
+// integerElement возвращает целое число, представимое int64; логические значения числами не считаются.
+func integerElement(value any) (int64, bool) {
+	if _, isBool := value.(bool); isBool {
+		return 0, false
+	}
+	r, ok := toBig(value)
+	if !ok || !r.IsInt() || !r.Num().IsInt64() {
+		return 0, false
+	}
+	return r.Num().Int64(), true
+}
+
+// compositeRepr строит строковое представление несравнимого значения по ключам его элементов.
+// Мапы Go равны при совместимых типах ключей, поэтому тип значений в ключ не входит.
+func compositeRepr(rv reflect.Value) string {
+	var sb strings.Builder
+	switch rv.Kind() {
+	case reflect.Map:
+		keys := rv.MapKeys()
+		entries := make([]string, 0, len(keys))
+		for _, key := range keys {
+			entries = append(entries, fmt.Sprintf("%#v:%#v", HashKey(key.Interface()), HashKey(rv.MapIndex(key).Interface())))
+		}
+		sort.Strings(entries)
+		sb.WriteString("map[" + rv.Type().Key().String() + "]{" + strings.Join(entries, ",") + "}")
+	default:
+		sb.WriteString(rv.Type().String())
+		fmt.Fprintf(&sb, "%#v", rv.Interface())
+	}
+	return sb.String()
+}
+
+// Set - множество, сохраняющее исходные типы элементов и порядок добавления.
+type Set struct {
+	index map[any]int
+	items []any
+}
+
+// NewSet создаёт множество из элементов.
+func NewSet(items ...any) *Set {
+	s := &Set{index: make(map[any]int, len(items))}
+	for _, item := range items {
+		s.Add(item)
+	}
+	return s
+}
+
+// Add добавляет элемент; возвращает false, если он уже был в множестве.
+func (s *Set) Add(item any) bool {
+	key := HashKey(item)
+	if _, ok := s.index[key]; ok {
+		return false
+	}
+	s.index[key] = len(s.items)
+	s.items = append(s.items, item)
+	return true
+}
+
+// Remove удаляет элемент; возвращает false, если его не было.
+func (s *Set) Remove(item any) bool {
+	key := HashKey(item)
+	pos, ok := s.index[key]
+	if !ok {
+		return false
+	}
+	delete(s.index, key)
+	s.items = append(s.items[:pos], s.items[pos+1:]...)
+	for i := pos; i < len(s.items); i++ {
+		s.index[HashKey(s.items[i])] = i
+	}
+	return true
+}
+
+// Contains сообщает, содержится ли элемент в множестве.
+func (s *Set) Contains(item any) bool {
+	_, ok := s.index[HashKey(item)]
+	return ok
+}
+
+// Size возвращает число элементов.
+func (s *Set) Size() int {
+	return len(s.items)
+}
+
+// IsEmpty сообщает, пусто ли множество.
+func (s *Set) IsEmpty() bool {
+	return len(s.items) == 0
+}
+
+// Values возвращает копию элементов в порядке добавления.
+func (s *Set) Values() []any {
+	return append([]any(nil), s.items...)
+}
+
+// Iterator возвращает итератор элементов в порядке добавления.
+func (s *Set) Iterator() iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for _, item := range s.items {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// ToSlice преобразует множество в слайс, рекурсивно преобразуя вложенные Set и Map.
+func (s *Set) ToSlice() []any {
+	result := make([]any, len(s.items))
+	for i, item := range s.items {
+		result[i] = ToGoValue(item)
+	}
+	return result
+}
+
+// String возвращает представление множества в синтаксисе JEXL.
+func (s *Set) String() string {
+	parts := make([]string, len(s.items))
+	for i, item := range s.items {
+		parts[i] = formatElement(item)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// MarshalJSON сериализует множество как JSON-массив.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.ToSlice())
+}
+
+// Map - упорядоченная мапа, сохраняющая исходные типы ключей и порядок вставки.
+type Map struct {
+	index  map[any]int
+	keys   []any
+	values []any
+}
+
+// NewMap создаёт пустую мапу.
+func NewMap() *Map {
+	return &Map{index: make(map[any]int)}
+}
+
+// NewMapFrom создаёт мапу из Go-мапы. Ключи добавляются в порядке сортировки
+// их строкового представления, так как порядок Go-мапы не определён.
+func NewMapFrom(m any) *Map {
+	result := NewMap()
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Map {
+		return result
+	}
+	keys := rv.MapKeys()
+	names := make([]string, len(keys))
+	byName := make(map[string]reflect.Value, len(keys))
+	for i, key := range keys {
+		names[i] = fmt.Sprintf("%T:%v", key.Interface(), key.Interface())
+		byName[names[i]] = key
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := byName[name]
+		result.Put(key.Interface(), rv.MapIndex(key).Interface())
+	}
+	return result
+}
+
+// Get возвращает значение по ключу.
+func (m *Map) Get(key any) (any, bool) {
+	pos, ok := m.index[HashKey(key)]
+	if !ok {
+		return nil, false
+	}
+	return m.values[pos], true
+}
+
+// Put устанавливает значение; новый ключ добавляется в конец, существующий сохраняет позицию.
+func (m *Map) Put(key, value any) {
+	hash := HashKey(key)
+	if pos, ok := m.index[hash]; ok {
+		m.values[pos] = value
+		return
+	}
+	m.index[hash] = len(m.keys)
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+}
+
+// Remove удаляет ключ; возвращает false, если его не было.
+func (m *Map) Remove(key any) bool {
+	hash := HashKey(key)
+	pos, ok := m.index[hash]
+	if !ok {
+		return false
+	}
+	delete(m.index, hash)
+	m.keys = append(m.keys[:pos], m.keys[pos+1:]...)
+	m.values = append(m.values[:pos], m.values[pos+1:]...)
+	for i := pos; i < len(m.keys); i++ {
+		m.index[HashKey(m.keys[i])] = i
+	}
+	return true
+}
+
+// Has сообщает, содержится ли ключ в мапе.
+func (m *Map) Has(key any) bool {
+	_, ok := m.index[HashKey(key)]
+	return ok
+}
+
+// Size возвращает число записей.
+func (m *Map) Size() int {
+	return len(m.keys)
+}
+
+// IsEmpty сообщает, пуста ли мапа.
+func (m *Map) IsEmpty() bool {
+	return len(m.keys) == 0
+}
+
+// Keys возвращает копию ключей в порядке вставки.
+func (m *Map) Keys() []any {
+	return append([]any(nil), m.keys...)
+}
+
+// Values возвращает копию значений в порядке вставки.
+func (m *Map) Values() []any {
+	return append([]any(nil), m.values...)
+}
+
+// Iterator возвращает итератор пар ключ/значение в порядке вставки.
+func (m *Map) Iterator() iter.Seq2[any, any] {
+	return func(yield func(any, any) bool) {
+		for i := 0; i < len(m.keys); i++ {
+			if !yield(m.keys[i], m.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// ToGo преобразует мапу в map[any]any, рекурсивно преобразуя вложенные Set и Map.
+func (m *Map) ToGo() map[any]any {
+	result := make(map[any]any, len(m.keys))
+	for i, key := range m.keys {
+		if !reflect.ValueOf(key).Comparable() {
+			key = fmt.Sprintf("%v", key)
+		}
+		result[key] = ToGoValue(m.values[i])
+	}
+	return result
+}
+
+// ToStringMap преобразует мапу в map[string]any; нестроковые ключи форматируются через %v.
+func (m *Map) ToStringMap() map[string]any {
+	result := make(map[string]any, len(m.keys))
+	for i, key := range m.keys {
+		name, ok := key.(string)
+		if !ok {
+			name = fmt.Sprintf("%v", key)
+		}
+		result[name] = ToGoValue(m.values[i])
+	}
+	return result
+}
+
+// String возвращает представление мапы в синтаксисе JEXL.
+func (m *Map) String() string {
+	parts := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		parts[i] = formatElement(key) + ": " + formatElement(m.values[i])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// MarshalJSON сериализует мапу как JSON-объект с сохранением порядка ключей.
+// Нестроковые ключи форматируются через %v; если два ключа дают одно имя
+// (например, 1 и '1'), возвращается ошибка.
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteString("{")
+	names := make(map[string]bool, len(m.keys))
+	for i, key := range m.keys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		name, ok := key.(string)
+		if !ok {
+			name = fmt.Sprintf("%v", key)
+		}
+		if names[name] {
+			return nil, NewError(fmt.Sprintf("duplicate JSON object key %q", name))
+		}
+		names[name] = true
+		k, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		sb.Write(k)
+		sb.WriteString(":")
+		sb.Write(v)
+	}
+	sb.WriteString("}")
+	return []byte(sb.String()), nil
+}
+
+// ToGoValue рекурсивно преобразует Set в []any и Map в map[any]any;
+// остальные значения возвращаются как есть.
+func ToGoValue(value any) any {
+	switch v := value.(type) {
+	case *Set:
+		return v.ToSlice()
+	case *Map:
+		return v.ToGo()
+	}
+	return value
+}
+
+// formatElement форматирует элемент коллекции, заключая строки в кавычки.
+func formatElement(value any) string {
+	if s, ok := value.(string); ok {
+		return "'" + strings.ReplaceAll(s, "'", "\\'") + "'"
+	}
+	if value == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%v", value)
+}
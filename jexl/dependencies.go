@@ -0,0 +1,492 @@
+package jexl
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Access - вид обращения к зависимости, набор флагов.
+type Access uint8
+
+const (
+	AccessRead  Access = 1 << iota // значение читается
+	AccessWrite                    // значение присваивается
+	AccessCall                     // значение вызывается как функция или метод
+)
+
+// String возвращает флаги через "|", например "read|write".
+func (a Access) String() string {
+	var names []string
+	for _, flag := range []struct {
+		access Access
+		name   string
+	}{{AccessRead, "read"}, {AccessWrite, "write"}, {AccessCall, "call"}} {
+		if a&flag.access != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// DependencyKind - вид зависимости скрипта от контекста.
+type DependencyKind int
+
+const (
+	DependencyVariable  DependencyKind = iota // путь доступа к глобальной переменной
+	DependencyFunction                        // функция контекста: f(x)
+	DependencyNamespace                       // функция пространства имён: ns:f(x)
+)
+
+// Dependency - зависимость скрипта от контекста: глобальная переменная с путём
+// доступа, функция или функция пространства имён.
+type Dependency struct {
+	Kind      DependencyKind
+	Namespace string   // пространство имён для DependencyNamespace
+	Path      []string // имя и ключи доступа; "*" - индекс, вычисляемый при выполнении
+	Access    Access
+}
+
+// Root возвращает имя глобальной переменной или функции.
+func (d Dependency) Root() string {
+	if len(d.Path) == 0 {
+		return ""
+	}
+	return d.Path[0]
+}
+
+// String возвращает путь в виде order.customer.tier, items[*].price или ns:f.
+func (d Dependency) String() string {
+	var b strings.Builder
+	if d.Kind == DependencyNamespace {
+		b.WriteString(d.Namespace + ":")
+	}
+	for i, key := range d.Path {
+		switch {
+		case i == 0:
+			b.WriteString(key)
+		case key == "*":
+			b.WriteString("[*]")
+		case isPathName(key):
+			b.WriteString("." + key)
+		case key != "" && strings.Trim(key, "0123456789") == "":
+			b.WriteString("[" + key + "]")
+		default:
+			b.WriteString("['" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(key) + "']")
+		}
+	}
+	return b.String()
+}
+
+// isPathName проверяет, можно ли записать ключ через точку.
+func isPathName(key string) bool {
+	for i, r := range key {
+		if r != '_' && r != '$' && !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return key != ""
+}
+
+// AnalyzeDependencies находит зависимости узла от контекста: пути доступа к
+// глобальным переменным с видом обращения, функции и функции пространств имён.
+// Локальные переменные, параметры, объявленные в скрипте и встроенные функции
+// (Builtin) не входят в результат; встроенную функцию, которую хост переопределяет
+// функцией контекста, хост должен учитывать сам. Обращения к одному пути
+// объединяются, порядок - по первому обращению. Переменная цикла по глобальной
+// коллекции и параметр-элемент лямбды встроенного метода коллекции обозначают её
+// элементы: for (var i : items) { i.price } и items.map(i -> i.price) дают items[*].price.
+// collectMode как в Builder.CollectMode: 0 обрывает путь на индексе, иначе
+// константный ключ входит в путь, а вычисляемый обозначается "*".
+func AnalyzeDependencies(node Node, collectMode int) []Dependency {
+	a := &dependencyAnalyzer{
+		mode:   collectMode,
+		scopes: []map[string][]string{{}},
+		index:  make(map[string]int),
+	}
+	if script, ok := node.(*ScriptNode); ok {
+		a.lexical = script.Features() != nil && script.Features().IsLexical()
+		for _, def := range script.ParameterDefaults() {
+			a.walk(def)
+		}
+		for _, param := range script.Parameters() {
+			a.declare(param)
+		}
+	}
+	a.walk(node)
+	return a.deps
+}
+
+// isBuiltin проверяет, является ли имя встроенной функцией (см. Builtin).
+// Встроенные функции не входят в зависимости, даже если хост может
+// переопределить их одноимённой функцией контекста.
+func isBuiltin(name string) bool {
+	_, ok := builtinFunctions[name]
+	return ok
+}
+
+// elementMethods - встроенные методы коллекций, лямбда которых получает элемент;
+// значение - номер параметра-элемента (reduce получает аккумулятор первым).
+var elementMethods = map[string]int{
+	"map": 0, "filter": 0, "find": 0, "any": 0, "all": 0, "sortBy": 0, "groupBy": 0,
+	"flatMap": 0, "sum": 0, "min": 0, "max": 0, "avg": 0, "reduce": 1,
+}
+
+// keepsElements - методы коллекций, результат которых состоит из её же элементов:
+// в items.filter(f).map(i -> i.price) параметр i - по-прежнему элемент items.
+var keepsElements = map[string]bool{
+	"filter": true, "sortBy": true, "distinct": true, "skip": true, "take": true,
+}
+
+// dependencyAnalyzer собирает зависимости, отслеживая области видимости локальных имён.
+type dependencyAnalyzer struct {
+	mode    int
+	lexical bool                  // блоки открывают собственную область видимости
+	scopes  []map[string][]string // локальное имя -> путь элемента для переменной цикла или параметра-элемента
+	deps    []Dependency
+	index   map[string]int // ключ зависимости -> индекс в deps
+}
+
+// declare объявляет локальное имя в текущей области.
+func (a *dependencyAnalyzer) declare(name string) {
+	a.scopes[len(a.scopes)-1][name] = nil
+}
+
+// alias объявляет переменную цикла, обращения к которой - обращения к элементам
+// глобальной коллекции: для for (var i : items) путь i.price - items[*].price.
+func (a *dependencyAnalyzer) alias(name string, path []string) {
+	a.scopes[len(a.scopes)-1][name] = append(slices.Clip(path), "*")
+}
+
+// hoist объявляет функции блока до обхода его statements, как интерпретатор:
+// функцию можно вызвать до её объявления.
+func (a *dependencyAnalyzer) hoist(statements []Node) {
+	for _, statement := range statements {
+		if fn, ok := statement.(*FunctionNode); ok {
+			a.declare(fn.Name().Name())
+		}
+	}
+}
+
+// lookup ищет локальное имя от внутренней области к внешней и возвращает
+// путь элемента, если имя - переменная цикла по глобальной коллекции.
+func (a *dependencyAnalyzer) lookup(name string) (alias []string, local bool) {
+	for i := len(a.scopes) - 1; i >= 0; i-- {
+		if path, ok := a.scopes[i][name]; ok {
+			return path, true
+		}
+	}
+	return nil, false
+}
+
+// local проверяет, объявлено ли имя локально.
+func (a *dependencyAnalyzer) local(name string) bool {
+	_, local := a.lookup(name)
+	return local
+}
+
+// scoped выполняет f в новой области видимости. Блочная область создаётся
+// только в лексическом режиме, иначе имена видны до конца функции.
+func (a *dependencyAnalyzer) scoped(block bool, f func()) {
+	if block && !a.lexical {
+		f()
+		return
+	}
+	a.scopes = append(a.scopes, map[string][]string{})
+	f()
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+// record добавляет обращение к пути или объединяет его с уже найденным.
+func (a *dependencyAnalyzer) record(kind DependencyKind, namespace string, path []string, access Access) {
+	if len(path) == 0 || kind != DependencyNamespace && a.local(path[0]) {
+		return
+	}
+	key := fmt.Sprintf("%d:%s:%s", kind, namespace, strings.Join(path, "\x00"))
+	if i, ok := a.index[key]; ok {
+		a.deps[i].Access |= access
+		return
+	}
+	a.index[key] = len(a.deps)
+	a.deps = append(a.deps, Dependency{Kind: kind, Namespace: namespace, Path: slices.Clone(path), Access: access})
+}
+
+// walk обходит узел, считая обращения к переменным чтением.
+func (a *dependencyAnalyzer) walk(node Node) {
+	switch n := node.(type) {
+	case nil:
+	case *IdentifierNode, *PropertyAccessNode, *IndexAccessNode:
+		a.record(DependencyVariable, "", a.path(n), AccessRead)
+	case *MethodCallNode:
+		a.call(n)
+	case *AssignmentNode:
+		a.assign(n)
+	case *PipeNode:
+		a.walk(n.Value())
+		switch target := n.Target().(type) {
+		case *MethodCallNode:
+			a.call(target)
+		case *IdentifierNode:
+			if !isBuiltin(target.Name()) {
+				a.record(DependencyFunction, "", []string{target.Name()}, AccessCall)
+			}
+		default:
+			a.walk(target)
+		}
+	case *VarNode:
+		a.walk(n.Value())
+		a.declare(n.Name().Name())
+	case *DestructuringNode:
+		a.walk(n.Value())
+		a.bind(n.Pattern(), n.IsDeclaration())
+	case *ForeachNode:
+		items := a.path(n.Items())
+		a.record(DependencyVariable, "", items, AccessRead)
+		a.scoped(true, func() {
+			if key, ok := n.Key().(*IdentifierNode); ok {
+				a.declare(key.Name())
+			}
+			variable, ok := n.Variable().(*IdentifierNode)
+			if ok && a.aliased(items) {
+				a.alias(variable.Name(), items)
+			} else {
+				a.bind(n.Variable(), true)
+			}
+			a.walk(n.Body())
+		})
+	case *LambdaNode:
+		a.lambda(n, -1, nil)
+	case *FunctionNode:
+		a.declare(n.Name().Name())
+		a.walk(n.Lambda())
+	case *TryNode:
+		a.scoped(true, func() {
+			for _, resource := range n.Resources() {
+				a.walk(resource)
+			}
+			a.walk(n.TryBlock())
+		})
+		a.scoped(true, func() {
+			if n.CatchVar() != "" {
+				a.declare(n.CatchVar())
+			}
+			a.walk(n.CatchBlock())
+		})
+		a.walk(n.FinallyBlock())
+	case *ScriptNode:
+		a.hoist(n.Children())
+		for _, child := range n.Children() {
+			a.walk(child)
+		}
+	case *BlockNode, *ForNode:
+		a.scoped(true, func() {
+			if _, ok := n.(*BlockNode); ok {
+				a.hoist(n.Children())
+			}
+			for _, child := range n.Children() {
+				a.walk(child)
+			}
+		})
+	default:
+		for _, child := range node.Children() {
+			a.walk(child)
+		}
+	}
+}
+
+// aliased проверяет, обозначает ли переменная элементы коллекции items:
+// коллекция - глобальная переменная, а путь не обрывается на индексе.
+func (a *dependencyAnalyzer) aliased(items []string) bool {
+	return a.mode != 0 && len(items) > 0 && !a.local(items[0])
+}
+
+// lambda обходит лямбду в собственной области видимости. Параметр с номером
+// element обозначает элементы коллекции items (см. alias), если она глобальная.
+func (a *dependencyAnalyzer) lambda(n *LambdaNode, element int, items []string) {
+	a.scoped(false, func() {
+		defaults := n.Defaults()
+		for i, param := range n.Parameters() {
+			if i < len(defaults) {
+				a.walk(defaults[i])
+			}
+			if i == element && a.aliased(items) {
+				a.alias(param.Name(), items)
+			} else {
+				a.declare(param.Name())
+			}
+		}
+		a.walk(n.Body())
+	})
+}
+
+// path возвращает путь доступа к глобальной переменной для цепочки
+// обращений к свойствам и индексам. Части, не входящие в путь, обходятся
+// как обычные выражения; для них возвращается nil.
+func (a *dependencyAnalyzer) path(node Node) []string {
+	switch n := node.(type) {
+	case *IdentifierNode:
+		if alias, _ := a.lookup(n.Name()); alias != nil {
+			return slices.Clone(alias)
+		}
+		return []string{n.Name()}
+	case *PropertyAccessNode:
+		base := a.path(n.Object())
+		prop, ok := n.Property().(*IdentifierNode)
+		if base == nil || !ok {
+			a.record(DependencyVariable, "", base, AccessRead)
+			return nil
+		}
+		return append(base, prop.Name())
+	case *IndexAccessNode:
+		base := a.path(n.Object())
+		if base == nil || a.mode == 0 {
+			a.record(DependencyVariable, "", base, AccessRead)
+			a.walk(n.Index())
+			return nil
+		}
+		if lit, ok := n.Index().(*LiteralNode); ok && isStringOrNumber(lit.Value()) {
+			return append(base, fmt.Sprintf("%v", lit.Value()))
+		}
+		a.walk(n.Index())
+		return append(base, "*")
+	default:
+		a.walk(node)
+		return nil
+	}
+}
+
+// call учитывает вызов: метод переменной, функцию контекста или пространства имён.
+// В конвейере имя без цели может оказаться и методом значения, и функцией контекста;
+// встроенная функция не входит в зависимости и там. Возвращает путь глобальной
+// коллекции, элементы которой составляют результат вызова (см. keepsElements), или nil.
+func (a *dependencyAnalyzer) call(n *MethodCallNode) []string {
+	method, ok := n.Method().(*IdentifierNode)
+	var items []string
+	switch {
+	case !ok:
+		a.walk(n.Target())
+		a.walk(n.Method())
+	case n.Namespace() != "":
+		a.record(DependencyNamespace, n.Namespace(), []string{method.Name()}, AccessCall)
+	case n.Target() != nil:
+		var path []string
+		if inner, ok := n.Target().(*MethodCallNode); ok {
+			items = a.call(inner)
+		} else {
+			path = a.path(n.Target())
+			items = path
+		}
+		handled := items != nil && a.elementCall(n, method.Name(), items)
+		if !keepsElements[method.Name()] {
+			items = nil
+		}
+		if handled {
+			return items
+		}
+		if path != nil {
+			a.record(DependencyVariable, "", append(path, method.Name()), AccessCall)
+		}
+	case !isBuiltin(method.Name()):
+		a.record(DependencyFunction, "", []string{method.Name()}, AccessCall)
+	}
+	for _, arg := range n.Args() {
+		a.walk(arg)
+	}
+	return items
+}
+
+// elementCall учитывает вызов встроенного метода коллекции с лямбдой,
+// items.map(i -> i.price): коллекция читается, а обращения к параметру-элементу -
+// обращения к её элементам. Возвращает false, если вызов не такой.
+func (a *dependencyAnalyzer) elementCall(n *MethodCallNode, name string, items []string) bool {
+	element, ok := elementMethods[name]
+	if !ok {
+		return false
+	}
+	var fn *LambdaNode
+	for _, arg := range n.Args() {
+		if lambda, ok := arg.(*LambdaNode); ok {
+			fn = lambda
+		}
+	}
+	if fn == nil {
+		return false
+	}
+	a.record(DependencyVariable, "", items, AccessRead)
+	for _, arg := range n.Args() {
+		if arg == Node(fn) {
+			a.lambda(fn, element, items)
+		} else {
+			a.walk(arg)
+		}
+	}
+	return true
+}
+
+// assign учитывает присваивание; составное (x += y, x ??= y) также читает цель.
+func (a *dependencyAnalyzer) assign(n *AssignmentNode) {
+	access := AccessWrite
+	value := n.Value()
+	switch v := value.(type) {
+	case *BinaryOpNode:
+		if v.Left() == n.Target() {
+			access, value = AccessRead|AccessWrite, v.Right()
+		}
+	case *ElvisNode:
+		if v.Expr() == n.Target() {
+			access, value = AccessRead|AccessWrite, v.DefaultExpr()
+		}
+	}
+	a.walk(value)
+	if a.loopVariable(n.Target()) {
+		return
+	}
+	a.record(DependencyVariable, "", a.path(n.Target()), access)
+}
+
+// loopVariable проверяет, является ли цель присваивания переменной цикла по
+// глобальной коллекции: присваивание ей не изменяет элемент коллекции.
+func (a *dependencyAnalyzer) loopVariable(target Node) bool {
+	ident, ok := target.(*IdentifierNode)
+	if !ok {
+		return false
+	}
+	alias, _ := a.lookup(ident.Name())
+	return alias != nil
+}
+
+// bind связывает цель присваивания или шаблон деструктуризации:
+// при объявлении имена становятся локальными, иначе цели записываются.
+func (a *dependencyAnalyzer) bind(target Node, declare bool) {
+	var targets, defaults []Node
+	var rest *IdentifierNode
+	switch n := target.(type) {
+	case nil:
+		return
+	case *ArrayPatternNode:
+		targets, defaults, rest = n.Elements(), n.Defaults(), n.Rest()
+	case *MapPatternNode:
+		targets, defaults, rest = n.Targets(), n.Defaults(), n.Rest()
+	case *IdentifierNode:
+		if declare {
+			a.declare(n.Name())
+			return
+		}
+		if !a.loopVariable(n) {
+			a.record(DependencyVariable, "", a.path(n), AccessWrite)
+		}
+		return
+	default:
+		a.record(DependencyVariable, "", a.path(n), AccessWrite)
+		return
+	}
+	for i, t := range targets {
+		if i < len(defaults) {
+			a.walk(defaults[i])
+		}
+		a.bind(t, declare)
+	}
+	if rest != nil {
+		a.bind(rest, declare)
+	}
+}
@@ -0,0 +1,62 @@
+package jexl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity - важность диагностического сообщения.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityInfo
+)
+
+// String возвращает название важности.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "info"
+	default:
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+}
+
+// SuggestedFix - предлагаемое исправление: замена диапазона исходного текста.
+// Пустой диапазон (Offset == EndOffset) означает вставку.
+type SuggestedFix struct {
+	Message string // описание исправления, например "insert ';'"
+	Range   Position
+	NewText string
+}
+
+// Apply применяет исправление к исходному тексту.
+func (f *SuggestedFix) Apply(source string) string {
+	return source[:f.Range.Offset] + f.NewText + source[f.Range.EndOffset:]
+}
+
+// Diagnostic - сообщение о проблеме в исходном тексте скрипта.
+type Diagnostic struct {
+	Severity Severity
+	Message  string
+	Range    Position
+	Expected []string // ожидаемые токены, например "')'" или "identifier"
+	Found    string   // найденный токен
+	Fix      *SuggestedFix
+}
+
+// String форматирует сообщение в виде line:column: severity: message.
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: %v: %s", d.Range, d.Severity, d.Message)
+	if d.Fix != nil {
+		fmt.Fprintf(&b, " (%s)", d.Fix.Message)
+	}
+	return b.String()
+}
@@ -12,6 +12,23 @@ type Engine interface {
 	// CreateScript компилирует строку в исполняемый скрипт.
 	CreateScript(features *Features, info *Info, source string, names ...string) (Script, error)
 
+	// CreateScriptFromAST создаёт скрипт из готового AST, например, преобразованного Rewrite.
+	CreateScriptFromAST(ast *ScriptNode) (Script, error)
+
+	// LoadScript создаёт скрипт из AST, сериализованного MarshalAST или
+	// MarshalASTBinary, без повторного разбора исходного текста.
+	LoadScript(data []byte) (Script, error)
+
+	// ParseDiagnostics разбирает скрипт, не останавливаясь на первой ошибке: возвращает
+	// все синтаксические ошибки и частичный AST из успешно разобранных инструкций.
+	ParseDiagnostics(source string, features *Features) ([]Diagnostic, *ScriptNode)
+
+	// CheckTypes выводит типы выражений скрипта и проверяет их по схеме переменных:
+	// неизвестные переменные, свойства и методы, число аргументов вызовов, типы операндов
+	// и обращения к значениям, которые могут быть null. Свойства и методы типов Go
+	// разрешаются по правилам Uberspect.
+	CheckTypes(ast *ScriptNode, schema *Schema) []Diagnostic
+
 	// CreateTemplateEngine создаёт движок шаблонов JXLT.
 	CreateTemplateEngine(opts ...TemplateOption) (*TemplateEngine, error)
 
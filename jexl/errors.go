@@ -18,6 +18,9 @@ func (e *Error) Error() string {
 		return "<nil>"
 	}
 	if e.cause != nil {
+		if e.message == "" {
+			return e.cause.Error()
+		}
 		return fmt.Sprintf("%s: %v", e.message, e.cause)
 	}
 	return e.message
@@ -53,16 +56,21 @@ func WrapError(message string, cause error, info *Info) *Error {
 	}
 }
 
+// baseError - псевдоним Error для встраивания в типизированные ошибки.
+// Встраивание через псевдоним не создаёт поля Error, поэтому метод Error()
+// продвигается и типизированные ошибки реализуют интерфейс error.
+type baseError = Error
+
 // ParsingError представляет ошибку парсинга.
 type ParsingError struct {
-	*Error
+	*baseError
 	expression string
 }
 
 // NewParsingError создаёт ошибку парсинга.
 func NewParsingError(message, expression string, info *Info) *ParsingError {
 	return &ParsingError{
-		Error:      WrapError(message, nil, info),
+		baseError:  WrapError(message, nil, info),
 		expression: expression,
 	}
 }
@@ -74,7 +82,7 @@ func (e *ParsingError) Expression() string {
 
 // MethodError представляет ошибку вызова метода.
 type MethodError struct {
-	*Error
+	*baseError
 	method string
 	args   []any
 }
@@ -82,9 +90,9 @@ type MethodError struct {
 // NewMethodError создаёт ошибку вызова метода.
 func NewMethodError(method string, args []any, info *Info, cause error) *MethodError {
 	return &MethodError{
-		Error:  WrapError("unsolvable function/method '"+methodSignature(method, args)+"'", cause, info),
-		method: method,
-		args:   args,
+		baseError: WrapError("unsolvable function/method '"+methodSignature(method, args)+"'", cause, info),
+		method:    method,
+		args:      args,
 	}
 }
 
@@ -100,15 +108,15 @@ func (e *MethodError) Args() []any {
 
 // OperatorError представляет ошибку оператора.
 type OperatorError struct {
-	*Error
+	*baseError
 	symbol string
 }
 
 // NewOperatorError создаёт ошибку оператора.
 func NewOperatorError(symbol string, info *Info, cause error) *OperatorError {
 	return &OperatorError{
-		Error:  WrapError("error calling operator '"+symbol+"'", cause, info),
-		symbol: symbol,
+		baseError: WrapError("error calling operator '"+symbol+"'", cause, info),
+		symbol:    symbol,
 	}
 }
 
@@ -119,15 +127,15 @@ func (e *OperatorError) Symbol() string {
 
 // PropertyError представляет ошибку доступа к свойству.
 type PropertyError struct {
-	*Error
+	*baseError
 	property string
 }
 
 // NewPropertyError создаёт ошибку доступа к свойству.
 func NewPropertyError(property string, info *Info, cause error) *PropertyError {
 	return &PropertyError{
-		Error:    WrapError("error accessing property '"+property+"'", cause, info),
-		property: property,
+		baseError: WrapError("error accessing property '"+property+"'", cause, info),
+		property:  property,
 	}
 }
 
@@ -136,6 +144,57 @@ func (e *PropertyError) Property() string {
 	return e.property
 }
 
+// SuppressedError дополняет основную ошибку ошибками, подавленными при её обработке.
+// Аналог Throwable.getSuppressed из Java: например, ошибки закрытия ресурсов try
+// присоединяются к ошибке, возникшей в теле try.
+type SuppressedError struct {
+	err        error
+	suppressed []error
+}
+
+// WithSuppressed присоединяет подавленные ошибки к основной ошибке.
+// Если основная ошибка уже является SuppressedError, список дополняется.
+func WithSuppressed(err error, suppressed ...error) error {
+	if err == nil || len(suppressed) == 0 {
+		return err
+	}
+	if se, ok := err.(*SuppressedError); ok {
+		return &SuppressedError{
+			err:        se.err,
+			suppressed: append(append([]error(nil), se.suppressed...), suppressed...),
+		}
+	}
+	return &SuppressedError{err: err, suppressed: append([]error(nil), suppressed...)}
+}
+
+// Error реализует интерфейс error.
+func (e *SuppressedError) Error() string {
+	if e == nil {
+		return "<nil>"
+	}
+	msgs := make([]string, len(e.suppressed))
+	for i, s := range e.suppressed {
+		msgs[i] = s.Error()
+	}
+	return fmt.Sprintf("%v (suppressed: %s)", e.err, strings.Join(msgs, "; "))
+}
+
+// Unwrap возвращает основную ошибку.
+func (e *SuppressedError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.err
+}
+
+// Suppressed возвращает подавленные ошибки.
+func (e *SuppressedError) Suppressed() []error {
+	if e == nil {
+		return nil
+	}
+	return append([]error(nil), e.suppressed...)
+}
+
 // methodSignature создаёт строку сигнатуры метода.
 func methodSignature(method string, args []any) string {
 	if len(args) == 0 {
@@ -8,6 +8,7 @@ type Expression interface {
 	// Evaluate вычисляет выражение в заданном контексте.
 	Evaluate(ctx Context) (any, error)
 	// ParsedText возвращает восстановленный текст выражения из AST.
+	// При Options.Optimize текст остаётся исходным, а не оптимизированным.
 	ParsedText() string
 	// SourceText возвращает исходный текст.
 	SourceText() string
@@ -0,0 +1,89 @@
+// Package format приводит исходный текст JEXL к каноническому виду:
+// отступы, расстановка фигурных скобок, пробелы вокруг операторов,
+// необязательное удаление лишних скобок и сохранение комментариев.
+// Форматирование идемпотентно: повторный вызов не меняет результат.
+package format
+
+import (
+	"strings"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/internal"
+)
+
+// BraceStyle - расположение открывающей фигурной скобки инструкций.
+type BraceStyle int
+
+const (
+	// BraceSameLine оставляет скобку на строке заголовка: if (x) {
+	BraceSameLine BraceStyle = iota
+	// BraceNextLine переносит скобку на отдельную строку (кроме тел lambda).
+	BraceNextLine
+)
+
+// Options - настройки стиля.
+type Options struct {
+	IndentWidth    int  // ширина отступа в пробелах, 0 - 4
+	UseTabs        bool // отступ табуляцией вместо пробелов
+	Braces         BraceStyle
+	MinimizeParens bool // удалять скобки, не влияющие на разбор
+}
+
+// DefaultOptions возвращает стиль по умолчанию.
+func DefaultOptions() *Options {
+	return &Options{IndentWidth: 4}
+}
+
+// indent возвращает строку одного уровня отступа.
+func (o *Options) indent() string {
+	if o.UseTabs {
+		return "\t"
+	}
+	width := o.IndentWidth
+	if width <= 0 {
+		width = 4
+	}
+	return strings.Repeat(" ", width)
+}
+
+// Source форматирует исходный текст скрипта с сохранением комментариев.
+// Ошибка разбора возвращается без изменений.
+func Source(source string, opts *Options) (string, error) {
+	ast, err := internal.NewDefaultParser().ParseScript(nil, source, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	p := newPrinter(opts)
+	p.source = source
+	p.comments = scanComments(source)
+	p.script(ast)
+	return p.String(), nil
+}
+
+// Node форматирует AST без комментариев. Узлы без исходного текста
+// (например, построенные программно) печатаются по значениям.
+func Node(node jexl.Node, opts *Options) string {
+	p := newPrinter(opts)
+	if script, ok := node.(*jexl.ScriptNode); ok {
+		p.script(script)
+	} else {
+		p.statement(node)
+	}
+	return p.String()
+}
+
+// comment - комментарий исходного текста.
+type comment struct {
+	start, end int
+	text       string
+	done       bool // уже напечатан
+}
+
+// scanComments находит комментарии исходного текста (см. internal.ScanComments).
+func scanComments(source string) []*comment {
+	var comments []*comment
+	for _, c := range internal.ScanComments(source) {
+		comments = append(comments, &comment{start: c.Start, end: c.End, text: c.Text})
+	}
+	return comments
+}
@@ -0,0 +1,1125 @@
+package format
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/internal"
+)
+
+// Уровни приоритета при печати: на единицу больше приоритета парсера,
+// чтобы нулевой уровень остался для присваивания и lambda.
+const (
+	levelLowest   = 0  // присваивание, lambda
+	levelTernary  = 1  // c ? a : b, a ?: b
+	levelCoalesce = 4  // a ?? b
+	levelCompare  = 10 // сравнения, .., |>
+	levelUnary    = 16 // -x, !x, ++x, empty(x)
+	levelPostfix  = 18 // первичные выражения, a.b, a[b], f(x)
+)
+
+// binaryLevels - уровни бинарных операторов.
+var binaryLevels = map[string]int{
+	"||": 2, "or": 2,
+	"&&": 3, "and": 3,
+	"?": 3, // в ветвях тернарного оператора a ? b без : разбирается как бинарный
+	"|": 4, "^": 5, "&": 6,
+	"=~": 8, "!~": 8, "=^": 8, "!^": 8, "=$": 8, "!$": 8,
+	"in": 8, "!in": 8, "instanceof": 8, "!instanceof": 8,
+	"==": 9, "!=": 9, "eq": 9, "ne": 9,
+	"<": 10, "<=": 10, ">": 10, ">=": 10, "lt": 10, "le": 10, "gt": 10, "ge": 10,
+	"+": 11, "-": 11,
+	"<<": 12, ">>": 12, ">>>": 12,
+	"*": 13, "/": 13, "%": 13, "div": 13,
+	"**": 17, // сильнее унарных: -2 ** 2 == -(2 ** 2)
+}
+
+// compoundOperators - операторы, которые парсер разворачивает из x op= y.
+var compoundOperators = map[string]bool{
+	"+": true, "-": true, "*": true, "/": true, "%": true, "**": true,
+	"&": true, "|": true, "^": true, "<<": true, ">>": true, ">>>": true,
+}
+
+// printer накапливает отформатированный текст.
+type printer struct {
+	opts     Options
+	unit     string // один уровень отступа
+	source   string // исходный текст; пуст при печати AST без источника
+	comments []*comment
+	out      *strings.Builder
+	depth    int
+	bol      bool // начало строки: перед выводом нужен отступ
+	last     int  // конец последнего напечатанного фрагмента исходного текста
+	top      bool // следующая инструкция - инструкция верхнего уровня скрипта
+}
+
+// newPrinter создаёт printer с указанным стилем.
+func newPrinter(opts *Options) *printer {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
+	return &printer{opts: *opts, unit: opts.indent(), out: &strings.Builder{}, bol: true}
+}
+
+// String возвращает напечатанный текст.
+func (p *printer) String() string {
+	return p.out.String()
+}
+
+func (p *printer) write(s string) {
+	if s == "" {
+		return
+	}
+	if p.bol {
+		p.out.WriteString(strings.Repeat(p.unit, p.depth))
+		p.bol = false
+	}
+	p.out.WriteString(s)
+}
+
+func (p *printer) newline() {
+	p.out.WriteByte('\n')
+	p.bol = true
+}
+
+// capture печатает фрагмент в отдельный буфер и возвращает его текст.
+func (p *printer) capture(f func()) string {
+	out, bol := p.out, p.bol
+	p.out, p.bol = &strings.Builder{}, false
+	f()
+	text := p.out.String()
+	p.out, p.bol = out, bol
+	return text
+}
+
+// span возвращает границы узла в исходном тексте или -1, если их нет.
+func (p *printer) span(node jexl.Node) (int, int) {
+	if p.source == "" || node == nil {
+		return -1, -1
+	}
+	pos := node.Position()
+	if !pos.IsValid() || pos.EndOffset > len(p.source) {
+		return -1, -1
+	}
+	return pos.Offset, pos.EndOffset
+}
+
+// gap сохраняет одну пустую строку, если она была в исходном тексте перед offset.
+func (p *printer) gap(offset int, first *bool) {
+	if !*first && p.last >= 0 && offset > p.last && strings.Count(p.source[p.last:offset], "\n") > 1 {
+		p.newline()
+	}
+	*first = false
+}
+
+// leading печатает отдельными строками комментарии, начинающиеся до offset.
+func (p *printer) leading(offset int, first *bool) {
+	for _, c := range p.comments {
+		if c.done {
+			continue
+		}
+		if c.start >= offset {
+			break
+		}
+		p.gap(c.start, first)
+		p.write(c.text)
+		p.newline()
+		c.done, p.last = true, c.end
+	}
+}
+
+// trailing печатает в конце строки комментарии, оставшиеся внутри инструкции
+// [.., stop), и комментарии на той же строке после неё (до limit).
+func (p *printer) trailing(stop, limit int) {
+	p.last = stop
+	lineComment := false
+	for _, c := range p.comments {
+		if c.done {
+			continue
+		}
+		if c.start >= limit || c.start >= stop && strings.Contains(p.source[stop:c.start], "\n") {
+			break
+		}
+		if lineComment {
+			// После // до конца строки ничего не допишешь: остальные комментарии - отдельными строками
+			p.newline()
+			p.write(c.text)
+		} else {
+			p.write(" " + c.text)
+		}
+		lineComment = lineComment || !strings.HasPrefix(c.text, "/*")
+		c.done = true
+		p.last = max(p.last, c.end)
+	}
+}
+
+// hasComments проверяет, остались ли комментарии до offset.
+func (p *printer) hasComments(offset int) bool {
+	for _, c := range p.comments {
+		if !c.done && c.start < offset {
+			return true
+		}
+	}
+	return false
+}
+
+// script печатает инструкции скрипта, завершая текст переводом строки.
+func (p *printer) script(script *jexl.ScriptNode) {
+	end := -1
+	if p.source != "" {
+		end = len(p.source) + 1
+	}
+	p.statements(script.Children(), end, true)
+}
+
+// statements печатает инструкции по одной на строке; end - граница
+// комментариев списка (конец блока). В скрипте последняя инструкция
+// остаётся без точки с запятой.
+func (p *printer) statements(stmts []jexl.Node, end int, script bool) {
+	first := true
+	for i, stmt := range stmts {
+		start, stop := p.span(stmt)
+		if start >= 0 {
+			p.leading(start, &first)
+			p.gap(start, &first)
+		}
+		first = false
+		p.top = script
+		p.statement(stmt)
+		if needsSemicolon(stmt) && (!script || i < len(stmts)-1) || script && ambiguousEnd(stmts[i:]) {
+			p.write(";")
+		}
+		if stop >= 0 {
+			limit := end
+			if i+1 < len(stmts) {
+				if next, _ := p.span(stmts[i+1]); next >= 0 {
+					limit = next
+				}
+			}
+			p.trailing(stop, limit)
+		}
+		p.newline()
+	}
+	if end >= 0 {
+		p.leading(end, &first)
+	}
+}
+
+// needsSemicolon проверяет, нужна ли точка с запятой после инструкции.
+func needsSemicolon(node jexl.Node) bool {
+	switch n := node.(type) {
+	case nil, *jexl.BlockNode, *jexl.FunctionNode, *jexl.TryNode, *jexl.SwitchNode:
+		return false
+	case *jexl.IfNode:
+		if n.ElseBranch() != nil {
+			return needsSemicolon(n.ElseBranch())
+		}
+		return needsSemicolon(n.ThenBranch())
+	case *jexl.ForNode:
+		return needsSemicolon(n.Body())
+	case *jexl.ForeachNode:
+		return needsSemicolon(n.Body())
+	case *jexl.WhileNode:
+		return needsSemicolon(n.Body())
+	}
+	return true
+}
+
+// ambiguousEnd проверяет, нужна ли точка с запятой после инструкции скрипта,
+// завершённой блоком: парсер отвергает две такие инструкции подряд,
+// если за ними идёт выражение.
+func ambiguousEnd(stmts []jexl.Node) bool {
+	if len(stmts) < 3 || needsSemicolon(stmts[0]) || needsSemicolon(stmts[1]) || !isKeywordStatement(stmts[1]) {
+		return false
+	}
+	if _, ok := stmts[1].(*jexl.FunctionNode); ok {
+		return false
+	}
+	return !isKeywordStatement(stmts[2])
+}
+
+// isKeywordStatement проверяет, начинается ли инструкция с ключевого слова.
+func isKeywordStatement(node jexl.Node) bool {
+	switch n := node.(type) {
+	case *jexl.IfNode, *jexl.ForNode, *jexl.ForeachNode, *jexl.WhileNode, *jexl.DoWhileNode,
+		*jexl.ReturnNode, *jexl.BreakNode, *jexl.ContinueNode, *jexl.VarNode,
+		*jexl.TryNode, *jexl.SwitchNode, *jexl.FunctionNode:
+		return true
+	case *jexl.DestructuringNode:
+		return n.IsDeclaration()
+	}
+	return false
+}
+
+// block печатает блок; непустой блок занимает несколько строк.
+func (p *printer) block(block *jexl.BlockNode) {
+	_, end := p.span(block)
+	stmts := block.Statements()
+	if len(stmts) == 0 && (end < 0 || !p.hasComments(end)) {
+		p.write("{}")
+		return
+	}
+	p.write("{")
+	p.newline()
+	p.depth++
+	p.statements(stmts, end, false)
+	p.depth--
+	p.write("}")
+}
+
+// openBrace ставит разделитель перед открывающей скобкой инструкции.
+func (p *printer) openBrace() {
+	if p.opts.Braces == BraceNextLine {
+		p.newline()
+	} else {
+		p.write(" ")
+	}
+}
+
+// body печатает тело инструкции после заголовка.
+func (p *printer) body(node jexl.Node) {
+	switch n := node.(type) {
+	case nil:
+		p.write(" ;")
+	case *jexl.BlockNode:
+		p.openBrace()
+		p.block(n)
+	default:
+		p.write(" ")
+		p.statement(node)
+	}
+}
+
+// continuation печатает разделитель перед else, catch, finally и while после тела.
+func (p *printer) continuation(body jexl.Node) {
+	switch body.(type) {
+	case nil:
+		p.write(" ")
+	case *jexl.BlockNode:
+		p.openBrace()
+	default:
+		if needsSemicolon(body) {
+			p.write(";")
+		}
+		p.write(" ")
+	}
+}
+
+// statement печатает инструкцию без завершающей точки с запятой.
+func (p *printer) statement(node jexl.Node) {
+	top := p.top
+	p.top = false
+	switch n := node.(type) {
+	case *jexl.BlockNode:
+		p.block(n)
+	case *jexl.IfNode:
+		p.write("if (")
+		p.bare(n.Condition())
+		p.write(")")
+		p.body(n.ThenBranch())
+		if els := n.ElseBranch(); els != nil {
+			p.continuation(n.ThenBranch())
+			p.write("else")
+			if _, ok := els.(*jexl.IfNode); ok {
+				p.write(" ")
+				p.statement(els)
+			} else {
+				p.body(els)
+			}
+		}
+	case *jexl.ForNode:
+		p.write("for (")
+		if n.Init() != nil {
+			p.expr(n.Init(), levelLowest)
+		}
+		p.write(";")
+		if n.Condition() != nil {
+			p.write(" ")
+			p.expr(n.Condition(), levelLowest)
+		}
+		p.write(";")
+		if n.Step() != nil {
+			p.write(" ")
+			p.expr(n.Step(), levelLowest)
+		}
+		p.write(")")
+		p.body(n.Body())
+	case *jexl.ForeachNode:
+		p.write("for (")
+		if _, ident := n.Variable().(*jexl.IdentifierNode); !ident || strings.HasPrefix(n.SourceText(), "for (var ") {
+			p.write("var ")
+		}
+		if n.Key() != nil {
+			p.target(n.Key())
+			p.write(", ")
+		}
+		p.target(n.Variable())
+		p.write(" : ")
+		p.expr(n.Items(), levelLowest)
+		p.write(")")
+		p.body(n.Body())
+	case *jexl.WhileNode:
+		p.write("while (")
+		p.bare(n.Condition())
+		p.write(")")
+		p.body(n.Body())
+	case *jexl.DoWhileNode:
+		p.write("do")
+		p.body(n.Body())
+		p.continuation(n.Body())
+		p.write("while (")
+		p.bare(n.Condition())
+		p.write(")")
+	case *jexl.BreakNode:
+		p.write("break")
+	case *jexl.ContinueNode:
+		p.write("continue")
+	case *jexl.ReturnNode:
+		p.write("return")
+		if n.Value() != nil {
+			p.write(" ")
+			p.expr(n.Value(), levelLowest)
+		}
+	case *jexl.VarNode:
+		p.write("var " + n.Name().Name())
+		if n.Value() != nil {
+			p.write(" = ")
+			p.expr(n.Value(), levelLowest)
+		}
+	case *jexl.FunctionNode:
+		p.write("function " + n.Name().Name())
+		p.parameters(n.Lambda())
+		if body, ok := n.Lambda().Body().(*jexl.BlockNode); ok {
+			p.openBrace()
+			p.block(body)
+		} else {
+			p.write(" {")
+			p.bare(n.Lambda().Body())
+			p.write("}")
+		}
+	case *jexl.SwitchNode:
+		p.switchNode(n)
+	case *jexl.TryNode:
+		p.tryNode(n)
+	default:
+		text := p.capture(func() { p.exprNode(node) })
+		// Внутри блока { в начале инструкции открывает вложенный блок
+		if !top && strings.HasPrefix(text, "{") {
+			text = "(" + text + ")"
+		}
+		p.write(text)
+	}
+}
+
+// switchNode печатает switch; ветви - по одной на строке.
+func (p *printer) switchNode(n *jexl.SwitchNode) {
+	p.write("switch (")
+	p.bare(n.Expression())
+	p.write(")")
+	p.openBrace()
+	p.write("{")
+	p.newline()
+	p.depth++
+	_, end := p.span(n)
+	cases := n.Cases()
+	first := true
+	for i, c := range cases {
+		start, stop := p.span(c)
+		if start >= 0 {
+			p.leading(start, &first)
+			p.gap(start, &first)
+		}
+		first = false
+		p.switchCase(n, c)
+		if stop >= 0 {
+			limit := end
+			if i+1 < len(cases) {
+				if next, _ := p.span(cases[i+1]); next >= 0 {
+					limit = next
+				}
+			}
+			p.trailing(stop, limit)
+		}
+		p.newline()
+	}
+	if end >= 0 {
+		p.leading(end, &first)
+	}
+	p.depth--
+	p.write("}")
+}
+
+// switchCase печатает ветвь switch. Значения ветви берутся из исходного текста:
+// CaseNode хранит для выражений только их текст.
+func (p *printer) switchCase(sw *jexl.SwitchNode, c *jexl.CaseNode) {
+	separator := ":"
+	if !sw.IsStatement() {
+		separator = "->"
+	}
+	if c.IsDefault() {
+		p.write("default")
+	} else {
+		values, arrow := p.caseValues(c)
+		p.write("case " + values)
+		if arrow != "" {
+			separator = arrow
+		}
+	}
+	p.write(separator + " ")
+	if block, ok := c.Body().(*jexl.BlockNode); ok {
+		p.block(block)
+	} else if sw.IsStatement() {
+		p.statement(c.Body())
+	} else {
+		p.expr(c.Body(), levelLowest)
+	}
+}
+
+// caseValues возвращает текст значений ветви и использованную стрелку.
+func (p *printer) caseValues(c *jexl.CaseNode) (string, string) {
+	start, _ := p.span(c)
+	body, _ := p.span(c.Body())
+	if start < 0 || body < start {
+		values := make([]string, len(c.Values()))
+		for i, value := range c.Values() {
+			values[i] = renderValue(value)
+		}
+		return strings.Join(values, ", "), ""
+	}
+	// Текст между case и телом без комментариев: они попадут в конец строки
+	var text strings.Builder
+	from := start
+	for _, comment := range p.comments {
+		if comment.start >= from && comment.end <= body {
+			text.WriteString(p.source[from:comment.start])
+			from = comment.end
+		}
+	}
+	text.WriteString(p.source[from:body])
+	values := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text.String()), "case"))
+	arrow := ""
+	for _, separator := range []string{":", "->", "=>"} {
+		if strings.HasSuffix(values, separator) {
+			values = strings.TrimSpace(strings.TrimSuffix(values, separator))
+			if separator != ":" {
+				arrow = separator
+			}
+			break
+		}
+	}
+	return p.canonicalList(values), arrow
+}
+
+// canonicalList форматирует список выражений через запятую;
+// при ошибке разбора текст возвращается как есть.
+func (p *printer) canonicalList(text string) string {
+	source := "[" + text + "]"
+	ast, err := internal.NewDefaultParser().ParseExpression(nil, source, nil)
+	if err != nil || len(ast.Children()) != 1 {
+		return text
+	}
+	array, ok := ast.Children()[0].(*jexl.ArrayLiteralNode)
+	if !ok {
+		return text
+	}
+	list := newPrinter(&p.opts)
+	list.source = source
+	list.list(array.Elements())
+	return list.String()
+}
+
+// tryNode печатает try с ресурсами, catch и finally.
+func (p *printer) tryNode(n *jexl.TryNode) {
+	p.write("try")
+	if n.HasResources() {
+		p.write(" (")
+		for i, resource := range n.Resources() {
+			if i > 0 {
+				p.write("; ")
+			}
+			p.statement(resource)
+		}
+		p.write(")")
+	}
+	p.tryBlock(n.TryBlock())
+	if n.HasCatch() {
+		p.continuation(n.TryBlock())
+		p.write("catch")
+		if n.CatchVar() != "" {
+			p.write(" (" + n.CatchVar() + ")")
+		}
+		p.tryBlock(n.CatchBlock())
+	}
+	if n.HasFinally() {
+		p.continuation(n.TryBlock())
+		p.write("finally")
+		p.tryBlock(n.FinallyBlock())
+	}
+}
+
+// tryBlock печатает блок try, catch или finally.
+func (p *printer) tryBlock(node jexl.Node) {
+	p.openBrace()
+	if block, ok := node.(*jexl.BlockNode); ok {
+		p.block(block)
+		return
+	}
+	p.write("{")
+	p.bare(node)
+	p.write("}")
+}
+
+// bare печатает выражение внутри синтаксических скобок: if (x), f(x).
+func (p *printer) bare(node jexl.Node) {
+	p.exprNode(node)
+}
+
+// expr печатает выражение, заключая его в скобки, если его уровень ниже min
+// или скобки были в исходном тексте (без Options.MinimizeParens).
+func (p *printer) expr(node jexl.Node, min int) {
+	if level(node) < min || !p.opts.MinimizeParens && p.parenthesized(node) {
+		p.write("(")
+		p.exprNode(node)
+		p.write(")")
+		return
+	}
+	p.exprNode(node)
+}
+
+// parenthesized проверяет, заключён ли узел в скобки в исходном тексте.
+func (p *printer) parenthesized(node jexl.Node) bool {
+	start, end := p.span(node)
+	if start < 0 {
+		return false
+	}
+	before := strings.TrimRight(p.source[:start], " \t\r\n")
+	after := strings.TrimLeft(p.source[end:], " \t\r\n")
+	return strings.HasSuffix(before, "(") && strings.HasPrefix(after, ")")
+}
+
+// level возвращает уровень приоритета узла.
+func level(node jexl.Node) int {
+	switch n := node.(type) {
+	case *jexl.AssignmentNode:
+		if isIncrement(n) {
+			return levelUnary
+		}
+		return levelLowest
+	case *jexl.LambdaNode, *jexl.DestructuringNode:
+		return levelLowest
+	case *jexl.TernaryNode:
+		return levelTernary
+	case *jexl.ElvisNode:
+		if isShortElvis(n) {
+			return levelTernary
+		}
+		return levelCoalesce
+	case *jexl.BinaryOpNode:
+		return binaryLevels[n.Op()]
+	case *jexl.RangeNode, *jexl.PipeNode:
+		return levelCompare
+	case *jexl.UnaryOpNode:
+		return levelUnary
+	case *jexl.MethodCallNode:
+		if isEmptyCall(n) {
+			return levelUnary
+		}
+	}
+	return levelPostfix
+}
+
+// isIncrement распознаёт развёрнутые парсером ++x и --x.
+func isIncrement(n *jexl.AssignmentNode) bool {
+	src := n.SourceText()
+	return strings.HasPrefix(src, "++") || strings.HasPrefix(src, "--")
+}
+
+// compound распознаёт развёрнутое парсером x op= y и возвращает op.
+func compound(n *jexl.AssignmentNode) (string, jexl.Node, bool) {
+	switch value := n.Value().(type) {
+	case *jexl.BinaryOpNode:
+		if value.Left() == n.Target() && compoundOperators[value.Op()] {
+			return value.Op(), value.Right(), true
+		}
+	case *jexl.ElvisNode:
+		if value.Expr() == n.Target() {
+			return "??", value.DefaultExpr(), true
+		}
+	}
+	return "", nil, false
+}
+
+// isShortElvis отличает a ?: b от a ?? b.
+func isShortElvis(n *jexl.ElvisNode) bool {
+	return n.Expr() != nil && strings.HasPrefix(strings.TrimPrefix(n.SourceText(), n.Expr().SourceText()), " ?: ")
+}
+
+// isEmptyCall распознаёт оператор empty, который парсер превращает в вызов.
+func isEmptyCall(n *jexl.MethodCallNode) bool {
+	ident, ok := n.Method().(*jexl.IdentifierNode)
+	return ok && n.Target() == nil && n.Namespace() == "" && ident.Name() == "empty" && len(n.Args()) == 1
+}
+
+// exprNode печатает выражение без внешних скобок.
+func (p *printer) exprNode(node jexl.Node) {
+	switch n := node.(type) {
+	case nil:
+	case *jexl.LiteralNode:
+		if n.SourceText() != "" {
+			p.write(n.SourceText())
+		} else {
+			p.write(renderValue(n.Value()))
+		}
+	case *jexl.IdentifierNode:
+		p.write(n.Name())
+	case *jexl.BinaryOpNode:
+		p.binary(n)
+	case *jexl.UnaryOpNode:
+		p.unary(n)
+	case *jexl.PropertyAccessNode:
+		property := p.capture(func() { p.exprNode(n.Property()) })
+		p.object(n.Object(), property)
+		p.write("." + property)
+	case *jexl.IndexAccessNode:
+		p.expr(n.Object(), levelPostfix)
+		p.write("[")
+		p.expr(n.Index(), levelLowest)
+		p.write("]")
+	case *jexl.MethodCallNode:
+		p.call(n, false)
+	case *jexl.AssignmentNode:
+		p.assignment(n)
+	case *jexl.TernaryNode:
+		p.expr(n.Condition(), levelTernary+1)
+		p.write(" ? ")
+		p.expr(n.TrueExpr(), levelTernary+2)
+		p.write(" : ")
+		p.expr(n.FalseExpr(), levelTernary+2)
+	case *jexl.ElvisNode:
+		if isShortElvis(n) {
+			p.expr(n.Expr(), levelTernary+1)
+			p.write(" ?: ")
+			p.expr(n.DefaultExpr(), levelTernary+2)
+		} else {
+			p.expr(n.Expr(), levelPostfix)
+			p.write(" ?? ")
+			// a ?? b ?? c разбирается справа налево
+			if def, ok := n.DefaultExpr().(*jexl.ElvisNode); ok && !isShortElvis(def) {
+				p.expr(def, levelCoalesce)
+			} else {
+				p.expr(n.DefaultExpr(), levelCoalesce+1)
+			}
+		}
+	case *jexl.RangeNode:
+		p.expr(n.Left(), levelCompare)
+		p.write(" .. ")
+		p.expr(n.Right(), levelCompare+1)
+		if n.Step() != nil {
+			p.write(" step ")
+			p.expr(n.Step(), levelCompare+1)
+		}
+	case *jexl.PipeNode:
+		p.expr(n.Value(), levelCompare)
+		p.write(" |> ")
+		if call, ok := n.Target().(*jexl.MethodCallNode); ok && call.Namespace() != "" &&
+			call.Args() == nil && !strings.HasSuffix(call.SourceText(), ")") {
+			p.call(call, true)
+		} else {
+			p.expr(n.Target(), levelCompare+1)
+		}
+	case *jexl.ArrayLiteralNode:
+		p.write("[")
+		p.list(n.Elements())
+		p.write("]")
+	case *jexl.SetLiteralNode:
+		p.write("{")
+		elements := n.Elements()
+		first := p.capture(func() { p.expr(elements[0], levelLowest) })
+		if startsWithNamespaceCall(first) {
+			// Без скобок {ns:f(x)} читается как мапа
+			first = "(" + first + ")"
+		}
+		p.write(first)
+		if len(elements) > 1 {
+			p.write(", ")
+			p.list(elements[1:])
+		}
+		p.write("}")
+	case *jexl.MapLiteralNode:
+		p.write("{")
+		for i, entry := range n.Entries() {
+			if i > 0 {
+				p.write(", ")
+			}
+			p.expr(entry.Key, levelLowest)
+			p.write(": ")
+			p.expr(entry.Value, levelLowest)
+		}
+		p.write("}")
+	case *jexl.LambdaNode:
+		p.lambda(n)
+	case *jexl.DestructuringNode:
+		if n.IsDeclaration() {
+			p.write("var ")
+		}
+		p.target(n.Pattern())
+		p.write(" = ")
+		p.expr(n.Value(), levelLowest)
+	case *jexl.ArrayPatternNode, *jexl.MapPatternNode:
+		p.target(n)
+	case *jexl.BlockNode, *jexl.IfNode, *jexl.ForNode, *jexl.ForeachNode, *jexl.WhileNode,
+		*jexl.DoWhileNode, *jexl.BreakNode, *jexl.ContinueNode, *jexl.ReturnNode, *jexl.VarNode,
+		*jexl.FunctionNode, *jexl.SwitchNode, *jexl.TryNode:
+		p.statement(node)
+	default:
+		p.write(node.SourceText())
+	}
+}
+
+// binary печатает бинарную операцию. Все операторы левоассоциативны, кроме **.
+func (p *printer) binary(n *jexl.BinaryOpNode) {
+	op := n.Op()
+	level := binaryLevels[op]
+	left, right := level, level+1
+	if op == "**" {
+		left, right = level+1, levelUnary
+	}
+	p.expr(n.Left(), left)
+	p.write(" " + op + " ")
+	if op == "instanceof" || op == "!instanceof" {
+		if isTypeName(n.Right()) {
+			p.write(n.Right().SourceText())
+			return
+		}
+		// Справа от instanceof идентификатор читается как имя типа
+		p.write("(")
+		p.exprNode(n.Right())
+		p.write(")")
+		return
+	}
+	p.expr(n.Right(), right)
+}
+
+// isTypeName проверяет, записан ли литерал как имя типа: instanceof java.util.List.
+func isTypeName(node jexl.Node) bool {
+	lit, ok := node.(*jexl.LiteralNode)
+	if !ok {
+		return false
+	}
+	name, ok := lit.Value().(string)
+	if !ok || name != lit.SourceText() {
+		return false
+	}
+	if name == "function" {
+		return true
+	}
+	for part := range strings.SplitSeq(name, ".") {
+		if !isIdentifier(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// unary печатает унарную операцию; not сохраняется словом.
+func (p *printer) unary(n *jexl.UnaryOpNode) {
+	op := n.Op()
+	if op == "!" && strings.HasPrefix(n.SourceText(), "not ") {
+		p.write("not ")
+		p.expr(n.Operand(), levelUnary)
+		return
+	}
+	operand := p.capture(func() { p.expr(n.Operand(), levelUnary) })
+	// - -x, + +x, ! ~/re/ и ! $ не должны слиться в другой токен
+	first := ""
+	if operand != "" {
+		first = operand[:1]
+	}
+	if (op == "-" || op == "+") && strings.ContainsAny(first, "+-") || op == "!" && strings.ContainsAny(first, "~=$^") {
+		op += " "
+	}
+	p.write(op + operand)
+}
+
+// call печатает вызов метода, функции или функции пространства имён.
+// bare - функция пространства имён без скобок в правой части |>.
+func (p *printer) call(n *jexl.MethodCallNode, bare bool) {
+	switch {
+	case isEmptyCall(n):
+		p.write("empty(")
+		p.bare(n.Args()[0])
+		p.write(")")
+		return
+	case n.Namespace() != "":
+		p.write(n.Namespace() + ":")
+		p.exprNode(n.Method())
+		if bare {
+			return
+		}
+	case n.Target() != nil:
+		method := p.capture(func() { p.exprNode(n.Method()) })
+		p.object(n.Target(), method)
+		p.write("." + method)
+	default:
+		p.expr(n.Method(), levelPostfix)
+	}
+	p.write("(")
+	for i, arg := range n.Args() {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.bare(arg)
+	}
+	p.write(")")
+}
+
+// assignment печатает присваивание, восстанавливая ++x и x op= y.
+func (p *printer) assignment(n *jexl.AssignmentNode) {
+	if isIncrement(n) {
+		p.write(n.SourceText()[:2])
+		p.expr(n.Target(), levelPostfix)
+		return
+	}
+	p.expr(n.Target(), levelPostfix)
+	if op, value, ok := compound(n); ok {
+		p.write(" " + op + "= ")
+		p.expr(value, levelTernary)
+		return
+	}
+	p.write(" = ")
+	p.expr(n.Value(), levelLowest)
+}
+
+// object печатает объект перед точкой и свойством property. Число берётся
+// в скобки, иначе точка станет его частью: (1).foo, (a.1).2.
+func (p *printer) object(node jexl.Node, property string) {
+	text := p.capture(func() { p.expr(node, levelPostfix) })
+	endsWithDigit := text != "" && text[len(text)-1] >= '0' && text[len(text)-1] <= '9'
+	if startsWithDigit(text) || endsWithDigit && startsWithDigit(property) {
+		text = "(" + text + ")"
+	}
+	p.write(text)
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// list печатает элементы через запятую.
+func (p *printer) list(nodes []jexl.Node) {
+	for i, node := range nodes {
+		if i > 0 {
+			p.write(", ")
+		}
+		p.expr(node, levelLowest)
+	}
+}
+
+// lambda печатает lambda в исходной форме: x -> ..., (a, b) => ... или function(a) {...}.
+func (p *printer) lambda(n *jexl.LambdaNode) {
+	body := n.Body()
+	block, isBlock := body.(*jexl.BlockNode)
+	if isBlock && strings.HasPrefix(n.SourceText(), "function") {
+		p.write("function")
+		p.parameters(n)
+		p.write(" ")
+		p.block(block)
+		return
+	}
+	params := n.Parameters()
+	if len(params) == 1 && n.Defaults() == nil && !n.HasRest() {
+		p.write(params[0].Name())
+	} else {
+		p.parameters(n)
+	}
+	arrow := "->"
+	if body != nil && strings.HasSuffix(n.SourceText(), "=> "+body.SourceText()) {
+		arrow = "=>"
+	}
+	p.write(" " + arrow + " ")
+	if isBlock {
+		p.block(block)
+		return
+	}
+	text := p.capture(func() { p.expr(body, levelLowest) })
+	// Тело, начинающееся с {, читается как блок
+	if strings.HasPrefix(text, "{") {
+		text = "(" + text + ")"
+	}
+	p.write(text)
+}
+
+// parameters печатает список параметров в скобках.
+func (p *printer) parameters(n *jexl.LambdaNode) {
+	p.write("(")
+	defaults := n.Defaults()
+	for i, param := range n.Parameters() {
+		if i > 0 {
+			p.write(", ")
+		}
+		if n.HasRest() && i == len(n.Parameters())-1 {
+			p.write("...")
+		}
+		p.write(param.Name())
+		if i < len(defaults) && defaults[i] != nil {
+			p.write(" = ")
+			p.expr(defaults[i], levelLowest)
+		}
+	}
+	p.write(")")
+}
+
+// target печатает цель присваивания: идентификатор или шаблон деструктуризации.
+func (p *printer) target(node jexl.Node) {
+	switch n := node.(type) {
+	case *jexl.ArrayPatternNode:
+		p.write("[")
+		elements, defaults := n.Elements(), n.Defaults()
+		for i, element := range elements {
+			if i > 0 {
+				p.write(", ")
+			}
+			if element != nil {
+				p.target(element)
+				p.patternDefault(defaults, i)
+			}
+		}
+		if n.Rest() != nil {
+			if len(elements) > 0 {
+				p.write(", ")
+			}
+			p.write("..." + n.Rest().Name())
+		} else if len(elements) > 0 && elements[len(elements)-1] == nil {
+			// Пропуск в конце отмечается лишней запятой: [a, ,]
+			p.write(",")
+		}
+		p.write("]")
+	case *jexl.MapPatternNode:
+		p.write("{")
+		keys, targets, defaults := n.Keys(), n.Targets(), n.Defaults()
+		for i, key := range keys {
+			if i > 0 {
+				p.write(", ")
+			}
+			ident, short := targets[i].(*jexl.IdentifierNode)
+			short = short && ident.Name() == key && isIdentifier(key)
+			if isIdentifier(key) {
+				p.write(key)
+			} else {
+				p.write(quote(key))
+			}
+			if !short {
+				p.write(": ")
+				p.target(targets[i])
+			}
+			p.patternDefault(defaults, i)
+		}
+		if n.Rest() != nil {
+			if len(keys) > 0 {
+				p.write(", ")
+			}
+			p.write("..." + n.Rest().Name())
+		}
+		p.write("}")
+	default:
+		p.expr(node, levelPostfix)
+	}
+}
+
+// patternDefault печатает значение по умолчанию элемента шаблона.
+func (p *printer) patternDefault(defaults []jexl.Node, i int) {
+	if i < len(defaults) && defaults[i] != nil {
+		p.write(" = ")
+		p.expr(defaults[i], levelLowest)
+	}
+}
+
+// keywords - слова, которые лексер не считает идентификаторами.
+var keywords = map[string]bool{
+	"true": true, "false": true, "null": true, "nil": true, "if": true, "else": true, "for": true,
+	"while": true, "do": true, "break": true, "continue": true, "return": true, "var": true,
+	"function": true, "empty": true, "size": true, "not": true, "switch": true, "case": true,
+	"default": true, "try": true, "catch": true, "finally": true,
+	"and": true, "or": true, "eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// isIdentifier проверяет, можно ли записать имя без кавычек.
+// Как и лексер, проверяет отдельные байты.
+func isIdentifier(name string) bool {
+	if name == "" || keywords[name] {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := rune(name[i])
+		if !unicode.IsLetter(c) && c != '_' && c != '$' && (i == 0 || !unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+// startsWithNamespaceCall проверяет, начинается ли текст с вызова ns:func(.
+func startsWithNamespaceCall(text string) bool {
+	ns, rest, ok := strings.Cut(text, ":")
+	if !ok || !isIdentifier(ns) {
+		return false
+	}
+	name, _, ok := strings.Cut(rest, "(")
+	return ok && isIdentifier(name)
+}
+
+// quote записывает строку литералом в одинарных кавычках.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < ' ' {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
+
+// renderValue записывает значение литералом JEXL.
+func renderValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quote(v)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Sprint(v)
+		}
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		return s
+	case *regexp.Regexp:
+		return "~/" + strings.ReplaceAll(v.String(), "/", `\/`) + "/"
+	default:
+		return fmt.Sprint(v)
+	}
+}
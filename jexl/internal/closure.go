@@ -22,7 +22,9 @@ func getBaseContext(ctx jexl.Context) jexl.Context {
 // Порт org.apache.commons.jexl3.internal.Closure.
 type closure struct {
 	*script
+	lambda          *jexl.LambdaNode
 	capturedContext jexl.Context
+	name            string // имя источника для Info ошибок
 }
 
 // NewClosure создаёт новый closure из lambda узла.
@@ -37,6 +39,8 @@ func NewClosure(engine jexl.Engine, lambda *jexl.LambdaNode, capturedContext jex
 		params = append(params, param.Name())
 	}
 	scriptNode.SetParameters(params)
+	scriptNode.SetParameterDefaults(lambda.Defaults())
+	scriptNode.SetRestParameter(lambda.HasRest())
 
 	// Создаём snapshot контекста для захвата переменных
 	// В Java версии closure захватывает ссылку на контекст, а не копию
@@ -52,22 +56,46 @@ func NewClosure(engine jexl.Engine, lambda *jexl.LambdaNode, capturedContext jex
 
 	return &closure{
 		script:          baseScript,
+		lambda:          lambda,
 		capturedContext: snapshot,
 	}
 }
 
 // Execute выполняет closure с аргументами, используя захваченный контекст.
 func (c *closure) Execute(ctx jexl.Context, args ...any) (any, error) {
-	// Создаём контекст для параметров
-	paramNames := c.Parameters()
-	paramValues := make(map[string]any, len(paramNames))
-	for i, name := range paramNames {
-		if i < len(args) {
-			paramValues[name] = args[i]
-		} else {
-			paramValues[name] = nil
-		}
+	return c.execute(ctx, args, nil)
+}
+
+// ExecuteNamed выполняет closure, связывая аргументы с параметрами по имени.
+func (c *closure) ExecuteNamed(ctx jexl.Context, named map[string]any) (any, error) {
+	return c.execute(ctx, nil, named)
+}
+
+// Curry создаёт closure с частично применёнными аргументами, сохраняя захваченный контекст.
+func (c *closure) Curry(args ...any) jexl.Script {
+	if len(args) == 0 {
+		return c
+	}
+	return &closure{
+		script:          c.script.Curry(args...).(*script),
+		lambda:          c.lambda,
+		capturedContext: c.capturedContext,
+		name:            c.name,
 	}
+}
+
+// CallableWithArgs создаёт Callable с аргументами.
+func (c *closure) CallableWithArgs(ctx jexl.Context, args ...any) func() (any, error) {
+	return func() (any, error) {
+		return c.Execute(ctx, args...)
+	}
+}
+
+// execute выполняет тело closure с позиционными и именованными аргументами.
+func (c *closure) execute(ctx jexl.Context, args []any, named map[string]any) (any, error) {
+	allArgs := append([]any{}, c.boundArgs...)
+	allArgs = append(allArgs, args...)
+	paramValues := make(map[string]any, len(c.Parameters()))
 
 	// Используем переданный контекст как базовый, если он есть
 	// Если переданный контекст nil, используем захваченный контекст
@@ -102,10 +130,19 @@ func (c *closure) Execute(ctx jexl.Context, args ...any) (any, error) {
 		capturedCtx = nil // Избегаем двойной проверки одного и того же контекста
 	}
 	execCtx := newClosureContext(baseCtx, capturedCtx, paramValues)
+	if err := bindParameters(c.engine, execCtx, paramValues, c.ast, allArgs, named); err != nil {
+		return nil, err
+	}
 
 	// Выполняем тело lambda напрямую через интерпретатор
 	interp := newInterpreter(c.engine, execCtx)
-	return interp.interpret(c.ast.Children()[0]) // Тело lambda - первый (и единственный) дочерний узел
+	interp.name = c.name
+	result, err := interp.run(c.program, c.ast.Children()[0]) // Тело lambda - первый (и единственный) дочерний узел
+	if returnErr, ok := err.(*ReturnError); ok {
+		// return завершает только саму функцию
+		return returnErr.Value, nil
+	}
+	return result, err
 }
 
 // closureContext объединяет несколько контекстов для closure.
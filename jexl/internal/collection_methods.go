@@ -0,0 +1,459 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// collectionMethod - встроенный метод коллекции (map, filter, reduce и т.д.).
+// Используется, когда у Go-типа нет собственного метода с таким именем.
+type collectionMethod struct {
+	name string
+	fn   func(target any, args []any) (any, error)
+}
+
+func (c *collectionMethod) Name() string {
+	return c.name
+}
+
+func (c *collectionMethod) Invoke(target any, args []any) (any, error) {
+	return c.fn(target, args)
+}
+
+// collectionMethodArity задаёт допустимое число аргументов встроенных методов коллекций.
+var collectionMethodArity = map[string][2]int{
+	"map":      {1, 1},
+	"filter":   {1, 1},
+	"reduce":   {1, 2},
+	"find":     {1, 1},
+	"any":      {1, 1},
+	"all":      {1, 1},
+	"sortBy":   {0, 1},
+	"groupBy":  {1, 1},
+	"distinct": {0, 0},
+	"flatMap":  {1, 1},
+	"take":     {1, 1},
+	"skip":     {1, 1},
+	"sum":      {0, 1},
+	"min":      {0, 1},
+	"max":      {0, 1},
+	"avg":      {0, 1},
+	"join":     {0, 1},
+	"keys":     {0, 0},
+	"values":   {0, 0},
+	"entries":  {0, 0},
+}
+
+// isCollection сообщает, поддерживает ли значение встроенные методы коллекций:
+// слайсы, массивы, мапы, jexl.Set, jexl.Map и jexl.Range.
+func isCollection(obj any) bool {
+	switch obj.(type) {
+	case *jexl.Set, *jexl.Map, *jexl.Range:
+		return true
+	}
+	switch reflect.ValueOf(obj).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	}
+	return false
+}
+
+// isKeyed сообщает, является ли коллекция мапой (элементы - значения по ключам).
+func isKeyed(obj any) bool {
+	if _, ok := obj.(*jexl.Map); ok {
+		return true
+	}
+	return reflect.ValueOf(obj).Kind() == reflect.Map
+}
+
+// getCollectionMethod возвращает встроенный метод коллекции или nil.
+func (u *uberspectImpl) getCollectionMethod(obj any, name string, args []any) (jexl.Method, error) {
+	arity, ok := collectionMethodArity[name]
+	if !ok || !isCollection(obj) {
+		return nil, nil
+	}
+	if len(args) < arity[0] || len(args) > arity[1] {
+		if arity[0] == arity[1] {
+			return nil, jexl.NewError(fmt.Sprintf("%s() takes %d argument(s)", name, arity[0]))
+		}
+		return nil, jexl.NewError(fmt.Sprintf("%s() takes %d to %d arguments", name, arity[0], arity[1]))
+	}
+	return &collectionMethod{
+		name: name,
+		fn: func(target any, a []any) (any, error) {
+			return u.invokeCollectionMethod(target, name, a)
+		},
+	}, nil
+}
+
+// arith возвращает арифметику движка для сравнений и агрегатов.
+func (u *uberspectImpl) arith() jexl.Arithmetic {
+	if u.arithmetic != nil {
+		return u.arithmetic
+	}
+	return jexl.NewBaseArithmetic(false, nil, 0)
+}
+
+// apply вызывает функцию-аргумент для элемента коллекции.
+// Функция - jexl.Script (лямбда получает значение и, если объявлен второй параметр, ключ или индекс)
+// либо строка - имя свойства элемента.
+func (u *uberspectImpl) apply(fn any, value, key any) (any, error) {
+	switch f := fn.(type) {
+	case jexl.Script:
+		if len(f.Parameters()) > 1 {
+			return f.Execute(nil, value, key)
+		}
+		return f.Execute(nil, value)
+	case string:
+		if value == nil {
+			return nil, nil
+		}
+		if get := u.GetProperty(value, f); get != nil {
+			return get.Invoke(value)
+		}
+		return nil, nil
+	case nil:
+		return value, nil
+	}
+	return nil, jexl.NewError(fmt.Sprintf("expected lambda or property name, got %T", fn))
+}
+
+// test вычисляет предикат для элемента.
+func (u *uberspectImpl) test(fn any, value, key any) (bool, error) {
+	result, err := u.apply(fn, value, key)
+	if err != nil {
+		return false, err
+	}
+	return u.arith().ToBoolean(result)
+}
+
+// compareValues сравнивает значения арифметикой, а несравнимые - по строковому представлению.
+func (u *uberspectImpl) compareValues(a, b any) int {
+	if cmp, err := u.arith().Compare(a, b); err == nil {
+		return cmp
+	}
+	return strings.Compare(u.str(a), u.str(b))
+}
+
+// str приводит значение к строке так же, как конкатенация строк в арифметике:
+// 2 вместо 2/1 для big.Rat. nil становится пустой строкой.
+func (u *uberspectImpl) str(value any) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	if result, err := u.arith().Add("", value); err == nil {
+		if s, ok := result.(string); ok {
+			return s
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// eachElement обходит элементы коллекции; visit возвращает false для остановки.
+// Ошибка visit прерывает обход и возвращается.
+func eachElement(obj any, visit func(key, value any) (bool, error)) error {
+	var visitErr error
+	err := iterateItems(obj, true, func(key, value any) bool {
+		cont, err := visit(key, value)
+		if err != nil {
+			visitErr = err
+			return false
+		}
+		return cont
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+	return err
+}
+
+// collectingMethods - методы, результат которых собирается из всех элементов
+// коллекции; слишком длинный диапазон они не материализуют.
+var collectingMethods = map[string]bool{
+	"map": true, "filter": true, "sortBy": true, "groupBy": true, "distinct": true,
+	"flatMap": true, "skip": true, "join": true, "keys": true, "values": true, "entries": true,
+}
+
+// checkRange возвращает ошибку, если метод name материализует слишком длинный
+// диапазон. take(n) собирает не больше n элементов и проверяется только при большом n.
+func (u *uberspectImpl) checkRange(obj any, name string, args []any) error {
+	r, ok := obj.(*jexl.Range)
+	if !ok {
+		return nil
+	}
+	if name == "take" {
+		if n, err := u.toInt(args[0]); err != nil || n < jexl.MaxRangeElements {
+			return nil
+		}
+	} else if !collectingMethods[name] {
+		return nil
+	}
+	return r.CheckMaterialize()
+}
+
+// invokeCollectionMethod выполняет встроенный метод коллекции.
+func (u *uberspectImpl) invokeCollectionMethod(obj any, name string, args []any) (any, error) {
+	var fn any
+	if len(args) > 0 {
+		fn = args[0]
+	}
+	if err := u.checkRange(obj, name, args); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "map":
+		result := []any{}
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			mapped, err := u.apply(fn, value, key)
+			result = append(result, mapped)
+			return true, err
+		})
+		return result, err
+
+	case "filter":
+		// Мапа и множество сохраняют свой тип, остальные коллекции дают список
+		var keys, values []any
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			ok, err := u.test(fn, value, key)
+			if ok {
+				keys, values = append(keys, key), append(values, value)
+			}
+			return true, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if isKeyed(obj) {
+			m := jexl.NewMap()
+			for j, key := range keys {
+				m.Put(key, values[j])
+			}
+			return m, nil
+		}
+		if _, ok := obj.(*jexl.Set); ok {
+			return jexl.NewSet(values...), nil
+		}
+		if values == nil {
+			values = []any{}
+		}
+		return values, nil
+
+	case "reduce":
+		// reduce(fn) начинает с первого элемента; начальное значение
+		// передаётся после функции или перед ней: reduce(fn, 10) и reduce(10, fn)
+		var acc any
+		started := len(args) > 1
+		if started {
+			acc = args[1]
+			if _, ok := fn.(jexl.Script); !ok {
+				if _, ok := args[1].(jexl.Script); ok {
+					fn, acc = args[1], args[0]
+				}
+			}
+		}
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			if !started {
+				acc, started = value, true
+				return true, nil
+			}
+			script, ok := fn.(jexl.Script)
+			if !ok {
+				return false, jexl.NewError(fmt.Sprintf("reduce() expects a lambda, got %T", fn))
+			}
+			next, err := script.Execute(nil, acc, value, key)
+			acc = next
+			return true, err
+		})
+		return acc, err
+
+	case "find":
+		var found any
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			ok, err := u.test(fn, value, key)
+			if ok {
+				found = value
+			}
+			return !ok, err
+		})
+		return found, err
+
+	case "any", "all":
+		result := name == "all"
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			ok, err := u.test(fn, value, key)
+			if ok == (name == "any") {
+				result = ok
+				return false, err
+			}
+			return true, err
+		})
+		return result, err
+
+	case "sortBy":
+		type sortItem struct {
+			value any
+			key   any
+		}
+		var items []sortItem
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			sortKey, err := u.apply(fn, value, key)
+			items = append(items, sortItem{value: value, key: sortKey})
+			return true, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(items, func(a, b int) bool {
+			return u.compareValues(items[a].key, items[b].key) < 0
+		})
+		result := make([]any, len(items))
+		for j, item := range items {
+			result[j] = item.value
+		}
+		return result, nil
+
+	case "groupBy":
+		groups := jexl.NewMap()
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			group, err := u.apply(fn, value, key)
+			if err != nil {
+				return false, err
+			}
+			list, _ := groups.Get(group)
+			items, _ := list.([]any)
+			groups.Put(group, append(items, value))
+			return true, nil
+		})
+		return groups, err
+
+	case "distinct":
+		seen := jexl.NewSet()
+		result := []any{}
+		err := eachElement(obj, func(_, value any) (bool, error) {
+			if seen.Add(value) {
+				result = append(result, value)
+			}
+			return true, nil
+		})
+		return result, err
+
+	case "flatMap":
+		result := []any{}
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			mapped, err := u.apply(fn, value, key)
+			if err != nil {
+				return false, err
+			}
+			if mapped != nil && isCollection(mapped) && !isKeyed(mapped) {
+				if err := u.checkRange(mapped, name, nil); err != nil {
+					return false, err
+				}
+				return true, eachElement(mapped, func(_, inner any) (bool, error) {
+					result = append(result, inner)
+					return true, nil
+				})
+			}
+			result = append(result, mapped)
+			return true, nil
+		})
+		return result, err
+
+	case "take", "skip":
+		n, err := u.toInt(fn)
+		if err != nil {
+			return nil, err
+		}
+		result := []any{}
+		index := 0
+		err = eachElement(obj, func(_, value any) (bool, error) {
+			if name == "take" && index >= n {
+				return false, nil
+			}
+			if name == "take" || index >= n {
+				result = append(result, value)
+			}
+			index++
+			return true, nil
+		})
+		return result, err
+
+	case "sum", "avg":
+		var total any = int64(0)
+		count := int64(0)
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			item, err := u.apply(fn, value, key)
+			if err != nil {
+				return false, err
+			}
+			total, err = u.arith().Add(total, item)
+			count++
+			return true, err
+		})
+		if err != nil || name == "sum" {
+			return total, err
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		return u.arith().Divide(total, count)
+
+	case "min", "max":
+		var best any
+		found := false
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			item, err := u.apply(fn, value, key)
+			if err != nil {
+				return false, err
+			}
+			if !found {
+				best, found = item, true
+				return true, nil
+			}
+			cmp, err := u.arith().Compare(item, best)
+			if err != nil {
+				return false, jexl.WrapError(fmt.Sprintf("%s() cannot compare %v and %v", name, item, best), err, nil)
+			}
+			if (name == "min" && cmp < 0) || (name == "max" && cmp > 0) {
+				best = item
+			}
+			return true, nil
+		})
+		return best, err
+
+	case "join":
+		separator := ","
+		if fn != nil {
+			separator = u.str(fn)
+		}
+		var parts []string
+		err := eachElement(obj, func(_, value any) (bool, error) {
+			parts = append(parts, u.str(value))
+			return true, nil
+		})
+		return strings.Join(parts, separator), err
+
+	case "keys", "values", "entries":
+		result := []any{}
+		err := eachElement(obj, func(key, value any) (bool, error) {
+			switch name {
+			case "keys":
+				result = append(result, key)
+			case "values":
+				result = append(result, value)
+			default:
+				result = append(result, []any{key, value})
+			}
+			return true, nil
+		})
+		return result, err
+	}
+	return nil, jexl.NewError("unknown collection method: " + name)
+}
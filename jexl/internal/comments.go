@@ -0,0 +1,66 @@
+package internal
+
+import "strings"
+
+// Comment - комментарий исходного текста с границами [Start, End).
+// Text - текст комментария без завершающих пробелов и переводов строк.
+type Comment struct {
+	Start, End int
+	Text       string
+}
+
+// ScanComments находит комментарии //, /* */ и #, пропуская строки и литералы
+// регулярных выражений так же, как лексер. Используется форматтером и линтером.
+func ScanComments(source string) []Comment {
+	var comments []Comment
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '\'' || c == '"':
+			i = skipQuoted(source, i+1, c)
+		case c == '~' && i+1 < len(source) && source[i+1] == '/' && (i == 0 || source[i-1] != '=' && source[i-1] != '!'):
+			// =~ и !~ - операторы, а не начало литерала ~/.../
+			i = skipQuoted(source, i+2, '/')
+		case c == '#' || c == '/' && i+1 < len(source) && source[i+1] == '/':
+			end := strings.IndexByte(source[i:], '\n')
+			if end < 0 {
+				end = len(source) - i
+			}
+			comments = append(comments, newComment(source, i, i+end))
+			i += end
+		case c == '/' && i+1 < len(source) && source[i+1] == '*':
+			end := strings.Index(source[i+2:], "*/")
+			if end < 0 {
+				end = len(source)
+			} else {
+				end += i + 4
+			}
+			comments = append(comments, newComment(source, i, end))
+			i = end
+		default:
+			i++
+		}
+	}
+	return comments
+}
+
+// skipQuoted пропускает литерал до закрывающего символа с учётом экранирования.
+func skipQuoted(source string, i int, quote byte) int {
+	for i < len(source) {
+		switch source[i] {
+		case '\\':
+			i += 2
+		case quote:
+			return i + 1
+		default:
+			i++
+		}
+	}
+	return len(source)
+}
+
+// newComment создаёт комментарий с границами [start, end).
+func newComment(source string, start, end int) Comment {
+	// Незакрытый /* поглощает текст до конца, включая переводы строк
+	return Comment{Start: start, End: end, Text: strings.TrimRight(source[start:end], " \t\r\n")}
+}
@@ -0,0 +1,1019 @@
+package internal
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// compiled - узел AST, скомпилированный в замыкание. Замыкание выполняет узел
+// в интерпретаторе так же, как interpret, но без разбора типа узла на каждом шаге.
+type compiled func(i *interpreter) (any, error)
+
+// program - скомпилированное тело скрипта или lambda. Компиляция выполняется
+// при первом выполнении в режиме Options.Compiled.
+type program struct {
+	once sync.Once
+	node jexl.Node
+	run  compiled
+}
+
+// get возвращает скомпилированное тело, компилируя его при первом обращении.
+func (p *program) get(engine jexl.Engine) compiled {
+	p.once.Do(func() {
+		p.run = newCompiler(engine).compile(p.node)
+	})
+	return p.run
+}
+
+// run выполняет узел: скомпилированным, если включён режим Options.Compiled,
+// иначе - интерпретацией.
+func (i *interpreter) run(p *program, node jexl.Node) (any, error) {
+	if p != nil && i.options != nil && i.options.Compiled() {
+		return p.get(i.engine)(i)
+	}
+	return i.interpret(node)
+}
+
+// compiler превращает AST в дерево замыканий. Узлы без специальной компиляции
+// выполняются интерпретатором, поэтому семантика обоих режимов совпадает.
+type compiler struct {
+	engine    jexl.Engine
+	folder    *interpreter // интерпретатор без контекста для свёртки констант
+	constants map[jexl.Node]constant
+	programs  map[*jexl.LambdaNode]*program // тела lambda и объявленных функций
+}
+
+// constant - результат свёртки константного узла.
+type constant struct {
+	value any
+	ok    bool
+}
+
+func newCompiler(engine jexl.Engine) *compiler {
+	return &compiler{
+		engine:    engine,
+		folder:    newInterpreter(engine, nil),
+		constants: map[jexl.Node]constant{},
+		programs:  map[*jexl.LambdaNode]*program{},
+	}
+}
+
+// compile компилирует узел. Ошибки скомпилированных узлов получают Info
+// с положением узла, как в interpret.
+func (c *compiler) compile(node jexl.Node) compiled {
+	if value, ok := c.constant(node); ok {
+		return constantValue(value)
+	}
+	if node == nil {
+		return constantValue(nil)
+	}
+	run := c.compileNode(node)
+	if run == nil {
+		return func(i *interpreter) (any, error) {
+			return i.interpret(node)
+		}
+	}
+	return func(i *interpreter) (any, error) {
+		result, err := run(i)
+		if err != nil {
+			return result, i.locate(node, err)
+		}
+		return result, nil
+	}
+}
+
+// compileNode компилирует узел в зависимости от его типа. Для узлов без
+// специальной компиляции возвращает nil.
+func (c *compiler) compileNode(node jexl.Node) compiled {
+	switch n := node.(type) {
+	case *jexl.ScriptNode:
+		return c.compileScript(n)
+	case *jexl.IdentifierNode:
+		return func(i *interpreter) (any, error) {
+			return i.interpretIdentifier(n)
+		}
+	case *jexl.BinaryOpNode:
+		return c.compileBinaryOp(n)
+	case *jexl.UnaryOpNode:
+		operand := c.compile(n.Operand())
+		return func(i *interpreter) (any, error) {
+			value, err := operand(i)
+			if err != nil {
+				return nil, err
+			}
+			return i.applyUnaryOp(n, value)
+		}
+	case *jexl.PropertyAccessNode:
+		return c.compilePropertyAccess(n)
+	case *jexl.MethodCallNode:
+		return c.compileMethodCall(n)
+	case *jexl.TernaryNode:
+		return c.compileTernary(n)
+	case *jexl.ElvisNode:
+		return c.compileElvis(n)
+	case *jexl.LambdaNode:
+		body := c.program(n)
+		return func(i *interpreter) (any, error) {
+			fn := i.newClosure(n, i.context)
+			fn.program = body
+			return fn, nil
+		}
+	case *jexl.FunctionNode:
+		return c.compileFunction(n)
+	case *jexl.IndexAccessNode:
+		return c.compileIndexAccess(n)
+	case *jexl.AssignmentNode:
+		return c.compileAssignment(n)
+	case *jexl.PipeNode:
+		return c.compilePipe(n)
+	case *jexl.ArrayLiteralNode:
+		elements := c.compileAll(n.Elements())
+		return func(i *interpreter) (any, error) {
+			return evalAll(i, elements)
+		}
+	case *jexl.MapLiteralNode:
+		return c.compileMapLiteral(n)
+	case *jexl.SetLiteralNode:
+		elements := c.compileAll(n.Elements())
+		return func(i *interpreter) (any, error) {
+			result := jexl.NewSet()
+			for _, element := range elements {
+				value, err := element(i)
+				if err != nil {
+					return nil, err
+				}
+				result.Add(value)
+			}
+			return result, nil
+		}
+	case *jexl.IfNode:
+		return c.compileIf(n)
+	case *jexl.ForNode:
+		return c.compileFor(n)
+	case *jexl.ForeachNode:
+		return c.compileForeach(n)
+	case *jexl.WhileNode:
+		return c.compileWhile(n)
+	case *jexl.DoWhileNode:
+		return c.compileDoWhile(n)
+	case *jexl.BlockNode:
+		return c.compileBlock(n.Statements())
+	case *jexl.BreakNode:
+		return func(*interpreter) (any, error) {
+			return nil, &BreakError{}
+		}
+	case *jexl.ContinueNode:
+		return func(*interpreter) (any, error) {
+			return nil, &ContinueError{}
+		}
+	case *jexl.ReturnNode:
+		value := c.compile(n.Value())
+		return func(i *interpreter) (any, error) {
+			result, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			return nil, &ReturnError{Value: result}
+		}
+	case *jexl.VarNode:
+		return c.compileVar(n)
+	case *jexl.DestructuringNode:
+		value := c.compile(n.Value())
+		return func(i *interpreter) (any, error) {
+			result, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			if err := i.destructure(n.Pattern(), result); err != nil {
+				return nil, err
+			}
+			return result, nil
+		}
+	}
+	return nil
+}
+
+// compileAll компилирует список узлов.
+func (c *compiler) compileAll(nodes []jexl.Node) []compiled {
+	result := make([]compiled, len(nodes))
+	for k, node := range nodes {
+		result[k] = c.compile(node)
+	}
+	return result
+}
+
+// evalAll выполняет скомпилированные узлы по порядку и возвращает их значения.
+func evalAll(i *interpreter, nodes []compiled) ([]any, error) {
+	values := make([]any, len(nodes))
+	for k, node := range nodes {
+		value, err := node(i)
+		if err != nil {
+			return nil, err
+		}
+		values[k] = value
+	}
+	return values, nil
+}
+
+// program возвращает общее для всех замыканий скомпилированное тело lambda.
+func (c *compiler) program(lambda *jexl.LambdaNode) *program {
+	body, ok := c.programs[lambda]
+	if !ok {
+		body = &program{node: lambda.Body()}
+		c.programs[lambda] = body
+	}
+	return body
+}
+
+// constantValue возвращает замыкание, отдающее значение. Числа big.Rat копируются,
+// чтобы вызывающий код не мог изменить общую константу.
+func constantValue(value any) compiled {
+	if r, ok := value.(*big.Rat); ok {
+		return func(*interpreter) (any, error) {
+			return new(big.Rat).Set(r), nil
+		}
+	}
+	return func(*interpreter) (any, error) {
+		return value, nil
+	}
+}
+
+// constant сворачивает литералы и операции над ними через арифметику движка.
+// Операция, завершившаяся ошибкой, не сворачивается: ошибка возникнет при выполнении.
+func (c *compiler) constant(node jexl.Node) (any, bool) {
+	if cached, ok := c.constants[node]; ok {
+		return cached.value, cached.ok
+	}
+	var result constant
+	switch n := node.(type) {
+	case *jexl.LiteralNode:
+		result = constant{n.Value(), true}
+	case *jexl.BinaryOpNode:
+		left, leftOk := c.constant(n.Left())
+		right, rightOk := c.constant(n.Right())
+		if leftOk && rightOk {
+			value, err := c.folder.applyBinaryOp(n, left, right)
+			result = constant{value, err == nil}
+		}
+	case *jexl.UnaryOpNode:
+		if operand, ok := c.constant(n.Operand()); ok {
+			value, err := c.folder.applyUnaryOp(n, operand)
+			result = constant{value, err == nil}
+		}
+	}
+	c.constants[node] = result
+	return result.value, result.ok
+}
+
+// compileScript компилирует ScriptNode (см. interpretScript).
+func (c *compiler) compileScript(n *jexl.ScriptNode) compiled {
+	children := c.compileAll(n.Children())
+	hoist := c.compileHoist(n.Children())
+	return func(i *interpreter) (any, error) {
+		var result any
+		var err error
+		if hoist != nil {
+			defer hoist(i)()
+		}
+		for _, child := range children {
+			result, err = child(i)
+			if err != nil {
+				if returnErr, ok := err.(*ReturnError); ok {
+					return returnErr.Value, nil
+				}
+				return nil, err
+			}
+			if returnErr, ok := result.(*ReturnError); ok {
+				return returnErr.Value, nil
+			}
+		}
+		return result, nil
+	}
+}
+
+// compileHoist возвращает подъём функций, объявленных среди statements, с общими
+// скомпилированными телами (см. hoistFunctions), или nil, если функций нет.
+func (c *compiler) compileHoist(statements []jexl.Node) func(i *interpreter) func() {
+	programs := map[*jexl.LambdaNode]*program{}
+	for _, stmt := range statements {
+		if fn, ok := stmt.(*jexl.FunctionNode); ok {
+			programs[fn.Lambda()] = c.program(fn.Lambda())
+		}
+	}
+	if len(programs) == 0 {
+		return nil
+	}
+	return func(i *interpreter) func() {
+		return i.hoistFunctions(statements, programs)
+	}
+}
+
+// compileBlock компилирует BlockNode (см. interpretBlock).
+func (c *compiler) compileBlock(statements []jexl.Node) compiled {
+	children := c.compileAll(statements)
+	hoist := c.compileHoist(statements)
+	return func(i *interpreter) (any, error) {
+		if hoist != nil {
+			defer hoist(i)()
+		}
+		var result any
+		for _, child := range children {
+			var err error
+			result, err = child(i)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// compileFunction компилирует FunctionNode (см. interpretFunction).
+func (c *compiler) compileFunction(n *jexl.FunctionNode) compiled {
+	body := c.program(n.Lambda())
+	return func(i *interpreter) (any, error) {
+		if i.context == nil {
+			return nil, jexl.NewError("context is nil")
+		}
+		if existing, ok := i.context.Get(n.Name().Name()).(*closure); ok && existing.lambda == n.Lambda() {
+			return existing, nil
+		}
+		fn := i.newClosure(n.Lambda(), i.context)
+		fn.program = body
+		return fn, nil
+	}
+}
+
+// compileVar компилирует VarNode (см. interpretVar).
+func (c *compiler) compileVar(n *jexl.VarNode) compiled {
+	name := n.Name().Name()
+	value := c.compile(n.Value())
+	return func(i *interpreter) (any, error) {
+		if i.context == nil {
+			return nil, jexl.NewError("context is nil")
+		}
+		result, err := value(i)
+		if err != nil {
+			return nil, err
+		}
+		i.context.Set(name, result)
+		return result, nil
+	}
+}
+
+// compileIndexAccess компилирует IndexAccessNode (см. interpretIndexAccess).
+func (c *compiler) compileIndexAccess(n *jexl.IndexAccessNode) compiled {
+	object, index := c.compile(n.Object()), c.compile(n.Index())
+	return func(i *interpreter) (any, error) {
+		obj, err := object(i)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			if i.options != nil && i.options.Safe() {
+				return nil, nil
+			}
+			return nil, jexl.NewError("cannot access index on nil")
+		}
+		key, err := index(i)
+		if err != nil {
+			return nil, err
+		}
+		return i.indexValue(obj, key)
+	}
+}
+
+// compileAssignment компилирует AssignmentNode (см. interpretAssignment).
+// Постфиксные ++ и -- над свойствами и индексами выполняются интерпретатором.
+func (c *compiler) compileAssignment(n *jexl.AssignmentNode) compiled {
+	source := n.SourceText()
+	increment, decrement := strings.HasSuffix(source, "++"), strings.HasSuffix(source, "--")
+	value := c.compile(n.Value())
+	switch target := n.Target().(type) {
+	case *jexl.IdentifierNode:
+		name := target.Name()
+		if increment || decrement {
+			return func(i *interpreter) (any, error) {
+				var old any
+				if i.context != nil {
+					old = i.context.Get(name)
+				}
+				result, err := value(i)
+				if err != nil {
+					return nil, err
+				}
+				if i.context == nil {
+					return nil, jexl.NewError("context is nil")
+				}
+				if old == nil {
+					// Старое значение восстанавливается из нового, как в interpretAssignment
+					if arithmetic := i.engine.Arithmetic(); arithmetic != nil {
+						if increment {
+							old, _ = arithmetic.Subtract(result, int64(1))
+						} else {
+							old, _ = arithmetic.Add(result, int64(1))
+						}
+					}
+				}
+				i.context.Set(name, result)
+				return old, nil
+			}
+		}
+		return func(i *interpreter) (any, error) {
+			result, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			if i.context == nil {
+				return nil, jexl.NewError("context is nil")
+			}
+			i.context.Set(name, result)
+			return result, nil
+		}
+	case *jexl.PropertyAccessNode:
+		if increment || decrement {
+			return nil
+		}
+		object := c.compile(target.Object())
+		return func(i *interpreter) (any, error) {
+			result, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			obj, err := object(i)
+			if err != nil {
+				return nil, err
+			}
+			if obj == nil {
+				if i.options != nil && i.options.Safe() {
+					return nil, nil
+				}
+				return nil, jexl.NewError("cannot assign property on nil")
+			}
+			return i.setProperty(target, obj, result)
+		}
+	case *jexl.IndexAccessNode:
+		if increment || decrement {
+			return nil
+		}
+		object, index := c.compile(target.Object()), c.compile(target.Index())
+		return func(i *interpreter) (any, error) {
+			result, err := value(i)
+			if err != nil {
+				return nil, err
+			}
+			obj, err := object(i)
+			if err != nil {
+				return nil, err
+			}
+			if obj == nil {
+				if i.options != nil && i.options.Safe() {
+					return nil, nil
+				}
+				return nil, jexl.NewError("cannot assign index on nil")
+			}
+			key, err := index(i)
+			if err != nil {
+				return nil, err
+			}
+			return i.setIndex(obj, key, result)
+		}
+	}
+	return nil
+}
+
+// compileMapLiteral компилирует MapLiteralNode (см. interpretMapLiteral).
+func (c *compiler) compileMapLiteral(n *jexl.MapLiteralNode) compiled {
+	keys := make([]compiled, len(n.Entries()))
+	values := make([]compiled, len(n.Entries()))
+	for k, entry := range n.Entries() {
+		keys[k], values[k] = c.compile(entry.Key), c.compile(entry.Value)
+	}
+	return func(i *interpreter) (any, error) {
+		result := jexl.NewMap()
+		for k := range keys {
+			key, err := keys[k](i)
+			if err != nil {
+				return nil, err
+			}
+			value, err := values[k](i)
+			if err != nil {
+				return nil, err
+			}
+			result.Put(key, value)
+		}
+		return result, nil
+	}
+}
+
+// compilePipe компилирует PipeNode (см. interpretPipe).
+func (c *compiler) compilePipe(n *jexl.PipeNode) compiled {
+	value := c.compile(n.Value())
+	switch target := n.Target().(type) {
+	case *jexl.MethodCallNode:
+		if method, ok := target.Method().(*jexl.IdentifierNode); ok {
+			args := c.compileAll(target.Args())
+			local := target.Target() == nil && target.Namespace() == ""
+			return func(i *interpreter) (any, error) {
+				piped, err := value(i)
+				if err != nil {
+					return nil, err
+				}
+				values, err := evalAll(i, args)
+				if err != nil {
+					return nil, err
+				}
+				if local && !i.isDefined(method.Name()) && i.hasMethod(piped, method.Name(), values) {
+					return i.invokeObjectMethod(piped, method.Name(), values)
+				}
+				return i.invokeMethodCall(target, append([]any{piped}, values...))
+			}
+		}
+	}
+	fn := c.compile(n.Target())
+	ident, _ := n.Target().(*jexl.IdentifierNode)
+	return func(i *interpreter) (any, error) {
+		piped, err := value(i)
+		if err != nil {
+			return nil, err
+		}
+		if ident != nil && !i.isDefined(ident.Name()) {
+			return i.invokeObjectMethod(piped, ident.Name(), []any{})
+		}
+		target, err := fn(i)
+		if err != nil {
+			return nil, err
+		}
+		if target == nil {
+			return nil, jexl.NewError("pipeline target is null: " + n.Target().SourceText())
+		}
+		return i.callFunctionValue(n.Target().SourceText(), target, []any{piped})
+	}
+}
+
+// compileIf компилирует IfNode (см. interpretIf).
+func (c *compiler) compileIf(n *jexl.IfNode) compiled {
+	condition, thenBranch := c.compile(n.Condition()), c.compile(n.ThenBranch())
+	var elseBranch compiled
+	if n.ElseBranch() != nil {
+		elseBranch = c.compile(n.ElseBranch())
+	}
+	return func(i *interpreter) (any, error) {
+		value, err := condition(i)
+		if err != nil {
+			return nil, err
+		}
+		test, err := i.truth(value)
+		if err != nil {
+			return nil, err
+		}
+		if test {
+			return thenBranch(i)
+		}
+		if elseBranch != nil {
+			return elseBranch(i)
+		}
+		return nil, nil
+	}
+}
+
+// compileCondition компилирует условие цикла; nil-условие всегда истинно.
+func (c *compiler) compileCondition(node jexl.Node) func(i *interpreter) (bool, error) {
+	if node == nil {
+		return func(*interpreter) (bool, error) {
+			return true, nil
+		}
+	}
+	condition := c.compile(node)
+	return func(i *interpreter) (bool, error) {
+		value, err := condition(i)
+		if err != nil {
+			return false, err
+		}
+		return i.truth(value)
+	}
+}
+
+// compileLoopBody компилирует тело цикла; nil, если тела нет.
+func (c *compiler) compileLoopBody(node jexl.Node) compiled {
+	if node == nil {
+		return nil
+	}
+	return c.compile(node)
+}
+
+// compileFor компилирует ForNode (см. interpretFor).
+func (c *compiler) compileFor(n *jexl.ForNode) compiled {
+	init, step := c.compile(n.Init()), c.compile(n.Step())
+	condition, body := c.compileCondition(n.Condition()), c.compileLoopBody(n.Body())
+	return func(i *interpreter) (any, error) {
+		if _, err := init(i); err != nil {
+			return nil, err
+		}
+		var result any
+		for {
+			test, err := condition(i)
+			if err != nil {
+				return nil, err
+			}
+			if !test {
+				break
+			}
+			if body != nil {
+				value, err := body(i)
+				if err != nil {
+					if _, isBreak := err.(*BreakError); isBreak {
+						break
+					}
+					if _, isContinue := err.(*ContinueError); !isContinue {
+						return nil, err
+					}
+				} else {
+					result = value
+				}
+			}
+			if _, err := step(i); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+}
+
+// compileForeach компилирует ForeachNode (см. interpretForeach). Переменная
+// или ключ недопустимого вида оставляют узел интерпретатору, сообщающему ошибку.
+func (c *compiler) compileForeach(n *jexl.ForeachNode) compiled {
+	ident, isIdent := n.Variable().(*jexl.IdentifierNode)
+	switch n.Variable().(type) {
+	case *jexl.IdentifierNode, *jexl.ArrayPatternNode, *jexl.MapPatternNode:
+	default:
+		return nil
+	}
+	keyName := ""
+	if key := n.Key(); key != nil {
+		keyIdent, ok := key.(*jexl.IdentifierNode)
+		if !ok {
+			return nil
+		}
+		keyName = keyIdent.Name()
+	}
+	items, body := c.compile(n.Items()), c.compileLoopBody(n.Body())
+	return func(i *interpreter) (any, error) {
+		values, err := items(i)
+		if err != nil {
+			return nil, err
+		}
+		sortedKeys := i.options != nil && i.options.SortedKeys()
+		var result any
+		var loopErr error
+		err = iterateItems(values, sortedKeys, func(key, item any) bool {
+			if keyName != "" && i.context != nil {
+				i.context.Set(keyName, key)
+			}
+			if !isIdent {
+				if loopErr = i.destructure(n.Variable(), item); loopErr != nil {
+					return false
+				}
+			} else if i.context != nil {
+				i.context.Set(ident.Name(), item)
+			}
+			if body == nil {
+				return true
+			}
+			value, err := body(i)
+			if err != nil {
+				if _, isBreak := err.(*BreakError); isBreak {
+					return false
+				}
+				if _, isContinue := err.(*ContinueError); isContinue {
+					return true
+				}
+				loopErr = err
+				return false
+			}
+			result = value
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if loopErr != nil {
+			return nil, loopErr
+		}
+		return result, nil
+	}
+}
+
+// compileWhile компилирует WhileNode (см. interpretWhile).
+func (c *compiler) compileWhile(n *jexl.WhileNode) compiled {
+	condition, body := c.compileCondition(n.Condition()), c.compileLoopBody(n.Body())
+	return func(i *interpreter) (any, error) {
+		var result any
+		for {
+			test, err := condition(i)
+			if err != nil {
+				return nil, err
+			}
+			if !test {
+				break
+			}
+			if body == nil {
+				continue
+			}
+			value, err := body(i)
+			if err != nil {
+				if _, isBreak := err.(*BreakError); isBreak {
+					break
+				}
+				if _, isContinue := err.(*ContinueError); isContinue {
+					continue
+				}
+				return nil, err
+			}
+			result = value
+		}
+		return result, nil
+	}
+}
+
+// compileDoWhile компилирует DoWhileNode (см. interpretDoWhile).
+func (c *compiler) compileDoWhile(n *jexl.DoWhileNode) compiled {
+	condition, body := c.compileCondition(n.Condition()), c.compileLoopBody(n.Body())
+	return func(i *interpreter) (any, error) {
+		var result any
+		for {
+			if body != nil {
+				value, err := body(i)
+				if err != nil {
+					if _, isBreak := err.(*BreakError); isBreak {
+						break
+					}
+					if _, isContinue := err.(*ContinueError); !isContinue {
+						return nil, err
+					}
+				} else {
+					result = value
+				}
+			}
+			test, err := condition(i)
+			if err != nil {
+				return nil, err
+			}
+			if !test {
+				break
+			}
+		}
+		return result, nil
+	}
+}
+
+// compileBinaryOp компилирует BinaryOpNode (см. interpretBinaryOp).
+func (c *compiler) compileBinaryOp(n *jexl.BinaryOpNode) compiled {
+	left, right := c.compile(n.Left()), c.compile(n.Right())
+	return func(i *interpreter) (any, error) {
+		lv, err := left(i)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := right(i)
+		if err != nil {
+			return nil, err
+		}
+		return i.applyBinaryOp(n, lv, rv)
+	}
+}
+
+// compileTernary компилирует TernaryNode (см. interpretTernary).
+func (c *compiler) compileTernary(n *jexl.TernaryNode) compiled {
+	condition, trueExpr, falseExpr := c.compile(n.Condition()), c.compile(n.TrueExpr()), c.compile(n.FalseExpr())
+	return func(i *interpreter) (any, error) {
+		value, err := condition(i)
+		if err != nil {
+			return nil, err
+		}
+		test, err := i.truth(value)
+		if err != nil {
+			return nil, err
+		}
+		if test {
+			return trueExpr(i)
+		}
+		return falseExpr(i)
+	}
+}
+
+// compileElvis компилирует ElvisNode (см. interpretElvis).
+func (c *compiler) compileElvis(n *jexl.ElvisNode) compiled {
+	expr, defaultExpr := c.compile(n.Expr()), c.compile(n.DefaultExpr())
+	return func(i *interpreter) (any, error) {
+		value, err := expr(i)
+		if err != nil {
+			if i.options != nil && i.options.Safe() {
+				return defaultExpr(i)
+			}
+			if i.options == nil || !i.options.Strict() {
+				return defaultExpr(i)
+			}
+			return nil, err
+		}
+		if value == nil {
+			return defaultExpr(i)
+		}
+		return value, nil
+	}
+}
+
+// compilePropertyAccess компилирует PropertyAccessNode (см. interpretPropertyAccess)
+// с кэшем доступа к свойству по типу получателя.
+func (c *compiler) compilePropertyAccess(n *jexl.PropertyAccessNode) compiled {
+	propIdent, ok := n.Property().(*jexl.IdentifierNode)
+	if !ok {
+		return func(i *interpreter) (any, error) {
+			return i.interpretPropertyAccess(n)
+		}
+	}
+	// Переменная с точкой в имени (ant-style) имеет приоритет над доступом к свойству
+	antName := ""
+	if objIdent, ok := n.Object().(*jexl.IdentifierNode); ok {
+		antName = objIdent.Name() + "." + propIdent.Name()
+	}
+	object := c.compile(n.Object())
+	site := &propertySite{name: propIdent.Name()}
+	return func(i *interpreter) (any, error) {
+		if antName != "" && i.context != nil && i.context.Has(antName) {
+			return i.context.Get(antName), nil
+		}
+		obj, err := object(i)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			if i.options != nil && i.options.Safe() {
+				return nil, nil
+			}
+			return nil, jexl.NewError("cannot access property on nil")
+		}
+		return site.get(i, obj)
+	}
+}
+
+// compileMethodCall компилирует MethodCallNode (см. interpretMethodCall)
+// с кэшем выбора метода Go по типам получателя и аргументов.
+func (c *compiler) compileMethodCall(n *jexl.MethodCallNode) compiled {
+	methodIdent, ok := n.Method().(*jexl.IdentifierNode)
+	if !ok {
+		return func(i *interpreter) (any, error) {
+			return i.interpretMethodCall(n)
+		}
+	}
+	args := c.compileAll(n.Args())
+	evalArgs := func(i *interpreter) ([]any, error) {
+		return evalAll(i, args)
+	}
+	if n.Target() == nil || n.Namespace() != "" {
+		return func(i *interpreter) (any, error) {
+			values, err := evalArgs(i)
+			if err != nil {
+				return nil, err
+			}
+			return i.invokeMethodCall(n, values)
+		}
+	}
+	target := c.compile(n.Target())
+	site := &methodSite{name: methodIdent.Name()}
+	return func(i *interpreter) (any, error) {
+		// Как и в interpretMethodCall, аргументы вычисляются до получателя
+		values, err := evalArgs(i)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := target(i)
+		if err != nil {
+			return nil, err
+		}
+		return site.call(i, obj, values)
+	}
+}
+
+// propertySite - кэш доступа к свойству в точке программы (inline cache).
+// Хранит индекс поля структуры для последнего типа получателя.
+type propertySite struct {
+	name  string
+	entry atomic.Pointer[fieldEntry]
+}
+
+// fieldEntry - разрешение свойства для типа получателя; index < 0 - поле не кэшируется.
+type fieldEntry struct {
+	typ   reflect.Type
+	index int
+	ptr   bool
+}
+
+// get читает свойство ненулевого объекта. Кэш используется только со стандартным
+// Uberspect: иной Uberspect (например, песочница) может разрешать свойства иначе.
+func (s *propertySite) get(i *interpreter, obj any) (any, error) {
+	if _, ok := i.engine.Uberspect().(*uberspectImpl); ok {
+		switch v := obj.(type) {
+		case map[string]any:
+			return v[s.name], nil
+		case *jexl.Map:
+			value, _ := v.Get(s.name)
+			return value, nil
+		}
+		if value, ok := s.field(obj); ok {
+			return value, nil
+		}
+	}
+	return i.getProperty(obj, s.name)
+}
+
+// field читает закэшированное поле структуры или указателя на структуру.
+func (s *propertySite) field(obj any) (any, bool) {
+	typ := reflect.TypeOf(obj)
+	entry := s.entry.Load()
+	if entry == nil || entry.typ != typ {
+		entry = &fieldEntry{typ: typ, index: -1}
+		structType := typ
+		if typ.Kind() == reflect.Ptr {
+			structType = typ.Elem()
+			entry.ptr = true
+		}
+		entry.index = structFieldIndex(structType, s.name)
+		s.entry.Store(entry)
+	}
+	if entry.index < 0 {
+		return nil, false
+	}
+	val := reflect.ValueOf(obj)
+	if entry.ptr {
+		if val.IsNil() {
+			return nil, false
+		}
+		val = val.Elem()
+	}
+	return val.Field(entry.index).Interface(), true
+}
+
+// methodSite - кэш выбора метода Go в точке вызова (inline cache).
+// Выбор метода зависит только от типов получателя и аргументов.
+type methodSite struct {
+	name  string
+	entry atomic.Pointer[methodEntry]
+}
+
+// methodEntry - метод Go, выбранный для типов получателя и аргументов.
+type methodEntry struct {
+	typ   reflect.Type
+	args  []reflect.Type
+	index int
+	elem  bool
+	ok    bool
+}
+
+// matches проверяет, совпадают ли типы получателя и аргументов с закэшированными.
+func (e *methodEntry) matches(typ reflect.Type, args []any) bool {
+	if e.typ != typ || len(e.args) != len(args) {
+		return false
+	}
+	for k, arg := range args {
+		if e.args[k] != reflect.TypeOf(arg) {
+			return false
+		}
+	}
+	return true
+}
+
+// call вызывает метод объекта. Методы строк, коллекций и вызовы через иной Uberspect
+// выполняются через invokeObjectMethod.
+func (s *methodSite) call(i *interpreter, obj any, args []any) (any, error) {
+	u, ok := i.engine.Uberspect().(*uberspectImpl)
+	if !ok || obj == nil {
+		return i.invokeObjectMethod(obj, s.name, args)
+	}
+	val := reflect.ValueOf(obj)
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		return i.invokeObjectMethod(obj, s.name, args)
+	}
+	entry := s.entry.Load()
+	if entry == nil || !entry.matches(val.Type(), args) {
+		entry = &methodEntry{typ: val.Type(), args: make([]reflect.Type, len(args))}
+		for k, arg := range args {
+			entry.args[k] = reflect.TypeOf(arg)
+		}
+		entry.index, entry.elem, entry.ok = u.goMethodIndex(obj, s.name, args)
+		s.entry.Store(entry)
+	}
+	if !entry.ok {
+		return i.invokeObjectMethod(obj, s.name, args)
+	}
+	if entry.elem {
+		val = val.Elem()
+	}
+	method := &reflectionMethod{method: val.Method(entry.index), name: s.name}
+	return method.Invoke(obj, args)
+}
@@ -81,6 +81,22 @@ func NewEngine(builder *jexl.Builder) (jexl.Engine, error) {
 	}
 	eng.uberspect = uberspect
 
+	// Подключение стандартных пространств имён
+	if names := builder.StandardNamespacesValue(); len(names) > 0 {
+		namespaces := opts.Namespaces()
+		for _, name := range names {
+			if _, exists := namespaces[name]; exists {
+				continue
+			}
+			funcs, err := jexl.StandardNamespace(name, builder.PermissionsValue())
+			if err != nil {
+				return nil, err
+			}
+			namespaces[name] = funcs
+		}
+		opts.SetNamespaces(namespaces)
+	}
+
 	// Настройка sandbox
 	sandbox := builder.SandboxValue()
 	if sandbox != nil {
@@ -107,6 +123,9 @@ func NewEngine(builder *jexl.Builder) (jexl.Engine, error) {
 	if builder.ArithmeticValue() != nil {
 		eng.arithmetic = builder.ArithmeticValue()
 	}
+	if u, ok := uberspect.(*uberspectImpl); ok && u.arithmetic == nil {
+		u.arithmetic = eng.arithmetic
+	}
 	if builder.LoggerValue() != nil {
 		eng.logger = builder.LoggerValue()
 	}
@@ -152,6 +171,9 @@ func (e *engine) CreateExpression(info *jexl.Info, source string) (jexl.Expressi
 	if err != nil {
 		return nil, err
 	}
+	if e.options.Optimize() {
+		ast = optimizeScript(e, ast)
+	}
 
 	// Создаём script (который также реализует Expression)
 	return NewScript(e, source, ast), nil
@@ -176,10 +198,75 @@ func (e *engine) CreateScript(features *jexl.Features, info *jexl.Info, source s
 	if err != nil {
 		return nil, err
 	}
+	if e.options.Optimize() {
+		ast = optimizeScript(e, ast)
+	}
 
 	return NewScript(e, source, ast), nil
 }
 
+// CreateScriptFromAST создаёт скрипт из готового AST.
+func (e *engine) CreateScriptFromAST(ast *jexl.ScriptNode) (jexl.Script, error) {
+	if ast == nil {
+		return nil, jexl.NewError("script AST is nil")
+	}
+	if err := checkFeatures(ast, e.scriptFeatures); err != nil {
+		return nil, err
+	}
+	if e.options.Optimize() {
+		ast = optimizeScript(e, ast)
+	}
+	return NewScript(e, ast.SourceText(), ast), nil
+}
+
+// checkFeatures проверяет готовый AST на конструкции, которые парсер
+// отклонил бы при разборе с features: циклы и lambda.
+func checkFeatures(ast *jexl.ScriptNode, features *jexl.Features) error {
+	if features == nil {
+		return nil
+	}
+	var err error
+	jexl.Inspect(ast, func(node jexl.Node) bool {
+		if err != nil {
+			return false
+		}
+		var message string
+		switch node.(type) {
+		case *jexl.ForNode, *jexl.ForeachNode, *jexl.WhileNode, *jexl.DoWhileNode, *jexl.BreakNode, *jexl.ContinueNode:
+			if !features.SupportsLoops() {
+				message = "loops are not enabled"
+			}
+		case *jexl.LambdaNode, *jexl.FunctionNode:
+			if !features.SupportsLambda() {
+				message = "lambda functions are not enabled"
+			}
+		}
+		if message != "" {
+			err = jexl.NewParsingError(message, ast.SourceText(), jexl.NodeInfo("", node))
+		}
+		return err == nil
+	})
+	return err
+}
+
+// LoadScript создаёт скрипт из сериализованного AST.
+func (e *engine) LoadScript(data []byte) (jexl.Script, error) {
+	ast, err := jexl.UnmarshalAST(data)
+	if err != nil {
+		return nil, err
+	}
+	return e.CreateScriptFromAST(ast)
+}
+
+// ParseDiagnostics разбирает скрипт с восстановлением после ошибок.
+func (e *engine) ParseDiagnostics(source string, features *jexl.Features) ([]jexl.Diagnostic, *jexl.ScriptNode) {
+	if features == nil {
+		features = e.scriptFeatures
+	}
+	ast, diagnostics := e.getParser().ParseDiagnostics(e.createInfo(), source, features)
+	return diagnostics, ast
+}
+
 // getParser возвращает парсер, создавая его при необходимости.
 func (e *engine) getParser() Parser {
 	e.mu.RLock()
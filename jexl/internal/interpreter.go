@@ -1,9 +1,15 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math/big"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/mentatxx/jexl-golang/jexl"
 )
@@ -14,6 +20,7 @@ type interpreter struct {
 	engine  jexl.Engine
 	context jexl.Context
 	options *jexl.Options
+	name    string // имя источника для Info ошибок
 }
 
 // newInterpreter создаёт новый интерпретатор.
@@ -26,8 +33,33 @@ func newInterpreter(engine jexl.Engine, ctx jexl.Context) *interpreter {
 	}
 }
 
-// interpret выполняет AST узел.
+// interpret выполняет AST узел. Ошибка получает Info с положением узла.
 func (i *interpreter) interpret(node jexl.Node) (any, error) {
+	result, err := i.evaluate(node)
+	if err != nil {
+		return result, i.locate(node, err)
+	}
+	return result, nil
+}
+
+// locate привязывает к ошибке Info с положением узла, если у неё его ещё нет.
+// Ошибки управления (break, continue, return) не изменяются.
+func (i *interpreter) locate(node jexl.Node, err error) error {
+	if node == nil || isControlFlowError(err) {
+		return err
+	}
+	return jexl.AttachInfo(err, jexl.NodeInfo(i.name, node))
+}
+
+// newClosure создаёт closure, ошибки которого относятся к тому же источнику.
+func (i *interpreter) newClosure(lambda *jexl.LambdaNode, ctx jexl.Context) *closure {
+	fn := NewClosure(i.engine, lambda, ctx).(*closure)
+	fn.name = i.name
+	return fn
+}
+
+// evaluate выполняет AST узел в зависимости от его типа.
+func (i *interpreter) evaluate(node jexl.Node) (any, error) {
 	switch n := node.(type) {
 	case *jexl.ScriptNode:
 		return i.interpretScript(n)
@@ -45,6 +77,8 @@ func (i *interpreter) interpret(node jexl.Node) (any, error) {
 		return i.interpretIndexAccess(n)
 	case *jexl.MethodCallNode:
 		return i.interpretMethodCall(n)
+	case *jexl.PipeNode:
+		return i.interpretPipe(n)
 	case *jexl.AssignmentNode:
 		return i.interpretAssignment(n)
 	case *jexl.TernaryNode:
@@ -81,6 +115,10 @@ func (i *interpreter) interpret(node jexl.Node) (any, error) {
 		return i.interpretVar(n)
 	case *jexl.LambdaNode:
 		return i.interpretLambda(n)
+	case *jexl.FunctionNode:
+		return i.interpretFunction(n)
+	case *jexl.DestructuringNode:
+		return i.interpretDestructuring(n)
 	case *jexl.SwitchNode:
 		return i.interpretSwitch(n)
 	case *jexl.TryNode:
@@ -98,6 +136,7 @@ func (i *interpreter) interpretScript(node *jexl.ScriptNode) (any, error) {
 	var result any
 	var err error
 
+	defer i.hoistFunctions(node.Children(), nil)()
 	for _, child := range node.Children() {
 		result, err = i.interpret(child)
 		if err != nil {
@@ -133,6 +172,17 @@ func (i *interpreter) interpretIdentifier(node *jexl.IdentifierNode) (any, error
 	return i.context.Get(node.Name()), nil
 }
 
+// resolveType заменяет имя типа, зарегистрированное через Builder.Types, на reflect.Type.
+// Незарегистрированные имена передаются арифметике как встроенные имена типов.
+func (i *interpreter) resolveType(typ any) any {
+	if name, ok := typ.(string); ok && i.options != nil {
+		if t := i.options.ResolveType(name); t != nil {
+			return t
+		}
+	}
+	return typ
+}
+
 // interpretBinaryOp выполняет BinaryOpNode.
 func (i *interpreter) interpretBinaryOp(node *jexl.BinaryOpNode) (any, error) {
 	left, err := i.interpret(node.Left())
@@ -144,7 +194,11 @@ func (i *interpreter) interpretBinaryOp(node *jexl.BinaryOpNode) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return i.applyBinaryOp(node, left, right)
+}
 
+// applyBinaryOp применяет оператор BinaryOpNode к вычисленным операндам.
+func (i *interpreter) applyBinaryOp(node *jexl.BinaryOpNode, left, right any) (any, error) {
 	// Получаем арифметику из движка
 	arithmetic := i.engine.Arithmetic()
 	if arithmetic == nil {
@@ -164,14 +218,43 @@ func (i *interpreter) interpretBinaryOp(node *jexl.BinaryOpNode) (any, error) {
 		return arithmetic.Divide(left, right)
 	case "%":
 		return arithmetic.Modulo(left, right)
+	case "div":
+		return arithmetic.IntegerDivide(left, right)
+	case "**":
+		return arithmetic.Power(left, right)
+	case "in":
+		return arithmetic.In(left, right)
+	case "!in":
+		result, err := arithmetic.In(left, right)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := result.(bool); ok {
+			return !b, nil
+		}
+		return nil, jexl.NewError("in operation did not return boolean")
+	case "instanceof":
+		return arithmetic.InstanceOf(left, i.resolveType(right))
+	case "!instanceof":
+		result, err := arithmetic.InstanceOf(left, i.resolveType(right))
+		if err != nil {
+			return nil, err
+		}
+		return !result, nil
 	case "==", "eq":
 		cmp, err := arithmetic.Compare(left, right)
+		if errors.Is(err, jexl.ErrUnordered) {
+			return false, nil
+		}
 		if err != nil {
 			return nil, err
 		}
 		return cmp == 0, nil
 	case "!=", "ne":
 		cmp, err := arithmetic.Compare(left, right)
+		if errors.Is(err, jexl.ErrUnordered) {
+			return true, nil
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -298,7 +381,11 @@ func (i *interpreter) interpretUnaryOp(node *jexl.UnaryOpNode) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return i.applyUnaryOp(node, value)
+}
 
+// applyUnaryOp применяет оператор UnaryOpNode к вычисленному операнду.
+func (i *interpreter) applyUnaryOp(node *jexl.UnaryOpNode, value any) (any, error) {
 	arithmetic := i.engine.Arithmetic()
 	if arithmetic == nil {
 		arithmetic = jexl.NewBaseArithmetic(true, nil, 0)
@@ -362,8 +449,11 @@ func (i *interpreter) interpretPropertyAccess(node *jexl.PropertyAccessNode) (an
 		return nil, jexl.NewError("property must be an identifier")
 	}
 
-	propName := propIdent.Name()
+	return i.getProperty(obj, propIdent.Name())
+}
 
+// getProperty читает свойство ненулевого объекта через Uberspect.
+func (i *interpreter) getProperty(obj any, propName string) (any, error) {
 	// Используем Uberspect для получения свойства
 	uberspect := i.engine.Uberspect()
 	if uberspect == nil {
@@ -399,6 +489,38 @@ func (i *interpreter) interpretIndexAccess(node *jexl.IndexAccessNode) (any, err
 	if err != nil {
 		return nil, err
 	}
+	return i.indexValue(obj, index)
+}
+
+// indexValue читает элемент ненулевого объекта по вычисленному индексу.
+func (i *interpreter) indexValue(obj, index any) (any, error) {
+	// Ленивый диапазон отдаёт элемент по индексу без материализации
+	if r, ok := obj.(*jexl.Range); ok {
+		idx, err := toIntIndex(index)
+		if err != nil {
+			return nil, err
+		}
+		val, ok := r.Get(int64(idx))
+		if !ok {
+			if i.options != nil && i.options.Strict() {
+				return nil, jexl.NewError(fmt.Sprintf("range index out of bounds: %d", idx))
+			}
+			return nil, nil
+		}
+		return val, nil
+	}
+
+	// Упорядоченная мапа JEXL ищет ключ с сохранением типа
+	if m, ok := obj.(*jexl.Map); ok {
+		val, ok := m.Get(index)
+		if !ok {
+			if i.options != nil && i.options.Strict() {
+				return nil, jexl.NewError(fmt.Sprintf("map key not found: %v", index))
+			}
+			return nil, nil
+		}
+		return val, nil
+	}
 
 	// Сначала проверяем, является ли объект мапой
 	// Для мап индекс может быть строкой или числом (преобразуется в строку)
@@ -529,15 +651,28 @@ func (i *interpreter) interpretIndexAccess(node *jexl.IndexAccessNode) (any, err
 // interpretMethodCall выполняет MethodCallNode.
 func (i *interpreter) interpretMethodCall(node *jexl.MethodCallNode) (any, error) {
 	// Вычисляем аргументы
-	args := make([]any, len(node.Args()))
-	for j, argNode := range node.Args() {
+	args, err := i.interpretArgs(node.Args())
+	if err != nil {
+		return nil, err
+	}
+	return i.invokeMethodCall(node, args)
+}
+
+// interpretArgs вычисляет аргументы вызова.
+func (i *interpreter) interpretArgs(argNodes []jexl.Node) ([]any, error) {
+	args := make([]any, len(argNodes))
+	for j, argNode := range argNodes {
 		arg, err := i.interpret(argNode)
 		if err != nil {
 			return nil, err
 		}
 		args[j] = arg
 	}
+	return args, nil
+}
 
+// invokeMethodCall выполняет вызов MethodCallNode с вычисленными аргументами.
+func (i *interpreter) invokeMethodCall(node *jexl.MethodCallNode, args []any) (any, error) {
 	methodNode := node.Method()
 	methodIdent, ok := methodNode.(*jexl.IdentifierNode)
 	if !ok {
@@ -545,63 +680,43 @@ func (i *interpreter) interpretMethodCall(node *jexl.MethodCallNode) (any, error
 	}
 	methodName := methodIdent.Name()
 
+	if ns := node.Namespace(); ns != "" {
+		return i.interpretNamespaceCall(ns, methodName, args)
+	}
+
 	// Если есть target, это вызов метода объекта
 	if targetNode := node.Target(); targetNode != nil {
 		obj, err := i.interpret(targetNode)
 		if err != nil {
 			return nil, err
 		}
+		return i.invokeObjectMethod(obj, methodName, args)
+	}
 
-		if obj == nil {
-			if i.options != nil && i.options.Safe() {
-				return nil, nil
+	// Встроенные функции; matches(), groups(), duration() и now() уступают
+	// одноимённым функциям контекста (см. jexl.Builtin)
+	if overridable, ok := jexl.Builtin(methodName); ok && (!overridable || i.context == nil || !i.context.Has(methodName)) {
+		switch methodName {
+		case "empty":
+			if len(args) != 1 {
+				return nil, jexl.NewError("empty() requires exactly 1 argument")
 			}
-			return nil, jexl.NewError("cannot call method on nil")
-		}
-
-		// Используем Uberspect для вызова метода
-		uberspect := i.engine.Uberspect()
-		if uberspect == nil {
-			return nil, jexl.NewError("uberspect not available")
-		}
-
-		method, err := uberspect.GetMethod(obj, methodName, args)
-		if err != nil || method == nil {
-			// Если метод не найден, проверяем, не является ли свойство Script'ом
-			propGet := uberspect.GetProperty(obj, methodName)
-			if propGet != nil {
-				propValue, err := propGet.Invoke(obj)
-				if err == nil && propValue != nil {
-					// Если свойство - это Script, вызываем его
-					if script, ok := propValue.(jexl.Script); ok {
-						return script.Execute(i.context, args...)
-					}
-				}
+			return i.interpretEmpty(args[0])
+		case "size":
+			if len(args) != 1 {
+				return nil, jexl.NewError("size() requires exactly 1 argument")
 			}
-			
-			if i.options != nil && i.options.Strict() {
-				return nil, jexl.NewError("method not found: " + methodName)
+			return i.interpretSize(args[0])
+		case "matches", "groups":
+			if len(args) != 2 {
+				return nil, jexl.NewError(methodName + "() requires exactly 2 arguments")
 			}
-			return nil, nil
+			return i.interpretRegexBuiltin(methodName, args[0], args[1])
+		default:
+			return i.interpretTemporalBuiltin(methodName, args)
 		}
-
-		return method.Invoke(obj, args)
 	}
 
-	// Проверяем встроенные функции
-	switch methodName {
-	case "empty":
-		if len(args) != 1 {
-			return nil, jexl.NewError("empty() requires exactly 1 argument")
-		}
-		return i.interpretEmpty(args[0])
-	case "size":
-		if len(args) != 1 {
-			return nil, jexl.NewError("size() requires exactly 1 argument")
-		}
-		return i.interpretSize(args[0])
-	}
-	
 	// Функция верхнего уровня - ищем в контексте
 	if i.context == nil {
 		return nil, jexl.NewError("context is nil")
@@ -618,7 +733,99 @@ func (i *interpreter) interpretMethodCall(node *jexl.MethodCallNode) (any, error
 	if funcValue == nil {
 		return nil, nil
 	}
+	return i.callFunctionValue(methodName, funcValue, args)
+}
 
+// invokeObjectMethod вызывает метод объекта через Uberspect.
+func (i *interpreter) invokeObjectMethod(obj any, methodName string, args []any) (any, error) {
+	if obj == nil {
+		if i.options != nil && i.options.Safe() {
+			return nil, nil
+		}
+		return nil, jexl.NewError("cannot call method on nil")
+	}
+
+	// Используем Uberspect для вызова метода
+	uberspect := i.engine.Uberspect()
+	if uberspect == nil {
+		return nil, jexl.NewError("uberspect not available")
+	}
+
+	method, err := uberspect.GetMethod(obj, methodName, args)
+	if err != nil || method == nil {
+		// Если метод не найден, проверяем, не является ли свойство Script'ом
+		propGet := uberspect.GetProperty(obj, methodName)
+		if propGet != nil {
+			propValue, err := propGet.Invoke(obj)
+			if err == nil && propValue != nil {
+				// Если свойство - это Script, вызываем его
+				if script, ok := propValue.(jexl.Script); ok {
+					return script.Execute(i.context, args...)
+				}
+			}
+		}
+
+		if i.options != nil && i.options.Strict() {
+			return nil, jexl.NewError("method not found: " + methodName)
+		}
+		return nil, nil
+	}
+
+	return method.Invoke(obj, args)
+}
+
+// interpretPipe выполняет оператор конвейера value |> target.
+// Вызов справа получает значение первым аргументом, функция или лямбда - единственным.
+// Имя, не определённое в контексте, вызывается как метод значения: s |> trim |> substring(1).
+func (i *interpreter) interpretPipe(node *jexl.PipeNode) (any, error) {
+	value, err := i.interpret(node.Value())
+	if err != nil {
+		return nil, err
+	}
+	switch target := node.Target().(type) {
+	case *jexl.MethodCallNode:
+		if method, ok := target.Method().(*jexl.IdentifierNode); ok {
+			args, err := i.interpretArgs(target.Args())
+			if err != nil {
+				return nil, err
+			}
+			if target.Target() == nil && target.Namespace() == "" && !i.isDefined(method.Name()) && i.hasMethod(value, method.Name(), args) {
+				return i.invokeObjectMethod(value, method.Name(), args)
+			}
+			return i.invokeMethodCall(target, append([]any{value}, args...))
+		}
+	case *jexl.IdentifierNode:
+		if !i.isDefined(target.Name()) {
+			return i.invokeObjectMethod(value, target.Name(), []any{})
+		}
+	}
+	fn, err := i.interpret(node.Target())
+	if err != nil {
+		return nil, err
+	}
+	if fn == nil {
+		return nil, jexl.NewError("pipeline target is null: " + node.Target().SourceText())
+	}
+	return i.callFunctionValue(node.Target().SourceText(), fn, []any{value})
+}
+
+// isDefined проверяет, определено ли имя в контексте.
+func (i *interpreter) isDefined(name string) bool {
+	return i.context != nil && i.context.Has(name)
+}
+
+// hasMethod проверяет, есть ли у значения метод с данным именем и аргументами.
+func (i *interpreter) hasMethod(obj any, name string, args []any) bool {
+	uberspect := i.engine.Uberspect()
+	if obj == nil || uberspect == nil {
+		return false
+	}
+	method, err := uberspect.GetMethod(obj, name, args)
+	return err == nil && method != nil
+}
+
+// callFunctionValue вызывает значение-функцию: Script или func(...any) (any, error).
+func (i *interpreter) callFunctionValue(name string, funcValue any, args []any) (any, error) {
 	// Проверяем, является ли значение Script'ом
 	if script, ok := funcValue.(jexl.Script); ok {
 		return script.Execute(i.context, args...)
@@ -629,7 +836,55 @@ func (i *interpreter) interpretMethodCall(node *jexl.MethodCallNode) (any, error
 		return fn(args...)
 	}
 
-	return nil, jexl.NewError("value is not callable: " + methodName)
+	return nil, jexl.NewError("value is not callable: " + name)
+}
+
+// resolveNamespace ищет пространство имён сначала в контексте, затем в опциях движка.
+func (i *interpreter) resolveNamespace(name string) any {
+	if resolver, ok := i.context.(jexl.NamespaceResolver); ok {
+		if ns := resolver.ResolveNamespace(name); ns != nil {
+			return ns
+		}
+	}
+	if i.options != nil {
+		return i.options.ResolveNamespace(name)
+	}
+	return nil
+}
+
+// interpretNamespaceCall выполняет вызов функции пространства имён ns:func(args).
+// Пространство имён - jexl.NamespaceFuncs, map[string]any с функциями
+// или объект, чей метод вызывается через Uberspect.
+func (i *interpreter) interpretNamespaceCall(namespace, name string, args []any) (any, error) {
+	ns := i.resolveNamespace(namespace)
+	if functor, ok := ns.(jexl.NamespaceFunctor); ok {
+		ns = functor.CreateFunctor(i.context)
+	}
+	qualified := namespace + ":" + name
+	if ns == nil {
+		return nil, jexl.NewError("namespace not found: " + namespace)
+	}
+
+	switch n := ns.(type) {
+	case jexl.NamespaceFuncs:
+		if fn, ok := n[name]; ok {
+			return fn(args...)
+		}
+	case map[string]any:
+		if funcValue, ok := n[name]; ok && funcValue != nil {
+			return i.callFunctionValue(qualified, funcValue, args)
+		}
+	default:
+		if uberspect := i.engine.Uberspect(); uberspect != nil {
+			if method, err := uberspect.GetMethod(ns, name, args); err == nil && method != nil {
+				return method.Invoke(ns, args)
+			}
+		}
+	}
+	if i.options != nil && i.options.Strict() {
+		return nil, jexl.NewError("function not found: " + qualified)
+	}
+	return nil, nil
 }
 
 func (i *interpreter) interpretAssignment(node *jexl.AssignmentNode) (any, error) {
@@ -745,7 +1000,11 @@ func (i *interpreter) assignProperty(target *jexl.PropertyAccessNode, value any)
 		}
 		return nil, jexl.NewError("cannot assign property on nil")
 	}
+	return i.setProperty(target, obj, value)
+}
 
+// setProperty присваивает свойство target ненулевому объекту obj.
+func (i *interpreter) setProperty(target *jexl.PropertyAccessNode, obj, value any) (any, error) {
 	propIdent, ok := target.Property().(*jexl.IdentifierNode)
 	if !ok {
 		return nil, jexl.NewError("property must be an identifier")
@@ -787,6 +1046,15 @@ func (i *interpreter) assignIndex(target *jexl.IndexAccessNode, value any) (any,
 	if err != nil {
 		return nil, err
 	}
+	return i.setIndex(obj, index, value)
+}
+
+// setIndex присваивает элемент ненулевого объекта по вычисленному индексу.
+func (i *interpreter) setIndex(obj, index, value any) (any, error) {
+	if m, ok := obj.(*jexl.Map); ok {
+		m.Put(index, value)
+		return value, nil
+	}
 
 	objValue := reflect.ValueOf(obj)
 	if objValue.Kind() == reflect.Ptr {
@@ -1014,7 +1282,24 @@ func (i *interpreter) interpretRange(node *jexl.RangeNode) (any, error) {
 	}
 
 	// Создаём range через арифметику
-	return arithmetic.CreateRange(left, right)
+	result, err := arithmetic.CreateRange(left, right)
+	if err != nil || node.Step() == nil {
+		return result, err
+	}
+
+	stepValue, err := i.interpret(node.Step())
+	if err != nil {
+		return nil, err
+	}
+	step, err := toIntIndex(stepValue)
+	if err != nil {
+		return nil, jexl.NewError(fmt.Sprintf("range step must be an integer, got %v", stepValue))
+	}
+	r, ok := result.(*jexl.Range)
+	if !ok {
+		return nil, jexl.NewError(fmt.Sprintf("range step is not supported for %T", result))
+	}
+	return r.WithStep(int64(step))
 }
 
 // interpretArrayLiteral выполняет литерал массива.
@@ -1036,7 +1321,7 @@ func (i *interpreter) interpretArrayLiteral(node *jexl.ArrayLiteralNode) (any, e
 // interpretMapLiteral выполняет литерал мапы.
 func (i *interpreter) interpretMapLiteral(node *jexl.MapLiteralNode) (any, error) {
 	entries := node.Entries()
-	result := make(map[string]any, len(entries))
+	result := jexl.NewMap()
 
 	for _, entry := range entries {
 		key, err := i.interpret(entry.Key)
@@ -1047,37 +1332,23 @@ func (i *interpreter) interpretMapLiteral(node *jexl.MapLiteralNode) (any, error
 		if err != nil {
 			return nil, err
 		}
-
-		keyStr, ok := key.(string)
-		if !ok {
-			// Пробуем преобразовать в строку
-			keyStr = fmt.Sprintf("%v", key)
-		}
-		result[keyStr] = value
+		result.Put(key, value)
 	}
 
 	return result, nil
 }
 
 // interpretSetLiteral выполняет литерал множества.
-// В JEXL множества реализованы как мапы, где ключи - это элементы множества, а значения - true
+// Элементы сохраняют исходные типы: {1, '1'} содержит два элемента.
 func (i *interpreter) interpretSetLiteral(node *jexl.SetLiteralNode) (any, error) {
-	elements := node.Elements()
-	if elements == nil {
-		return make(map[string]any), nil
-	}
-
-	result := make(map[string]any)
+	result := jexl.NewSet()
 
-	for _, elem := range elements {
+	for _, elem := range node.Elements() {
 		val, err := i.interpret(elem)
 		if err != nil {
 			return nil, err
 		}
-
-		// Преобразуем значение в строку для использования в качестве ключа мапы
-		keyStr := fmt.Sprintf("%v", val)
-		result[keyStr] = true
+		result.Add(val)
 	}
 
 	return result, nil
@@ -1104,17 +1375,9 @@ func (i *interpreter) interpretIf(node *jexl.IfNode) (any, error) {
 		return nil, err
 	}
 
-	arithmetic := i.engine.Arithmetic()
-	if arithmetic == nil {
-		arithmetic = jexl.NewBaseArithmetic(true, nil, 0)
-	}
-
-	test, err := arithmetic.ToBoolean(condition)
+	test, err := i.truth(condition)
 	if err != nil {
-		if i.options != nil && i.options.Strict() {
-			return nil, err
-		}
-		test = false
+		return nil, err
 	}
 
 	if test {
@@ -1128,6 +1391,23 @@ func (i *interpreter) interpretIf(node *jexl.IfNode) (any, error) {
 	return nil, nil
 }
 
+// truth приводит значение условия к boolean. Вне строгого режима значение,
+// не приводимое к boolean, считается ложным.
+func (i *interpreter) truth(value any) (bool, error) {
+	arithmetic := i.engine.Arithmetic()
+	if arithmetic == nil {
+		arithmetic = jexl.NewBaseArithmetic(true, nil, 0)
+	}
+	test, err := arithmetic.ToBoolean(value)
+	if err != nil {
+		if i.options != nil && i.options.Strict() {
+			return false, err
+		}
+		return false, nil
+	}
+	return test, nil
+}
+
 // interpretFor выполняет цикл for (init; condition; step) body.
 func (i *interpreter) interpretFor(node *jexl.ForNode) (any, error) {
 	// Инициализация
@@ -1206,41 +1486,37 @@ func (i *interpreter) interpretForeach(node *jexl.ForeachNode) (any, error) {
 
 	varName := node.Variable()
 	var ident *jexl.IdentifierNode
-	if id, ok := varName.(*jexl.IdentifierNode); ok {
-		ident = id
-	} else {
-		return nil, jexl.NewError("foreach variable must be an identifier")
+	switch v := varName.(type) {
+	case *jexl.IdentifierNode:
+		ident = v
+	case *jexl.ArrayPatternNode, *jexl.MapPatternNode:
+		// Переменная цикла - шаблон деструктуризации
+	default:
+		return nil, jexl.NewError("foreach variable must be an identifier or pattern")
 	}
 
-	// Преобразуем items в итерируемую коллекцию
-	var iterable []any
-	switch v := items.(type) {
-	case []any:
-		iterable = v
-	case []int:
-		iterable = make([]any, len(v))
-		for j, val := range v {
-			iterable[j] = val
-		}
-	case []string:
-		iterable = make([]any, len(v))
-		for j, val := range v {
-			iterable[j] = val
-		}
-	case map[string]any:
-		// Для мап итерируем по значениям
-		iterable = make([]any, 0, len(v))
-		for _, val := range v {
-			iterable = append(iterable, val)
+	var keyName string
+	if key := node.Key(); key != nil {
+		keyIdent, ok := key.(*jexl.IdentifierNode)
+		if !ok {
+			return nil, jexl.NewError("foreach key must be an identifier")
 		}
-	default:
-		return nil, jexl.NewError("foreach items must be iterable")
+		keyName = keyIdent.Name()
 	}
 
+	sortedKeys := i.options != nil && i.options.SortedKeys()
 	var result any
-	for _, item := range iterable {
-		// Устанавливаем переменную цикла
-		if i.context != nil {
+	var loopErr error
+	err = iterateItems(items, sortedKeys, func(key, item any) bool {
+		// Устанавливаем переменные цикла
+		if keyName != "" && i.context != nil {
+			i.context.Set(keyName, key)
+		}
+		if ident == nil {
+			if loopErr = i.destructure(varName, item); loopErr != nil {
+				return false
+			}
+		} else if i.context != nil {
 			i.context.Set(ident.Name(), item)
 		}
 
@@ -1249,17 +1525,24 @@ func (i *interpreter) interpretForeach(node *jexl.ForeachNode) (any, error) {
 			bodyResult, err := i.interpret(node.Body())
 			if err != nil {
 				if _, isBreak := err.(*BreakError); isBreak {
-					break
+					return false
 				}
 				if _, isContinue := err.(*ContinueError); isContinue {
-					continue
+					return true
 				}
-				return nil, err
+				loopErr = err
+				return false
 			}
 			result = bodyResult
 		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if loopErr != nil {
+		return nil, loopErr
 	}
-
 	return result, nil
 }
 
@@ -1369,6 +1652,7 @@ func (i *interpreter) interpretDoWhile(node *jexl.DoWhileNode) (any, error) {
 // interpretBlock выполняет блок кода.
 func (i *interpreter) interpretBlock(node *jexl.BlockNode) (any, error) {
 	var result any
+	defer i.hoistFunctions(node.Statements(), nil)()
 	for _, stmt := range node.Statements() {
 		var err error
 		result, err = i.interpret(stmt)
@@ -1402,16 +1686,231 @@ func (i *interpreter) interpretVar(node *jexl.VarNode) (any, error) {
 	return nil, nil
 }
 
+// interpretDestructuring выполняет деструктурирующее объявление или присваивание.
+// Результат - исходное значение, как у обычного присваивания.
+func (i *interpreter) interpretDestructuring(node *jexl.DestructuringNode) (any, error) {
+	value, err := i.interpret(node.Value())
+	if err != nil {
+		return nil, err
+	}
+	if err := i.destructure(node.Pattern(), value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// destructure присваивает части value целям шаблона.
+func (i *interpreter) destructure(target jexl.Node, value any) error {
+	if i.context == nil {
+		return jexl.NewError("context is nil")
+	}
+	switch pattern := target.(type) {
+	case *jexl.IdentifierNode:
+		i.context.Set(pattern.Name(), value)
+		return nil
+	case *jexl.ArrayPatternNode:
+		return i.destructureArray(pattern, value)
+	case *jexl.MapPatternNode:
+		return i.destructureMap(pattern, value)
+	default:
+		return jexl.NewError(fmt.Sprintf("invalid destructuring target: %T", target))
+	}
+}
+
+// destructureArray присваивает элементы последовательности целям [a, b, ...rest].
+func (i *interpreter) destructureArray(pattern *jexl.ArrayPatternNode, value any) error {
+	var items []any
+	switch v := value.(type) {
+	case nil:
+		if i.options != nil && i.options.Strict() {
+			return jexl.NewError("cannot destructure null")
+		}
+	case *jexl.Range:
+		// Диапазон материализуется целиком только для остатка ...rest,
+		// и слишком длинный диапазон не материализуется вовсе
+		size := v.Size()
+		if pattern.Rest() != nil {
+			if err := v.CheckMaterialize(); err != nil {
+				return err
+			}
+		} else if n := int64(len(pattern.Elements())); size > n {
+			size = n
+		}
+		for j := int64(0); j < size; j++ {
+			n, _ := v.Get(j)
+			items = append(items, n)
+		}
+	case *jexl.Set:
+		items = v.Values()
+	default:
+		val := reflect.ValueOf(value)
+		if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+			return jexl.NewError(fmt.Sprintf("cannot destructure %T as array", value))
+		}
+		items = make([]any, val.Len())
+		for j := range items {
+			items[j] = val.Index(j).Interface()
+		}
+	}
+
+	for j, element := range pattern.Elements() {
+		if element == nil {
+			continue
+		}
+		var item any
+		if j < len(items) {
+			item = items[j]
+		}
+		item, err := i.destructureDefault(item, pattern.Defaults(), j)
+		if err != nil {
+			return err
+		}
+		if err := i.destructure(element, item); err != nil {
+			return err
+		}
+	}
+
+	if rest := pattern.Rest(); rest != nil {
+		remaining := []any{}
+		if len(items) > len(pattern.Elements()) {
+			remaining = append(remaining, items[len(pattern.Elements()):]...)
+		}
+		i.context.Set(rest.Name(), remaining)
+	}
+	return nil
+}
+
+// destructureMap присваивает свойства объекта целям {name, age: years, ...rest}.
+// Свойства читаются через Uberspect, поэтому структуры обрабатываются так же, как мапы.
+func (i *interpreter) destructureMap(pattern *jexl.MapPatternNode, value any) error {
+	if value == nil && i.options != nil && i.options.Strict() {
+		return jexl.NewError("cannot destructure null")
+	}
+
+	uberspect := i.engine.Uberspect()
+	for j, key := range pattern.Keys() {
+		var prop any
+		if value != nil && uberspect != nil {
+			if propGet := uberspect.GetProperty(value, key); propGet != nil {
+				v, err := propGet.Invoke(value)
+				if err != nil {
+					return err
+				}
+				prop = v
+			} else if i.options != nil && i.options.Strict() && pattern.Defaults()[j] == nil {
+				return jexl.NewError(fmt.Sprintf("property not found: %s", key))
+			}
+		}
+		prop, err := i.destructureDefault(prop, pattern.Defaults(), j)
+		if err != nil {
+			return err
+		}
+		if err := i.destructure(pattern.Targets()[j], prop); err != nil {
+			return err
+		}
+	}
+
+	if rest := pattern.Rest(); rest != nil {
+		i.context.Set(rest.Name(), remainingProperties(value, pattern.Keys()))
+	}
+	return nil
+}
+
+// destructureDefault возвращает значение по умолчанию для отсутствующего (nil) значения.
+func (i *interpreter) destructureDefault(value any, defaults []jexl.Node, index int) (any, error) {
+	if value != nil || index >= len(defaults) || defaults[index] == nil {
+		return value, nil
+	}
+	return i.interpret(defaults[index])
+}
+
+// remainingProperties собирает в мапу JEXL ключи мапы или экспортированные поля
+// структуры, не перечисленные в used, сохраняя их порядок. Ключи любого типа,
+// кроме деструктурированных строковых, сохраняются.
+func remainingProperties(value any, used []string) *jexl.Map {
+	result := jexl.NewMap()
+	val := reflect.ValueOf(value)
+	for val.IsValid() && val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return result
+		}
+		val = val.Elem()
+	}
+	m, ok := value.(*jexl.Map)
+	if !ok && val.Kind() == reflect.Map {
+		m = jexl.NewMapFrom(val.Interface())
+	}
+	if m != nil {
+		for _, key := range m.Keys() {
+			if name, ok := key.(string); !ok || !slices.Contains(used, name) {
+				v, _ := m.Get(key)
+				result.Put(key, v)
+			}
+		}
+		return result
+	}
+	if val.Kind() == reflect.Struct {
+		typ := val.Type()
+		for j := 0; j < typ.NumField(); j++ {
+			field := typ.Field(j)
+			if field.IsExported() && !slices.ContainsFunc(used, func(key string) bool { return strings.EqualFold(key, field.Name) }) {
+				result.Put(field.Name, val.Field(j).Interface())
+			}
+		}
+	}
+	return result
+}
+
 // interpretLambda выполняет lambda функцию и создаёт Closure.
 func (i *interpreter) interpretLambda(node *jexl.LambdaNode) (any, error) {
 	// Создаём closure с захваченным контекстом
 	// Захватываем весь контекст, включая argumentContext, чтобы lambda видела переменные, установленные через var
 	// В Java версии closure захватывает Frame, который содержит все переменные из скрипта
 	capturedCtx := i.context
-	closure := NewClosure(i.engine, node, capturedCtx)
+	closure := i.newClosure(node, capturedCtx)
 	return closure, nil
 }
 
+// interpretFunction выполняет объявление функции: возвращает функцию,
+// поднятую в области видимости блока или скрипта.
+func (i *interpreter) interpretFunction(node *jexl.FunctionNode) (any, error) {
+	if i.context == nil {
+		return nil, jexl.NewError("context is nil")
+	}
+	if existing, ok := i.context.Get(node.Name().Name()).(*closure); ok && existing.lambda == node.Lambda() {
+		return existing, nil
+	}
+	// Объявление вне блока (тело if без скобок) ничего не связывает
+	return i.newClosure(node.Lambda(), i.context), nil
+}
+
+// hoistFunctions создаёт область видимости функций блока или скрипта поверх текущего
+// контекста и объявляет в ней функции до выполнения statements, чтобы их можно было
+// вызывать до объявления и рекурсивно. Возвращаемая функция восстанавливает контекст,
+// поэтому функции не видны после блока и не попадают в контекст вызывающего.
+// programs - скомпилированные тела функций в режиме Options.Compiled, иначе nil.
+func (i *interpreter) hoistFunctions(statements []jexl.Node, programs map[*jexl.LambdaNode]*program) func() {
+	outer := i.context
+	funcs := map[string]any{}
+	for _, stmt := range statements {
+		if fn, ok := stmt.(*jexl.FunctionNode); ok {
+			funcs[fn.Name().Name()] = nil
+		}
+	}
+	if outer == nil || len(funcs) == 0 {
+		return func() {}
+	}
+	i.context = newArgumentContext(outer, funcs)
+	for _, stmt := range statements {
+		if fn, ok := stmt.(*jexl.FunctionNode); ok {
+			closure := i.newClosure(fn.Lambda(), i.context)
+			closure.program = programs[fn.Lambda()]
+			funcs[fn.Name().Name()] = closure
+		}
+	}
+	return func() { i.context = outer }
+}
+
 // ReturnError используется для возврата значения из скрипта.
 type ReturnError struct {
 	Value any
@@ -1448,6 +1947,12 @@ func (i *interpreter) interpretEmpty(value any) (any, error) {
 		return len(v) == 0, nil
 	case map[string]any:
 		return len(v) == 0, nil
+	case *jexl.Map:
+		return v.IsEmpty(), nil
+	case *jexl.Set:
+		return v.IsEmpty(), nil
+	case *jexl.Range:
+		return v.IsEmpty(), nil
 	case bool:
 		return !v, nil
 	default:
@@ -1464,6 +1969,48 @@ func (i *interpreter) interpretEmpty(value any) (any, error) {
 	}
 }
 
+// interpretTemporalBuiltin выполняет now() и duration(value).
+// duration принимает ISO-8601 (P3DT4H), формат Go (1h30m) или число миллисекунд.
+func (i *interpreter) interpretTemporalBuiltin(name string, args []any) (any, error) {
+	if name == "now" {
+		if len(args) != 0 {
+			return nil, jexl.NewError("now() takes no arguments")
+		}
+		return time.Now(), nil
+	}
+	if len(args) != 1 {
+		return nil, jexl.NewError("duration() requires exactly 1 argument")
+	}
+	d, err := jexl.ToDuration(args[0])
+	if err != nil {
+		return nil, jexl.NewMethodError(name, args, nil, err)
+	}
+	return d, nil
+}
+
+// interpretRegexBuiltin выполняет matches(value, pattern) и groups(value, pattern).
+// pattern - строка или *regexp.Regexp; groups возвращает мапу индексных и именованных групп.
+func (i *interpreter) interpretRegexBuiltin(name string, value, pattern any) (any, error) {
+	re, err := jexl.ToRegex(pattern)
+	if err != nil {
+		return nil, jexl.NewMethodError(name, []any{value, pattern}, nil, err)
+	}
+	if value == nil {
+		if name == "matches" {
+			return false, nil
+		}
+		return nil, nil
+	}
+	s := fmt.Sprintf("%v", value)
+	if name == "matches" {
+		return re.MatchString(s), nil
+	}
+	if groups := jexl.RegexGroups(re, s); groups != nil {
+		return groups, nil
+	}
+	return nil, nil
+}
+
 // interpretSize возвращает размер коллекции или строки.
 func (i *interpreter) interpretSize(value any) (any, error) {
 	if value == nil {
@@ -1472,11 +2019,18 @@ func (i *interpreter) interpretSize(value any) (any, error) {
 	
 	switch v := value.(type) {
 	case string:
-		return int64(len(v)), nil
+		// Размер строки - в символах, как у length() и str:length
+		return int64(utf8.RuneCountInString(v)), nil
 	case []any:
 		return int64(len(v)), nil
 	case map[string]any:
 		return int64(len(v)), nil
+	case *jexl.Map:
+		return int64(v.Size()), nil
+	case *jexl.Set:
+		return int64(v.Size()), nil
+	case *jexl.Range:
+		return v.Size(), nil
 	default:
 		// Для других типов пробуем использовать reflection
 		rv := reflect.ValueOf(value)
@@ -1552,11 +2106,16 @@ func (i *interpreter) interpretTry(node *jexl.TryNode) (any, error) {
 	var result any
 	var err error
 	
-	// Выполняем try блок
-	result, err = i.interpret(node.TryBlock())
+	// Выполняем try блок (ресурсы закрываются до catch и finally, как в Java)
+	if node.HasResources() {
+		result, err = i.interpretTryResources(node)
+	} else {
+		result, err = i.interpret(node.TryBlock())
+	}
 	
 	// Если произошла ошибка и есть catch блок
-	if err != nil && node.HasCatch() {
+	// break, continue и return не являются ошибками и не перехватываются
+	if err != nil && node.HasCatch() && !isControlFlowError(err) {
 		// Сохраняем ошибку в переменную catch, если она указана
 		if node.CatchVar() != "" {
 			// Создаём строковое представление ошибки
@@ -1591,3 +2150,84 @@ func (i *interpreter) interpretTry(node *jexl.TryNode) (any, error) {
 	return result, nil
 }
 
+
+// interpretTryResources инициализирует ресурсы try, выполняет блок try и закрывает
+// ресурсы в обратном порядке. Ошибки закрытия присоединяются к основной ошибке как подавленные.
+func (i *interpreter) interpretTryResources(node *jexl.TryNode) (any, error) {
+	var result any
+	var err error
+
+	// Переменные ресурсов видны только внутри оператора: они объявляются
+	// в отдельном контексте поверх текущего, который восстанавливается после закрытия
+	if i.context != nil {
+		locals := map[string]any{}
+		for _, resource := range node.Resources() {
+			if v, ok := resource.(*jexl.VarNode); ok {
+				locals[v.Name().Name()] = nil
+			}
+		}
+		if len(locals) > 0 {
+			outer := i.context
+			i.context = newArgumentContext(outer, locals)
+			defer func() { i.context = outer }()
+		}
+	}
+
+	opened := make([]any, 0, len(node.Resources()))
+	for _, resource := range node.Resources() {
+		var value any
+		value, err = i.interpret(resource)
+		if err != nil {
+			break
+		}
+		opened = append(opened, value)
+	}
+	if err == nil {
+		result, err = i.interpret(node.TryBlock())
+	}
+
+	for j := len(opened) - 1; j >= 0; j-- {
+		closeErr := i.closeResource(opened[j])
+		if closeErr == nil {
+			continue
+		}
+		if err == nil || isControlFlowError(err) {
+			// Ошибка закрытия отменяет обычное завершение, break, continue и return
+			result, err = nil, closeErr
+		} else {
+			err = jexl.WithSuppressed(err, closeErr)
+		}
+	}
+	return result, err
+}
+
+// closeResource закрывает ресурс через io.Closer или метод close()/Close(), найденный Uberspect.
+// nil-ресурсы и значения, которые нельзя закрыть, пропускаются.
+func (i *interpreter) closeResource(resource any) error {
+	if resource == nil {
+		return nil
+	}
+	if closer, ok := resource.(io.Closer); ok {
+		return closer.Close()
+	}
+	uberspect := i.engine.Uberspect()
+	if uberspect == nil {
+		return jexl.NewError("uberspect not available")
+	}
+	method, err := uberspect.GetMethod(resource, "close", nil)
+	if err != nil || method == nil {
+		return nil
+	}
+	_, err = method.Invoke(resource, nil)
+	return err
+}
+
+// isControlFlowError проверяет, является ли ошибка сигналом break, continue или return.
+func isControlFlowError(err error) bool {
+	switch err.(type) {
+	case *BreakError, *ContinueError, *ReturnError:
+		return true
+	default:
+		return false
+	}
+}
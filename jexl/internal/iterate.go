@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// iterateItems вызывает visit для каждой пары ключ/значение коллекции.
+// Поддерживаются слайсы и массивы любого типа (ключ - индекс), мапы, структуры
+// (ключ - имя экспортированного поля), каналы, iter.Seq/iter.Seq2 и типы с методом Iterator().
+// visit возвращает false, чтобы прекратить итерацию.
+func iterateItems(items any, sortedKeys bool, visit func(key, value any) bool) error {
+	if items == nil {
+		return jexl.NewError("foreach items must be iterable")
+	}
+
+	val := reflect.ValueOf(items)
+	if iterator := val.MethodByName("Iterator"); iterator.IsValid() && iterator.Type().NumIn() == 0 && iterator.Type().NumOut() > 0 {
+		return iterateIterator(iterator, sortedKeys, visit)
+	}
+
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return jexl.NewError("foreach items must be iterable")
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for j := 0; j < val.Len(); j++ {
+			if !visit(int64(j), val.Index(j).Interface()) {
+				return nil
+			}
+		}
+		return nil
+	case reflect.Map:
+		keys := val.MapKeys()
+		if sortedKeys {
+			sortKeys(keys)
+		}
+		for _, key := range keys {
+			value := val.MapIndex(key)
+			if !value.IsValid() {
+				continue // ключ удалён во время итерации
+			}
+			if !visit(key.Interface(), value.Interface()) {
+				return nil
+			}
+		}
+		return nil
+	case reflect.Struct:
+		typ := val.Type()
+		for j := 0; j < typ.NumField(); j++ {
+			if !typ.Field(j).IsExported() {
+				continue
+			}
+			if !visit(typ.Field(j).Name, val.Field(j).Interface()) {
+				return nil
+			}
+		}
+		return nil
+	case reflect.Chan:
+		if val.Type().ChanDir()&reflect.RecvDir == 0 {
+			return jexl.NewError("foreach cannot receive from send-only channel")
+		}
+		for index := int64(0); ; index++ {
+			value, ok := val.Recv()
+			if !ok || !visit(index, value.Interface()) {
+				return nil
+			}
+		}
+	case reflect.Func:
+		if isSeq(val.Type()) {
+			return iterateSeq(val, visit)
+		}
+	}
+	return jexl.NewError(fmt.Sprintf("foreach items must be iterable, got %T", items))
+}
+
+// isSeq проверяет, является ли тип функцией вида iter.Seq или iter.Seq2.
+func isSeq(typ reflect.Type) bool {
+	if typ.NumIn() != 1 || typ.NumOut() != 0 {
+		return false
+	}
+	yield := typ.In(0)
+	return yield.Kind() == reflect.Func && (yield.NumIn() == 1 || yield.NumIn() == 2) &&
+		yield.NumOut() == 1 && yield.Out(0).Kind() == reflect.Bool
+}
+
+// iterateSeq итерирует iter.Seq (ключ - порядковый номер) или iter.Seq2.
+func iterateSeq(seq reflect.Value, visit func(key, value any) bool) error {
+	yieldType := seq.Type().In(0)
+	var index int64
+	done := false
+	yield := reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+		if done {
+			panic("jexl: iterator continued after stop")
+		}
+		var cont bool
+		if len(args) == 2 {
+			cont = visit(args[0].Interface(), args[1].Interface())
+		} else {
+			cont = visit(index, args[0].Interface())
+			index++
+		}
+		done = !cont
+		return []reflect.Value{reflect.ValueOf(cont)}
+	})
+	seq.Call([]reflect.Value{yield})
+	return nil
+}
+
+// iterateIterator итерирует результат метода Iterator().
+// Результат может быть любой итерируемой коллекцией или итератором с методами HasNext() и Next().
+func iterateIterator(method reflect.Value, sortedKeys bool, visit func(key, value any) bool) error {
+	results := method.Call(nil)
+	if last := results[len(results)-1]; len(results) > 1 && last.Type().Implements(errorType) && !last.IsNil() {
+		return last.Interface().(error)
+	}
+	iterator := results[0].Interface()
+	if iterator == nil {
+		return nil
+	}
+
+	itVal := reflect.ValueOf(iterator)
+	hasNext := itVal.MethodByName("HasNext")
+	next := itVal.MethodByName("Next")
+	if !hasNext.IsValid() || !next.IsValid() || hasNext.Type().NumIn() != 0 || next.Type().NumIn() != 0 ||
+		hasNext.Type().NumOut() != 1 || hasNext.Type().Out(0).Kind() != reflect.Bool || next.Type().NumOut() == 0 {
+		return iterateItems(iterator, sortedKeys, visit)
+	}
+
+	for index := int64(0); hasNext.Call(nil)[0].Bool(); index++ {
+		values := next.Call(nil)
+		if last := values[len(values)-1]; len(values) > 1 && last.Type().Implements(errorType) && !last.IsNil() {
+			return last.Interface().(error)
+		}
+		if !visit(index, values[0].Interface()) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// sortKeys сортирует ключи мапы: числа - по значению, строки и прочие - по строковому представлению.
+func sortKeys(keys []reflect.Value) {
+	sort.SliceStable(keys, func(a, b int) bool {
+		ka, kb := keys[a], keys[b]
+		if na, ok := numericKey(ka); ok {
+			if nb, ok := numericKey(kb); ok {
+				return na < nb
+			}
+		}
+		return fmt.Sprint(ka.Interface()) < fmt.Sprint(kb.Interface())
+	})
+}
+
+// numericKey возвращает числовое значение ключа для сортировки.
+func numericKey(key reflect.Value) (float64, bool) {
+	for key.Kind() == reflect.Interface && !key.IsNil() {
+		key = key.Elem()
+	}
+	switch key.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(key.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(key.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return key.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,214 @@
+package internal
+
+import (
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// optimizer упрощает AST после разбора (режим Options.Optimize):
+//   - сворачивает операции над литералами через арифметику движка;
+//   - упрощает true && x, x || false и подобные до логического значения x;
+//   - удаляет недостижимые ветви if с константным условием;
+//   - сливает вложенные блоки с объемлющим блоком или скриптом.
+//
+// Узлы, которые не изменились, сохраняются как есть. Новые узлы получают исходный
+// текст и положение заменённых, поэтому ParsedText и сообщения об ошибках
+// показывают исходный текст.
+type optimizer struct {
+	arithmetic jexl.Arithmetic
+	folder     *interpreter // интерпретатор без контекста для свёртки констант
+}
+
+func newOptimizer(engine jexl.Engine) *optimizer {
+	arithmetic := engine.Arithmetic()
+	if arithmetic == nil {
+		arithmetic = jexl.NewBaseArithmetic(true, nil, 0)
+	}
+	return &optimizer{
+		arithmetic: arithmetic,
+		folder:     newInterpreter(engine, nil),
+	}
+}
+
+// optimizeScript возвращает оптимизированную копию скрипта или сам скрипт,
+// если оптимизировать нечего.
+func optimizeScript(engine jexl.Engine, ast *jexl.ScriptNode) *jexl.ScriptNode {
+	optimized, err := jexl.Rewrite(ast, newOptimizer(engine).simplify)
+	if err != nil {
+		return ast
+	}
+	script := optimized.(*jexl.ScriptNode)
+	if script.SourceText() != ast.SourceText() {
+		// Rewrite собирает текст из упрощённых инструкций, а ParsedText показывает исходный
+		script = copyScript(ast, script, script.Children())
+	}
+	return script
+}
+
+// simplify упрощает узел, потомки которого уже упрощены (см. jexl.Rewrite).
+func (o *optimizer) simplify(node jexl.Node) jexl.Node {
+	switch n := node.(type) {
+	case *jexl.BinaryOpNode:
+		return o.simplifyBinaryOp(n)
+	case *jexl.UnaryOpNode:
+		if literal, ok := n.Operand().(*jexl.LiteralNode); ok {
+			if value, err := o.folder.applyUnaryOp(n, literal.Value()); err == nil {
+				return at(jexl.NewLiteralNode(value, n.SourceText()), n)
+			}
+		}
+	case *jexl.IfNode:
+		return o.simplifyIf(n)
+	case *jexl.BlockNode:
+		if statements, changed := o.statements(n.Statements()); changed {
+			return at(jexl.NewBlockNode(statements, n.SourceText()), n)
+		}
+	case *jexl.ScriptNode:
+		if statements, changed := o.statements(n.Children()); changed {
+			return copyScript(n, n, statements)
+		}
+	}
+	return node
+}
+
+// copyScript создаёт скрипт с текстом и положением source, свойствами n и инструкциями statements.
+func copyScript(source, n *jexl.ScriptNode, statements []jexl.Node) *jexl.ScriptNode {
+	result := at(jexl.NewScriptNode(n.Info(), source.SourceText(), n.Features()), source)
+	for _, child := range statements {
+		result.AddChild(child)
+	}
+	for key, value := range n.Pragmas() {
+		result.SetPragma(key, value)
+	}
+	result.SetVariables(n.Variables())
+	result.SetParameters(n.Parameters())
+	result.SetParameterDefaults(n.ParameterDefaults())
+	result.SetRestParameter(n.HasRestParameter())
+	return result
+}
+
+// statements упрощает последовательность инструкций блока или скрипта.
+// Вложенные блоки без объявлений функций сливаются с последовательностью (блоки
+// не создают области видимости), литералы не в последней позиции удаляются.
+// Результат последовательности - значение последней инструкции - сохраняется.
+func (o *optimizer) statements(nodes []jexl.Node) ([]jexl.Node, bool) {
+	result := make([]jexl.Node, 0, len(nodes))
+	changed := false
+	for k, stmt := range nodes {
+		if block, ok := stmt.(*jexl.BlockNode); ok && !declaresFunctions(block) {
+			result = append(result, block.Statements()...)
+			if len(block.Statements()) == 0 && k == len(nodes)-1 {
+				// Пустой блок в конце даёт null
+				result = append(result, at(jexl.NewLiteralNode(nil, block.SourceText()), block))
+			}
+			changed = true
+			continue
+		}
+		result = append(result, stmt)
+	}
+	// Удаляем литералы, значение которых не используется
+	statements := result[:0]
+	for k, stmt := range result {
+		if _, ok := stmt.(*jexl.LiteralNode); ok && k < len(result)-1 {
+			changed = true
+			continue
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, changed
+}
+
+// declaresFunctions проверяет, объявляет ли блок функции: они поднимаются
+// в начало блока, поэтому слияние с объемлющим блоком изменило бы их видимость.
+func declaresFunctions(block *jexl.BlockNode) bool {
+	for _, stmt := range block.Statements() {
+		if _, ok := stmt.(*jexl.FunctionNode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// simplifyBinaryOp сворачивает операцию над литералами и упрощает логические
+// операции с константным операндом. Операция, завершившаяся ошибкой,
+// не сворачивается: ошибка возникнет при выполнении.
+func (o *optimizer) simplifyBinaryOp(n *jexl.BinaryOpNode) jexl.Node {
+	leftLiteral, leftOk := n.Left().(*jexl.LiteralNode)
+	rightLiteral, rightOk := n.Right().(*jexl.LiteralNode)
+	if leftOk && rightOk {
+		if value, err := o.folder.applyBinaryOp(n, leftLiteral.Value(), rightLiteral.Value()); err == nil {
+			return at(jexl.NewLiteralNode(value, n.SourceText()), n)
+		}
+	}
+	// Операнды && и || вычисляются всегда, поэтому упрощается только
+	// операнд, не влияющий на результат: true && x и x || false дают логическое значение x
+	var neutral bool
+	switch n.Op() {
+	case "&&", "and":
+		neutral = true
+	case "||", "or":
+		neutral = false
+	default:
+		return n
+	}
+	if leftOk && o.isBoolean(leftLiteral, neutral) {
+		return o.toBoolean(n.Right(), n)
+	}
+	if rightOk && o.isBoolean(rightLiteral, neutral) {
+		return o.toBoolean(n.Left(), n)
+	}
+	return n
+}
+
+// isBoolean проверяет, что литерал приводится к заданному логическому значению.
+func (o *optimizer) isBoolean(literal *jexl.LiteralNode, expected bool) bool {
+	value, err := o.arithmetic.ToBoolean(literal.Value())
+	return err == nil && value == expected
+}
+
+// toBoolean возвращает узел, вычисляющий логическое значение node: сам узел,
+// если он уже логический, иначе !!node с текстом и положением заменяемого узла.
+func (o *optimizer) toBoolean(node, replaced jexl.Node) jexl.Node {
+	switch n := node.(type) {
+	case *jexl.LiteralNode:
+		if _, ok := n.Value().(bool); ok {
+			return n
+		}
+	case *jexl.UnaryOpNode:
+		if n.Op() == "!" {
+			return n
+		}
+	case *jexl.BinaryOpNode:
+		switch n.Op() {
+		case "==", "eq", "!=", "ne", "<", "lt", ">", "gt", "<=", "le", ">=", "ge",
+			"&&", "and", "||", "or", "instanceof", "!instanceof":
+			return n
+		}
+	}
+	source := replaced.SourceText()
+	return at(jexl.NewUnaryOpNode("!", at(jexl.NewUnaryOpNode("!", node, source), replaced), source), replaced)
+}
+
+// simplifyIf заменяет if с константным условием выбранной ветвью.
+// Без ветви else результат if (false) - null.
+func (o *optimizer) simplifyIf(n *jexl.IfNode) jexl.Node {
+	literal, ok := n.Condition().(*jexl.LiteralNode)
+	if !ok {
+		return n
+	}
+	test, err := o.arithmetic.ToBoolean(literal.Value())
+	if err != nil {
+		return n
+	}
+	if test {
+		return n.ThenBranch()
+	}
+	if n.ElseBranch() != nil {
+		return n.ElseBranch()
+	}
+	return at(jexl.NewLiteralNode(nil, n.SourceText()), n)
+}
+
+// at задаёт новому узлу положение заменяемого узла.
+func at[T jexl.Node](node T, replaced jexl.Node) T {
+	setPosition(node, replaced.Position())
+	return node
+}
@@ -1,10 +1,14 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/mentatxx/jexl-golang/jexl"
 )
@@ -13,6 +17,7 @@ import (
 type Parser interface {
 	ParseExpression(info *jexl.Info, source string, features *jexl.Features) (*jexl.ScriptNode, error)
 	ParseScript(info *jexl.Info, source string, features *jexl.Features, names []string) (*jexl.ScriptNode, error)
+	ParseDiagnostics(info *jexl.Info, source string, features *jexl.Features) (*jexl.ScriptNode, []jexl.Diagnostic)
 }
 
 // defaultParser простая реализация парсера.
@@ -36,18 +41,39 @@ func (p *defaultParser) ParseExpression(info *jexl.Info, source string, features
 
 	ast := jexl.NewScriptNode(info, strings.TrimSpace(source), features)
 	ast.AddChild(node)
+	builder.finishPositions(ast)
 	return ast, nil
 }
 
 // ParseScript парсит скрипт.
 func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *jexl.Features, names []string) (*jexl.ScriptNode, error) {
+	return parseScript(newSimpleParser(info, source, features), names)
+}
+
+// ParseDiagnostics парсит скрипт, восстанавливаясь после ошибок на границах
+// инструкций: возвращает частичный AST и все найденные ошибки.
+func (p *defaultParser) ParseDiagnostics(info *jexl.Info, source string, features *jexl.Features) (*jexl.ScriptNode, []jexl.Diagnostic) {
 	builder := newSimpleParser(info, source, features)
-	ast := jexl.NewScriptNode(info, strings.TrimSpace(source), features)
+	builder.recovering = true
+	ast, err := parseScript(builder, nil)
+	if err != nil {
+		builder.report(err)
+		ast = jexl.NewScriptNode(info, strings.TrimSpace(source), features)
+		builder.finishPositions(ast)
+	}
+	return ast, builder.diagnostics
+}
+
+// parseScript разбирает инструкции скрипта.
+func parseScript(builder *simpleParser, names []string) (*jexl.ScriptNode, error) {
+	info, features := builder.info, builder.features
+	ast := jexl.NewScriptNode(info, strings.TrimSpace(builder.source), features)
 
 	for {
 		if builder.peek().typ == tokenEOF {
 			break
 		}
+		start := builder.pos
 		// Пропускаем точку с запятой в начале
 		if builder.peek().typ == tokenSemicolon {
 			builder.next()
@@ -66,23 +92,31 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 						// Это else if - парсим как вложенный if
 						elseBranch, err := builder.parseIfStatement()
 						if err != nil {
-							return nil, err
+							if !builder.recover(err, start, false) {
+								return nil, err
+							}
+							continue
 						}
 						// Обновляем IfNode с else branch
 						// Но IfNode неизменяем, нужно создать новый
 						// Проще всего - заменить последний узел новым IfNode с else branch
 						newIfNode := jexl.NewIfNode(ifNode.Condition(), ifNode.ThenBranch(), elseBranch,
 							fmt.Sprintf("%s else %s", ifNode.SourceText(), elseBranch.SourceText()))
+						builder.mark(newIfNode, ifNode.Position().Offset)
 						ast.SetChild(len(children)-1, newIfNode)
 						continue
 					} else {
 						// Обычный else
 						elseBranch, err := builder.parseStatementOrBlock()
 						if err != nil {
-							return nil, err
+							if !builder.recover(err, start, false) {
+								return nil, err
+							}
+							continue
 						}
 						newIfNode := jexl.NewIfNode(ifNode.Condition(), ifNode.ThenBranch(), elseBranch,
 							fmt.Sprintf("%s else %s", ifNode.SourceText(), elseBranch.SourceText()))
+						builder.mark(newIfNode, ifNode.Position().Offset)
 						ast.SetChild(len(children)-1, newIfNode)
 						continue
 					}
@@ -99,20 +133,27 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 		// Простой способ: если скрипт начинается с { и это не ключевое слово перед {, пробуем expression сначала
 		if builder.peek().typ == tokenLBrace {
 			// Пробуем парсить как выражение (SetLiteral/MapLiteral)
+			builder.speculating++
 			exprNode, exprErr := builder.parseExpression(0)
+			builder.speculating--
 			if exprErr == nil && exprNode != nil {
 				// Проверяем, является ли это SetLiteral или MapLiteral
 				if _, ok := exprNode.(*jexl.SetLiteralNode); ok {
 					node = exprNode
 				} else if _, ok := exprNode.(*jexl.MapLiteralNode); ok {
 					node = exprNode
+				} else if _, ok := exprNode.(*jexl.DestructuringNode); ok {
+					node = exprNode
 				} else {
 					// Это не литерал, пробуем как statement
 					// Но мы уже прочитали токены, поэтому нужно откатиться
 					// Проще всего - пробуем statement сначала, если не получается - expression
 					node, err = builder.parseStatement()
 					if err != nil {
-						return nil, err
+						if !builder.recover(err, start, false) {
+							return nil, err
+						}
+						continue
 					}
 					if node == nil {
 						// Если statement не распарсился, используем expression
@@ -120,16 +161,23 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 					}
 				}
 			} else {
-				// Не удалось распарсить как expression, пробуем как statement
+				// Не удалось распарсить как expression, пробуем как statement с начала
+				builder.pos = start
 				node, err = builder.parseStatement()
 				if err != nil {
-					return nil, err
+					if !builder.recover(err, start, false) {
+						return nil, err
+					}
+					continue
 				}
 				if node == nil {
 					// Если statement не распарсился, пробуем expression еще раз
 					node, err = builder.parseExpression(0)
 					if err != nil {
-						return nil, err
+						if !builder.recover(err, start, false) {
+							return nil, err
+						}
+						continue
 					}
 				}
 			}
@@ -137,13 +185,19 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 			// Пробуем распарсить statement, если не получается - expression
 			node, err = builder.parseStatement()
 			if err != nil {
-				return nil, err
+				if !builder.recover(err, start, false) {
+					return nil, err
+				}
+				continue
 			}
 			if node == nil {
 				// Если statement не распарсился, пробуем expression
 				node, err = builder.parseExpression(0)
 				if err != nil {
-					return nil, err
+					if !builder.recover(err, start, false) {
+						return nil, err
+					}
+					continue
 				}
 			}
 		}
@@ -165,7 +219,8 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 			nextTok.typ == tokenWhile || nextTok.typ == tokenDo ||
 			nextTok.typ == tokenReturn || nextTok.typ == tokenBreak ||
 			nextTok.typ == tokenContinue || nextTok.typ == tokenVar ||
-			nextTok.typ == tokenTry || nextTok.typ == tokenSwitch
+			nextTok.typ == tokenTry || nextTok.typ == tokenSwitch ||
+			nextTok.typ == tokenFunction
 
 		// Проверяем, является ли текущий узел expression (не statement)
 		isCurrentExpression := isExpressionNode(node)
@@ -180,8 +235,10 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 				nextTok.typ == tokenString || nextTok.typ == tokenLParen ||
 				nextTok.typ == tokenLBracket || nextTok.typ == tokenPlus ||
 				nextTok.typ == tokenMinus || nextTok.typ == tokenBang ||
-				nextTok.typ == tokenLBrace {
-				return nil, builder.errorf("missing semicolon between expressions")
+				nextTok.typ == tokenLBrace || nextTok.typ == tokenTilde {
+				if err := builder.missingSemicolon("expressions"); !builder.report(err) {
+					return nil, err
+				}
 			}
 		}
 
@@ -191,15 +248,20 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 			// Сохраняем позицию для проверки
 			savedPos := builder.pos
 			// Пробуем распарсить следующее выражение
+			builder.speculating++
 			nextNode, err := builder.parseExpression(0)
+			builder.speculating--
 			if err == nil && nextNode != nil {
 				// Проверяем, что идет после этого выражения
 				if builder.peek().typ != tokenEOF && builder.peek().typ != tokenSemicolon {
 					// Если после expression идет еще что-то (не EOF и не ;), это ошибка
 					if builder.peek().typ == tokenLBrace || builder.peek().typ == tokenNumber ||
 						builder.peek().typ == tokenIdent || builder.peek().typ == tokenString {
+						err := builder.missingSemicolon("expressions")
 						builder.pos = savedPos // Откатываемся
-						return nil, builder.errorf("missing semicolon between expressions")
+						if !builder.report(err) {
+							return nil, err
+						}
 					}
 				}
 			}
@@ -212,8 +274,12 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 			// Сохраняем позицию
 			savedPos := builder.pos
 			// Пробуем распарсить следующий statement
+			builder.speculating++
 			nextStmt, err := builder.parseStatement()
-			if err == nil && nextStmt != nil {
+			builder.speculating--
+			// Объявление функции завершается телом-блоком и не требует точки с запятой
+			_, isFunction := nextStmt.(*jexl.FunctionNode)
+			if err == nil && nextStmt != nil && !isFunction {
 				// Проверяем, что идет после statement
 				// Если это expression (не statement и не EOF), это ошибка
 				afterStmt := builder.peek()
@@ -223,11 +289,14 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 						afterStmt.typ == tokenReturn || afterStmt.typ == tokenBreak ||
 						afterStmt.typ == tokenContinue || afterStmt.typ == tokenVar ||
 						afterStmt.typ == tokenTry || afterStmt.typ == tokenSwitch ||
-						afterStmt.typ == tokenLBrace
+						afterStmt.typ == tokenFunction || afterStmt.typ == tokenLBrace
 					if !isAfterStmt {
 						// После statement идет expression без точки с запятой - ошибка
+						err := builder.missingSemicolon("statements")
 						builder.pos = savedPos
-						return nil, builder.errorf("missing semicolon between statements")
+						if !builder.report(err) {
+							return nil, err
+						}
 					}
 				}
 			}
@@ -244,7 +313,9 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 
 	// Устанавливаем параметры скрипта, если они указаны
 	if len(names) > 0 {
-		ast.SetParameters(names)
+		if err := setScriptParameters(ast, info, names, features); err != nil {
+			return nil, err
+		}
 	} else {
 		// Если параметры не указаны, проверяем, является ли скрипт только lambda
 		// Если да, устанавливаем параметры lambda в ScriptNode
@@ -257,13 +328,37 @@ func (p *defaultParser) ParseScript(info *jexl.Info, source string, features *je
 					params = append(params, param.Name())
 				}
 				ast.SetParameters(params)
+				ast.SetParameterDefaults(lambdaNode.Defaults())
+				ast.SetRestParameter(lambdaNode.HasRest())
 			}
 		}
 	}
 
+	builder.finishPositions(ast)
 	return ast, nil
 }
 
+// setScriptParameters разбирает имена параметров скрипта.
+// Имена поддерживают тот же синтаксис, что и параметры lambda: "a", "b = 10", "...rest".
+func setScriptParameters(ast *jexl.ScriptNode, info *jexl.Info, names []string, features *jexl.Features) error {
+	builder := newSimpleParser(info, strings.Join(names, ", ")+")", features)
+	parameters, defaults, rest, _, err := builder.parseParameters()
+	if err != nil {
+		return err
+	}
+	if err := builder.expect(tokenEOF); err != nil {
+		return err
+	}
+	params := make([]string, 0, len(parameters))
+	for _, param := range parameters {
+		params = append(params, param.Name())
+	}
+	ast.SetParameters(params)
+	ast.SetParameterDefaults(defaults)
+	ast.SetRestParameter(rest)
+	return nil
+}
+
 // simpleParser реализует примитивный Pratt-парсер для базовой арифметики.
 type simpleParser struct {
 	info      *jexl.Info
@@ -271,28 +366,43 @@ type simpleParser struct {
 	features  *jexl.Features
 	tokens    []token
 	pos       int
-	loopCount int // Счетчик вложенных циклов для проверки break/continue
+	loopCount int        // Счетчик вложенных циклов для проверки break/continue
+	lines     *lineIndex // строки исходного текста для положений узлов
+	eof       token      // токен конца текста
+	mapKey    int        // индекс первого токена разбираемого ключа мапы или -1
+
+	// Режим диагностики (ParseDiagnostics)
+	recovering  bool              // ошибки записываются в diagnostics, разбор продолжается
+	speculating int               // глубина пробного разбора, ошибки которого не записываются
+	diagnostics []jexl.Diagnostic // найденные ошибки
+	failure     syntaxFailure     // подробности последней ошибки
 }
 
 func newSimpleParser(info *jexl.Info, source string, features *jexl.Features) *simpleParser {
 	lexer := newLexer(source)
 	tokens := lexer.lex()
+	eof := token{typ: tokenEOF, pos: len(source), end: len(source)}
+	eof.line, eof.column = lexer.lines.lineColumn(len(source))
 	return &simpleParser{
 		info:     info,
 		source:   source,
 		features: features,
 		tokens:   tokens,
+		lines:    lexer.lines,
+		eof:      eof,
+		mapKey:   -1,
 	}
 }
 
-func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
+func (p *simpleParser) parseExpression(precedence int) (result jexl.Node, err error) {
 	tok := p.next()
 	var left jexl.Node
+	defer p.markResult(&result, tok.pos)
 
 	switch tok.typ {
 	case tokenPlus, tokenMinus, tokenBang, tokenTilde:
-		// Используем prefixPrecedence + 1 для правоассоциативности унарных операторов
-		operand, err := p.parseExpression(prefixPrecedence + 1)
+		// Степень связывает сильнее унарных операторов: -2 ** 2 == -(2 ** 2)
+		operand, err := p.parseExpression(infixPrecedence(tokenStarStar))
 		if err != nil {
 			return nil, err
 		}
@@ -323,6 +433,13 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 		one := jexl.NewLiteralNode(int64(1), "1")
 		subNode := jexl.NewBinaryOpNode("-", operand, one, fmt.Sprintf("%s - 1", operand.SourceText()))
 		left = jexl.NewAssignmentNode(operand, subNode, fmt.Sprintf("--%s", operand.SourceText()))
+	case tokenFunction:
+		// Анонимная функция: function(x, y) { ... }
+		lambda, err := p.parseFunctionLambda("function")
+		if err != nil {
+			return nil, err
+		}
+		left = lambda
 	case tokenEmpty:
 		// Оператор empty: empty x
 		operand, err := p.parseExpression(prefixPrecedence)
@@ -330,7 +447,7 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 			return nil, err
 		}
 		// Создаём вызов функции empty(operand)
-		emptyIdent := jexl.NewIdentifierNode("empty", "empty")
+		emptyIdent := atToken(p, jexl.NewIdentifierNode("empty", "empty"), tok)
 		args := []jexl.Node{operand}
 		left = jexl.NewMethodCallNode(nil, emptyIdent, args, fmt.Sprintf("empty(%s)", operand.SourceText()))
 	case tokenSize:
@@ -338,7 +455,7 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 		// Проверяем, есть ли скобки после size
 		if p.peek().typ == tokenLParen {
 			// size(x) - вызов функции
-			sizeIdent := jexl.NewIdentifierNode("size", "size")
+			sizeIdent := atToken(p, jexl.NewIdentifierNode("size", "size"), tok)
 			left = sizeIdent
 			// parseCall обработает вызов
 		} else {
@@ -348,7 +465,7 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 				return nil, err
 			}
 			// Создаём вызов функции size(operand)
-			sizeIdent := jexl.NewIdentifierNode("size", "size")
+			sizeIdent := atToken(p, jexl.NewIdentifierNode("size", "size"), tok)
 			args := []jexl.Node{operand}
 			left = jexl.NewMethodCallNode(nil, sizeIdent, args, fmt.Sprintf("size(%s)", operand.SourceText()))
 		}
@@ -380,18 +497,30 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 		if err != nil {
 			return nil, p.errorf("invalid number %s", tok.literal)
 		}
-		left = jexl.NewLiteralNode(value, tok.literal)
+		left = atToken(p, jexl.NewLiteralNode(value, tok.literal), tok)
 	case tokenString:
 		val, err := parseStringLiteral(tok.literal)
 		if err != nil {
 			return nil, err
 		}
-		left = jexl.NewLiteralNode(val, tok.literal)
+		left = atToken(p, jexl.NewLiteralNode(val, tok.literal), tok)
+	case tokenRegex:
+		re, err := p.compileRegex(tok)
+		if err != nil {
+			return nil, err
+		}
+		left = atToken(p, jexl.NewLiteralNode(re, tok.literal), tok)
 	case tokenIdent:
 		// Проверяем, не является ли это lambda функцией с одним параметром без скобок: x -> x + 1
-		if p.isLambdaStartAfterIdent() {
+		if p.isNamespaceCall(tok) && !p.isMapEntry() {
+			call, err := p.parseNamespaceCall(tok)
+			if err != nil {
+				return nil, err
+			}
+			left = call
+		} else if p.isLambdaStartAfterIdent() {
 			// Это lambda функция - параметр уже прочитан в tok
-			param := jexl.NewIdentifierNode(tok.literal, tok.literal)
+			param := atToken(p, jexl.NewIdentifierNode(tok.literal, tok.literal), tok)
 			parameters := []*jexl.IdentifierNode{param}
 
 			// Парсим стрелку: -> или =>
@@ -421,13 +550,18 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 			source := tok.literal + " " + arrow + " " + body.SourceText()
 			left = jexl.NewLambdaNode(parameters, body, source)
 		} else {
-			left = jexl.NewIdentifierNode(tok.literal, tok.literal)
+			left = atToken(p, jexl.NewIdentifierNode(tok.literal, tok.literal), tok)
 		}
 	case tokenBool:
-		left = jexl.NewLiteralNode(tok.value, tok.literal)
+		left = atToken(p, jexl.NewLiteralNode(tok.value, tok.literal), tok)
 	case tokenNull:
-		left = jexl.NewLiteralNode(nil, tok.literal)
+		left = atToken(p, jexl.NewLiteralNode(nil, tok.literal), tok)
 	case tokenLBracket:
+		// Деструктурирующее присваивание: [a, b] = expr
+		if p.isPatternAssignment(p.pos - 1) {
+			p.pos--
+			return p.parseDestructuringAssignment()
+		}
 		// Массив: [1, 2, 3]
 		arrayNode, err := p.parseArrayLiteral()
 		if err != nil {
@@ -435,8 +569,17 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 		}
 		left = arrayNode
 	case tokenLBrace:
+		// Деструктурирующее присваивание: {name, age} = expr
+		if p.isPatternAssignment(p.pos - 1) {
+			p.pos--
+			return p.parseDestructuringAssignment()
+		}
 		// Мапа или множество: {key: value} или {1, 2, 3}
-		return p.parseMapOrSetLiteral()
+		literal, err := p.parseMapOrSetLiteral()
+		if err != nil {
+			return nil, err
+		}
+		left = literal
 	case tokenLParen:
 		// Проверяем, не является ли это lambda функцией
 		// Lookahead: если после ( идут идентификаторы, а затем -> или =>, то это lambda
@@ -465,17 +608,35 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 			}
 			left = expr
 		}
+	case tokenIllegal:
+		return nil, p.errorAt(tok, nil, p.removeFix(tok), "%s %s", tok.literal, p.describe(tok))
 	default:
-		return nil, p.errorf("unexpected token %s", tok.literal)
+		return nil, p.errorAt(tok, []string{"expression"}, nil, "expected expression, found %s", p.describe(tok))
 	}
 
 	// Обрабатываем постфиксные операции (вызовы методов, доступ к свойствам, индексация)
 	for {
+		// Узел, построенный на предыдущем шаге, начинается с первого токена выражения
+		p.mark(left, tok.pos)
 		next := p.peek()
 		if next.typ == tokenEOF || next.typ == tokenRParen || next.typ == tokenSemicolon {
 			break
 		}
 
+		// Литерал ~/.../ не продолжает выражение: a ~/x/ - не сопоставление
+		// и не две инструкции без разделителя
+		if next.typ == tokenRegex {
+			return nil, p.errorAt(next, []string{"=~", tokenSemicolon.String()}, nil,
+				"unexpected regex literal %s after expression: use =~ to match or ';' to separate", next.literal)
+		}
+
+		// in, instanceof и div - контекстные ключевые слова: после выражения это
+		// операторы, в остальных позициях - обычные идентификаторы (for (var in : list))
+		if tt, ok := contextualOperators[next.literal]; ok && next.typ == tokenIdent {
+			p.tokens[p.pos].typ = tt
+			next = p.peek()
+		}
+
 		// Вызов метода или функции: expr(...)
 		if next.typ == tokenLParen {
 			var err error
@@ -500,15 +661,13 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 			} else {
 				return nil, p.errorf("expected identifier or number after '.'")
 			}
-			propNode := jexl.NewIdentifierNode(propName, propName)
+			propNode := atToken(p, jexl.NewIdentifierNode(propName, propName), prop)
 			left = jexl.NewPropertyAccessNode(left, propNode, fmt.Sprintf("%s.%s", left.SourceText(), propName))
 			continue
 		}
 
 		// Side-effect операторы: expr += value, expr -= value, и т.д.
-		if next.typ == tokenPlusEqual || next.typ == tokenMinusEqual ||
-			next.typ == tokenStarEqual || next.typ == tokenSlashEqual ||
-			next.typ == tokenPercentEqual {
+		if opSymbol, ok := compoundOperators[next.typ]; ok {
 			if !isAssignableTarget(left) {
 				return nil, p.errorf("left-hand side of assignment is not assignable")
 			}
@@ -518,22 +677,14 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 				return nil, err
 			}
 			// Преобразуем side-effect оператор в обычное присваивание с операцией
-			// x += 3 становится x = x + 3
-			var opSymbol string
-			switch op.typ {
-			case tokenPlusEqual:
-				opSymbol = "+"
-			case tokenMinusEqual:
-				opSymbol = "-"
-			case tokenStarEqual:
-				opSymbol = "*"
-			case tokenSlashEqual:
-				opSymbol = "/"
-			case tokenPercentEqual:
-				opSymbol = "%"
-			}
-			// Создаём бинарную операцию left op right
-			opNode := jexl.NewBinaryOpNode(opSymbol, left, right, fmt.Sprintf("%s %s %s", left.SourceText(), opSymbol, right.SourceText()))
+			// x += 3 становится x = x + 3, x ??= 3 - x = x ?? 3
+			var opNode jexl.Node
+			opSource := fmt.Sprintf("%s %s %s", left.SourceText(), opSymbol, right.SourceText())
+			if op.typ == tokenQuestionQuestionEqual {
+				opNode = jexl.NewElvisNode(left, right, opSource)
+			} else {
+				opNode = jexl.NewBinaryOpNode(opSymbol, left, right, opSource)
+			}
 			// Создаём присваивание left = (left op right)
 			source := fmt.Sprintf("%s %s %s", left.SourceText(), op.literal, right.SourceText())
 			left = jexl.NewAssignmentNode(left, opNode, source)
@@ -627,10 +778,32 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 				return nil, err
 			}
 			source := fmt.Sprintf("%s .. %s", left.SourceText(), right.SourceText())
+			// Необязательный шаг: left .. right step n (step - контекстное слово)
+			if stepTok := p.peek(); stepTok.typ == tokenIdent && stepTok.literal == "step" {
+				p.next() // consume 'step'
+				step, err := p.parseExpression(infixPrecedence(tokenRange) + 1)
+				if err != nil {
+					return nil, err
+				}
+				source = fmt.Sprintf("%s step %s", source, step.SourceText())
+				left = jexl.NewRangeStepNode(left, right, step, source)
+				continue
+			}
 			left = jexl.NewRangeNode(left, right, source)
 			continue
 		}
 
+		// Отрицание принадлежности и проверки типа: x !in y, x !instanceof T
+		negated := false
+		if next.typ == tokenBang && p.isNegatedKeyword() {
+			if infixPrecedence(tokenIn) < precedence {
+				break
+			}
+			p.next() // consume '!'
+			next = p.peek()
+			negated = true
+		}
+
 		// Бинарные операции
 		nextPrec := infixPrecedence(next.typ)
 		if nextPrec < 0 || nextPrec < precedence {
@@ -638,11 +811,32 @@ func (p *simpleParser) parseExpression(precedence int) (jexl.Node, error) {
 		}
 
 		op := p.next()
-		right, err := p.parseExpression(nextPrec + 1)
+		symbol := op.literal
+		if negated {
+			symbol = "!" + symbol
+		}
+		var right jexl.Node
+		var err error
+		switch op.typ {
+		case tokenStarStar:
+			// Возведение в степень правоассоциативно: 2 ** 3 ** 2 == 2 ** 9
+			right, err = p.parseExpression(nextPrec)
+		case tokenInstanceof:
+			right, err = p.parseTypeName()
+		case tokenPipeline:
+			right, err = p.parsePipeTarget(nextPrec + 1)
+			if err != nil {
+				return nil, err
+			}
+			left = jexl.NewPipeNode(left, right, fmt.Sprintf("%s |> %s", left.SourceText(), right.SourceText()))
+			continue
+		default:
+			right, err = p.parseExpression(nextPrec + 1)
+		}
 		if err != nil {
 			return nil, err
 		}
-		left = jexl.NewBinaryOpNode(op.literal, left, right, fmt.Sprintf("%s %s %s", left.SourceText(), op.literal, right.SourceText()))
+		left = jexl.NewBinaryOpNode(symbol, left, right, fmt.Sprintf("%s %s %s", left.SourceText(), symbol, right.SourceText()))
 	}
 
 	return left, nil
@@ -663,7 +857,7 @@ func (p *simpleParser) parseArrayLiteral() (jexl.Node, error) {
 			if p.peek().typ == tokenRBracket {
 				break
 			}
-			if err := p.expect(tokenComma); err != nil {
+			if err := p.expectSeparator(tokenRBracket); err != nil {
 				return nil, err
 			}
 			if p.peek().typ == tokenRBracket {
@@ -707,9 +901,28 @@ func (p *simpleParser) parseMapOrSetLiteral() (jexl.Node, error) {
 		return nil, p.errorf("unexpected token after ':' in map literal")
 	}
 
+	// {a:f(x)} разбирается как мапа; если это не удалось, первый элемент -
+	// вызов пространства имён во множестве: {ns:f(x), y}
+	if p.namespaceCallAt(p.peek(), p.pos+1) {
+		savedPos := p.pos
+		p.speculating++
+		node, err := p.parseMapOrSetElements(true)
+		p.speculating--
+		if err == nil {
+			return node, nil
+		}
+		p.pos = savedPos
+		return p.parseMapOrSetElements(false)
+	}
+	return p.parseMapOrSetElements(true)
+}
+
+// parseMapOrSetElements парсит элементы литерала мапы или множества после {.
+// entry определяет разбор ns:func(...) в начале первого элемента (см. parseMapKey).
+func (p *simpleParser) parseMapOrSetElements(entry bool) (jexl.Node, error) {
 	// Пробуем определить, это мапа или множество
 	// Если следующий токен после первого выражения - двоеточие, это мапа
-	firstExpr, err := p.parseExpression(0)
+	firstExpr, err := p.parseMapKey(entry)
 	if err != nil {
 		return nil, err
 	}
@@ -731,7 +944,7 @@ func (p *simpleParser) parseMapOrSetLiteral() (jexl.Node, error) {
 			if p.peek().typ == tokenRBrace {
 				break
 			}
-			key, err := p.parseExpression(0)
+			key, err := p.parseMapKey(true)
 			if err != nil {
 				return nil, err
 			}
@@ -785,6 +998,11 @@ func (p *simpleParser) parseMapOrSetLiteral() (jexl.Node, error) {
 		if i > 0 {
 			source += ", "
 		}
+		if i == 0 && startsWithNamespaceCall(elem.SourceText()) {
+			// Без скобок {ns:f(x)} читается как мапа
+			source += "(" + elem.SourceText() + ")"
+			continue
+		}
 		source += elem.SourceText()
 	}
 	source += "}"
@@ -793,6 +1011,7 @@ func (p *simpleParser) parseMapOrSetLiteral() (jexl.Node, error) {
 }
 
 // parseLambda парсит lambda функцию: (x, y) -> x + y или x -> x + 1 или (x, y) => x + y
+// Параметры в скобках могут иметь значения по умолчанию и ...rest: (a, b = 10, ...rest) -> ...
 // lparenRead указывает, был ли уже прочитан токен ( (true) или нет (false)
 func (p *simpleParser) parseLambda(lparenRead bool) (jexl.Node, error) {
 	// Проверяем, включена ли поддержка lambda
@@ -801,96 +1020,34 @@ func (p *simpleParser) parseLambda(lparenRead bool) (jexl.Node, error) {
 	}
 
 	var parameters []*jexl.IdentifierNode
+	var defaults []jexl.Node
+	var rest bool
 	var sourceStart string
 
-	// Парсим параметры
-	// Проверяем, является ли текущий токен ( или идентификатор
-	if !lparenRead && p.peek().typ == tokenLParen {
-		// Множественные параметры в скобках: (x, y) - ( еще не прочитан
-		p.next() // consume '('
-		sourceStart = "("
-	} else if p.peek().typ == tokenIdent {
-		// Один параметр без скобок: x -> ...
-		// Или параметры в скобках: (x) -> ... или (x, y) -> ...
-		if lparenRead {
-			// ( уже прочитан, начинаем с идентификатора
-			sourceStart = "("
-		} else {
-			// Проверяем, есть ли запятая после идентификатора - если да, то это множественные параметры
-			savedPos := p.pos
-			p.pos++
-			hasComma := p.pos < len(p.tokens) && p.tokens[p.pos].typ == tokenComma
-			p.pos = savedPos
-
-			if hasComma {
-				// Множественные параметры в скобках: (x, y) - но ( еще не прочитан, это ошибка
-				return nil, p.errorf("expected '(' before lambda parameters")
-			} else {
-				// Один параметр без скобок: x -> ...
-				paramTok := p.next()
-				param := jexl.NewIdentifierNode(paramTok.literal, paramTok.literal)
-				parameters = append(parameters, param)
-				sourceStart = paramTok.literal
-
-				// Парсим стрелку и тело
-				var arrow string
-				if p.peek().typ == tokenLambda {
-					p.next() // consume '->'
-					arrow = "->"
-				} else if p.peek().typ == tokenFatArrow {
-					p.next() // consume '=>'
-					arrow = "=>"
-				} else {
-					return nil, p.errorf("expected '->' or '=>' in lambda")
-				}
-
-				var body jexl.Node
-				var err error
-				if p.peek().typ == tokenLBrace {
-					body, err = p.parseBlock()
-				} else {
-					body, err = p.parseExpression(0)
-				}
-				if err != nil {
-					return nil, err
-				}
-
-				source := sourceStart + " " + arrow + " " + body.SourceText()
-				return jexl.NewLambdaNode(parameters, body, source), nil
-			}
-		}
-	} else {
-		return nil, p.errorf("expected lambda parameters")
-	}
-
-	// Парсим множественные параметры в скобках
-	for {
-		if p.peek().typ == tokenRParen {
-			p.next() // consume ')'
-			sourceStart += ")"
-			break
-		}
-		if p.peek().typ != tokenIdent {
-			return nil, p.errorf("expected identifier in lambda parameters")
+	if !lparenRead && p.peek().typ == tokenIdent {
+		// Проверяем, есть ли запятая после идентификатора - если да, то это множественные параметры
+		if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].typ == tokenComma {
+			// Множественные параметры в скобках: (x, y) - но ( еще не прочитан, это ошибка
+			return nil, p.errorf("expected '(' before lambda parameters")
 		}
+		// Один параметр без скобок: x -> ...
 		paramTok := p.next()
-		param := jexl.NewIdentifierNode(paramTok.literal, paramTok.literal)
-		parameters = append(parameters, param)
-		if len(parameters) > 1 {
-			sourceStart += ", "
-		}
-		sourceStart += paramTok.literal
-
-		if p.peek().typ == tokenComma {
-			p.next() // consume ','
-			sourceStart += ","
-			continue
+		parameters = append(parameters, atToken(p, jexl.NewIdentifierNode(paramTok.literal, paramTok.literal), paramTok))
+		sourceStart = paramTok.literal
+	} else {
+		if !lparenRead {
+			if p.peek().typ != tokenLParen {
+				return nil, p.errorf("expected lambda parameters")
+			}
+			p.next() // consume '('
 		}
-		if p.peek().typ == tokenRParen {
-			p.next() // consume ')'
-			sourceStart += ")"
-			break
+		var paramSource string
+		var err error
+		parameters, defaults, rest, paramSource, err = p.parseParameters()
+		if err != nil {
+			return nil, err
 		}
+		sourceStart = "(" + paramSource + ")"
 	}
 
 	// Парсим стрелку: -> или =>
@@ -911,23 +1068,128 @@ func (p *simpleParser) parseLambda(lparenRead bool) (jexl.Node, error) {
 	if p.peek().typ == tokenLBrace {
 		// Блок: { return x + y; }
 		body, err = p.parseBlock()
-		if err != nil {
-			return nil, err
-		}
 	} else {
 		// Выражение: x + y
 		body, err = p.parseExpression(0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source := sourceStart + " " + arrow + " " + body.SourceText()
+	return jexl.NewLambdaNodeWithSignature(parameters, defaults, rest, body, source), nil
+}
+
+// parseParameters парсит список параметров до закрывающей скобки включительно (( уже прочитан).
+// Параметр может иметь значение по умолчанию (b = 10), последний параметр может быть ...rest.
+// Возвращает параметры, значения по умолчанию (nil, если ни одного нет), признак rest и исходный текст.
+func (p *simpleParser) parseParameters() ([]*jexl.IdentifierNode, []jexl.Node, bool, string, error) {
+	var parameters []*jexl.IdentifierNode
+	var defaults []jexl.Node
+	var parts []string
+	hasDefaults := false
+	rest := false
+
+	for p.peek().typ != tokenRParen {
+		if rest {
+			return nil, nil, false, "", p.errorf("rest parameter must be last")
+		}
+		isRest := p.match(tokenEllipsis)
+		paramTok, err := p.expectIdent("in parameters")
 		if err != nil {
-			return nil, err
+			return nil, nil, false, "", err
+		}
+		for _, param := range parameters {
+			if param.Name() == paramTok.literal {
+				return nil, nil, false, "", p.errorf("duplicate parameter: %s", paramTok.literal)
+			}
+		}
+		parameters = append(parameters, atToken(p, jexl.NewIdentifierNode(paramTok.literal, paramTok.literal), paramTok))
+		part := paramTok.literal
+
+		var def jexl.Node
+		if isRest {
+			rest = true
+			part = "..." + part
+		} else if p.match(tokenEqual) {
+			var err error
+			def, err = p.parseExpression(0)
+			if err != nil {
+				return nil, nil, false, "", err
+			}
+			hasDefaults = true
+			part += " = " + def.SourceText()
+		} else if hasDefaults {
+			return nil, nil, false, "", p.errorf("required parameter %s follows parameter with default value", paramTok.literal)
+		}
+		defaults = append(defaults, def)
+		parts = append(parts, part)
+
+		if !p.match(tokenComma) {
+			break
 		}
 	}
+	if err := p.expect(tokenRParen); err != nil {
+		return nil, nil, false, "", err
+	}
+	if !hasDefaults {
+		defaults = nil
+	}
+	return parameters, defaults, rest, strings.Join(parts, ", "), nil
+}
 
-	source := sourceStart + " " + arrow + " " + body.SourceText()
-	return jexl.NewLambdaNode(parameters, body, source), nil
+// parseFunctionDeclaration парсит объявление функции: function name(x, y) { ... }
+func (p *simpleParser) parseFunctionDeclaration() (jexl.Node, error) {
+	p.next() // consume 'function'
+	nameTok := p.next()
+	name := atToken(p, jexl.NewIdentifierNode(nameTok.literal, nameTok.literal), nameTok)
+
+	lambda, err := p.parseFunctionLambda("function " + nameTok.literal)
+	if err != nil {
+		return nil, err
+	}
+	return jexl.NewFunctionNode(name, lambda, lambda.SourceText()), nil
+}
+
+// parseFunctionLambda парсит параметры и тело функции после 'function' (и имени): (x, y) { ... }
+// prefix используется для построения исходного текста
+func (p *simpleParser) parseFunctionLambda(prefix string) (result *jexl.LambdaNode, err error) {
+	start := p.peek().pos
+	defer func() {
+		if result != nil {
+			p.mark(result, start)
+		}
+	}()
+	if p.features != nil && !p.features.SupportsLambda() {
+		return nil, p.errorf("lambda functions are not enabled")
+	}
+	if err := p.expect(tokenLParen); err != nil {
+		return nil, err
+	}
+	parameters, defaults, rest, paramSource, err := p.parseParameters()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().typ != tokenLBrace {
+		return nil, p.errorf("expected '{' before function body")
+	}
+
+	// break/continue внутри тела функции не относятся к внешнему циклу
+	savedLoopCount := p.loopCount
+	p.loopCount = 0
+	body, err := p.parseBlock()
+	p.loopCount = savedLoopCount
+	if err != nil {
+		return nil, err
+	}
+
+	source := fmt.Sprintf("%s(%s) %s", prefix, paramSource, body.SourceText())
+	return jexl.NewLambdaNodeWithSignature(parameters, defaults, rest, body, source), nil
 }
 
 // parseStatement парсит statement (if, for, while, etc.)
-func (p *simpleParser) parseStatement() (jexl.Node, error) {
+func (p *simpleParser) parseStatement() (result jexl.Node, err error) {
+	defer p.markResult(&result, p.peek().pos)
 	next := p.peek()
 	switch next.typ {
 	case tokenIf:
@@ -972,7 +1234,17 @@ func (p *simpleParser) parseStatement() (jexl.Node, error) {
 		return p.parseSwitchStatement()
 	case tokenTry:
 		return p.parseTryStatement()
+	case tokenFunction:
+		// function name(...) - объявление, function(...) - выражение
+		if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].typ == tokenIdent {
+			return p.parseFunctionDeclaration()
+		}
+		return nil, nil
 	case tokenLBrace:
+		// {a, b} = expr - это выражение, а не блок
+		if p.isPatternAssignment(p.pos) {
+			return nil, nil
+		}
 		return p.parseBlock()
 	default:
 		return nil, nil // Не statement, вернём nil
@@ -1051,9 +1323,15 @@ func (p *simpleParser) parseForStatement() (jexl.Node, error) {
 	if peek.typ == tokenVar {
 		// Это foreach: for (var x : items)
 		p.next() // consume 'var'
-		varName := p.next()
-		if varName.typ != tokenIdent {
-			return nil, p.errorf("expected identifier after 'var'")
+		if p.peek().typ == tokenLBracket || p.peek().typ == tokenLBrace {
+			return p.parseForeachPattern()
+		}
+		varName, err := p.expectIdent("after 'var'")
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().typ == tokenComma {
+			return p.parseForeachKeyValue("var ", varName)
 		}
 		if err := p.expect(tokenColon); err != nil {
 			return nil, err
@@ -1079,10 +1357,14 @@ func (p *simpleParser) parseForStatement() (jexl.Node, error) {
 		} else {
 			source += " ;"
 		}
-		return jexl.NewForeachNode(jexl.NewIdentifierNode(varName.literal, varName.literal), items, body, source), nil
+		return jexl.NewForeachNode(atToken(p, jexl.NewIdentifierNode(varName.literal, varName.literal), varName), items, body, source), nil
 	} else if peek.typ == tokenIdent {
-		// Может быть foreach без var: for (x : items)
+		// Может быть foreach без var: for (x : items) или for (k, v : items)
 		varName := p.next()
+		if p.peek().typ == tokenComma && p.pos+2 < len(p.tokens) &&
+			p.tokens[p.pos+1].typ == tokenIdent && p.tokens[p.pos+2].typ == tokenColon {
+			return p.parseForeachKeyValue("", varName)
+		}
 		if p.match(tokenColon) {
 			// Это foreach: for (x : items)
 			items, err := p.parseExpression(0)
@@ -1106,7 +1388,7 @@ func (p *simpleParser) parseForStatement() (jexl.Node, error) {
 			} else {
 				source += " ;"
 			}
-			return jexl.NewForeachNode(jexl.NewIdentifierNode(varName.literal, varName.literal), items, body, source), nil
+			return jexl.NewForeachNode(atToken(p, jexl.NewIdentifierNode(varName.literal, varName.literal), varName), items, body, source), nil
 		}
 		// Не foreach, возвращаемся назад
 		p.pos--
@@ -1276,14 +1558,29 @@ func (p *simpleParser) parseReturnStatement() (jexl.Node, error) {
 // parseVarStatement парсит var statement (var x или var x = value)
 func (p *simpleParser) parseVarStatement() (jexl.Node, error) {
 	p.next() // consume 'var'
-	nameTok := p.next()
-	if nameTok.typ != tokenIdent {
-		return nil, p.errorf("expected identifier after 'var'")
+	if p.peek().typ == tokenLBracket || p.peek().typ == tokenLBrace {
+		// Деструктурирующее объявление: var [a, b] = expr или var {a, b} = expr
+		pattern, err := p.parsePattern()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenEqual); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpression(0)
+		if err != nil {
+			return nil, err
+		}
+		source := fmt.Sprintf("var %s = %s", pattern.SourceText(), value.SourceText())
+		return jexl.NewDestructuringNode(pattern, value, true, source), nil
+	}
+	nameTok, err := p.expectIdent("after 'var'")
+	if err != nil {
+		return nil, err
 	}
-	name := jexl.NewIdentifierNode(nameTok.literal, nameTok.literal)
+	name := atToken(p, jexl.NewIdentifierNode(nameTok.literal, nameTok.literal), nameTok)
 
 	var value jexl.Node
-	var err error
 	source := "var " + nameTok.literal
 
 	// Проверяем, есть ли присваивание
@@ -1299,30 +1596,301 @@ func (p *simpleParser) parseVarStatement() (jexl.Node, error) {
 	return jexl.NewVarNode(name, value, source), nil
 }
 
-// parseSwitchStatement парсит switch statement или expression.
-// switch (expr) { case 1: ... case 2: ... default: ... }
-func (p *simpleParser) parseSwitchStatement() (jexl.Node, error) {
-	p.next() // consume 'switch'
-
-	if err := p.expect(tokenLParen); err != nil {
+// parseForeachKeyValue парсит foreach с ключом и значением: for (var k, v : items)
+// (переменная ключа уже прочитана, следующий токен - запятая)
+func (p *simpleParser) parseForeachKeyValue(prefix string, keyTok token) (jexl.Node, error) {
+	p.next() // consume ','
+	valueTok, err := p.expectIdent("for foreach value")
+	if err != nil {
 		return nil, err
 	}
-
-	expression, err := p.parseExpression(0)
+	if keyTok.literal == valueTok.literal {
+		return nil, p.errorf("foreach key and value must have different names")
+	}
+	if err := p.expect(tokenColon); err != nil {
+		return nil, err
+	}
+	items, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenRParen); err != nil {
+		return nil, err
+	}
+	body, err := p.parseStatementOrBlock()
 	if err != nil {
 		return nil, err
 	}
+	source := fmt.Sprintf("for (%s%s, %s : %s)", prefix, keyTok.literal, valueTok.literal, items.SourceText())
+	if body != nil {
+		source += " " + body.SourceText()
+	} else {
+		source += " ;"
+	}
+	key := atToken(p, jexl.NewIdentifierNode(keyTok.literal, keyTok.literal), keyTok)
+	value := atToken(p, jexl.NewIdentifierNode(valueTok.literal, valueTok.literal), valueTok)
+	return jexl.NewForeachKeyValueNode(key, value, items, body, source), nil
+}
 
+// parseForeachPattern парсит foreach с деструктуризацией: for (var [k, v] : items) (var уже прочитан)
+func (p *simpleParser) parseForeachPattern() (jexl.Node, error) {
+	pattern, err := p.parsePattern()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(tokenColon); err != nil {
+		return nil, err
+	}
+	items, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
 	if err := p.expect(tokenRParen); err != nil {
 		return nil, err
 	}
+	body, err := p.parseStatementOrBlock()
+	if err != nil {
+		return nil, err
+	}
+	source := fmt.Sprintf("for (var %s : %s)", pattern.SourceText(), items.SourceText())
+	if body != nil {
+		source += " " + body.SourceText()
+	} else {
+		source += " ;"
+	}
+	return jexl.NewForeachNode(pattern, items, body, source), nil
+}
 
-	if err := p.expect(tokenLBrace); err != nil {
+// parseDestructuringAssignment парсит присваивание с деструктуризацией: [a, b] = expr
+func (p *simpleParser) parseDestructuringAssignment() (jexl.Node, error) {
+	pattern, err := p.parsePattern()
+	if err != nil {
 		return nil, err
 	}
+	if err := p.expect(tokenEqual); err != nil {
+		return nil, err
+	}
+	value, err := p.parseExpression(infixPrecedence(tokenEqual))
+	if err != nil {
+		return nil, err
+	}
+	source := fmt.Sprintf("%s = %s", pattern.SourceText(), value.SourceText())
+	return jexl.NewDestructuringNode(pattern, value, false, source), nil
+}
 
-	var cases []*jexl.CaseNode
-	var isStatement bool = true
+// isPatternAssignment проверяет, что скобка [ или { на позиции start закрывается и за ней идёт =
+func (p *simpleParser) isPatternAssignment(start int) bool {
+	depth := 0
+	for pos := start; pos < len(p.tokens); pos++ {
+		switch p.tokens[pos].typ {
+		case tokenLBracket, tokenLBrace, tokenLParen:
+			depth++
+		case tokenRBracket, tokenRBrace, tokenRParen:
+			depth--
+			if depth == 0 {
+				return pos+1 < len(p.tokens) && p.tokens[pos+1].typ == tokenEqual
+			}
+		case tokenEOF:
+			return false
+		}
+	}
+	return false
+}
+
+// parsePattern парсит шаблон деструктуризации: [a, b = 1, ...rest] или {name, age: years, ...others}
+func (p *simpleParser) parsePattern() (jexl.Node, error) {
+	if p.peek().typ == tokenLBracket {
+		return p.parseArrayPattern()
+	}
+	if p.peek().typ == tokenLBrace {
+		return p.parseMapPattern()
+	}
+	return nil, p.errorf("expected destructuring pattern, got %v", p.peek().typ)
+}
+
+// parsePatternTarget парсит цель элемента шаблона: идентификатор или вложенный шаблон
+func (p *simpleParser) parsePatternTarget() (jexl.Node, error) {
+	if p.peek().typ == tokenIdent {
+		tok := p.next()
+		return atToken(p, jexl.NewIdentifierNode(tok.literal, tok.literal), tok), nil
+	}
+	if p.peek().typ == tokenLBracket || p.peek().typ == tokenLBrace {
+		return p.parsePattern()
+	}
+	return nil, p.errorf("expected identifier or pattern in destructuring, got %v", p.peek().typ)
+}
+
+// parsePatternRest парсит ...rest в конце шаблона (... уже прочитан)
+func (p *simpleParser) parsePatternRest(closing tokenType) (*jexl.IdentifierNode, error) {
+	tok, err := p.expectIdent("after '...'")
+	if err != nil {
+		return nil, err
+	}
+	p.match(tokenComma)
+	if p.peek().typ != closing {
+		return nil, p.errorf("rest element must be last")
+	}
+	return atToken(p, jexl.NewIdentifierNode(tok.literal, tok.literal), tok), nil
+}
+
+// parsePatternDefault парсит необязательное значение по умолчанию: = expr
+func (p *simpleParser) parsePatternDefault() (jexl.Node, string, error) {
+	if !p.match(tokenEqual) {
+		return nil, "", nil
+	}
+	def, err := p.parseExpression(0)
+	if err != nil {
+		return nil, "", err
+	}
+	return def, " = " + def.SourceText(), nil
+}
+
+// parseArrayPattern парсит [a, , b = 1, [c, d], ...rest]
+func (p *simpleParser) parseArrayPattern() (result jexl.Node, err error) {
+	defer p.markResult(&result, p.peek().pos)
+	p.next() // consume '['
+
+	var elements, defaults []jexl.Node
+	var rest *jexl.IdentifierNode
+	var parts []string
+	for p.peek().typ != tokenRBracket {
+		if p.match(tokenComma) {
+			// Пропущенный элемент: [a, , c]
+			elements = append(elements, nil)
+			defaults = append(defaults, nil)
+			parts = append(parts, "")
+			continue
+		}
+		if p.match(tokenEllipsis) {
+			var err error
+			rest, err = p.parsePatternRest(tokenRBracket)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, "..."+rest.Name())
+			break
+		}
+		target, err := p.parsePatternTarget()
+		if err != nil {
+			return nil, err
+		}
+		def, defSource, err := p.parsePatternDefault()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, target)
+		defaults = append(defaults, def)
+		parts = append(parts, target.SourceText()+defSource)
+		if !p.match(tokenComma) {
+			break
+		}
+	}
+	if err := p.expect(tokenRBracket); err != nil {
+		return nil, err
+	}
+	if len(elements) == 0 && rest == nil {
+		return nil, p.errorf("empty destructuring pattern")
+	}
+	source := "[" + strings.Join(parts, ", ") + "]"
+	return jexl.NewArrayPatternNode(elements, defaults, rest, source), nil
+}
+
+// parseMapPattern парсит {name, age: years = 0, 'full name': full, address: {city}, ...others}
+func (p *simpleParser) parseMapPattern() (result jexl.Node, err error) {
+	defer p.markResult(&result, p.peek().pos)
+	p.next() // consume '{'
+
+	var keys []string
+	var targets, defaults []jexl.Node
+	var rest *jexl.IdentifierNode
+	var parts []string
+	for p.peek().typ != tokenRBrace {
+		if p.match(tokenEllipsis) {
+			var err error
+			rest, err = p.parsePatternRest(tokenRBrace)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, "..."+rest.Name())
+			break
+		}
+
+		keyTok := p.next()
+		var key string
+		switch keyTok.typ {
+		case tokenIdent:
+			key = keyTok.literal
+		case tokenString:
+			val, err := parseStringLiteral(keyTok.literal)
+			if err != nil {
+				return nil, err
+			}
+			key = val
+		default:
+			return nil, p.errorf("expected property name in destructuring, got %v", keyTok.typ)
+		}
+
+		var target jexl.Node
+		part := keyTok.literal
+		if p.match(tokenColon) {
+			var err error
+			target, err = p.parsePatternTarget()
+			if err != nil {
+				return nil, err
+			}
+			part += ": " + target.SourceText()
+		} else if keyTok.typ == tokenIdent {
+			target = atToken(p, jexl.NewIdentifierNode(key, key), keyTok)
+		} else {
+			return nil, p.errorf("expected ':' after quoted property name %s", keyTok.literal)
+		}
+
+		def, defSource, err := p.parsePatternDefault()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		targets = append(targets, target)
+		defaults = append(defaults, def)
+		parts = append(parts, part+defSource)
+		if !p.match(tokenComma) {
+			break
+		}
+	}
+	if err := p.expect(tokenRBrace); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 && rest == nil {
+		return nil, p.errorf("empty destructuring pattern")
+	}
+	source := "{" + strings.Join(parts, ", ") + "}"
+	return jexl.NewMapPatternNode(keys, targets, defaults, rest, source), nil
+}
+
+// parseSwitchStatement парсит switch statement или expression.
+// switch (expr) { case 1: ... case 2: ... default: ... }
+func (p *simpleParser) parseSwitchStatement() (jexl.Node, error) {
+	p.next() // consume 'switch'
+
+	if err := p.expect(tokenLParen); err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseExpression(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(tokenRParen); err != nil {
+		return nil, err
+	}
+
+	if err := p.expect(tokenLBrace); err != nil {
+		return nil, err
+	}
+
+	var cases []*jexl.CaseNode
+	var isStatement bool = true
 	caseMap := make(map[any]bool) // Для проверки дубликатов
 	hasDefault := false
 
@@ -1335,7 +1903,9 @@ func (p *simpleParser) parseSwitchStatement() (jexl.Node, error) {
 		savedPos := p.pos
 		p.next() // consume 'case'
 		// Парсим значение case
+		p.speculating++
 		_, err := p.parseExpression(0)
+		p.speculating--
 		if err == nil {
 			// Проверяем, что идет после значения
 			if p.peek().typ == tokenLambda || p.peek().typ == tokenFatArrow {
@@ -1384,7 +1954,13 @@ func (p *simpleParser) parseSwitchStatement() (jexl.Node, error) {
 }
 
 // parseCaseNode парсит case узел.
-func (p *simpleParser) parseCaseNode(isStatement bool, caseMap map[any]bool, hasDefault *bool) (*jexl.CaseNode, error) {
+func (p *simpleParser) parseCaseNode(isStatement bool, caseMap map[any]bool, hasDefault *bool) (result *jexl.CaseNode, err error) {
+	start := p.peek().pos
+	defer func() {
+		if result != nil {
+			p.mark(result, start)
+		}
+	}()
 	p.next() // consume 'case'
 
 	var values []any
@@ -1425,7 +2001,6 @@ func (p *simpleParser) parseCaseNode(isStatement bool, caseMap map[any]bool, has
 	}
 
 	var body jexl.Node
-	var err error
 
 	if isStatement {
 		// Для statement ожидаем ':'
@@ -1458,7 +2033,13 @@ func (p *simpleParser) parseCaseNode(isStatement bool, caseMap map[any]bool, has
 }
 
 // parseDefaultNode парсит default узел.
-func (p *simpleParser) parseDefaultNode(isStatement bool, hasDefault *bool) (*jexl.CaseNode, error) {
+func (p *simpleParser) parseDefaultNode(isStatement bool, hasDefault *bool) (result *jexl.CaseNode, err error) {
+	start := p.peek().pos
+	defer func() {
+		if result != nil {
+			p.mark(result, start)
+		}
+	}()
 	if *hasDefault {
 		return nil, p.errorf("duplicate default case")
 	}
@@ -1467,7 +2048,6 @@ func (p *simpleParser) parseDefaultNode(isStatement bool, hasDefault *bool) (*je
 	p.next() // consume 'default'
 
 	var body jexl.Node
-	var err error
 	sourceStart := "default"
 
 	if isStatement {
@@ -1502,10 +2082,29 @@ func (p *simpleParser) parseDefaultNode(isStatement bool, hasDefault *bool) (*je
 
 // parseTryStatement парсит try/catch/finally statement.
 // try { ... } catch (e) { ... } finally { ... }
+// try (var r = expr; ...) { ... } catch (e) { ... } finally { ... }
 func (p *simpleParser) parseTryStatement() (jexl.Node, error) {
 	sourceStart := "try"
 	p.next() // consume 'try'
 
+	// Парсим ресурсы: try (var a = expr; var b = expr)
+	var resources []jexl.Node
+	if p.peek().typ == tokenLParen {
+		var err error
+		resources, err = p.parseTryResources()
+		if err != nil {
+			return nil, err
+		}
+		sourceStart += " ("
+		for i, resource := range resources {
+			if i > 0 {
+				sourceStart += "; "
+			}
+			sourceStart += resource.SourceText()
+		}
+		sourceStart += ")"
+	}
+
 	// Парсим try блок
 	tryBlock, err := p.parseBlock()
 	if err != nil {
@@ -1568,11 +2167,56 @@ func (p *simpleParser) parseTryStatement() (jexl.Node, error) {
 		source += " finally " + finallyBlock.SourceText()
 	}
 
+	if len(resources) > 0 {
+		return jexl.NewTryResourcesNode(resources, tryBlock, catchVar, catchBlock, finallyBlock, source), nil
+	}
 	return jexl.NewTryNode(tryBlock, catchVar, catchBlock, finallyBlock, source), nil
 }
 
+// parseTryResources парсит список ресурсов try: (var a = expr; var b = expr; c).
+// Ресурс - это объявление переменной с инициализатором или ссылка на существующую переменную.
+func (p *simpleParser) parseTryResources() ([]jexl.Node, error) {
+	p.next() // consume '('
+
+	var resources []jexl.Node
+	for p.peek().typ != tokenRParen {
+		var resource jexl.Node
+		switch p.peek().typ {
+		case tokenVar:
+			varNode, err := p.parseVarStatement()
+			if err != nil {
+				return nil, err
+			}
+			if v, ok := varNode.(*jexl.VarNode); !ok || v.Value() == nil {
+				return nil, p.errorf("try resource must be initialized")
+			}
+			resource = varNode
+		case tokenIdent:
+			tok := p.next()
+			resource = atToken(p, jexl.NewIdentifierNode(tok.literal, tok.literal), tok)
+		default:
+			return nil, p.errorf("expected resource declaration in try, got %v", p.peek().typ)
+		}
+		resources = append(resources, resource)
+
+		// Ресурсы разделяются точкой с запятой, завершающая точка с запятой допустима
+		if !p.match(tokenSemicolon) {
+			break
+		}
+	}
+
+	if err := p.expect(tokenRParen); err != nil {
+		return nil, err
+	}
+	if len(resources) == 0 {
+		return nil, p.errorf("try resources must not be empty")
+	}
+	return resources, nil
+}
+
 // parseBlock парсит блок { statements }
-func (p *simpleParser) parseBlock() (jexl.Node, error) {
+func (p *simpleParser) parseBlock() (result jexl.Node, err error) {
+	defer p.markResult(&result, p.peek().pos)
 	p.next() // consume '{'
 
 	// Проверяем, не является ли это пустым блоком
@@ -1588,14 +2232,21 @@ func (p *simpleParser) parseBlock() (jexl.Node, error) {
 	var statements []jexl.Node
 
 	for p.peek().typ != tokenRBrace && p.peek().typ != tokenEOF {
+		start := p.pos
 		stmt, err := p.parseStatement()
 		if err != nil {
+			if p.recover(err, start, true) {
+				continue
+			}
 			return nil, err
 		}
 		if stmt == nil {
 			// Пробуем expression
 			expr, err := p.parseExpression(0)
 			if err != nil {
+				if p.recover(err, start, true) {
+					continue
+				}
 				return nil, err
 			}
 			statements = append(statements, expr)
@@ -1610,7 +2261,7 @@ func (p *simpleParser) parseBlock() (jexl.Node, error) {
 		}
 	}
 
-	if err := p.expect(tokenRBrace); err != nil {
+	if err := p.expect(tokenRBrace); err != nil && !p.recover(err, p.pos, true) {
 		return nil, err
 	}
 
@@ -1672,7 +2323,7 @@ func (p *simpleParser) parseCall(target jexl.Node) (jexl.Node, error) {
 			if p.peek().typ == tokenRParen {
 				break
 			}
-			if err := p.expect(tokenComma); err != nil {
+			if err := p.expectSeparator(tokenRParen); err != nil {
 				return nil, err
 			}
 		}
@@ -1736,6 +2387,135 @@ func (p *simpleParser) parseCall(target jexl.Node) (jexl.Node, error) {
 	}
 }
 
+// isNegatedKeyword проверяет, следует ли за '!' вплотную in или instanceof,
+// и помечает найденный идентификатор как оператор.
+func (p *simpleParser) isNegatedKeyword() bool {
+	if p.pos+1 >= len(p.tokens) {
+		return false
+	}
+	bang, keyword := p.tokens[p.pos], p.tokens[p.pos+1]
+	tt, ok := contextualOperators[keyword.literal]
+	if !ok || tt == tokenDiv || (keyword.typ != tokenIdent && keyword.typ != tt) || keyword.pos != bang.pos+1 {
+		return false
+	}
+	p.tokens[p.pos+1].typ = tt
+	return true
+}
+
+// parseTypeName разбирает правый операнд instanceof: имя типа (возможно, составное через точку)
+// становится строковым литералом, любое другое выражение вычисляется.
+func (p *simpleParser) parseTypeName() (jexl.Node, error) {
+	if p.peek().typ == tokenFunction {
+		return atToken(p, jexl.NewLiteralNode("function", "function"), p.next()), nil
+	}
+	if p.peek().typ != tokenIdent {
+		return p.parseExpression(infixPrecedence(tokenInstanceof) + 1)
+	}
+	first := p.next()
+	name := first.literal
+	for p.peek().typ == tokenDot && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].typ == tokenIdent {
+		p.next() // consume '.'
+		name += "." + p.next().literal
+	}
+	node := jexl.NewLiteralNode(name, name)
+	p.mark(node, first.pos)
+	return node, nil
+}
+
+// parsePipeTarget разбирает правую часть оператора |>. Функция пространства имён
+// без скобок (str:trim) и встроенные size и empty становятся вызовом без аргументов.
+func (p *simpleParser) parsePipeTarget(precedence int) (jexl.Node, error) {
+	if p.pos+2 < len(p.tokens) {
+		ns, colon, fn := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+		if ns.typ == tokenIdent && colon.typ == tokenColon && fn.typ == tokenIdent &&
+			colon.pos == ns.pos+len(ns.literal) && fn.pos == colon.pos+1 &&
+			(p.pos+3 >= len(p.tokens) || p.tokens[p.pos+3].typ != tokenLParen) {
+			p.pos += 3
+			source := ns.literal + ":" + fn.literal
+			node := jexl.NewNamespaceCallNode(ns.literal, atToken(p, jexl.NewIdentifierNode(fn.literal, fn.literal), fn), nil, source)
+			p.mark(node, ns.pos)
+			return node, nil
+		}
+	}
+	// size и empty - ключевые слова, а не операнды: без скобок это вызов
+	// встроенной функции без аргументов (list |> size), со скобками - обычный
+	// вызов (list |> empty())
+	if tok := p.peek(); tok.typ == tokenSize || tok.typ == tokenEmpty {
+		if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].typ == tokenLParen {
+			p.tokens[p.pos].typ = tokenIdent
+			return p.parseExpression(precedence)
+		}
+		p.next()
+		node := jexl.NewMethodCallNode(nil, atToken(p, jexl.NewIdentifierNode(tok.literal, tok.literal), tok), nil, tok.literal)
+		p.mark(node, tok.pos)
+		return node, nil
+	}
+	return p.parseExpression(precedence)
+}
+
+// isNamespaceCall проверяет, начинается ли с tok вызов функции пространства имён ns:func(...).
+// Имя, двоеточие и функция должны быть записаны слитно, иначе это тернарный оператор
+// или элемент мапы: a ? b : f(x), {a : f(x)}.
+func (p *simpleParser) isNamespaceCall(tok token) bool {
+	return p.namespaceCallAt(tok, p.pos)
+}
+
+// namespaceCallAt проверяет, что за tok с индекса j следуют :func( - вызов ns:func(...).
+func (p *simpleParser) namespaceCallAt(tok token, j int) bool {
+	if tok.typ != tokenIdent || j+2 >= len(p.tokens) {
+		return false
+	}
+	colon, name, lparen := p.tokens[j], p.tokens[j+1], p.tokens[j+2]
+	return colon.typ == tokenColon && name.typ == tokenIdent && lparen.typ == tokenLParen &&
+		colon.pos == tok.pos+len(tok.literal) && name.pos == colon.pos+1
+}
+
+// isMapEntry проверяет, что ns:func(...) в начале ключа мапы - это элемент
+// {ns: func(...)}. Вызовом пространства имён запись считается, только если
+// за ней следует двоеточие: {ns:func(x): value}.
+func (p *simpleParser) isMapEntry() bool {
+	if p.mapKey != p.pos-1 {
+		return false
+	}
+	depth := 0
+	for j := p.pos + 2; j < len(p.tokens); j++ {
+		switch p.tokens[j].typ {
+		case tokenLParen:
+			depth++
+		case tokenRParen:
+			depth--
+			if depth == 0 {
+				return j+1 >= len(p.tokens) || p.tokens[j+1].typ != tokenColon
+			}
+		}
+	}
+	return true
+}
+
+// parseMapKey разбирает ключ мапы или первый элемент литерала {...}.
+// При entry = false запись ns:func(...) в начале ключа всегда считается вызовом.
+func (p *simpleParser) parseMapKey(entry bool) (jexl.Node, error) {
+	if !entry {
+		return p.parseExpression(0)
+	}
+	saved := p.mapKey
+	p.mapKey = p.pos
+	defer func() { p.mapKey = saved }()
+	return p.parseExpression(0)
+}
+
+// parseNamespaceCall разбирает вызов ns:func(args); токен пространства имён уже прочитан.
+func (p *simpleParser) parseNamespaceCall(ns token) (jexl.Node, error) {
+	p.next() // consume ':'
+	name := p.next()
+	node, err := p.parseCall(atToken(p, jexl.NewIdentifierNode(name.literal, name.literal), name))
+	if err != nil {
+		return nil, err
+	}
+	call := node.(*jexl.MethodCallNode)
+	return jexl.NewNamespaceCallNode(ns.literal, call.Method(), call.Args(), ns.literal+":"+call.SourceText()), nil
+}
+
 func (p *simpleParser) match(tt tokenType) bool {
 	if p.peek().typ == tt {
 		p.next()
@@ -1746,7 +2526,7 @@ func (p *simpleParser) match(tt tokenType) bool {
 
 func (p *simpleParser) next() token {
 	if p.pos >= len(p.tokens) {
-		return token{typ: tokenEOF, literal: ""}
+		return p.eof
 	}
 	tok := p.tokens[p.pos]
 	p.pos++
@@ -1755,28 +2535,311 @@ func (p *simpleParser) next() token {
 
 func (p *simpleParser) peek() token {
 	if p.pos >= len(p.tokens) {
-		return token{typ: tokenEOF, literal: ""}
+		return p.eof
 	}
 	return p.tokens[p.pos]
 }
 
 func (p *simpleParser) expect(tt tokenType) error {
-	if p.peek().typ != tt {
-		return p.errorf("expected %v, got %v", tt, p.peek().typ)
+	if tok := p.peek(); tok.typ != tt {
+		var fix *jexl.SuggestedFix
+		if tt.isDelimiter() {
+			fix = p.insertFix(tokenTexts[tt])
+		}
+		return p.errorAt(tok, []string{tt.String()}, fix, "expected %v, found %s", tt, p.describe(tok))
 	}
 	p.next()
 	return nil
 }
 
+// expectIdent читает идентификатор; where уточняет место в сообщении об ошибке.
+func (p *simpleParser) expectIdent(where string) (token, error) {
+	if tok := p.peek(); tok.typ != tokenIdent {
+		return tok, p.errorAt(tok, []string{tokenIdent.String()}, nil, "expected identifier %s, found %s", where, p.describe(tok))
+	}
+	return p.next(), nil
+}
+
+// expectSeparator читает запятую между элементами списка, закрываемого токеном closing.
+func (p *simpleParser) expectSeparator(closing tokenType) error {
+	if tok := p.peek(); tok.typ != tokenComma {
+		return p.errorAt(tok, []string{tokenComma.String(), closing.String()}, p.insertFix(tokenTexts[closing]),
+			"expected %v or %v, found %s", tokenComma, closing, p.describe(tok))
+	}
+	p.next()
+	return nil
+}
+
+// compileRegex компилирует литерал регулярного выражения во время разбора.
+// Поддерживаются флаги Go: i, m, s и U.
+func (p *simpleParser) compileRegex(tok token) (*regexp.Regexp, error) {
+	lit := tok.value.(regexLiteral)
+	for _, flag := range lit.flags {
+		if !strings.ContainsRune("imsU", flag) {
+			return nil, p.errorf("invalid regex flag '%c' in %s", flag, tok.literal)
+		}
+	}
+	pattern := lit.pattern
+	if lit.flags != "" {
+		pattern = "(?" + lit.flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, p.errorf("invalid regex literal %s: %v", tok.literal, err)
+	}
+	return re, nil
+}
+
+// mark задаёт положение узла от смещения start до конца последнего прочитанного
+// токена. Положение, заданное раньше (вложенным правилом), не меняется.
+func (p *simpleParser) mark(node jexl.Node, start int) {
+	if node == nil || node.Position().IsValid() {
+		return
+	}
+	end := start
+	if p.pos > 0 && p.pos <= len(p.tokens) {
+		end = max(start, p.tokens[p.pos-1].end)
+	}
+	setPosition(node, p.lines.position(start, end))
+}
+
+// markResult задаёт положение результата правила; вызывается через defer.
+func (p *simpleParser) markResult(result *jexl.Node, start int) {
+	p.mark(*result, start)
+}
+
+// setPosition устанавливает положение узла, созданного конструктором jexl.
+func setPosition(node jexl.Node, pos jexl.Position) {
+	if positioned, ok := node.(interface{ SetPosition(jexl.Position) }); ok {
+		positioned.SetPosition(pos)
+	}
+}
+
+// finishPositions задаёт положение скрипта (исходный текст без пробелов по краям)
+// и положения всех узлов, оставшихся без него.
+func (p *simpleParser) finishPositions(ast *jexl.ScriptNode) {
+	start := len(p.source) - len(strings.TrimLeftFunc(p.source, unicode.IsSpace))
+	end := max(start, len(strings.TrimRightFunc(p.source, unicode.IsSpace)))
+	ast.SetPosition(p.lines.position(start, end))
+	p.fillPositions(ast)
+}
+
+// fillPositions задаёт положения узлам, построенным парсером без токена
+// (узлы раскрытия x++ и x += y): такой узел получает положение родителя.
+// Имена и литералы получают положение своего токена при создании (atToken).
+func (p *simpleParser) fillPositions(node jexl.Node) {
+	for _, child := range node.Children() {
+		if child == nil {
+			continue
+		}
+		if !child.Position().IsValid() {
+			setPosition(child, node.Position())
+		}
+		p.fillPositions(child)
+	}
+}
+
+// atToken задаёт узлу положение токена, из которого он построен.
+func atToken[T jexl.Node](p *simpleParser, node T, tok token) T {
+	setPosition(node, p.lines.position(tok.pos, tok.end))
+	return node
+}
+
+// startsWithNamespaceCall проверяет, начинается ли текст с вызова ns:func(.
+func startsWithNamespaceCall(text string) bool {
+	ns, rest, ok := strings.Cut(text, ":")
+	if !ok || ns == "" || strings.IndexFunc(ns, func(r rune) bool { return !isNameRune(r) }) >= 0 {
+		return false
+	}
+	name, _, ok := strings.Cut(rest, "(")
+	return ok && name != "" && strings.IndexFunc(name, func(r rune) bool { return !isNameRune(r) }) < 0
+}
+
+// isNameRune проверяет, может ли символ входить в имя.
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
 func (p *simpleParser) errorf(format string, args ...any) error {
-	return fmt.Errorf(format, args...)
+	return p.errorAt(p.peek(), nil, nil, format, args...)
+}
+
+// syntaxFailure - подробности синтаксической ошибки для диагностики.
+type syntaxFailure struct {
+	err      *jexl.ParsingError
+	tok      token
+	expected []string
+	fix      *jexl.SuggestedFix
+}
+
+// errorAt создаёт ошибку разбора с положением токена tok, ожидаемыми токенами
+// и предлагаемым исправлением.
+func (p *simpleParser) errorAt(tok token, expected []string, fix *jexl.SuggestedFix, format string, args ...any) error {
+	name := ""
+	if p.info != nil {
+		name = p.info.Name()
+	}
+	info := jexl.PositionInfo(name, p.lines.position(tok.pos, tok.end), p.source[tok.pos:tok.end])
+	err := jexl.NewParsingError(fmt.Sprintf(format, args...), p.source, info)
+	p.failure = syntaxFailure{err: err, tok: tok, expected: expected, fix: fix}
+	return err
+}
+
+// missingSemicolon создаёт ошибку об отсутствующей точке с запятой перед текущим токеном.
+func (p *simpleParser) missingSemicolon(between string) error {
+	tok := p.peek()
+	return p.errorAt(tok, []string{tokenSemicolon.String()}, p.insertFix(";"),
+		"expected ';' between %s, found %s", between, p.describe(tok))
+}
+
+// describe описывает токен для сообщений об ошибках.
+func (p *simpleParser) describe(tok token) string {
+	text := p.source[tok.pos:tok.end]
+	switch tok.typ {
+	case tokenEOF:
+		return tokenEOF.String()
+	case tokenIdent:
+		return fmt.Sprintf("identifier '%s'", text)
+	case tokenNumber, tokenString:
+		return fmt.Sprintf("%v %s", tok.typ, text)
+	default:
+		return fmt.Sprintf("'%s'", text)
+	}
+}
+
+// insertFix предлагает вставить text после последнего прочитанного токена.
+func (p *simpleParser) insertFix(text string) *jexl.SuggestedFix {
+	offset := 0
+	if last := min(p.pos, len(p.tokens)) - 1; last >= 0 {
+		offset = p.tokens[last].end
+	}
+	return &jexl.SuggestedFix{
+		Message: fmt.Sprintf("insert '%s'", text),
+		Range:   p.lines.position(offset, offset),
+		NewText: text,
+	}
+}
+
+// removeFix предлагает удалить токен.
+func (p *simpleParser) removeFix(tok token) *jexl.SuggestedFix {
+	return &jexl.SuggestedFix{
+		Message: fmt.Sprintf("remove '%s'", p.source[tok.pos:tok.end]),
+		Range:   p.lines.position(tok.pos, tok.end),
+	}
+}
+
+// report записывает ошибку в диагностику. Возвращает false вне режима диагностики
+// и при пробном разборе: тогда ошибку нужно вернуть вызывающему.
+func (p *simpleParser) report(err error) bool {
+	if !p.recovering || p.speculating > 0 {
+		return false
+	}
+	diagnostic := jexl.Diagnostic{Severity: jexl.SeverityError, Message: err.Error()}
+	var parsing *jexl.ParsingError
+	if errors.As(err, &parsing) && parsing == p.failure.err {
+		tok := p.failure.tok
+		diagnostic.Range = p.lines.position(tok.pos, tok.end)
+		diagnostic.Expected = p.failure.expected
+		diagnostic.Found = p.describe(tok)
+		diagnostic.Fix = p.failure.fix
+	} else {
+		tok := p.peek()
+		diagnostic.Range = p.lines.position(tok.pos, tok.end)
+	}
+	// Разбор после отката может повторно найти ту же ошибку
+	for _, existing := range p.diagnostics {
+		if existing.Range.Offset == diagnostic.Range.Offset && existing.Message == diagnostic.Message {
+			return true
+		}
+	}
+	p.diagnostics = append(p.diagnostics, diagnostic)
+	return true
+}
+
+// recover записывает ошибку в диагностику и пропускает токены инструкции, начатой
+// с токена start, до её границы: точки с запятой (она пропускается), закрытия блока
+// верхнего уровня, начала следующей инструкции или, внутри блока (inBlock), закрывающей
+// его скобки. Возвращает false, если ошибку нужно вернуть вызывающему (см. report).
+func (p *simpleParser) recover(err error, start int, inBlock bool) bool {
+	if !p.report(err) {
+		return false
+	}
+	p.pos = max(p.pos, start)
+	var parsing *jexl.ParsingError
+	if errors.As(err, &parsing) && parsing == p.failure.err {
+		// Ошибка могла быть найдена до отката: продолжаем с её токена
+		index, _ := slices.BinarySearchFunc(p.tokens, p.failure.tok.pos, func(tok token, offset int) int {
+			return tok.pos - offset
+		})
+		p.pos = max(index, start)
+	}
+	depth := 0
+	for ; p.pos < len(p.tokens); p.pos++ {
+		tok := p.tokens[p.pos]
+		switch {
+		case tok.typ == tokenSemicolon && depth == 0:
+			p.pos++
+			return true
+		case tok.typ == tokenLParen || tok.typ == tokenLBracket || tok.typ == tokenLBrace:
+			depth++
+		case tok.typ.isClosing() && depth > 0:
+			depth--
+			if depth == 0 && tok.typ == tokenRBrace {
+				p.pos++
+				return true
+			}
+		case tok.typ == tokenRBrace && inBlock:
+			return true
+		case depth == 0 && p.pos > start && tok.typ.startsStatement():
+			return true
+		}
+	}
+	return true
+}
+
+// lineIndex вычисляет строки и столбцы по смещениям в исходном тексте.
+type lineIndex struct {
+	source string
+	starts []int // смещения начала строк
+}
+
+func newLineIndex(source string) *lineIndex {
+	starts := []int{0}
+	for k := 0; k < len(source); k++ {
+		if source[k] == '\n' {
+			starts = append(starts, k+1)
+		}
+	}
+	return &lineIndex{source: source, starts: starts}
+}
+
+// lineColumn возвращает строку и столбец (в символах) смещения, нумерация с 1.
+func (x *lineIndex) lineColumn(offset int) (int, int) {
+	offset = min(max(offset, 0), len(x.source))
+	line, _ := slices.BinarySearch(x.starts, offset+1)
+	return line, utf8.RuneCountInString(x.source[x.starts[line-1]:offset]) + 1
+}
+
+// position возвращает положение диапазона [start, end).
+func (x *lineIndex) position(start, end int) jexl.Position {
+	line, column := x.lineColumn(start)
+	endLine, endColumn := x.lineColumn(end)
+	return jexl.Position{
+		Offset:    start,
+		EndOffset: end,
+		Line:      line,
+		Column:    column,
+		EndLine:   endLine,
+		EndColumn: endColumn,
+	}
 }
 
 // tokenType представляет тип токена.
 type tokenType int
 
 const (
-	tokenEOF tokenType = iota
+	tokenEOF     tokenType = iota
+	tokenIllegal           // ошибка лексера, literal - сообщение
 	tokenIdent
 	tokenNumber
 	tokenString
@@ -1825,14 +2888,29 @@ const (
 	tokenNotStartsWith // !^
 	tokenNotEndsWith   // !$
 	tokenRange         // ..
+	tokenEllipsis      // ...
+	tokenRegex         // ~/pattern/flags
+	tokenStarStar      // **
+	tokenIn            // in
+	tokenInstanceof    // instanceof
+	tokenDiv           // div
+	tokenPipeline      // |>
 	// Side-effect операторы
-	tokenPlusEqual    // +=
-	tokenMinusEqual   // -=
-	tokenStarEqual    // *=
-	tokenSlashEqual   // /=
-	tokenPercentEqual // %=
-	tokenPlusPlus     // ++
-	tokenMinusMinus   // --
+	tokenPlusEqual             // +=
+	tokenMinusEqual            // -=
+	tokenStarEqual             // *=
+	tokenSlashEqual            // /=
+	tokenPercentEqual          // %=
+	tokenStarStarEqual         // **=
+	tokenAmpEqual              // &=
+	tokenPipeEqual             // |=
+	tokenCaretEqual            // ^=
+	tokenShiftLeftEqual        // <<=
+	tokenShiftRightEqual       // >>=
+	tokenShiftRightUEqual      // >>>=
+	tokenQuestionQuestionEqual // ??=
+	tokenPlusPlus              // ++
+	tokenMinusMinus            // --
 	// Ключевые слова
 	tokenIf
 	tokenElse
@@ -1852,16 +2930,96 @@ const (
 	tokenTry
 	tokenCatch
 	tokenFinally
+	tokenFunction
 	// Lambda операторы
 	tokenLambda   // ->
 	tokenFatArrow // =>
 )
 
+// tokenTexts - текст операторов, разделителей и ключевых слов.
+var tokenTexts = map[tokenType]string{
+	tokenPlus: "+", tokenMinus: "-", tokenStar: "*", tokenSlash: "/", tokenPercent: "%",
+	tokenLParen: "(", tokenRParen: ")", tokenLBracket: "[", tokenRBracket: "]",
+	tokenLBrace: "{", tokenRBrace: "}", tokenDot: ".", tokenComma: ",",
+	tokenSemicolon: ";", tokenColon: ":", tokenBang: "!", tokenEqual: "=",
+	tokenEqualEqual: "==", tokenBangEqual: "!=", tokenLess: "<", tokenLessEqual: "<=",
+	tokenGreater: ">", tokenGreaterEqual: ">=", tokenAnd: "&&", tokenOr: "||",
+	tokenQuestion: "?", tokenQuestionQuestion: "??",
+	tokenAmpersand: "&", tokenPipe: "|", tokenCaret: "^", tokenTilde: "~",
+	tokenShiftLeft: "<<", tokenShiftRight: ">>", tokenShiftRightU: ">>>",
+	tokenContains: "=~", tokenStartsWith: "=^", tokenEndsWith: "=$",
+	tokenNotContains: "!~", tokenNotStartsWith: "!^", tokenNotEndsWith: "!$",
+	tokenRange: "..", tokenEllipsis: "...", tokenStarStar: "**",
+	tokenIn: "in", tokenInstanceof: "instanceof", tokenDiv: "div", tokenPipeline: "|>",
+	tokenPlusEqual: "+=", tokenMinusEqual: "-=", tokenStarEqual: "*=", tokenSlashEqual: "/=",
+	tokenPercentEqual: "%=", tokenStarStarEqual: "**=", tokenAmpEqual: "&=", tokenPipeEqual: "|=",
+	tokenCaretEqual: "^=", tokenShiftLeftEqual: "<<=", tokenShiftRightEqual: ">>=",
+	tokenShiftRightUEqual: ">>>=", tokenQuestionQuestionEqual: "??=",
+	tokenPlusPlus: "++", tokenMinusMinus: "--", tokenNull: "null",
+	tokenIf: "if", tokenElse: "else", tokenFor: "for", tokenWhile: "while", tokenDo: "do",
+	tokenBreak: "break", tokenContinue: "continue", tokenReturn: "return", tokenVar: "var",
+	tokenEmpty: "empty", tokenSize: "size", tokenNot: "not", tokenSwitch: "switch",
+	tokenCase: "case", tokenDefault: "default", tokenTry: "try", tokenCatch: "catch",
+	tokenFinally: "finally", tokenFunction: "function",
+	tokenLambda: "->", tokenFatArrow: "=>",
+}
+
+// String возвращает название типа токена для сообщений об ошибках:
+// текст в кавычках для операторов и ключевых слов, иначе название класса токенов.
+func (tt tokenType) String() string {
+	if text, ok := tokenTexts[tt]; ok {
+		return "'" + text + "'"
+	}
+	switch tt {
+	case tokenEOF:
+		return "end of input"
+	case tokenIllegal:
+		return "illegal token"
+	case tokenIdent:
+		return "identifier"
+	case tokenNumber:
+		return "number"
+	case tokenString:
+		return "string"
+	case tokenBool:
+		return "boolean"
+	case tokenRegex:
+		return "regex"
+	default:
+		return fmt.Sprintf("token(%d)", int(tt))
+	}
+}
+
+// isClosing сообщает, является ли токен закрывающей скобкой.
+func (tt tokenType) isClosing() bool {
+	return tt == tokenRParen || tt == tokenRBracket || tt == tokenRBrace
+}
+
+// isDelimiter сообщает, является ли токен разделителем, пропуск которого
+// исправляется вставкой.
+func (tt tokenType) isDelimiter() bool {
+	return tt.isClosing() || tt == tokenSemicolon || tt == tokenColon || tt == tokenComma
+}
+
+// startsStatement сообщает, начинает ли токен инструкцию.
+func (tt tokenType) startsStatement() bool {
+	switch tt {
+	case tokenIf, tokenFor, tokenWhile, tokenDo, tokenReturn, tokenBreak,
+		tokenContinue, tokenVar, tokenTry, tokenSwitch, tokenFunction:
+		return true
+	}
+	return false
+}
+
 // token представляет токен.
 type token struct {
 	typ     tokenType
 	literal string
 	value   any
+	pos     int // смещение начала токена в исходном тексте
+	end     int // смещение конца токена (не включая)
+	line    int
+	column  int
 }
 
 // lexer разбирает исходный текст на токены.
@@ -1869,10 +3027,11 @@ type lexer struct {
 	source string
 	pos    int
 	start  int
+	lines  *lineIndex
 }
 
 func newLexer(source string) *lexer {
-	return &lexer{source: source}
+	return &lexer{source: source, lines: newLineIndex(source)}
 }
 
 func (l *lexer) lex() []token {
@@ -1880,6 +3039,8 @@ func (l *lexer) lex() []token {
 	for !l.isAtEnd() {
 		l.start = l.pos
 		tok := l.nextToken()
+		tok.pos, tok.end = l.start, l.pos
+		tok.line, tok.column = l.lines.lineColumn(l.start)
 		if tok.typ != tokenEOF || len(tokens) == 0 {
 			tokens = append(tokens, tok)
 		}
@@ -1923,6 +3084,12 @@ func (l *lexer) nextToken() token {
 		if l.match('=') {
 			return token{typ: tokenStarEqual, literal: "*="}
 		}
+		if l.match('*') {
+			if l.match('=') {
+				return token{typ: tokenStarStarEqual, literal: "**="}
+			}
+			return token{typ: tokenStarStar, literal: "**"}
+		}
 		return token{typ: tokenStar, literal: "*"}
 	case '/':
 		if l.match('=') {
@@ -1956,6 +3123,9 @@ func (l *lexer) nextToken() token {
 		return token{typ: tokenRBrace, literal: "}"}
 	case '.':
 		if l.match('.') {
+			if l.match('.') {
+				return token{typ: tokenEllipsis, literal: "..."}
+			}
 			return token{typ: tokenRange, literal: ".."}
 		}
 		return token{typ: tokenDot, literal: "."}
@@ -1999,7 +3169,7 @@ func (l *lexer) nextToken() token {
 	case '<':
 		if l.match('<') {
 			if l.match('=') {
-				return token{typ: tokenEOF, literal: "<<="} // TODO: поддержка <<=
+				return token{typ: tokenShiftLeftEqual, literal: "<<="}
 			}
 			return token{typ: tokenShiftLeft, literal: "<<"}
 		}
@@ -2010,10 +3180,13 @@ func (l *lexer) nextToken() token {
 	case '>':
 		if l.match('>') {
 			if l.match('>') {
+				if l.match('=') {
+					return token{typ: tokenShiftRightUEqual, literal: ">>>="}
+				}
 				return token{typ: tokenShiftRightU, literal: ">>>"}
 			}
 			if l.match('=') {
-				return token{typ: tokenEOF, literal: ">>="} // TODO: поддержка >>=
+				return token{typ: tokenShiftRightEqual, literal: ">>="}
 			}
 			return token{typ: tokenShiftRight, literal: ">>"}
 		}
@@ -2025,18 +3198,36 @@ func (l *lexer) nextToken() token {
 		if l.match('&') {
 			return token{typ: tokenAnd, literal: "&&"}
 		}
+		if l.match('=') {
+			return token{typ: tokenAmpEqual, literal: "&="}
+		}
 		return token{typ: tokenAmpersand, literal: "&"}
 	case '|':
 		if l.match('|') {
 			return token{typ: tokenOr, literal: "||"}
 		}
+		if l.match('=') {
+			return token{typ: tokenPipeEqual, literal: "|="}
+		}
+		if l.match('>') {
+			return token{typ: tokenPipeline, literal: "|>"}
+		}
 		return token{typ: tokenPipe, literal: "|"}
 	case '^':
+		if l.match('=') {
+			return token{typ: tokenCaretEqual, literal: "^="}
+		}
 		return token{typ: tokenCaret, literal: "^"}
 	case '~':
+		if l.match('/') {
+			return l.regex()
+		}
 		return token{typ: tokenTilde, literal: "~"}
 	case '?':
 		if l.match('?') {
+			if l.match('=') {
+				return token{typ: tokenQuestionQuestionEqual, literal: "??="}
+			}
 			return token{typ: tokenQuestionQuestion, literal: "??"}
 		}
 		return token{typ: tokenQuestion, literal: "?"}
@@ -2136,6 +3327,47 @@ func (l *lexer) string(quote rune) token {
 	return token{typ: tokenString, literal: l.source[l.start:l.pos], value: value.String()}
 }
 
+// regexLiteral - шаблон и флаги литерала ~/pattern/flags
+type regexLiteral struct {
+	pattern string
+	flags   string
+}
+
+// regex читает литерал регулярного выражения после ~/.
+// Экранированный слэш \/ становится частью шаблона, остальные экранирования сохраняются.
+func (l *lexer) regex() token {
+	var pattern strings.Builder
+	for {
+		if l.isAtEnd() {
+			return l.errorToken("unterminated regex literal")
+		}
+		c := l.advance()
+		if c == '/' {
+			break
+		}
+		if c == '\\' && l.peek() == '/' {
+			l.advance()
+			pattern.WriteRune('/')
+			continue
+		}
+		if c == '\\' && !l.isAtEnd() {
+			pattern.WriteRune(c)
+			c = l.advance()
+		}
+		pattern.WriteRune(c)
+	}
+
+	flagsStart := l.pos
+	for unicode.IsLetter(l.peek()) {
+		l.advance()
+	}
+	return token{
+		typ:     tokenRegex,
+		literal: l.source[l.start:l.pos],
+		value:   regexLiteral{pattern: pattern.String(), flags: l.source[flagsStart:l.pos]},
+	}
+}
+
 func (l *lexer) number() token {
 	for unicode.IsDigit(l.peek()) {
 		l.advance()
@@ -2203,6 +3435,8 @@ func (l *lexer) identifier() token {
 		tokType = tokenCatch
 	case "finally":
 		tokType = tokenFinally
+	case "function":
+		tokType = tokenFunction
 	case "eq":
 		tokType = tokenEqualEqual
 	case "ne":
@@ -2225,36 +3459,40 @@ func (l *lexer) identifier() token {
 }
 
 func (l *lexer) match(expected rune) bool {
-	if l.isAtEnd() {
+	if l.isAtEnd() || l.peek() != expected {
 		return false
 	}
-	if rune(l.source[l.pos]) != expected {
-		return false
-	}
-	l.pos++
+	l.advance()
 	return true
 }
 
+// peek возвращает текущий символ; символы декодируются из UTF-8 целиком.
 func (l *lexer) peek() rune {
 	if l.isAtEnd() {
 		return 0
 	}
-	return rune(l.source[l.pos])
+	c, _ := utf8.DecodeRuneInString(l.source[l.pos:])
+	return c
 }
 
 func (l *lexer) peekNext() rune {
-	if l.pos+1 >= len(l.source) {
+	if l.isAtEnd() {
+		return 0
+	}
+	_, size := utf8.DecodeRuneInString(l.source[l.pos:])
+	if l.pos+size >= len(l.source) {
 		return 0
 	}
-	return rune(l.source[l.pos+1])
+	c, _ := utf8.DecodeRuneInString(l.source[l.pos+size:])
+	return c
 }
 
 func (l *lexer) advance() rune {
 	if l.isAtEnd() {
 		return 0
 	}
-	c := rune(l.source[l.pos])
-	l.pos++
+	c, size := utf8.DecodeRuneInString(l.source[l.pos:])
+	l.pos += size
 	return c
 }
 
@@ -2304,8 +3542,9 @@ func (l *lexer) isAtEnd() bool {
 	return l.pos >= len(l.source)
 }
 
+// errorToken возвращает недопустимый токен с сообщением об ошибке.
 func (l *lexer) errorToken(message string) token {
-	return token{typ: tokenEOF, literal: message}
+	return token{typ: tokenIllegal, literal: message}
 }
 
 // precedence константы для операторов
@@ -2315,9 +3554,35 @@ const (
 	ternaryPrecedence    = 2
 )
 
+// contextualOperators - операторы, записываемые идентификаторами.
+var contextualOperators = map[string]tokenType{
+	"in":         tokenIn,
+	"instanceof": tokenInstanceof,
+	"div":        tokenDiv,
+}
+
+// compoundOperators сопоставляет side-effect операторы с бинарными операторами.
+var compoundOperators = map[tokenType]string{
+	tokenPlusEqual:             "+",
+	tokenMinusEqual:            "-",
+	tokenStarEqual:             "*",
+	tokenSlashEqual:            "/",
+	tokenPercentEqual:          "%",
+	tokenStarStarEqual:         "**",
+	tokenAmpEqual:              "&",
+	tokenPipeEqual:             "|",
+	tokenCaretEqual:            "^",
+	tokenShiftLeftEqual:        "<<",
+	tokenShiftRightEqual:       ">>",
+	tokenShiftRightUEqual:      ">>>",
+	tokenQuestionQuestionEqual: "??",
+}
+
 func infixPrecedence(tt tokenType) int {
 	switch tt {
-	case tokenEqual, tokenPlusEqual, tokenMinusEqual, tokenStarEqual, tokenSlashEqual, tokenPercentEqual:
+	case tokenEqual, tokenPlusEqual, tokenMinusEqual, tokenStarEqual, tokenSlashEqual, tokenPercentEqual,
+		tokenStarStarEqual, tokenAmpEqual, tokenPipeEqual, tokenCaretEqual,
+		tokenShiftLeftEqual, tokenShiftRightEqual, tokenShiftRightUEqual, tokenQuestionQuestionEqual:
 		return assignmentPrecedence
 	case tokenQuestion:
 		return ternaryPrecedence
@@ -2325,9 +3590,9 @@ func infixPrecedence(tt tokenType) int {
 		return 3
 	case tokenDot, tokenLBracket:
 		return 14
-	case tokenLParen:
+	case tokenLParen, tokenStarStar:
 		return 13
-	case tokenStar, tokenSlash, tokenPercent:
+	case tokenStar, tokenSlash, tokenPercent, tokenDiv:
 		return 12
 	case tokenShiftLeft, tokenShiftRight, tokenShiftRightU:
 		return 11
@@ -2335,11 +3600,14 @@ func infixPrecedence(tt tokenType) int {
 		return 10
 	case tokenLess, tokenLessEqual, tokenGreater, tokenGreaterEqual:
 		return 9
+	case tokenPipeline:
+		return 9 // Конвейер слабее арифметики: x * 2 |> f
+	case tokenRange:
+		return 9 // Range связывает сильнее сравнений: x =~ 1 .. 10, r == 1 .. 3
 	case tokenEqualEqual, tokenBangEqual:
 		return 8
-	case tokenRange:
-		return 7 // Range имеет тот же приоритет, что и строковые операторы
-	case tokenContains, tokenStartsWith, tokenEndsWith, tokenNotContains, tokenNotStartsWith, tokenNotEndsWith:
+	case tokenContains, tokenStartsWith, tokenEndsWith, tokenNotContains, tokenNotStartsWith, tokenNotEndsWith,
+		tokenIn, tokenInstanceof:
 		return 7
 	case tokenAmpersand:
 		return 5
@@ -2481,14 +3749,23 @@ func (p *simpleParser) isLambdaStartAfterLParen() bool {
 	}
 	// Сохраняем текущую позицию
 	savedPos := p.pos
+	p.speculating++
 	defer func() {
 		p.pos = savedPos
+		p.speculating--
 	}()
 
-	// Проверяем, идут ли после ( идентификаторы
+	// Проверяем, идут ли после ( параметры: x, b = выражение, ...rest
 	hasParams := false
 	for p.pos < len(p.tokens) {
 		tok := p.tokens[p.pos]
+		if tok.typ == tokenEllipsis {
+			p.pos++
+			if p.pos >= len(p.tokens) || p.tokens[p.pos].typ != tokenIdent {
+				return false
+			}
+			tok = p.tokens[p.pos]
+		}
 		if tok.typ == tokenIdent {
 			hasParams = true
 			p.pos++
@@ -2497,6 +3774,14 @@ func (p *simpleParser) isLambdaStartAfterLParen() bool {
 				return false
 			}
 			next := p.tokens[p.pos]
+			if next.typ == tokenEqual {
+				// Значение по умолчанию - пропускаем выражение до , или ) на нулевой глубине
+				p.pos++
+				if !p.skipParameterDefault() {
+					return false
+				}
+				next = p.tokens[p.pos]
+			}
 			if next.typ == tokenComma {
 				p.pos++
 				continue
@@ -2529,6 +3814,33 @@ func (p *simpleParser) isLambdaStartAfterLParen() bool {
 	return false
 }
 
+// skipParameterDefault пропускает выражение значения по умолчанию при lookahead.
+// Останавливается на , или ) вне вложенных скобок; возвращает false, если их нет.
+func (p *simpleParser) skipParameterDefault() bool {
+	depth := 0
+	for p.pos < len(p.tokens) {
+		switch p.tokens[p.pos].typ {
+		case tokenLParen, tokenLBracket, tokenLBrace:
+			depth++
+		case tokenRBracket, tokenRBrace:
+			depth--
+		case tokenRParen:
+			if depth == 0 {
+				return true
+			}
+			depth--
+		case tokenComma:
+			if depth == 0 {
+				return true
+			}
+		case tokenEOF:
+			return false
+		}
+		p.pos++
+	}
+	return false
+}
+
 // isLambdaStart проверяет, является ли текущая позиция началом lambda функции в скобках: (x, y) -> ...
 func (p *simpleParser) isLambdaStart() bool {
 	if p.pos >= len(p.tokens) {
@@ -2536,8 +3848,10 @@ func (p *simpleParser) isLambdaStart() bool {
 	}
 	// Сохраняем текущую позицию
 	savedPos := p.pos
+	p.speculating++
 	defer func() {
 		p.pos = savedPos
+		p.speculating--
 	}()
 
 	// Пропускаем (
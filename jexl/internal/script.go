@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/mentatxx/jexl-golang/jexl"
@@ -13,19 +14,31 @@ type script struct {
 	source    string
 	ast       *jexl.ScriptNode
 	boundArgs []any
+	program   *program // дерево замыканий для режима Options.Compiled
 }
 
 // NewScript создаёт новый script из AST.
 func NewScript(engine jexl.Engine, source string, ast *jexl.ScriptNode) jexl.Script {
 	return &script{
-		engine: engine,
-		source: source,
-		ast:    ast,
+		engine:  engine,
+		source:  source,
+		ast:     ast,
+		program: &program{node: ast},
 	}
 }
 
 // Execute выполняет скрипт с контекстом и аргументами.
 func (s *script) Execute(ctx jexl.Context, args ...any) (any, error) {
+	return s.execute(ctx, args, nil)
+}
+
+// ExecuteNamed выполняет скрипт, связывая аргументы с параметрами по имени.
+func (s *script) ExecuteNamed(ctx jexl.Context, named map[string]any) (any, error) {
+	return s.execute(ctx, nil, named)
+}
+
+// execute выполняет скрипт с позиционными и именованными аргументами.
+func (s *script) execute(ctx jexl.Context, args []any, named map[string]any) (any, error) {
 	execCtx := ctx
 	if execCtx == nil {
 		execCtx = jexl.NewMapContext()
@@ -34,34 +47,93 @@ func (s *script) Execute(ctx jexl.Context, args ...any) (any, error) {
 	allArgs := append([]any{}, s.boundArgs...)
 	allArgs = append(allArgs, args...)
 
-	paramNames := s.Parameters()
-	if len(paramNames) > 0 {
-		params := make(map[string]any, len(paramNames))
-		for i, name := range paramNames {
-			if i < len(allArgs) {
-				params[name] = allArgs[i]
-			} else {
-				params[name] = nil
-			}
-		}
+	if len(s.Parameters()) > 0 {
+		params := make(map[string]any, len(s.Parameters()))
 		execCtx = newArgumentContext(execCtx, params)
+		if err := bindParameters(s.engine, execCtx, params, s.ast, allArgs, named); err != nil {
+			return nil, err
+		}
+	} else if len(named) > 0 {
+		return nil, jexl.NewError("script has no parameters")
 	}
 
 	interp := newInterpreter(s.engine, execCtx)
-	result, err := interp.interpret(s.ast)
+	if info := s.ast.Info(); info != nil {
+		interp.name = info.Name()
+	}
+	result, err := interp.run(s.program, s.ast)
 	if err != nil {
 		return nil, err
 	}
 	
 	// Если скрипт содержит только lambda функцию и переданы аргументы,
 	// автоматически вызываем lambda с этими аргументами
-	if script, ok := result.(jexl.Script); ok && len(allArgs) > 0 {
-		return script.Execute(execCtx, allArgs...)
+	if script, ok := result.(jexl.Script); ok {
+		if len(named) > 0 {
+			return script.Curry(allArgs...).ExecuteNamed(execCtx, named)
+		}
+		if len(allArgs) > 0 {
+			return script.Execute(execCtx, allArgs...)
+		}
 	}
 	
 	return result, nil
 }
 
+// bindParameters заполняет params значениями аргументов согласно параметрам узла.
+// Недостающие параметры получают значение по умолчанию (вычисляемое в scope, где видны
+// предыдущие параметры) или nil; ...rest получает срез оставшихся аргументов.
+func bindParameters(engine jexl.Engine, scope jexl.Context, params map[string]any, ast *jexl.ScriptNode, args []any, named map[string]any) error {
+	names := ast.Parameters()
+	defaults := ast.ParameterDefaults()
+	fixed := len(names)
+	if ast.HasRestParameter() {
+		fixed--
+	}
+
+	for name := range named {
+		index := slices.Index(names, name)
+		if index < 0 {
+			return jexl.NewError("unknown parameter: " + name)
+		}
+		if index < len(args) && index < fixed {
+			return jexl.NewError("parameter already bound: " + name)
+		}
+	}
+
+	for i := 0; i < fixed; i++ {
+		name := names[i]
+		if i < len(args) {
+			params[name] = args[i]
+			continue
+		}
+		if value, ok := named[name]; ok {
+			params[name] = value
+			continue
+		}
+		params[name] = nil
+		if i < len(defaults) && defaults[i] != nil {
+			value, err := newInterpreter(engine, scope).interpret(defaults[i])
+			if err != nil {
+				return err
+			}
+			params[name] = value
+		}
+	}
+
+	if fixed < len(names) {
+		name := names[fixed]
+		if len(args) > fixed {
+			params[name] = append([]any{}, args[fixed:]...)
+		} else if value, ok := named[name]; ok {
+			params[name] = value
+		} else {
+			params[name] = []any{}
+		}
+	}
+	return nil
+}
+
 // Evaluate реализует Expression.Evaluate.
 func (s *script) Evaluate(ctx jexl.Context) (any, error) {
 	return s.Execute(ctx)
@@ -72,6 +144,11 @@ func (s *script) SourceText() string {
 	return s.source
 }
 
+// AST возвращает корневой узел скрипта.
+func (s *script) AST() *jexl.ScriptNode {
+	return s.ast
+}
+
 // ParsedText возвращает распарсенный текст.
 func (s *script) ParsedText() string {
 	return s.ast.String()
@@ -103,18 +180,59 @@ func (s *script) LocalVariables() []string {
 	return s.ast.Variables()
 }
 
+// Functions возвращает имена функций, объявленных на верхнем уровне скрипта.
+func (s *script) Functions() []string {
+	if s.ast == nil {
+		return nil
+	}
+	var names []string
+	for _, child := range s.ast.Children() {
+		if fn, ok := child.(*jexl.FunctionNode); ok {
+			names = append(names, fn.Name().Name())
+		}
+	}
+	return names
+}
+
+// Function возвращает объявленную на верхнем уровне функцию name. Функции скрипта
+// поднимаются в отдельной области поверх ctx, как при выполнении, поэтому они могут
+// вызывать друг друга, а ctx не изменяется.
+func (s *script) Function(ctx jexl.Context, name string) (jexl.Script, error) {
+	if !slices.Contains(s.Functions(), name) {
+		return nil, jexl.NewError("function not declared: " + name)
+	}
+	if ctx == nil {
+		ctx = jexl.NewMapContext()
+	}
+	interp := newInterpreter(s.engine, ctx)
+	if info := s.ast.Info(); info != nil {
+		interp.name = info.Name()
+	}
+	interp.hoistFunctions(s.ast.Children(), nil)
+	return interp.context.Get(name).(jexl.Script), nil
+}
+
 // Pragmas возвращает pragma директивы.
 func (s *script) Pragmas() map[string]any {
 	return s.ast.Pragmas()
 }
 
-// UnboundParameters возвращает несвязанные параметры.
+// UnboundParameters возвращает несвязанные обязательные параметры.
+// Параметры со значением по умолчанию и ...rest не считаются обязательными.
 func (s *script) UnboundParameters() []string {
 	params := s.Parameters()
-	if len(s.boundArgs) >= len(params) {
-		return nil
+	defaults := s.ast.ParameterDefaults()
+	var unbound []string
+	for i := len(s.boundArgs); i < len(params); i++ {
+		if s.ast.HasRestParameter() && i == len(params)-1 {
+			continue
+		}
+		if i < len(defaults) && defaults[i] != nil {
+			continue
+		}
+		unbound = append(unbound, params[i])
 	}
-	return params[len(s.boundArgs):]
+	return unbound
 }
 
 // Variables возвращает переменные скрипта.
@@ -124,10 +242,8 @@ func (s *script) Variables() [][]string {
 		return nil
 	}
 	
-	// Используем collectMode = 1 (расширенный режим) по умолчанию
-	// В будущем можно добавить метод в Engine для получения collectMode
-	collectMode := 1
-	
+	collectMode := s.collectMode()
+
 	// Собираем переменные из всех дочерних узлов
 	var allVars [][]string
 	for _, child := range s.ast.Children() {
@@ -149,6 +265,22 @@ func (s *script) Variables() [][]string {
 	return result
 }
 
+// Dependencies возвращает зависимости скрипта от контекста.
+func (s *script) Dependencies() []jexl.Dependency {
+	if s.ast == nil {
+		return nil
+	}
+	return jexl.AnalyzeDependencies(s.ast, s.collectMode())
+}
+
+// collectMode возвращает режим сбора переменных движка, по умолчанию расширенный.
+func (s *script) collectMode() int {
+	if e, ok := s.engine.(*engine); ok {
+		return e.collectMode
+	}
+	return 1
+}
+
 // Curry создаёт новый скрипт с частично применёнными аргументами.
 func (s *script) Curry(args ...any) jexl.Script {
 	if len(args) == 0 {
@@ -162,6 +294,7 @@ func (s *script) Curry(args ...any) jexl.Script {
 		source:    s.source,
 		ast:       s.ast,
 		boundArgs: allArgs,
+		program:   s.program,
 	}
 }
 
@@ -0,0 +1,340 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// stringMethodSpec описывает встроенный метод строки: допустимое число аргументов
+// (maxArgs < 0 - без ограничения) и реализацию.
+// Индексы и длины считаются в символах (рунах), а не в байтах.
+type stringMethodSpec struct {
+	minArgs int
+	maxArgs int
+	fn      func(u *uberspectImpl, s string, args []any) (any, error)
+}
+
+// stringMethods - таблица методов строк по образцу java.lang.String.
+var stringMethods = map[string]stringMethodSpec{
+	"length": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return int64(utf8.RuneCountInString(s)), nil
+	}},
+	"isEmpty": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return s == "", nil
+	}},
+	"isBlank": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return strings.TrimSpace(s) == "", nil
+	}},
+	"toString": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return s, nil
+	}},
+	"hashCode": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		// Аналог Java String.hashCode()
+		hash := int32(0)
+		for _, c := range s {
+			hash = 31*hash + int32(c)
+		}
+		return int64(hash), nil
+	}},
+	"toLowerCase": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return strings.ToLower(s), nil
+	}},
+	"toUpperCase": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return strings.ToUpper(s), nil
+	}},
+	"trim": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		// Как в Java: отбрасываются управляющие символы и пробел (<= U+0020)
+		return strings.TrimFunc(s, func(r rune) bool { return r <= ' ' }), nil
+	}},
+	"strip": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return strings.TrimFunc(s, unicode.IsSpace), nil
+	}},
+	"stripLeading": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return strings.TrimLeftFunc(s, unicode.IsSpace), nil
+	}},
+	"stripTrailing": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		return strings.TrimRightFunc(s, unicode.IsSpace), nil
+	}},
+	"charAt": {1, 1, func(u *uberspectImpl, s string, a []any) (any, error) {
+		runes := []rune(s)
+		index, err := u.toInt(a[0])
+		if err != nil {
+			return nil, err
+		}
+		if index < 0 || index >= len(runes) {
+			return nil, jexl.NewError(fmt.Sprintf("charAt index %d out of bounds for length %d", index, len(runes)))
+		}
+		return string(runes[index]), nil
+	}},
+	"substring": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		runes := []rune(s)
+		start, err := u.toInt(a[0])
+		if err != nil {
+			return nil, err
+		}
+		if start < 0 || start > len(runes) {
+			return nil, jexl.NewError("substring start index out of bounds")
+		}
+		if len(a) == 1 {
+			return string(runes[start:]), nil
+		}
+		end, err := u.toInt(a[1])
+		if err != nil {
+			return nil, err
+		}
+		if end < start || end > len(runes) {
+			return nil, jexl.NewError("substring end index out of bounds")
+		}
+		return string(runes[start:end]), nil
+	}},
+	"indexOf": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		runes := []rune(s)
+		from := 0
+		if len(a) > 1 {
+			var err error
+			if from, err = u.toInt(a[1]); err != nil {
+				return nil, err
+			}
+		}
+		from = max(from, 0)
+		if from > len(runes) {
+			return int64(-1), nil
+		}
+		index := strings.Index(string(runes[from:]), argString(a[0]))
+		if index < 0 {
+			return int64(-1), nil
+		}
+		return int64(from + utf8.RuneCountInString(string(runes[from:])[:index])), nil
+	}},
+	"lastIndexOf": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		runes := []rune(s)
+		sub := []rune(argString(a[0]))
+		from := len(runes)
+		if len(a) > 1 {
+			var err error
+			if from, err = u.toInt(a[1]); err != nil {
+				return nil, err
+			}
+		}
+		// Вхождение должно начинаться не правее from
+		limit := min(from+len(sub), len(runes))
+		if limit < 0 {
+			return int64(-1), nil
+		}
+		index := strings.LastIndex(string(runes[:limit]), string(sub))
+		if index < 0 {
+			return int64(-1), nil
+		}
+		return int64(utf8.RuneCountInString(s[:index])), nil
+	}},
+	"contains": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		return strings.Contains(s, argString(a[0])), nil
+	}},
+	"startsWith": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		runes := []rune(s)
+		offset := 0
+		if len(a) > 1 {
+			var err error
+			if offset, err = u.toInt(a[1]); err != nil {
+				return nil, err
+			}
+		}
+		if offset < 0 || offset > len(runes) {
+			return false, nil
+		}
+		return strings.HasPrefix(string(runes[offset:]), argString(a[0])), nil
+	}},
+	"endsWith": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		return strings.HasSuffix(s, argString(a[0])), nil
+	}},
+	"concat": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		return s + argString(a[0]), nil
+	}},
+	"repeat": {1, 1, func(u *uberspectImpl, s string, a []any) (any, error) {
+		count, err := u.toInt(a[0])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, jexl.NewError(fmt.Sprintf("repeat count is negative: %d", count))
+		}
+		return strings.Repeat(s, count), nil
+	}},
+	"equalsIgnoreCase": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		if a[0] == nil {
+			return false, nil
+		}
+		return strings.EqualFold(s, argString(a[0])), nil
+	}},
+	"compareTo": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		return compareRunes(s, argString(a[0])), nil
+	}},
+	"compareToIgnoreCase": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		return compareRunes(strings.ToLower(s), strings.ToLower(argString(a[0]))), nil
+	}},
+	"matches": {1, 1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		// Как в Java: шаблон должен совпасть со всей строкой
+		re, err := jexl.CompileRegex("^(?:" + argString(a[0]) + ")$")
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(s), nil
+	}},
+	"split": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		re, err := jexl.ToRegex(a[0])
+		if err != nil {
+			return nil, err
+		}
+		limit := 0
+		if len(a) > 1 {
+			if limit, err = u.toInt(a[1]); err != nil {
+				return nil, err
+			}
+		}
+		n := -1
+		if limit > 0 {
+			n = limit
+		}
+		parts := re.Split(s, n)
+		// Как в Java: при limit == 0 завершающие пустые строки отбрасываются
+		if limit == 0 {
+			for len(parts) > 0 && parts[len(parts)-1] == "" {
+				parts = parts[:len(parts)-1]
+			}
+		}
+		result := make([]any, len(parts))
+		for i, part := range parts {
+			result[i] = part
+		}
+		return result, nil
+	}},
+	"replace": {2, 2, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		return strings.ReplaceAll(s, argString(a[0]), argString(a[1])), nil
+	}},
+	"replaceAll": {2, 2, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		re, err := jexl.ToRegex(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(s, jexl.RegexReplacement(argString(a[1]))), nil
+	}},
+	"replaceFirst": {2, 2, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		re, err := jexl.ToRegex(a[0])
+		if err != nil {
+			return nil, err
+		}
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			return s, nil
+		}
+		replaced := re.ExpandString(nil, jexl.RegexReplacement(argString(a[1])), s, loc)
+		return s[:loc[0]] + string(replaced) + s[loc[1]:], nil
+	}},
+	"padStart": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		pad, err := padding(u, s, a)
+		if err != nil {
+			return nil, err
+		}
+		return pad + s, nil
+	}},
+	"padEnd": {1, 2, func(u *uberspectImpl, s string, a []any) (any, error) {
+		pad, err := padding(u, s, a)
+		if err != nil {
+			return nil, err
+		}
+		return s + pad, nil
+	}},
+	"chars": {0, 0, func(_ *uberspectImpl, s string, _ []any) (any, error) {
+		// Как в Java: коды символов
+		result := make([]any, 0, len(s))
+		for _, r := range s {
+			result = append(result, int64(r))
+		}
+		return result, nil
+	}},
+	"format": {0, -1, func(_ *uberspectImpl, s string, a []any) (any, error) {
+		args := make([]any, len(a))
+		for i, arg := range a {
+			args[i] = formatArg(arg)
+		}
+		return fmt.Sprintf(s, args...), nil
+	}},
+}
+
+// getStringMethod возвращает метод строки из таблицы stringMethods.
+func (u *uberspectImpl) getStringMethod(str string, name string, args []any) (jexl.Method, error) {
+	spec, ok := stringMethods[name]
+	if !ok {
+		return nil, jexl.NewError(fmt.Sprintf("string method %s not found", name))
+	}
+	if len(args) < spec.minArgs || (spec.maxArgs >= 0 && len(args) > spec.maxArgs) {
+		switch {
+		case spec.maxArgs == 0:
+			return nil, jexl.NewError(name + "() takes no arguments")
+		case spec.minArgs == spec.maxArgs:
+			return nil, jexl.NewError(fmt.Sprintf("%s() takes %d argument(s)", name, spec.minArgs))
+		default:
+			return nil, jexl.NewError(fmt.Sprintf("%s() takes %d or %d arguments", name, spec.minArgs, spec.maxArgs))
+		}
+	}
+	return &stringMethod{
+		name: name,
+		fn: func(s string, a []any) (any, error) {
+			return spec.fn(u, s, a)
+		},
+	}, nil
+}
+
+// argString приводит аргумент к строке; nil становится "null", как в Java.
+func argString(value any) string {
+	if value == nil {
+		return "null"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// compareRunes сравнивает строки посимвольно как Java String.compareTo:
+// разность первых различающихся символов или разность длин.
+func compareRunes(a, b string) int64 {
+	ra, rb := []rune(a), []rune(b)
+	for i := 0; i < len(ra) && i < len(rb); i++ {
+		if ra[i] != rb[i] {
+			return int64(ra[i] - rb[i])
+		}
+	}
+	return int64(len(ra) - len(rb))
+}
+
+// padding вычисляет дополнение строки до длины a[0] символами a[1] (по умолчанию пробел).
+func padding(u *uberspectImpl, s string, a []any) (string, error) {
+	length, err := u.toInt(a[0])
+	if err != nil {
+		return "", err
+	}
+	pad := " "
+	if len(a) > 1 {
+		pad = argString(a[1])
+	}
+	return jexl.Padding(s, length, pad), nil
+}
+
+// formatArg приводит числа арифметики к типам, понятным fmt: целые *big.Rat - к int64,
+// дробные - к float64.
+func formatArg(value any) any {
+	if r, ok := value.(*big.Rat); ok {
+		if r.IsInt() && r.Num().IsInt64() {
+			return r.Num().Int64()
+		}
+		f, _ := r.Float64()
+		return f
+	}
+	return value
+}
@@ -0,0 +1,959 @@
+package internal
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// CheckTypes выводит типы выражений скрипта и проверяет их по схеме.
+func (e *engine) CheckTypes(ast *jexl.ScriptNode, schema *jexl.Schema) []jexl.Diagnostic {
+	if ast == nil {
+		return nil
+	}
+	if schema == nil {
+		schema = &jexl.Schema{}
+	}
+	c := &typeChecker{
+		options: e.options,
+		schema:  schema,
+		scopes:  []map[string]*jexl.Type{{}},
+		nonNull: make(map[string]int),
+	}
+	c.lexical = ast.Features() != nil && ast.Features().IsLexical()
+	for _, param := range ast.Parameters() {
+		c.declare(param, anyType)
+	}
+	for _, child := range ast.Children() {
+		c.check(child)
+	}
+	return c.diagnostics
+}
+
+var (
+	anyType     = jexl.AnyType()
+	nullType    = &jexl.Type{Kind: jexl.TypeNull, Nullable: true}
+	booleanType = &jexl.Type{Kind: jexl.TypeBoolean}
+	integerType = &jexl.Type{Kind: jexl.TypeInteger}
+	numberType  = &jexl.Type{Kind: jexl.TypeNumber}
+	stringType  = &jexl.Type{Kind: jexl.TypeString}
+	lambdaType  = &jexl.Type{Kind: jexl.TypeFunction, Variadic: true}
+)
+
+// builtinFunctionTypes - функции, которые интерпретатор вызывает без обращения к контексту.
+var builtinFunctionTypes = map[string]*jexl.Type{
+	"empty":   jexl.FunctionOf(booleanType, anyType),
+	"size":    jexl.FunctionOf(integerType, anyType),
+	"matches": jexl.FunctionOf(booleanType, anyType, anyType),
+	"groups":  jexl.FunctionOf(jexl.MapOf(stringType), anyType, anyType),
+}
+
+// stringMethodResults - виды результатов методов строк, не возвращающих строку.
+var stringMethodResults = map[string]*jexl.Type{
+	"length":              integerType,
+	"indexOf":             integerType,
+	"lastIndexOf":         integerType,
+	"hashCode":            integerType,
+	"compareTo":           integerType,
+	"compareToIgnoreCase": integerType,
+	"isEmpty":             booleanType,
+	"isBlank":             booleanType,
+	"contains":            booleanType,
+	"startsWith":          booleanType,
+	"endsWith":            booleanType,
+	"equalsIgnoreCase":    booleanType,
+	"matches":             booleanType,
+	"split":               jexl.ListOf(stringType),
+	"chars":               jexl.ListOf(stringType),
+}
+
+// typeChecker выводит типы и собирает диагностику. Локальные переменные видны
+// до конца функции, а при FeatureLexical - до конца блока, как в анализе зависимостей.
+type typeChecker struct {
+	options     *jexl.Options
+	schema      *jexl.Schema
+	lexical     bool
+	scopes      []map[string]*jexl.Type
+	nonNull     map[string]int // пути, проверенные на null в текущей ветви
+	diagnostics []jexl.Diagnostic
+}
+
+// report добавляет диагностическое сообщение с положением узла.
+func (c *typeChecker) report(severity jexl.Severity, node jexl.Node, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, jexl.Diagnostic{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Range:    node.Position(),
+	})
+}
+
+// declare объявляет локальное имя в текущей области.
+func (c *typeChecker) declare(name string, t *jexl.Type) {
+	c.scopes[len(c.scopes)-1][name] = t
+}
+
+// lookup ищет локальное имя.
+func (c *typeChecker) lookup(name string) (*jexl.Type, bool) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if t, ok := c.scopes[i][name]; ok {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// scoped выполняет f в новой области видимости (блочной - только в лексическом режиме).
+func (c *typeChecker) scoped(block bool, f func()) {
+	if block && !c.lexical {
+		f()
+		return
+	}
+	c.scopes = append(c.scopes, map[string]*jexl.Type{})
+	f()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// narrowed проверяет узел, считая пути paths не равными null.
+func (c *typeChecker) narrowed(paths []string, node jexl.Node) *jexl.Type {
+	for _, path := range paths {
+		c.nonNull[path]++
+	}
+	t := c.check(node)
+	for _, path := range paths {
+		c.nonNull[path]--
+	}
+	return t
+}
+
+// check выводит тип узла, проверяя его потомков. Инструкции имеют тип any.
+func (c *typeChecker) check(node jexl.Node) *jexl.Type {
+	switch n := node.(type) {
+	case nil:
+		return anyType
+	case *jexl.LiteralNode:
+		return literalType(n.Value())
+	case *jexl.IdentifierNode, *jexl.PropertyAccessNode, *jexl.IndexAccessNode:
+		t := c.access(n)
+		if t.Nullable && c.nonNull[pathKey(n)] > 0 {
+			return notNull(t)
+		}
+		return t
+	case *jexl.MethodCallNode:
+		return c.call(n)
+	case *jexl.AssignmentNode:
+		return c.assign(n)
+	case *jexl.BinaryOpNode:
+		switch n.Op() {
+		case "&&", "and":
+			c.check(n.Left())
+			c.narrowed(guards(n.Left(), true), n.Right())
+			return booleanType
+		case "||", "or":
+			c.check(n.Left())
+			c.narrowed(guards(n.Left(), false), n.Right())
+			return booleanType
+		}
+		return c.operands(n.Op(), c.check(n.Left()), c.check(n.Right()), n)
+	case *jexl.UnaryOpNode:
+		t := c.check(n.Operand())
+		switch n.Op() {
+		case "+", "-":
+			if !numeric(t) && !loose(t) {
+				c.report(jexl.SeverityError, n, "invalid operand for unary %s: %s", n.Op(), t)
+				return anyType
+			}
+			return t
+		case "~":
+			if t.Kind != jexl.TypeInteger && !loose(t) {
+				c.report(jexl.SeverityError, n, "invalid operand for ~: %s", t)
+			}
+			return integerType
+		}
+		return booleanType
+	case *jexl.TernaryNode:
+		c.check(n.Condition())
+		t := c.narrowed(guards(n.Condition(), true), n.TrueExpr())
+		f := c.narrowed(guards(n.Condition(), false), n.FalseExpr())
+		return join(t, f)
+	case *jexl.ElvisNode:
+		return join(notNull(c.check(n.Expr())), c.check(n.DefaultExpr()))
+	case *jexl.RangeNode:
+		for _, child := range n.Children() {
+			if t := c.check(child); t.Kind != jexl.TypeInteger && !loose(t) {
+				c.report(jexl.SeverityError, child, "range bound must be integer, got %s", t)
+			}
+		}
+		return jexl.ListOf(integerType)
+	case *jexl.PipeNode:
+		c.check(n.Value())
+		if call, ok := n.Target().(*jexl.MethodCallNode); ok {
+			// Цель конвейера получает значение дополнительным аргументом
+			for _, arg := range call.Args() {
+				c.check(arg)
+			}
+		} else if _, ok := n.Target().(*jexl.IdentifierNode); !ok {
+			c.check(n.Target())
+		}
+		return anyType
+	case *jexl.ArrayLiteralNode:
+		return jexl.ListOf(c.joinAll(n.Elements()))
+	case *jexl.SetLiteralNode:
+		return jexl.ListOf(c.joinAll(n.Elements()))
+	case *jexl.MapLiteralNode:
+		var values []jexl.Node
+		for _, entry := range n.Entries() {
+			c.check(entry.Key)
+			values = append(values, entry.Value)
+		}
+		return jexl.MapOf(c.joinAll(values))
+	case *jexl.LambdaNode:
+		c.scoped(false, func() {
+			defaults := n.Defaults()
+			for i, param := range n.Parameters() {
+				if i < len(defaults) {
+					c.check(defaults[i])
+				}
+				c.declare(param.Name(), anyType)
+			}
+			c.check(n.Body())
+		})
+		return lambdaType
+	case *jexl.FunctionNode:
+		c.declare(n.Name().Name(), lambdaType)
+		c.check(n.Lambda())
+	case *jexl.IfNode:
+		c.check(n.Condition())
+		c.narrowed(guards(n.Condition(), true), n.ThenBranch())
+		c.narrowed(guards(n.Condition(), false), n.ElseBranch())
+	case *jexl.WhileNode:
+		c.check(n.Condition())
+		c.narrowed(guards(n.Condition(), true), n.Body())
+	case *jexl.ForeachNode:
+		items := c.check(n.Items())
+		elem := anyType
+		switch items.Kind {
+		case jexl.TypeList, jexl.TypeMap:
+			elem = orAny(items.Elem)
+		case jexl.TypeBoolean, jexl.TypeInteger, jexl.TypeNumber, jexl.TypeFunction:
+			c.report(jexl.SeverityError, n.Items(), "cannot iterate over %s", items)
+		}
+		c.scoped(true, func() {
+			if key, ok := n.Key().(*jexl.IdentifierNode); ok {
+				c.declare(key.Name(), anyType)
+			}
+			if ident, ok := n.Variable().(*jexl.IdentifierNode); ok {
+				c.declare(ident.Name(), elem)
+			} else {
+				c.bind(n.Variable(), true)
+			}
+			c.check(n.Body())
+		})
+	case *jexl.BlockNode, *jexl.ForNode:
+		c.scoped(true, func() {
+			for _, child := range n.Children() {
+				c.check(child)
+			}
+		})
+	case *jexl.VarNode:
+		t := anyType
+		if n.Value() != nil {
+			t = c.check(n.Value())
+		}
+		c.declare(n.Name().Name(), t)
+	case *jexl.DestructuringNode:
+		c.check(n.Value())
+		c.bind(n.Pattern(), n.IsDeclaration())
+	case *jexl.TryNode:
+		c.scoped(true, func() {
+			for _, resource := range n.Resources() {
+				c.check(resource)
+			}
+			c.check(n.TryBlock())
+		})
+		c.scoped(true, func() {
+			if n.CatchVar() != "" {
+				c.declare(n.CatchVar(), stringType)
+			}
+			c.check(n.CatchBlock())
+		})
+		c.check(n.FinallyBlock())
+	default:
+		for _, child := range node.Children() {
+			c.check(child)
+		}
+	}
+	return anyType
+}
+
+// joinAll проверяет узлы и возвращает общий тип их значений.
+func (c *typeChecker) joinAll(nodes []jexl.Node) *jexl.Type {
+	var result *jexl.Type
+	for _, node := range nodes {
+		t := c.check(node)
+		if result == nil {
+			result = t
+		} else {
+			result = join(result, t)
+		}
+	}
+	return orAny(result)
+}
+
+// access выводит тип переменной или обращения к свойству или индексу.
+func (c *typeChecker) access(node jexl.Node) *jexl.Type {
+	switch n := node.(type) {
+	case *jexl.IdentifierNode:
+		if t, ok := c.lookup(n.Name()); ok {
+			return t
+		}
+		if c.schema.Context == nil {
+			return anyType
+		}
+		if t, ok := c.propertyOf(c.schema.Context, n.Name()); ok {
+			return t
+		}
+		c.report(jexl.SeverityError, n, "unknown variable %s", n.Name())
+		// Сообщаем о неизвестной переменной один раз
+		c.scopes[0][n.Name()] = anyType
+		return anyType
+	case *jexl.PropertyAccessNode:
+		object := c.dereference(n.Object())
+		prop, ok := n.Property().(*jexl.IdentifierNode)
+		if !ok {
+			c.check(n.Property())
+			return anyType
+		}
+		return c.property(object, prop.Name(), prop)
+	case *jexl.IndexAccessNode:
+		object := c.dereference(n.Object())
+		index := c.check(n.Index())
+		if lit, ok := n.Index().(*jexl.LiteralNode); ok && object.Kind == jexl.TypeObject {
+			if key, ok := lit.Value().(string); ok {
+				return c.property(object, key, lit)
+			}
+		}
+		switch object.Kind {
+		case jexl.TypeList:
+			if !numeric(index) && !loose(index) {
+				c.report(jexl.SeverityError, n.Index(), "list index must be integer, got %s", index)
+			}
+			return orAny(object.Elem)
+		case jexl.TypeMap:
+			return orAny(object.Elem)
+		case jexl.TypeNull, jexl.TypeBoolean, jexl.TypeInteger, jexl.TypeNumber, jexl.TypeFunction:
+			c.report(jexl.SeverityError, n, "cannot index %s", object)
+		}
+	}
+	return anyType
+}
+
+// dereference выводит тип объекта, к свойству или методу которого обращаются,
+// и предупреждает, если объект может быть null.
+func (c *typeChecker) dereference(node jexl.Node) *jexl.Type {
+	t := c.check(node)
+	switch {
+	case t.Kind == jexl.TypeNull:
+		c.report(jexl.SeverityError, node, "dereference of null %s", node.SourceText())
+		return anyType
+	case t.Nullable:
+		c.report(jexl.SeverityWarning, node, "possibly null dereference: %s may be null", node.SourceText())
+		return notNull(t)
+	}
+	return t
+}
+
+// property возвращает тип свойства, сообщая о неизвестном свойстве.
+func (c *typeChecker) property(t *jexl.Type, name string, at jexl.Node) *jexl.Type {
+	if pt, ok := c.propertyOf(t, name); ok {
+		return pt
+	}
+	c.report(jexl.SeverityError, at, "unknown property %s of %s", name, t)
+	return anyType
+}
+
+// propertyOf ищет свойство типа; свойства типов Go - по правилам GetProperty.
+func (c *typeChecker) propertyOf(t *jexl.Type, name string) (*jexl.Type, bool) {
+	switch {
+	case t.Kind == jexl.TypeAny:
+		return anyType, true
+	case t.GoType != nil:
+		typ, ok := propertyType(t.GoType, name)
+		if nullableField(t.GoType, name) {
+			return jexl.TypeOf(typ).OrNull(), ok
+		}
+		return jexl.TypeOf(typ), ok
+	case t.Kind == jexl.TypeObject:
+		if pt, ok := t.Properties[name]; ok {
+			return orAny(pt), true
+		}
+		return anyType, t.Open
+	case t.Kind == jexl.TypeMap:
+		return orAny(t.Elem), true
+	case t.Kind == jexl.TypeList:
+		_, err := strconv.Atoi(name)
+		return orAny(t.Elem), err == nil
+	}
+	return anyType, false
+}
+
+// nullableField проверяет, помечено ли поле структуры тегом jexl:"nullable":
+// значения типов Go допускают null, только если это указано явно.
+func nullableField(typ reflect.Type, name string) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	field, ok := structField(typ, name)
+	return ok && slices.Contains(strings.Split(field.Tag.Get("jexl"), ","), "nullable")
+}
+
+// call проверяет вызов метода, функции контекста или пространства имён.
+func (c *typeChecker) call(n *jexl.MethodCallNode) *jexl.Type {
+	method, ok := n.Method().(*jexl.IdentifierNode)
+	if !ok {
+		for _, child := range n.Children() {
+			c.check(child)
+		}
+		return anyType
+	}
+	name := method.Name()
+	var target *jexl.Type
+	if n.Target() != nil {
+		target = c.dereference(n.Target())
+	}
+	for _, arg := range n.Args() {
+		c.check(arg)
+	}
+	count := len(n.Args())
+	switch {
+	case n.Namespace() != "":
+		return c.namespaceCall(n, name, count)
+	case target != nil:
+		return c.method(target, name, count, method)
+	}
+	// matches(), groups(), duration() и now() уступают локальным функциям с тем же именем
+	if t, ok := c.lookup(name); ok {
+		if overridable, _ := jexl.Builtin(name); overridable {
+			return c.invoke(t, name, count, n)
+		}
+	}
+	if builtin, ok := builtinFunctionTypes[name]; ok {
+		if count != len(builtin.Params) {
+			c.report(jexl.SeverityError, n, "%s", arityMessage(name, len(builtin.Params), false, count))
+		}
+		return builtin.Result
+	}
+	if t, ok := c.lookup(name); ok {
+		return c.invoke(t, name, count, n)
+	}
+	if c.schema.Context == nil {
+		return anyType
+	}
+	if t, ok := c.propertyOf(c.schema.Context, name); ok {
+		return c.invoke(t, name, count, n)
+	}
+	if _, builtin := jexl.Builtin(name); !builtin {
+		c.report(jexl.SeverityError, method, "unknown function %s", name)
+	}
+	return anyType
+}
+
+// invoke проверяет вызов значения типа fn с count аргументами.
+func (c *typeChecker) invoke(fn *jexl.Type, name string, count int, at jexl.Node) *jexl.Type {
+	switch fn.Kind {
+	case jexl.TypeAny:
+		return anyType
+	case jexl.TypeFunction:
+		if fn.Variadic && count < len(fn.Params)-1 || !fn.Variadic && count != len(fn.Params) {
+			c.report(jexl.SeverityError, at, "%s", arityMessage(name, len(fn.Params), fn.Variadic, count))
+		}
+		return orAny(fn.Result)
+	}
+	c.report(jexl.SeverityError, at, "%s is not a function: %s", name, fn)
+	return anyType
+}
+
+// method проверяет вызов метода значения типа t в порядке GetMethod:
+// hashCode, методы строк, методы Go, встроенные методы коллекций.
+func (c *typeChecker) method(t *jexl.Type, name string, count int, at jexl.Node) *jexl.Type {
+	if t.Kind == jexl.TypeAny {
+		return anyType
+	}
+	if name == "hashCode" && count == 0 {
+		return integerType
+	}
+	if t.Kind == jexl.TypeString {
+		spec, ok := stringMethods[name]
+		if !ok {
+			c.report(jexl.SeverityError, at, "unknown method %s of string", name)
+			return anyType
+		}
+		if count < spec.minArgs || spec.maxArgs >= 0 && count > spec.maxArgs {
+			c.report(jexl.SeverityError, at, "%s() takes %s argument(s), got %d", name, arityRange(spec.minArgs, spec.maxArgs), count)
+		}
+		if result, ok := stringMethodResults[name]; ok {
+			return result
+		}
+		return stringType
+	}
+	if t.GoType != nil {
+		if result, found := c.goMethod(t.GoType, name, count, at); found {
+			return result
+		}
+	}
+	if t.Kind == jexl.TypeList || t.Kind == jexl.TypeMap || t.Kind == jexl.TypeObject && t.GoType == nil {
+		if arity, ok := collectionMethodArity[name]; ok {
+			if count < arity[0] || count > arity[1] {
+				c.report(jexl.SeverityError, at, "%s() takes %s argument(s), got %d", name, arityRange(arity[0], arity[1]), count)
+			}
+			return collectionResult(t, name)
+		}
+	}
+	if t.Kind == jexl.TypeObject && t.GoType == nil {
+		// Свойство-функция вызывается как метод
+		if fn, ok := t.Properties[name]; ok {
+			return c.invoke(orAny(fn), name, count, at)
+		}
+		if t.Open {
+			return anyType
+		}
+	}
+	c.report(jexl.SeverityError, at, "unknown method %s of %s", name, t)
+	return anyType
+}
+
+// goMethod проверяет вызов метода Go; found=false, если методов с таким именем нет.
+func (c *typeChecker) goMethod(typ reflect.Type, name string, count int, at jexl.Node) (*jexl.Type, bool) {
+	candidates := goMethods(typ, name)
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	for _, m := range candidates {
+		if acceptsArgCount(m, count) {
+			if m.Type.NumOut() == 0 {
+				return anyType, true
+			}
+			return jexl.TypeOf(m.Type.Out(0)), true
+		}
+	}
+	m := candidates[0]
+	c.report(jexl.SeverityError, at, "%s", arityMessage(name, m.Type.NumIn()-1, m.Type.IsVariadic(), count))
+	return anyType, true
+}
+
+// namespaceCall проверяет вызов ns:name. Пространство имён берётся из схемы,
+// а если там его нет - из опций движка.
+func (c *typeChecker) namespaceCall(n *jexl.MethodCallNode, name string, count int) *jexl.Type {
+	qualified := n.Namespace() + ":" + name
+	ns, ok := c.schema.Namespaces[n.Namespace()]
+	if !ok {
+		var value any
+		if c.options != nil {
+			value = c.options.ResolveNamespace(n.Namespace())
+		}
+		switch v := value.(type) {
+		case nil:
+			c.report(jexl.SeverityError, n, "unknown namespace %s", n.Namespace())
+			return anyType
+		case jexl.NamespaceFuncs:
+			if _, ok := v[name]; !ok {
+				c.report(jexl.SeverityError, n.Method(), "unknown function %s", qualified)
+			} else if min, max, ok := jexl.StandardFuncArity(n.Namespace(), name); ok && (count < min || max >= 0 && count > max) {
+				c.report(jexl.SeverityError, n, "%s() takes %s argument(s), got %d", qualified, arityRange(min, max), count)
+			}
+			return anyType
+		case jexl.NamespaceFunctor:
+			return anyType
+		case map[string]any:
+			fn, ok := v[name]
+			if !ok {
+				c.report(jexl.SeverityError, n.Method(), "unknown function %s", qualified)
+				return anyType
+			}
+			return c.invoke(jexl.TypeOf(reflect.TypeOf(fn)), qualified, count, n)
+		default:
+			ns = jexl.TypeOf(reflect.TypeOf(value))
+		}
+	}
+	ns = orAny(ns)
+	switch {
+	case ns.GoType != nil:
+		if result, found := c.goMethod(ns.GoType, name, count, n); found {
+			return result
+		}
+	case ns.Kind == jexl.TypeObject:
+		if fn, ok := ns.Properties[name]; ok {
+			return c.invoke(orAny(fn), qualified, count, n)
+		}
+		if ns.Open {
+			return anyType
+		}
+	default:
+		return anyType
+	}
+	c.report(jexl.SeverityError, n.Method(), "unknown function %s", qualified)
+	return anyType
+}
+
+// assign проверяет присваивание; составное (x += y) проверяется как операция над целью.
+func (c *typeChecker) assign(n *jexl.AssignmentNode) *jexl.Type {
+	switch value := n.Value().(type) {
+	case *jexl.BinaryOpNode:
+		if value.Left() == n.Target() {
+			return c.operands(value.Op(), c.check(n.Target()), c.check(value.Right()), n)
+		}
+	case *jexl.ElvisNode:
+		if value.Expr() == n.Target() {
+			return join(notNull(c.check(n.Target())), c.check(value.DefaultExpr()))
+		}
+	}
+	value := c.check(n.Value())
+	if ident, ok := n.Target().(*jexl.IdentifierNode); ok {
+		if current, ok := c.lookup(ident.Name()); ok {
+			if current.Kind != value.Kind {
+				c.update(ident.Name(), anyType)
+			}
+			return value
+		}
+		if c.schema.Context == nil {
+			c.scopes[0][ident.Name()] = value
+			return value
+		}
+		if _, ok := c.propertyOf(c.schema.Context, ident.Name()); !ok {
+			// Присваивание создаёт переменную контекста
+			c.scopes[0][ident.Name()] = value
+			return value
+		}
+	}
+	target := c.check(n.Target())
+	if !assignable(value, target) {
+		c.report(jexl.SeverityError, n, "cannot assign %s to %s of type %s", value, n.Target().SourceText(), target)
+	}
+	return value
+}
+
+// update меняет тип локального имени в области, где оно объявлено.
+func (c *typeChecker) update(name string, t *jexl.Type) {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if _, ok := c.scopes[i][name]; ok {
+			c.scopes[i][name] = t
+			return
+		}
+	}
+}
+
+// bind связывает шаблон деструктуризации: при объявлении имена становятся
+// локальными, иначе цели проверяются как обращения.
+func (c *typeChecker) bind(target jexl.Node, declare bool) {
+	var targets, defaults []jexl.Node
+	var rest *jexl.IdentifierNode
+	switch n := target.(type) {
+	case nil:
+		return
+	case *jexl.ArrayPatternNode:
+		targets, defaults, rest = n.Elements(), n.Defaults(), n.Rest()
+	case *jexl.MapPatternNode:
+		targets, defaults, rest = n.Targets(), n.Defaults(), n.Rest()
+	case *jexl.IdentifierNode:
+		if _, ok := c.lookup(n.Name()); !ok && declare {
+			c.declare(n.Name(), anyType)
+		} else if !ok {
+			c.scopes[0][n.Name()] = anyType
+		}
+		return
+	default:
+		c.check(n)
+		return
+	}
+	for i, t := range targets {
+		if i < len(defaults) {
+			c.check(defaults[i])
+		}
+		c.bind(t, declare)
+	}
+	if rest != nil {
+		c.bind(rest, declare)
+	}
+}
+
+// operands проверяет типы операндов бинарного оператора и возвращает тип результата.
+func (c *typeChecker) operands(op string, l, r *jexl.Type, at jexl.Node) *jexl.Type {
+	invalid := func() *jexl.Type {
+		c.report(jexl.SeverityError, at, "invalid operands for %s: %s and %s", op, l, r)
+		return anyType
+	}
+	switch op {
+	case "+":
+		switch {
+		case l.Kind == jexl.TypeString || r.Kind == jexl.TypeString:
+			return stringType
+		case loose(l) || loose(r):
+			return anyType
+		case numeric(l) && numeric(r):
+			return arithmetic(l, r)
+		case l.Kind == r.Kind && (l.Kind == jexl.TypeList || l.Kind == jexl.TypeMap):
+			return notNull(l)
+		}
+		return invalid()
+	case "-":
+		if l.Kind == r.Kind && (l.Kind == jexl.TypeList || l.Kind == jexl.TypeMap) {
+			return notNull(l)
+		}
+		fallthrough
+	case "*", "/", "%", "**":
+		if (numeric(l) || loose(l)) && (numeric(r) || loose(r)) {
+			return arithmetic(l, r)
+		}
+		return invalid()
+	case "div":
+		if (numeric(l) || loose(l)) && (numeric(r) || loose(r)) {
+			return integerType
+		}
+		return invalid()
+	case "<", "<=", ">", ">=", "lt", "le", "gt", "ge":
+		if !loose(l) && !loose(r) && !(numeric(l) && numeric(r)) && !(l.Kind == jexl.TypeString && r.Kind == jexl.TypeString) {
+			c.report(jexl.SeverityError, at, "cannot compare %s and %s", l, r)
+		}
+		return booleanType
+	case "&", "|", "^", "<<", ">>", ">>>":
+		bitwise := func(t *jexl.Type) bool {
+			return loose(t) || t.Kind == jexl.TypeInteger || t.Kind == jexl.TypeBoolean
+		}
+		if !bitwise(l) || !bitwise(r) {
+			return invalid()
+		}
+		if l.Kind == jexl.TypeBoolean && r.Kind == jexl.TypeBoolean {
+			return booleanType
+		}
+		return integerType
+	case "=~", "!~":
+		// Справа строка - регулярное выражение, коллекция - проверка вхождения
+		if r.Kind == jexl.TypeString && !loose(l) && l.Kind != jexl.TypeString {
+			c.report(jexl.SeverityError, at, "operator %s expects a string operand, got %s", op, l)
+		}
+		return booleanType
+	case "=^", "!^", "=$", "!$":
+		if !loose(l) && l.Kind != jexl.TypeString || !loose(r) && r.Kind != jexl.TypeString {
+			return invalid()
+		}
+		return booleanType
+	case "in", "!in":
+		switch r.Kind {
+		case jexl.TypeBoolean, jexl.TypeInteger, jexl.TypeNumber, jexl.TypeFunction:
+			c.report(jexl.SeverityError, at, "operator %s expects a collection or string, got %s", op, r)
+		}
+		return booleanType
+	case "==", "!=", "eq", "ne", "instanceof", "!instanceof":
+		return booleanType
+	}
+	return anyType
+}
+
+// guards возвращает пути, которые не равны null, если условие cond равно truth.
+func guards(cond jexl.Node, truth bool) []string {
+	switch n := cond.(type) {
+	case *jexl.BinaryOpNode:
+		switch n.Op() {
+		case "!=", "ne", "==", "eq":
+			if truth != (n.Op() == "!=" || n.Op() == "ne") {
+				return nil
+			}
+			if lit, ok := n.Right().(*jexl.LiteralNode); ok && lit.Value() == nil {
+				return nonEmpty(pathKey(n.Left()))
+			}
+			if lit, ok := n.Left().(*jexl.LiteralNode); ok && lit.Value() == nil {
+				return nonEmpty(pathKey(n.Right()))
+			}
+		case "&&", "and":
+			if truth {
+				return append(guards(n.Left(), true), guards(n.Right(), true)...)
+			}
+		case "||", "or":
+			if !truth {
+				return append(guards(n.Left(), false), guards(n.Right(), false)...)
+			}
+		}
+	case *jexl.UnaryOpNode:
+		if n.Op() == "!" {
+			return guards(n.Operand(), !truth)
+		}
+	case *jexl.MethodCallNode:
+		ident, ok := n.Method().(*jexl.IdentifierNode)
+		if ok && !truth && n.Target() == nil && n.Namespace() == "" && ident.Name() == "empty" && len(n.Args()) == 1 {
+			return nonEmpty(pathKey(n.Args()[0]))
+		}
+	case *jexl.IdentifierNode, *jexl.PropertyAccessNode, *jexl.IndexAccessNode:
+		if truth {
+			return nonEmpty(pathKey(n))
+		}
+	}
+	return nil
+}
+
+// nonEmpty возвращает список из пути, если он задан.
+func nonEmpty(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return []string{path}
+}
+
+// pathKey возвращает запись пути доступа для сужения типа или "", если узел - не путь.
+func pathKey(node jexl.Node) string {
+	switch n := node.(type) {
+	case *jexl.IdentifierNode:
+		return n.Name()
+	case *jexl.PropertyAccessNode:
+		if prop, ok := n.Property().(*jexl.IdentifierNode); ok {
+			if base := pathKey(n.Object()); base != "" {
+				return base + "." + prop.Name()
+			}
+		}
+	case *jexl.IndexAccessNode:
+		if lit, ok := n.Index().(*jexl.LiteralNode); ok {
+			if base := pathKey(n.Object()); base != "" {
+				return fmt.Sprintf("%s[%#v]", base, lit.Value())
+			}
+		}
+	}
+	return ""
+}
+
+// literalType возвращает тип значения литерала.
+func literalType(value any) *jexl.Type {
+	switch value.(type) {
+	case nil:
+		return nullType
+	case bool:
+		return booleanType
+	case string, *regexp.Regexp:
+		return stringType
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, *big.Int:
+		return integerType
+	case float32, float64, *big.Float, *big.Rat:
+		return numberType
+	}
+	return anyType
+}
+
+// collectionResult возвращает тип результата встроенного метода коллекции.
+func collectionResult(t *jexl.Type, name string) *jexl.Type {
+	elem := orAny(t.Elem)
+	switch name {
+	case "filter", "sortBy", "distinct", "take", "skip":
+		return notNull(t)
+	case "find", "min", "max":
+		return elem.OrNull()
+	case "any", "all":
+		return booleanType
+	case "sum", "avg":
+		return numberType
+	case "join":
+		return stringType
+	case "groupBy":
+		return jexl.MapOf(jexl.ListOf(elem))
+	case "values":
+		return jexl.ListOf(elem)
+	case "map", "flatMap", "keys", "entries":
+		return jexl.ListOf(anyType)
+	}
+	return anyType
+}
+
+// numeric сообщает, является ли тип числовым.
+func numeric(t *jexl.Type) bool {
+	return t.Kind == jexl.TypeInteger || t.Kind == jexl.TypeNumber
+}
+
+// loose сообщает, допускает ли тип любые операции: any и null.
+func loose(t *jexl.Type) bool {
+	return t.Kind == jexl.TypeAny || t.Kind == jexl.TypeNull
+}
+
+// arithmetic возвращает тип результата арифметической операции.
+func arithmetic(l, r *jexl.Type) *jexl.Type {
+	switch {
+	case l.Kind == jexl.TypeInteger && r.Kind == jexl.TypeInteger:
+		return integerType
+	case numeric(l) && numeric(r):
+		return numberType
+	}
+	return anyType
+}
+
+// join возвращает общий тип двух значений.
+func join(a, b *jexl.Type) *jexl.Type {
+	switch {
+	case a.Kind == jexl.TypeNull:
+		return b.OrNull()
+	case b.Kind == jexl.TypeNull:
+		return a.OrNull()
+	case a.Kind != b.Kind:
+		if numeric(a) && numeric(b) {
+			return numberType
+		}
+		return anyType
+	case a.Nullable || !b.Nullable:
+		return a
+	}
+	return b
+}
+
+// assignable проверяет, можно ли присвоить значение типа value цели типа target.
+func assignable(value, target *jexl.Type) bool {
+	switch {
+	case value.Kind == jexl.TypeAny || target.Kind == jexl.TypeAny:
+		return true
+	case value.Kind == jexl.TypeNull:
+		switch target.Kind {
+		case jexl.TypeList, jexl.TypeMap, jexl.TypeFunction:
+			return true
+		}
+		return target.Nullable || target.GoType != nil && target.GoType.Kind() == reflect.Ptr
+	case numeric(value) && numeric(target):
+		return true
+	}
+	return value.Kind == target.Kind
+}
+
+// notNull возвращает тип без null.
+func notNull(t *jexl.Type) *jexl.Type {
+	if !t.Nullable || t.Kind == jexl.TypeNull {
+		return t
+	}
+	nonNull := *t
+	nonNull.Nullable = false
+	return &nonNull
+}
+
+// orAny возвращает t или any, если тип не задан.
+func orAny(t *jexl.Type) *jexl.Type {
+	if t == nil {
+		return anyType
+	}
+	return t
+}
+
+// arityRange записывает допустимое число аргументов: 1, 1 to 2, at least 0.
+func arityRange(min, max int) string {
+	switch {
+	case max < 0:
+		return fmt.Sprintf("at least %d", min)
+	case min == max:
+		return strconv.Itoa(min)
+	}
+	return fmt.Sprintf("%d to %d", min, max)
+}
+
+// arityMessage формирует сообщение о неверном числе аргументов функции.
+func arityMessage(name string, params int, variadic bool, count int) string {
+	if variadic {
+		return fmt.Sprintf("%s() takes %s argument(s), got %d", name, arityRange(params-1, -1), count)
+	}
+	return fmt.Sprintf("%s() takes %s argument(s), got %d", name, arityRange(params, params), count)
+}
@@ -97,6 +97,7 @@ type uberspectImpl struct {
 	logger      jexl.Logger
 	strategy    jexl.ResolverStrategy
 	permissions *jexl.Permissions
+	arithmetic  jexl.Arithmetic // для встроенных методов коллекций
 }
 
 func (u *uberspectImpl) GetProperty(obj any, identifier string) jexl.PropertyGet {
@@ -121,6 +122,11 @@ func (u *uberspectImpl) GetProperty(obj any, identifier string) jexl.PropertyGet
 		return nil
 	}
 
+	// Для упорядоченной мапы JEXL
+	if m, ok := obj.(*jexl.Map); ok {
+		return &jexlMapPropertyGet{m: m, key: identifier}
+	}
+
 	// Для мапов
 	if val.Kind() == reflect.Map {
 		keyVal := reflect.ValueOf(identifier)
@@ -211,6 +217,97 @@ func (u *uberspectImpl) GetProperty(obj any, identifier string) jexl.PropertyGet
 	return nil
 }
 
+// structFieldIndex возвращает индекс экспортированного поля структуры typ, которое
+// GetProperty выберет для identifier, или -1, если GetProperty обращается к свойству
+// иначе (неэкспортированные и встроенные поля, геттеры). Порядок поиска тот же:
+// точное имя, имя с заглавной буквы, имя без учёта регистра.
+func structFieldIndex(typ reflect.Type, identifier string) int {
+	if typ.Kind() != reflect.Struct || identifier == "" {
+		return -1
+	}
+	exported := func(field reflect.StructField) int {
+		if len(field.Index) == 1 && field.IsExported() {
+			return field.Index[0]
+		}
+		return -1
+	}
+	if field, ok := typ.FieldByName(identifier); ok {
+		return exported(field)
+	}
+	capitalizedName := strings.ToUpper(identifier[:1]) + identifier[1:]
+	if field, ok := typ.FieldByName(capitalizedName); ok {
+		return exported(field)
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); strings.EqualFold(field.Name, identifier) {
+			return exported(field)
+		}
+	}
+	return -1
+}
+
+// propertyType возвращает тип свойства identifier, которое GetProperty найдёт у значений
+// типа typ, или ok=false, если не найдёт ни у одного. Порядок поиска тот же: ключ мапы,
+// индекс слайса, поле структуры, геттер GetXxx, метод без параметров.
+// Для свойств, тип которых известен только при выполнении, возвращается nil.
+func propertyType(typ reflect.Type, identifier string) (reflect.Type, bool) {
+	if identifier == "" {
+		return nil, false
+	}
+	if typ == reflect.TypeOf((*jexl.Map)(nil)) {
+		return nil, true
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		return nil, false
+	case reflect.Map:
+		if typ.Key() == reflect.TypeOf(identifier) {
+			return typ.Elem(), true
+		}
+	case reflect.Slice, reflect.Array:
+		if _, err := strconv.Atoi(identifier); err == nil {
+			return typ.Elem(), true
+		}
+		return nil, false
+	case reflect.Ptr:
+		typ = typ.Elem()
+	}
+	if field, ok := structField(typ, identifier); ok {
+		return field.Type, true
+	}
+	for _, name := range []string{"Get" + strings.ToUpper(identifier[:1]) + identifier[1:], identifier} {
+		if method, ok := typ.MethodByName(name); ok && method.Type.NumIn() == 1 {
+			if method.Type.NumOut() == 0 {
+				return nil, true
+			}
+			return method.Type.Out(0), true
+		}
+	}
+	return nil, false
+}
+
+// structField находит поле структуры typ для свойства identifier: по имени,
+// с заглавной буквы или без учёта регистра, как propertyType.
+func structField(typ reflect.Type, identifier string) (reflect.StructField, bool) {
+	if typ.Kind() != reflect.Struct || identifier == "" {
+		return reflect.StructField{}, false
+	}
+	if field, ok := typ.FieldByName(identifier); ok {
+		return field, true
+	}
+	capitalizedName := strings.ToUpper(identifier[:1]) + identifier[1:]
+	if field, ok := typ.FieldByName(capitalizedName); ok && field.IsExported() {
+		return field, true
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if field := typ.Field(i); strings.EqualFold(field.Name, identifier) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
 func (u *uberspectImpl) SetProperty(obj any, identifier string, value any) jexl.PropertySet {
 	if obj == nil {
 		return nil
@@ -221,6 +318,11 @@ func (u *uberspectImpl) SetProperty(obj any, identifier string, value any) jexl.
 		return nil
 	}
 
+	// Для упорядоченной мапы JEXL
+	if m, ok := obj.(*jexl.Map); ok {
+		return &jexlMapPropertySet{m: m, key: identifier}
+	}
+
 	// Для мапов
 	if val.Kind() == reflect.Map {
 		keyVal := reflect.ValueOf(identifier)
@@ -285,22 +387,43 @@ func (u *uberspectImpl) GetMethod(obj any, name string, args []any) (jexl.Method
 	}
 
 	// Специальная обработка для строк (в Go строки не имеют методов)
-	if val.Kind() == reflect.String {
-		str := val.String()
+	if str, ok := stringReceiver(val); ok {
 		return u.getStringMethod(str, name, args)
 	}
 
-	// Для указателей, разыменовываем для получения типа
-	typ := val.Type()
-	if typ.Kind() == reflect.Ptr {
-		typ = typ.Elem()
-		// Если указатель указывает на строку, обрабатываем как строку
-		if typ.Kind() == reflect.String && !val.IsNil() {
-			str := val.Elem().String()
-			return u.getStringMethod(str, name, args)
+	bestMethod, found, err := u.findGoMethod(val, name, args)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		// Встроенные методы коллекций: map, filter, reduce и т.д.
+		if method, err := u.getCollectionMethod(obj, name, args); method != nil || err != nil {
+			return method, err
 		}
+		return nil, jexl.NewError(fmt.Sprintf("method %s not found", name))
 	}
 
+	return &reflectionMethod{
+		method: boundMethod(val, bestMethod.Name),
+		name:   name,
+	}, nil
+}
+
+// stringReceiver возвращает строку, если методы значения - методы строки
+// (строка или ненулевой указатель на строку).
+func stringReceiver(val reflect.Value) (string, bool) {
+	if val.Kind() == reflect.String {
+		return val.String(), true
+	}
+	if val.Kind() == reflect.Ptr && val.Type().Elem().Kind() == reflect.String && !val.IsNil() {
+		return val.Elem().String(), true
+	}
+	return "", false
+}
+
+// findGoMethod ищет метод Go с именем name (или с заглавной буквы), наиболее подходящий
+// аргументам. found=false, если методов с таким именем нет.
+func (u *uberspectImpl) findGoMethod(val reflect.Value, name string, args []any) (reflect.Method, bool, error) {
 	// Собираем все кандидаты методов
 	var candidates []reflect.Method
 	
@@ -351,23 +474,85 @@ func (u *uberspectImpl) GetMethod(obj any, name string, args []any) (jexl.Method
 	}
 
 	if len(candidates) == 0 {
-		return nil, jexl.NewError(fmt.Sprintf("method %s not found", name))
+		return reflect.Method{}, false, nil
 	}
 
 	// Выбираем наиболее подходящий метод
-	bestMethod, err := u.selectBestMethod(candidates, val, args)
+	bestMethod, err := u.selectBestMethod(candidates, args)
 	if err != nil {
-		return nil, err
+		return reflect.Method{}, true, err
 	}
+	return bestMethod, true, nil
+}
 
-	return &reflectionMethod{
-		method: bestMethod,
-		name:   name,
-	}, nil
+// goMethods возвращает методы Go, среди которых findGoMethod выбирает вызываемый
+// для значений типа typ: с именем name или с заглавной буквы.
+func goMethods(typ reflect.Type, name string) []reflect.Method {
+	if name == "" {
+		return nil
+	}
+	nameVariants := []string{name, strings.ToUpper(name[:1]) + name[1:]}
+	var candidates []reflect.Method
+	collect := func(t reflect.Type) {
+		for _, variant := range nameVariants {
+			m, ok := t.MethodByName(variant)
+			if !ok {
+				continue
+			}
+			found := false
+			for _, c := range candidates {
+				if c.Name == m.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				candidates = append(candidates, m)
+			}
+		}
+	}
+	collect(typ)
+	if typ.Kind() == reflect.Ptr {
+		collect(typ.Elem())
+	}
+	return candidates
+}
+
+// acceptsArgCount проверяет, подходит ли методу Go число аргументов, как scoreMethod.
+func acceptsArgCount(method reflect.Method, count int) bool {
+	numIn := method.Type.NumIn() - 1 // -1 для receiver
+	return numIn == count || method.Type.IsVariadic() && numIn <= count+1
+}
+
+// goMethodIndex возвращает индекс метода Go, который GetMethod выберет для obj и args:
+// метод самого значения или (elem=true) разыменованного указателя. ok=false, если
+// GetMethod разрешает вызов иначе (hashCode, методы строк и коллекций).
+// Выбор зависит только от типов получателя и аргументов.
+func (u *uberspectImpl) goMethodIndex(obj any, name string, args []any) (index int, elem bool, ok bool) {
+	val := reflect.ValueOf(obj)
+	if obj == nil || (name == "hashCode" && len(args) == 0) {
+		return 0, false, false
+	}
+	if _, isString := stringReceiver(val); isString {
+		return 0, false, false
+	}
+	bestMethod, found, err := u.findGoMethod(val, name, args)
+	if !found || err != nil {
+		return 0, false, false
+	}
+	if m, ok := val.Type().MethodByName(bestMethod.Name); ok {
+		return m.Index, false, true
+	}
+	if val.Kind() == reflect.Ptr && !val.IsNil() {
+		if m, ok := val.Elem().Type().MethodByName(bestMethod.Name); ok {
+			return m.Index, true, true
+		}
+	}
+	return 0, false, false
 }
 
 // selectBestMethod выбирает наиболее подходящий метод из кандидатов.
-func (u *uberspectImpl) selectBestMethod(candidates []reflect.Method, val reflect.Value, args []any) (reflect.Value, error) {
+func (u *uberspectImpl) selectBestMethod(candidates []reflect.Method, args []any) (reflect.Method, error) {
 	var bestMethod reflect.Method
 	bestScore := -1
 
@@ -380,16 +565,18 @@ func (u *uberspectImpl) selectBestMethod(candidates []reflect.Method, val reflec
 	}
 
 	if bestScore < 0 {
-		return reflect.Value{}, jexl.NewError("no compatible method found")
+		return reflect.Method{}, jexl.NewError("no compatible method found")
 	}
+	return bestMethod, nil
+}
 
-	// Получаем reflect.Value метода
-	methodVal := val.MethodByName(bestMethod.Name)
+// boundMethod возвращает метод name, привязанный к значению или к разыменованному указателю.
+func boundMethod(val reflect.Value, name string) reflect.Value {
+	methodVal := val.MethodByName(name)
 	if !methodVal.IsValid() && val.Kind() == reflect.Ptr && !val.IsNil() {
-		methodVal = val.Elem().MethodByName(bestMethod.Name)
+		methodVal = val.Elem().MethodByName(name)
 	}
-
-	return methodVal, nil
+	return methodVal
 }
 
 // scoreMethod вычисляет оценку соответствия метода аргументам.
@@ -579,6 +766,16 @@ func (m *mapPropertyGet) Invoke(obj any) (any, error) {
 	return result.Interface(), nil
 }
 
+type jexlMapPropertyGet struct {
+	m   *jexl.Map
+	key string
+}
+
+func (m *jexlMapPropertyGet) Invoke(obj any) (any, error) {
+	value, _ := m.m.Get(m.key)
+	return value, nil
+}
+
 type slicePropertyGet struct {
 	sliceVal reflect.Value
 	index    int
@@ -674,6 +871,16 @@ func (m *mapPropertySet) Invoke(obj any, value any) error {
 	return nil
 }
 
+type jexlMapPropertySet struct {
+	m   *jexl.Map
+	key string
+}
+
+func (m *jexlMapPropertySet) Invoke(obj any, value any) error {
+	m.m.Put(m.key, value)
+	return nil
+}
+
 type slicePropertySet struct {
 	sliceVal  reflect.Value
 	index     int
@@ -707,100 +914,6 @@ func (s *slicePropertySet) Invoke(obj any, value any) error {
 }
 
 // getStringMethod возвращает метод для строки
-func (u *uberspectImpl) getStringMethod(str string, name string, args []any) (jexl.Method, error) {
-	switch name {
-	case "toLowerCase":
-		if len(args) != 0 {
-			return nil, jexl.NewError("toLowerCase() takes no arguments")
-		}
-		return &stringMethod{
-			name: name,
-			fn: func(s string, _ []any) (any, error) {
-				return strings.ToLower(s), nil
-			},
-		}, nil
-	case "toUpperCase":
-		if len(args) != 0 {
-			return nil, jexl.NewError("toUpperCase() takes no arguments")
-		}
-		return &stringMethod{
-			name: name,
-			fn: func(s string, _ []any) (any, error) {
-				return strings.ToUpper(s), nil
-			},
-		}, nil
-	case "substring":
-		if len(args) < 1 || len(args) > 2 {
-			return nil, jexl.NewError("substring() takes 1 or 2 arguments")
-		}
-		return &stringMethod{
-			name: name,
-			fn: func(s string, a []any) (any, error) {
-				// Преобразуем первый аргумент в int
-				start, err := u.toInt(a[0])
-				if err != nil {
-					return nil, err
-				}
-				if start < 0 || start > len(s) {
-					return nil, jexl.NewError("substring start index out of bounds")
-				}
-				
-				if len(a) == 1 {
-					// substring(start) - от start до конца
-					return s[start:], nil
-				}
-				
-				// substring(start, end) - от start до end
-				end, err := u.toInt(a[1])
-				if err != nil {
-					return nil, err
-				}
-				if end < start || end > len(s) {
-					return nil, jexl.NewError("substring end index out of bounds")
-				}
-				return s[start:end], nil
-			},
-		}, nil
-	case "length":
-		if len(args) != 0 {
-			return nil, jexl.NewError("length() takes no arguments")
-		}
-		return &stringMethod{
-			name: name,
-			fn: func(s string, _ []any) (any, error) {
-				return int64(len(s)), nil
-			},
-		}, nil
-	case "toString":
-		if len(args) != 0 {
-			return nil, jexl.NewError("toString() takes no arguments")
-		}
-		return &stringMethod{
-			name: name,
-			fn: func(s string, _ []any) (any, error) {
-				return s, nil
-			},
-		}, nil
-	case "hashCode":
-		if len(args) != 0 {
-			return nil, jexl.NewError("hashCode() takes no arguments")
-		}
-		return &stringMethod{
-			name: name,
-			fn: func(s string, _ []any) (any, error) {
-				// Вычисляем hash код строки (аналог Java String.hashCode())
-				hash := int32(0)
-				for _, c := range s {
-					hash = 31*hash + int32(c)
-				}
-				return int64(hash), nil
-			},
-		}, nil
-	default:
-		return nil, jexl.NewError(fmt.Sprintf("string method %s not found", name))
-	}
-}
-
 // getHashCodeMethod возвращает метод hashCode для любого объекта.
 func (u *uberspectImpl) getHashCodeMethod(obj any) (jexl.Method, error) {
 	return &hashCodeMethod{
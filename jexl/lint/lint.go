@@ -0,0 +1,207 @@
+// Package lint проверяет скрипты JEXL на ошибки стиля и безопасности:
+// неиспользуемые локальные переменные, присваивание в условии, затенение
+// имён, недостижимый код, константные условия, пустые блоки catch,
+// сравнение несовместимых литералов и конструкции, запрещённые политикой.
+//
+// Каждое правило имеет идентификатор и важность. Сообщения подавляются
+// комментарием "// lint:ignore rule-id" на строке сообщения или строкой выше,
+// а для всего скрипта - директивой "#pragma lint.disable rule-id".
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/internal"
+)
+
+// SyntaxRule - идентификатор сообщений об ошибках разбора.
+const SyntaxRule = "syntax"
+
+// Rule - правило проверки AST.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    jexl.Severity // важность по умолчанию
+	Check       func(p *Pass)
+}
+
+// Config - набор правил и политика.
+type Config struct {
+	Rules    []*Rule                  // правила; nil - DefaultRules()
+	Disabled []string                 // идентификаторы отключённых правил
+	Severity map[string]jexl.Severity // важность правил вместо заданной по умолчанию
+	// Policy - разрешённые конструкции для правила disabled-feature;
+	// nil разрешает всё. Лексическая область видимости также берётся из политики.
+	Policy *jexl.Features
+}
+
+// Issue - сообщение правила.
+type Issue struct {
+	File string // имя файла; заполняется вызывающей стороной
+	Rule string
+	jexl.Diagnostic
+}
+
+// String форматирует сообщение в виде file:line:column: severity: message [rule].
+func (i Issue) String() string {
+	s := i.Diagnostic.String() + " [" + i.Rule + "]"
+	if i.File != "" {
+		s = i.File + ":" + s
+	}
+	return s
+}
+
+// jsonFix - исправление в JSON-выводе.
+type jsonFix struct {
+	Message   string `json:"message"`
+	Offset    int    `json:"offset"`
+	EndOffset int    `json:"endOffset"`
+	NewText   string `json:"newText"`
+}
+
+// jsonIssue - сообщение в JSON-выводе.
+type jsonIssue struct {
+	File      string   `json:"file,omitempty"`
+	Rule      string   `json:"rule"`
+	Severity  string   `json:"severity"`
+	Message   string   `json:"message"`
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	EndLine   int      `json:"endLine"`
+	EndColumn int      `json:"endColumn"`
+	Offset    int      `json:"offset"`
+	EndOffset int      `json:"endOffset"`
+	Fix       *jsonFix `json:"fix,omitempty"`
+}
+
+// MarshalJSON кодирует сообщение плоским объектом с положением и исправлением.
+func (i Issue) MarshalJSON() ([]byte, error) {
+	r := i.Range
+	out := jsonIssue{
+		File: i.File, Rule: i.Rule, Severity: i.Severity.String(), Message: i.Message,
+		Line: r.Line, Column: r.Column, EndLine: r.EndLine, EndColumn: r.EndColumn,
+		Offset: r.Offset, EndOffset: r.EndOffset,
+	}
+	if f := i.Fix; f != nil {
+		out.Fix = &jsonFix{Message: f.Message, Offset: f.Range.Offset, EndOffset: f.Range.EndOffset, NewText: f.NewText}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(out); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// WriteJSON записывает сообщения в w массивом JSON.
+func WriteJSON(w io.Writer, issues []Issue) error {
+	if issues == nil {
+		issues = []Issue{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(issues)
+}
+
+// Pass - проверка одного скрипта правилом.
+type Pass struct {
+	Script *jexl.ScriptNode
+	Source string // исходный текст; пуст, если проверяется готовый AST
+	Policy *jexl.Features
+
+	linter *linter
+	rule   *Rule
+}
+
+// Report добавляет сообщение правила с необязательным исправлением.
+func (p *Pass) Report(pos jexl.Position, message string, fix *jexl.SuggestedFix) {
+	severity := p.rule.Severity
+	if s, ok := p.linter.config.Severity[p.rule.ID]; ok {
+		severity = s
+	}
+	p.linter.issues = append(p.linter.issues, Issue{
+		Rule:       p.rule.ID,
+		Diagnostic: jexl.Diagnostic{Severity: severity, Message: message, Range: pos, Fix: fix},
+	})
+}
+
+// Reportf добавляет сообщение о узле.
+func (p *Pass) Reportf(node jexl.Node, format string, args ...any) {
+	p.Report(node.Position(), fmt.Sprintf(format, args...), nil)
+}
+
+// resolve возвращает локальные имена скрипта, разрешая их при первом вызове.
+func (p *Pass) resolve() *resolution {
+	if p.linter.resolution == nil {
+		lexical := p.Policy != nil && p.Policy.IsLexical()
+		if features := p.Script.Features(); features != nil {
+			lexical = features.IsLexical()
+		}
+		p.linter.resolution = resolve(p.Script, lexical)
+	}
+	return p.linter.resolution
+}
+
+// linter хранит общее состояние проверки скрипта всеми правилами.
+type linter struct {
+	config     *Config
+	issues     []Issue
+	resolution *resolution
+}
+
+// Source разбирает и проверяет исходный текст скрипта. Ошибки разбора
+// возвращаются с правилом SyntaxRule; правила AST для такого текста не выполняются.
+func Source(source string, config *Config) []Issue {
+	ast, diagnostics := internal.NewDefaultParser().ParseDiagnostics(nil, source, nil)
+	if len(diagnostics) > 0 {
+		issues := make([]Issue, len(diagnostics))
+		for i, d := range diagnostics {
+			issues[i] = Issue{Rule: SyntaxRule, Diagnostic: d}
+		}
+		return issues
+	}
+	return run(ast, source, config)
+}
+
+// Script проверяет готовый AST. Комментарии подавления недоступны,
+// действует только pragma lint.disable скрипта.
+func Script(ast *jexl.ScriptNode, config *Config) []Issue {
+	return run(ast, "", config)
+}
+
+// run выполняет правила и отбрасывает подавленные сообщения.
+func run(ast *jexl.ScriptNode, source string, config *Config) []Issue {
+	if config == nil {
+		config = &Config{}
+	}
+	rules := config.Rules
+	if rules == nil {
+		rules = DefaultRules()
+	}
+	l := &linter{config: config}
+	suppressed := newSuppressions(source, ast)
+	for _, rule := range rules {
+		if slices.Contains(config.Disabled, rule.ID) || suppressed.script(rule.ID) {
+			continue
+		}
+		rule.Check(&Pass{Script: ast, Source: source, Policy: config.Policy, linter: l, rule: rule})
+	}
+	issues := slices.DeleteFunc(l.issues, func(i Issue) bool {
+		return suppressed.line(i.Rule, i.Range.Line)
+	})
+	slices.SortStableFunc(issues, func(a, b Issue) int {
+		if a.Range.Offset != b.Range.Offset {
+			return a.Range.Offset - b.Range.Offset
+		}
+		return strings.Compare(a.Rule, b.Rule)
+	})
+	return issues
+}
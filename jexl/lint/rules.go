@@ -0,0 +1,350 @@
+package lint
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// DefaultRules возвращает все встроенные правила.
+func DefaultRules() []*Rule {
+	return []*Rule{
+		{ID: "unused-local", Severity: jexl.SeverityWarning, Check: checkUnusedLocals,
+			Description: "local variable or function is declared but never read"},
+		{ID: "assign-in-condition", Severity: jexl.SeverityWarning, Check: checkAssignInCondition,
+			Description: "assignment used as a condition, probably instead of =="},
+		{ID: "shadow", Severity: jexl.SeverityWarning, Check: checkShadow,
+			Description: "declaration hides a variable of an enclosing scope"},
+		{ID: "unreachable", Severity: jexl.SeverityWarning, Check: checkUnreachable,
+			Description: "statement after return, break or continue is never executed"},
+		{ID: "constant-condition", Severity: jexl.SeverityWarning, Check: checkConstantCondition,
+			Description: "condition does not depend on variables"},
+		{ID: "empty-catch", Severity: jexl.SeverityWarning, Check: checkEmptyCatch,
+			Description: "catch block silently ignores the error"},
+		{ID: "incompatible-equality", Severity: jexl.SeverityWarning, Check: checkIncompatibleEquality,
+			Description: "== or != between values of different literal types relies on coercion"},
+		{ID: "disabled-feature", Severity: jexl.SeverityError, Check: checkDisabledFeatures,
+			Description: "construct is disabled by the feature policy"},
+	}
+}
+
+// checkUnusedLocals сообщает о локальных переменных и функциях, которые не читаются.
+// Параметры, переменные циклов и имена, начинающиеся с "_", не проверяются.
+func checkUnusedLocals(p *Pass) {
+	for _, b := range p.resolve().bindings {
+		if b.used || !b.pos.IsValid() || strings.HasPrefix(b.name, "_") {
+			continue
+		}
+		switch b.kind {
+		case bindVar:
+			p.Report(b.pos, fmt.Sprintf("local variable %s is never read", b.name), nil)
+		case bindFunction:
+			p.Report(b.pos, fmt.Sprintf("function %s is never called", b.name), nil)
+		}
+	}
+}
+
+// checkShadow сообщает об объявлениях, скрывающих имена внешних областей.
+func checkShadow(p *Pass) {
+	for _, s := range p.resolve().shadows {
+		if !s.inner.pos.IsValid() {
+			continue
+		}
+		if s.outer.pos.IsValid() {
+			p.Report(s.inner.pos, fmt.Sprintf("%s shadows the declaration at %v", s.inner.name, s.outer.pos), nil)
+		} else {
+			p.Report(s.inner.pos, fmt.Sprintf("%s shadows a parameter of the script", s.inner.name), nil)
+		}
+	}
+}
+
+// conditions вызывает f для условий ветвлений и циклов; loop - условие цикла.
+func conditions(script *jexl.ScriptNode, f func(cond jexl.Node, loop bool)) {
+	jexl.Inspect(script, func(node jexl.Node) bool {
+		switch n := node.(type) {
+		case *jexl.IfNode:
+			f(n.Condition(), false)
+		case *jexl.TernaryNode:
+			f(n.Condition(), false)
+		case *jexl.WhileNode:
+			f(n.Condition(), true)
+		case *jexl.DoWhileNode:
+			f(n.Condition(), true)
+		case *jexl.ForNode:
+			if n.Condition() != nil {
+				f(n.Condition(), true)
+			}
+		}
+		return true
+	})
+}
+
+// checkAssignInCondition сообщает о простом присваивании в условии
+// и предлагает заменить = на ==.
+func checkAssignInCondition(p *Pass) {
+	conditions(p.Script, func(cond jexl.Node, _ bool) {
+		n, ok := cond.(*jexl.AssignmentNode)
+		if !ok || compound(n) {
+			return
+		}
+		var fix *jexl.SuggestedFix
+		target, value := n.Target().Position(), n.Value().Position()
+		if p.Source != "" && target.EndOffset <= value.Offset && value.Offset <= len(p.Source) {
+			between := p.Source[target.EndOffset:value.Offset]
+			if i := strings.IndexByte(between, '='); i >= 0 && strings.TrimSpace(between) == "=" {
+				offset := target.EndOffset + i
+				fix = &jexl.SuggestedFix{
+					Message: "replace '=' with '=='",
+					Range:   sourcePosition(p.Source, offset, offset+1),
+					NewText: "==",
+				}
+			}
+		}
+		p.Report(n.Position(), "assignment in condition; did you mean ==?", fix)
+	})
+}
+
+// sourcePosition вычисляет строки и столбцы диапазона смещений исходного текста.
+func sourcePosition(source string, start, end int) jexl.Position {
+	pos := jexl.Position{Offset: start, EndOffset: end}
+	pos.Line, pos.Column = lineColumn(source, start)
+	pos.EndLine, pos.EndColumn = lineColumn(source, end)
+	return pos
+}
+
+// lineColumn возвращает строку и столбец (в символах) смещения.
+func lineColumn(source string, offset int) (int, int) {
+	lineStart := strings.LastIndexByte(source[:offset], '\n') + 1
+	return 1 + strings.Count(source[:lineStart], "\n"), 1 + utf8.RuneCountInString(source[lineStart:offset])
+}
+
+// compound проверяет, является ли присваивание составным (x += y, x ??= y).
+func compound(n *jexl.AssignmentNode) bool {
+	switch v := n.Value().(type) {
+	case *jexl.BinaryOpNode:
+		return v.Left() == n.Target()
+	case *jexl.ElvisNode:
+		return v.Expr() == n.Target()
+	}
+	return false
+}
+
+// checkConstantCondition сообщает об условиях из одних литералов.
+// Бесконечный цикл while (true) не считается ошибкой.
+func checkConstantCondition(p *Pass) {
+	conditions(p.Script, func(cond jexl.Node, loop bool) {
+		if !constant(cond) {
+			return
+		}
+		if lit, ok := cond.(*jexl.LiteralNode); ok && loop && lit.Value() == true {
+			return
+		}
+		p.Reportf(cond, "condition is constant")
+	})
+}
+
+// constant проверяет, вычисляется ли выражение из одних литералов.
+func constant(node jexl.Node) bool {
+	switch n := node.(type) {
+	case *jexl.LiteralNode:
+		return true
+	case *jexl.UnaryOpNode:
+		return constant(n.Operand())
+	case *jexl.BinaryOpNode:
+		return constant(n.Left()) && constant(n.Right())
+	}
+	return false
+}
+
+// checkUnreachable сообщает об инструкциях после return, break и continue.
+func checkUnreachable(p *Pass) {
+	jexl.Inspect(p.Script, func(node jexl.Node) bool {
+		var statements []jexl.Node
+		switch n := node.(type) {
+		case *jexl.ScriptNode:
+			statements = n.Children()
+		case *jexl.BlockNode:
+			statements = n.Statements()
+		default:
+			return true
+		}
+		for i, stmt := range statements[:max(len(statements)-1, 0)] {
+			if !terminates(stmt) {
+				continue
+			}
+			first, last := statements[i+1].Position(), statements[len(statements)-1].Position()
+			if first.IsValid() {
+				first.EndOffset, first.EndLine, first.EndColumn = last.EndOffset, last.EndLine, last.EndColumn
+				p.Report(first, "unreachable code", nil)
+			}
+			break
+		}
+		return true
+	})
+}
+
+// terminates проверяет, передаёт ли инструкция управление за пределы блока на любом пути.
+func terminates(node jexl.Node) bool {
+	switch n := node.(type) {
+	case *jexl.ReturnNode, *jexl.BreakNode, *jexl.ContinueNode:
+		return true
+	case *jexl.BlockNode:
+		for _, stmt := range n.Statements() {
+			if terminates(stmt) {
+				return true
+			}
+		}
+	case *jexl.IfNode:
+		return n.ElseBranch() != nil && terminates(n.ThenBranch()) && terminates(n.ElseBranch())
+	}
+	return false
+}
+
+// checkEmptyCatch сообщает о пустых блоках catch. Блок с комментарием
+// считается намеренно пустым.
+func checkEmptyCatch(p *Pass) {
+	jexl.Inspect(p.Script, func(node jexl.Node) bool {
+		n, ok := node.(*jexl.TryNode)
+		if !ok || !n.HasCatch() {
+			return true
+		}
+		block := n.CatchBlock()
+		if b, ok := block.(*jexl.BlockNode); block != nil && (!ok || len(b.Statements()) > 0) {
+			return true
+		}
+		pos := n.Position()
+		if block != nil {
+			pos = block.Position()
+			if p.Source != "" && pos.EndOffset <= len(p.Source) &&
+				strings.Trim(p.Source[pos.Offset:pos.EndOffset], "{} \t\r\n;") != "" {
+				return true
+			}
+		}
+		p.Report(pos, "empty catch block ignores the error", nil)
+		return true
+	})
+}
+
+// checkIncompatibleEquality сообщает о == и != между значениями разных
+// литеральных типов: такие сравнения зависят от приведения типов.
+func checkIncompatibleEquality(p *Pass) {
+	jexl.Inspect(p.Script, func(node jexl.Node) bool {
+		n, ok := node.(*jexl.BinaryOpNode)
+		if !ok || n.Op() != "==" && n.Op() != "!=" {
+			return true
+		}
+		left, right := literalKind(n.Left()), literalKind(n.Right())
+		if left != "" && right != "" && left != right && left != "null" && right != "null" {
+			p.Reportf(n, "%s compares %s with %s", n.Op(), left, right)
+		}
+		return true
+	})
+}
+
+// literalKind возвращает тип значения, известный без выполнения, или "".
+func literalKind(node jexl.Node) string {
+	switch n := node.(type) {
+	case *jexl.LiteralNode:
+		switch v := n.Value().(type) {
+		case nil:
+			return "null"
+		case bool:
+			return "boolean"
+		case string:
+			return "string"
+		case *big.Int, *big.Float, *big.Rat:
+			return "number"
+		default:
+			switch reflect.ValueOf(v).Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+				reflect.Float32, reflect.Float64:
+				return "number"
+			}
+		}
+	case *jexl.ArrayLiteralNode:
+		return "array"
+	case *jexl.MapLiteralNode:
+		return "map"
+	case *jexl.SetLiteralNode:
+		return "set"
+	case *jexl.UnaryOpNode:
+		switch n.Op() {
+		case "!":
+			return "boolean"
+		case "-", "+":
+			if literalKind(n.Operand()) == "number" {
+				return "number"
+			}
+		}
+	case *jexl.BinaryOpNode:
+		switch n.Op() {
+		case "==", "!=", "<", "<=", ">", ">=", "&&", "||", "=~", "!~", "=^", "!^", "=$", "!$":
+			return "boolean"
+		}
+	}
+	return ""
+}
+
+// checkDisabledFeatures сообщает о конструкциях, запрещённых политикой.
+func checkDisabledFeatures(p *Pass) {
+	policy := p.Policy
+	if policy == nil {
+		return
+	}
+	report := func(node jexl.Node, feature jexl.Feature, what string) {
+		if !policy.Enabled(feature) {
+			p.Reportf(node, "%s disabled by policy", what)
+		}
+	}
+	if len(p.Script.Pragmas()) > 0 {
+		report(p.Script, jexl.FeaturePragma, "pragmas are")
+	}
+	var visit func(node jexl.Node) bool
+	visit = func(node jexl.Node) bool {
+		switch n := node.(type) {
+		case *jexl.ForNode, *jexl.ForeachNode, *jexl.WhileNode, *jexl.DoWhileNode:
+			report(n, jexl.FeatureLoop, "loops are")
+		case *jexl.FunctionNode:
+			report(n, jexl.FeatureLambda, "functions are")
+			// О lambda функции не сообщается повторно
+			for _, child := range n.Lambda().Children() {
+				jexl.Inspect(child, visit)
+			}
+			return false
+		case *jexl.LambdaNode:
+			report(n, jexl.FeatureLambda, "lambdas are")
+		case *jexl.VarNode:
+			report(n, jexl.FeatureLocalVar, "local variables are")
+		case *jexl.DestructuringNode:
+			if n.IsDeclaration() {
+				report(n, jexl.FeatureLocalVar, "local variables are")
+			} else {
+				report(n, jexl.FeatureSideEffect, "assignments are")
+			}
+		case *jexl.AssignmentNode:
+			report(n, jexl.FeatureSideEffect, "assignments are")
+		case *jexl.MethodCallNode:
+			if n.Target() != nil {
+				report(n, jexl.FeatureMethodCall, "method calls are")
+			}
+		case *jexl.ArrayLiteralNode, *jexl.MapLiteralNode, *jexl.SetLiteralNode:
+			report(n, jexl.FeatureStructuredLiteral, "structured literals are")
+		}
+		return true
+	}
+	jexl.Inspect(p.Script, visit)
+	if policy.Enabled(jexl.FeatureSideEffect) {
+		seen := make(map[jexl.Node]bool)
+		for _, stmt := range p.resolve().globalWrites {
+			if !seen[stmt] {
+				seen[stmt] = true
+				report(stmt, jexl.FeatureSideEffectGlobal, "assignments to global variables are")
+			}
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package lint
+
+import "github.com/mentatxx/jexl-golang/jexl"
+
+// bindingKind - способ объявления локального имени.
+type bindingKind int
+
+const (
+	bindVar bindingKind = iota
+	bindFunction
+	bindParam
+	bindLoop
+	bindCatch
+)
+
+// binding - объявление локального имени.
+type binding struct {
+	name string
+	kind bindingKind
+	pos  jexl.Position // положение идентификатора; нулевое для параметров скрипта и catch
+	used bool          // значение читается
+}
+
+// shadowing - объявление, скрывающее имя внешней области.
+type shadowing struct {
+	inner, outer *binding
+}
+
+// resolution - локальные имена скрипта и обращения к ним.
+type resolution struct {
+	bindings     []*binding
+	shadows      []shadowing
+	globalWrites []jexl.Node // присваивания глобальным переменным
+}
+
+// resolver разрешает имена по тем же правилам видимости, что и интерпретатор.
+type resolver struct {
+	lexical bool // блоки открывают собственную область видимости
+	scopes  []map[string]*binding
+	res     *resolution
+}
+
+// resolve разрешает локальные имена скрипта.
+func resolve(script *jexl.ScriptNode, lexical bool) *resolution {
+	r := &resolver{lexical: lexical, scopes: []map[string]*binding{{}}, res: &resolution{}}
+	for _, def := range script.ParameterDefaults() {
+		r.walk(def)
+	}
+	for _, param := range script.Parameters() {
+		r.declare(param, bindParam, jexl.Position{})
+	}
+	for _, child := range script.Children() {
+		r.walk(child)
+	}
+	return r.res
+}
+
+// lookup находит объявление имени во всех видимых областях.
+func (r *resolver) lookup(name string) *binding {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if b := r.scopes[i][name]; b != nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// declare объявляет имя в текущей области. Повторное объявление в той же
+// области возвращает прежнее; скрытие имени внешней области запоминается.
+func (r *resolver) declare(name string, kind bindingKind, pos jexl.Position) {
+	scope := r.scopes[len(r.scopes)-1]
+	if scope[name] != nil {
+		return
+	}
+	b := &binding{name: name, kind: kind, pos: pos}
+	if outer := r.lookup(name); outer != nil {
+		r.res.shadows = append(r.res.shadows, shadowing{inner: b, outer: outer})
+	}
+	scope[name] = b
+	r.res.bindings = append(r.res.bindings, b)
+}
+
+// use отмечает чтение имени.
+func (r *resolver) use(name string) {
+	if b := r.lookup(name); b != nil {
+		b.used = true
+	}
+}
+
+// scoped выполняет f в новой области видимости. Блочная область создаётся
+// только в лексическом режиме, иначе имена видны до конца функции.
+func (r *resolver) scoped(block bool, f func()) {
+	if block && !r.lexical {
+		f()
+		return
+	}
+	r.scopes = append(r.scopes, map[string]*binding{})
+	f()
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// walk обходит узел, отмечая чтения локальных имён.
+func (r *resolver) walk(node jexl.Node) {
+	switch n := node.(type) {
+	case nil:
+	case *jexl.IdentifierNode:
+		r.use(n.Name())
+	case *jexl.PropertyAccessNode:
+		r.walk(n.Object())
+		if _, ok := n.Property().(*jexl.IdentifierNode); !ok {
+			r.walk(n.Property())
+		}
+	case *jexl.MethodCallNode:
+		r.walk(n.Target())
+		// Имя метода или функции пространства имён - не переменная;
+		// f(x) без цели может вызывать локальную lambda
+		if _, ok := n.Method().(*jexl.IdentifierNode); !ok || n.Target() == nil && n.Namespace() == "" {
+			r.walk(n.Method())
+		}
+		for _, arg := range n.Args() {
+			r.walk(arg)
+		}
+	case *jexl.MapLiteralNode:
+		for _, entry := range n.Entries() {
+			if _, ok := entry.Key.(*jexl.IdentifierNode); !ok {
+				r.walk(entry.Key)
+			}
+			r.walk(entry.Value)
+		}
+	case *jexl.AssignmentNode:
+		r.assign(n)
+	case *jexl.VarNode:
+		r.walk(n.Value())
+		r.declare(n.Name().Name(), bindVar, n.Name().Position())
+	case *jexl.DestructuringNode:
+		r.walk(n.Value())
+		r.bind(n, n.Pattern(), n.IsDeclaration())
+	case *jexl.ForeachNode:
+		r.walk(n.Items())
+		r.scoped(true, func() {
+			if key, ok := n.Key().(*jexl.IdentifierNode); ok {
+				r.declare(key.Name(), bindLoop, key.Position())
+			}
+			if v, ok := n.Variable().(*jexl.IdentifierNode); ok {
+				r.declare(v.Name(), bindLoop, v.Position())
+			} else {
+				r.bind(n, n.Variable(), true)
+			}
+			r.walk(n.Body())
+		})
+	case *jexl.LambdaNode:
+		r.scoped(false, func() {
+			defaults := n.Defaults()
+			for i, param := range n.Parameters() {
+				if i < len(defaults) {
+					r.walk(defaults[i])
+				}
+				r.declare(param.Name(), bindParam, param.Position())
+			}
+			r.walk(n.Body())
+		})
+	case *jexl.FunctionNode:
+		r.declare(n.Name().Name(), bindFunction, n.Name().Position())
+		r.walk(n.Lambda())
+	case *jexl.TryNode:
+		r.scoped(true, func() {
+			for _, resource := range n.Resources() {
+				r.walk(resource)
+			}
+			r.walk(n.TryBlock())
+		})
+		r.scoped(true, func() {
+			if n.CatchVar() != "" {
+				r.declare(n.CatchVar(), bindCatch, jexl.Position{})
+			}
+			r.walk(n.CatchBlock())
+		})
+		r.walk(n.FinallyBlock())
+	case *jexl.BlockNode, *jexl.ForNode:
+		r.scoped(true, func() {
+			for _, child := range n.Children() {
+				r.walk(child)
+			}
+		})
+	default:
+		for _, child := range node.Children() {
+			r.walk(child)
+		}
+	}
+}
+
+// assign учитывает присваивание. Запись (и составное x += y) не считается
+// чтением переменной.
+func (r *resolver) assign(n *jexl.AssignmentNode) {
+	value := n.Value()
+	switch v := value.(type) {
+	case *jexl.BinaryOpNode:
+		if v.Left() == n.Target() {
+			value = v.Right()
+		}
+	case *jexl.ElvisNode:
+		if v.Expr() == n.Target() {
+			value = v.DefaultExpr()
+		}
+	}
+	r.walk(value)
+	r.write(n, n.Target())
+}
+
+// write учитывает запись в цель присваивания stmt.
+func (r *resolver) write(stmt, target jexl.Node) {
+	root := target
+	for {
+		switch t := root.(type) {
+		case *jexl.PropertyAccessNode:
+			root = t.Object()
+			continue
+		case *jexl.IndexAccessNode:
+			root = t.Object()
+			continue
+		}
+		break
+	}
+	if target != root {
+		// Запись свойства читает сам объект
+		r.walk(target)
+	}
+	if id, ok := root.(*jexl.IdentifierNode); ok && r.lookup(id.Name()) == nil {
+		r.res.globalWrites = append(r.res.globalWrites, stmt)
+	}
+}
+
+// bind связывает шаблон деструктуризации: при объявлении имена
+// становятся локальными, иначе цели записываются.
+func (r *resolver) bind(stmt, target jexl.Node, declare bool) {
+	var targets, defaults []jexl.Node
+	var rest *jexl.IdentifierNode
+	switch n := target.(type) {
+	case nil:
+		return
+	case *jexl.ArrayPatternNode:
+		targets, defaults, rest = n.Elements(), n.Defaults(), n.Rest()
+	case *jexl.MapPatternNode:
+		targets, defaults, rest = n.Targets(), n.Defaults(), n.Rest()
+	case *jexl.IdentifierNode:
+		if declare {
+			r.declare(n.Name(), bindVar, n.Position())
+		} else {
+			r.write(stmt, n)
+		}
+		return
+	default:
+		r.write(stmt, n)
+		return
+	}
+	for i, t := range targets {
+		if i < len(defaults) {
+			r.walk(defaults[i])
+		}
+		r.bind(stmt, t, declare)
+	}
+	if rest != nil {
+		r.bind(stmt, rest, declare)
+	}
+}
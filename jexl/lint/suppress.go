@@ -0,0 +1,106 @@
+package lint
+
+import (
+	"strings"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/internal"
+)
+
+const (
+	ignoreDirective = "lint:ignore"  // комментарий подавления для строки
+	disablePragma   = "lint.disable" // pragma подавления для скрипта
+)
+
+// suppressions - подавленные правила скрипта и строк.
+// Правило "all" подавляет все правила.
+type suppressions struct {
+	all   []string         // правила, отключённые для всего скрипта
+	lines map[int][]string // правила, отключённые на строке
+}
+
+// newSuppressions собирает подавления из комментариев исходного текста и pragma скрипта.
+func newSuppressions(source string, ast *jexl.ScriptNode) *suppressions {
+	s := &suppressions{lines: make(map[int][]string)}
+	if value, ok := ast.Pragmas()[disablePragma]; ok {
+		s.all = append(s.all, pragmaRules(value)...)
+		if len(s.all) == 0 {
+			s.all = []string{"all"}
+		}
+	}
+	for _, c := range internal.ScanComments(source) {
+		if fields := strings.Fields(c.Text); len(fields) >= 2 && fields[0] == "#pragma" && fields[1] == disablePragma {
+			s.all = append(s.all, ruleList(strings.Join(fields[2:], " "), "all")...)
+			continue
+		}
+		text := strings.TrimSuffix(c.Text, "*/")
+		for _, prefix := range []string{"#", "//", "/*"} {
+			text = strings.TrimPrefix(text, prefix)
+		}
+		rest, ok := strings.CutPrefix(strings.TrimSpace(text), ignoreDirective)
+		if !ok {
+			continue
+		}
+		rules := ruleList(rest, "all")
+		line := 1 + strings.Count(source[:c.Start], "\n")
+		s.lines[line] = append(s.lines[line], rules...)
+		// Комментарий на отдельной строке относится к следующей строке
+		lineStart := strings.LastIndexByte(source[:c.Start], '\n') + 1
+		if strings.TrimSpace(source[lineStart:c.Start]) == "" {
+			next := line + 1 + strings.Count(c.Text, "\n")
+			s.lines[next] = append(s.lines[next], rules...)
+		}
+	}
+	return s
+}
+
+// script проверяет, отключено ли правило для всего скрипта.
+func (s *suppressions) script(rule string) bool {
+	return matchRule(s.all, rule)
+}
+
+// line проверяет, подавлено ли сообщение правила на строке.
+func (s *suppressions) line(rule string, line int) bool {
+	return matchRule(s.lines[line], rule)
+}
+
+// matchRule проверяет, входит ли правило в список; "all" подходит любому.
+func matchRule(rules []string, rule string) bool {
+	for _, r := range rules {
+		if r == rule || r == "all" {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleList разбирает идентификаторы правил, разделённые пробелами или запятыми.
+// Пустой список заменяется на fallback.
+func ruleList(text, fallback string) []string {
+	rules := strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\'' || r == '"' || r == '\n' || r == '\r'
+	})
+	if len(rules) == 0 {
+		return []string{fallback}
+	}
+	return rules
+}
+
+// pragmaRules возвращает правила из значения pragma: строки или списка строк.
+func pragmaRules(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return ruleList(v, "all")
+	case []string:
+		return v
+	case []any:
+		var rules []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				rules = append(rules, s)
+			}
+		}
+		return rules
+	}
+	return nil
+}
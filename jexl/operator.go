@@ -31,12 +31,15 @@ func (o *Operator) BaseOperator() *Operator {
 
 // Определение операторов
 var (
-	// Арифметические операторы
-	OpAdd      = &Operator{"+", "add", 2, nil}
-	OpSubtract = &Operator{"-", "subtract", 2, nil}
-	OpMultiply = &Operator{"*", "multiply", 2, nil}
-	OpDivide   = &Operator{"/", "divide", 2, nil}
-	OpMod      = &Operator{"%", "mod", 2, nil}
+	// Арифметические операторы. Целочисленное деление - ключевое слово div:
+	// // начинает комментарий.
+	OpAdd           = &Operator{"+", "add", 2, nil}
+	OpSubtract      = &Operator{"-", "subtract", 2, nil}
+	OpMultiply      = &Operator{"*", "multiply", 2, nil}
+	OpDivide        = &Operator{"/", "divide", 2, nil}
+	OpMod           = &Operator{"%", "mod", 2, nil}
+	OpIntegerDivide = &Operator{"div", "integerDivide", 2, nil}
+	OpPower         = &Operator{"**", "power", 2, nil}
 
 	// Побитовые операторы
 	OpAnd         = &Operator{"&", "and", 2, nil}
@@ -47,19 +50,26 @@ var (
 	OpShiftLeft   = &Operator{"<<", "shiftLeft", 2, nil}
 
 	// Операторы сравнения
-	OpEq      = &Operator{"==", "equals", 2, nil}
+	OpEq       = &Operator{"==", "equals", 2, nil}
 	OpEqStrict = &Operator{"===", "strictEquals", 2, nil}
-	OpNe      = &Operator{"!=", "notEquals", 2, nil}
-	OpLt      = &Operator{"<", "lessThan", 2, nil}
-	OpLe      = &Operator{"<=", "lessThanOrEqual", 2, nil}
-	OpGt      = &Operator{">", "greaterThan", 2, nil}
-	OpGe      = &Operator{">=", "greaterThanOrEqual", 2, nil}
+	OpNe       = &Operator{"!=", "notEquals", 2, nil}
+	OpLt       = &Operator{"<", "lessThan", 2, nil}
+	OpLe       = &Operator{"<=", "lessThanOrEqual", 2, nil}
+	OpGt       = &Operator{">", "greaterThan", 2, nil}
+	OpGe       = &Operator{">=", "greaterThanOrEqual", 2, nil}
 
 	// Строковые операторы
 	OpContains   = &Operator{"=~", "contains", 2, nil}
 	OpStartsWith = &Operator{"=^", "startsWith", 2, nil}
 	OpEndsWith   = &Operator{"=$", "endsWith", 2, nil}
 
+	// Операторы принадлежности и проверки типа
+	OpIn         = &Operator{"in", "in", 2, nil}
+	OpInstanceOf = &Operator{"instanceof", "instanceOf", 2, nil}
+
+	// Null-coalescing оператор
+	OpNullCoalesce = &Operator{"??", "nullCoalesce", 2, nil}
+
 	// Унарные операторы
 	OpNot        = &Operator{"!", "not", 1, nil}
 	OpComplement = &Operator{"~", "complement", 1, nil}
@@ -69,21 +79,23 @@ var (
 	OpSize       = &Operator{"size", "size", 1, nil}
 
 	// Side-effect операторы
-	OpSelfAdd      = &Operator{"+=", "selfAdd", 2, OpAdd}
-	OpSelfSubtract = &Operator{"-=", "selfSubtract", 2, OpSubtract}
-	OpSelfMultiply = &Operator{"*=", "selfMultiply", 2, OpMultiply}
-	OpSelfDivide   = &Operator{"/=", "selfDivide", 2, OpDivide}
-	OpSelfMod      = &Operator{"%=", "selfMod", 2, OpMod}
-	OpSelfAnd      = &Operator{"&=", "selfAnd", 2, OpAnd}
-	OpSelfOr       = &Operator{"|=", "selfOr", 2, OpOr}
-	OpSelfXor      = &Operator{"^=", "selfXor", 2, OpXor}
-	OpSelfShiftRight  = &Operator{">>=", "selfShiftRight", 2, OpShiftRight}
-	OpSelfShiftRightU = &Operator{">>>=", "selfShiftRightUnsigned", 2, OpShiftRightU}
-	OpSelfShiftLeft   = &Operator{"<<=", "selfShiftLeft", 2, OpShiftLeft}
+	OpSelfAdd          = &Operator{"+=", "selfAdd", 2, OpAdd}
+	OpSelfSubtract     = &Operator{"-=", "selfSubtract", 2, OpSubtract}
+	OpSelfMultiply     = &Operator{"*=", "selfMultiply", 2, OpMultiply}
+	OpSelfDivide       = &Operator{"/=", "selfDivide", 2, OpDivide}
+	OpSelfMod          = &Operator{"%=", "selfMod", 2, OpMod}
+	OpSelfAnd          = &Operator{"&=", "selfAnd", 2, OpAnd}
+	OpSelfOr           = &Operator{"|=", "selfOr", 2, OpOr}
+	OpSelfXor          = &Operator{"^=", "selfXor", 2, OpXor}
+	OpSelfShiftRight   = &Operator{">>=", "selfShiftRight", 2, OpShiftRight}
+	OpSelfShiftRightU  = &Operator{">>>=", "selfShiftRightUnsigned", 2, OpShiftRightU}
+	OpSelfShiftLeft    = &Operator{"<<=", "selfShiftLeft", 2, OpShiftLeft}
+	OpSelfPower        = &Operator{"**=", "selfPower", 2, OpPower}
+	OpSelfNullCoalesce = &Operator{"??=", "selfNullCoalesce", 2, OpNullCoalesce}
 
 	// Инкремент/декремент
-	OpIncrement      = &Operator{"+1", "increment", 1, nil}
-	OpDecrement      = &Operator{"-1", "decrement", 1, nil}
+	OpIncrement       = &Operator{"+1", "increment", 1, nil}
+	OpDecrement       = &Operator{"-1", "decrement", 1, nil}
 	OpIncrementAndGet = &Operator{"++.", "incrementAndGet", 1, OpIncrement}
 	OpGetAndIncrement = &Operator{".++", "getAndIncrement", 1, OpIncrement}
 	OpDecrementAndGet = &Operator{"--.", "decrementAndGet", 1, OpDecrement}
@@ -102,30 +114,35 @@ var (
 	OpNotContains   = &Operator{"!~", "", 2, OpContains}
 	OpNotStartsWith = &Operator{"!^", "", 2, OpStartsWith}
 	OpNotEndsWith   = &Operator{"!$", "", 2, OpEndsWith}
+	OpNotIn         = &Operator{"!in", "", 2, OpIn}
+	OpNotInstanceOf = &Operator{"!instanceof", "", 2, OpInstanceOf}
 )
 
 // OperatorFromSymbol возвращает оператор по символу.
 func OperatorFromSymbol(symbol string) *Operator {
 	operators := map[string]*Operator{
-		"+": OpAdd, "-": OpSubtract, "*": OpMultiply, "/": OpDivide, "%": OpMod,
-		"&": OpAnd, "|": OpOr, "^": OpXor,
+		"+": OpAdd, "-": OpSubtract, "*": OpMultiply, "/": OpDivide, "%": OpMod, "**": OpPower,
+		"div": OpIntegerDivide,
+		"&":   OpAnd, "|": OpOr, "^": OpXor,
 		">>": OpShiftRight, ">>>": OpShiftRightU, "<<": OpShiftLeft,
 		"==": OpEq, "===": OpEqStrict, "!=": OpNe,
 		"<": OpLt, "<=": OpLe, ">": OpGt, ">=": OpGe,
 		"=~": OpContains, "=^": OpStartsWith, "=$": OpEndsWith,
+		"in": OpIn, "instanceof": OpInstanceOf, "??": OpNullCoalesce,
 		"!": OpNot, "~": OpComplement,
 		"empty": OpEmpty, "size": OpSize,
 		"+=": OpSelfAdd, "-=": OpSelfSubtract, "*=": OpSelfMultiply,
 		"/=": OpSelfDivide, "%=": OpSelfMod,
 		"&=": OpSelfAnd, "|=": OpSelfOr, "^=": OpSelfXor,
 		">>=": OpSelfShiftRight, ">>>=": OpSelfShiftRightU, "<<=": OpSelfShiftLeft,
+		"**=": OpSelfPower, "??=": OpSelfNullCoalesce,
 		"++.": OpIncrementAndGet, ".++": OpGetAndIncrement,
 		"--.": OpDecrementAndGet, ".--": OpGetAndDecrement,
 		".": OpPropertyGet, ".=": OpPropertySet,
 		"[]": OpArrayGet, "[]=": OpArraySet,
 		"for(...)": OpForEach, "?": OpCondition, "<>": OpCompare,
 		"!~": OpNotContains, "!^": OpNotStartsWith, "!$": OpNotEndsWith,
+		"!in": OpNotIn, "!instanceof": OpNotInstanceOf,
 	}
 	return operators[symbol]
 }
-
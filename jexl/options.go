@@ -2,8 +2,10 @@ package jexl
 
 import (
 	"fmt"
+	"maps"
 	"math"
 	"math/big"
+	"reflect"
 	"slices"
 )
 
@@ -21,6 +23,7 @@ type Options struct {
 	strictArithmetic bool
 	flags            uint32
 	namespaces       map[string]any
+	types            map[string]reflect.Type
 	imports          []string
 }
 
@@ -36,6 +39,9 @@ const (
 	flagConstCapture
 	flagStrictInterpolation
 	flagBooleanLogical
+	flagSortedKeys
+	flagCompiled
+	flagOptimize
 )
 
 var optionFlagNames = []string{
@@ -50,6 +56,9 @@ var optionFlagNames = []string{
 	"constCapture",
 	"strictInterpolation",
 	"booleanShortCircuit",
+	"sortedKeys",
+	"compiled",
+	"optimize",
 }
 
 var defaultOptionFlags uint32 = flagCancellable | flagStrict | flagAntish | flagSafe
@@ -122,6 +131,7 @@ func (o *Options) Copy() *Options {
 	} else {
 		cp.namespaces = map[string]any{}
 	}
+	cp.types = maps.Clone(o.types)
 	cp.imports = slices.Clone(o.imports)
 	return &cp
 }
@@ -229,6 +239,12 @@ func (o *Options) Namespaces() map[string]any {
 	return res
 }
 
+// ResolveNamespace возвращает пространство имён по имени или nil.
+// Реализует NamespaceResolver.
+func (o *Options) ResolveNamespace(name string) any {
+	return o.namespaces[name]
+}
+
 // SetNamespaces задаёт пространства имён.
 func (o *Options) SetNamespaces(values map[string]any) {
 	if len(values) == 0 {
@@ -241,6 +257,21 @@ func (o *Options) SetNamespaces(values map[string]any) {
 	}
 }
 
+// Types возвращает реестр типов для оператора instanceof.
+func (o *Options) Types() map[string]reflect.Type {
+	return maps.Clone(o.types)
+}
+
+// ResolveType возвращает зарегистрированный тип по имени или nil.
+func (o *Options) ResolveType(name string) reflect.Type {
+	return o.types[name]
+}
+
+// SetTypes задаёт реестр типов для оператора instanceof.
+func (o *Options) SetTypes(values map[string]reflect.Type) {
+	o.types = maps.Clone(values)
+}
+
 // Utility методы проверки отдельных флагов.
 
 func (o *Options) isSet(flag uint32) bool {
@@ -261,16 +292,22 @@ func (o *Options) BooleanLogical() bool        { return o.isSet(flagBooleanLogic
 func (o *Options) SetBooleanLogical(flag bool) { o.set(flagBooleanLogical, flag) }
 func (o *Options) Cancellable() bool           { return o.isSet(flagCancellable) }
 func (o *Options) SetCancellable(flag bool)    { o.set(flagCancellable, flag) }
+func (o *Options) Compiled() bool              { return o.isSet(flagCompiled) }
+func (o *Options) SetCompiled(flag bool)       { o.set(flagCompiled, flag) }
 func (o *Options) ConstCapture() bool          { return o.isSet(flagConstCapture) }
 func (o *Options) SetConstCapture(flag bool)   { o.set(flagConstCapture, flag) }
 func (o *Options) Lexical() bool               { return o.isSet(flagLexical) }
 func (o *Options) SetLexical(flag bool)        { o.set(flagLexical, flag) }
 func (o *Options) LexicalShade() bool          { return o.isSet(flagLexicalShade) }
 func (o *Options) SetLexicalShade(flag bool)   { o.set(flagLexicalShade, flag) }
+func (o *Options) Optimize() bool              { return o.isSet(flagOptimize) }
+func (o *Options) SetOptimize(flag bool)       { o.set(flagOptimize, flag) }
 func (o *Options) Safe() bool                  { return o.isSet(flagSafe) }
 func (o *Options) SetSafe(flag bool)           { o.set(flagSafe, flag) }
 func (o *Options) SharedInstance() bool        { return o.isSet(flagSharedInstance) }
 func (o *Options) SetSharedInstance(flag bool) { o.set(flagSharedInstance, flag) }
+func (o *Options) SortedKeys() bool            { return o.isSet(flagSortedKeys) }
+func (o *Options) SetSortedKeys(flag bool)     { o.set(flagSortedKeys, flag) }
 func (o *Options) Silent() bool                { return o.isSet(flagSilent) }
 func (o *Options) SetSilent(flag bool)         { o.set(flagSilent, flag) }
 func (o *Options) Strict() bool                { return o.isSet(flagStrict) }
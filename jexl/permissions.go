@@ -1,5 +1,7 @@
 package jexl
 
+import "strings"
+
 // Permissions контролируют доступный набор пакетов и классов.
 // Структура служит портом org.apache.commons.jexl3.introspection.JexlPermissions.
 type Permissions struct {
@@ -31,6 +33,34 @@ func (p *Permissions) Denied() []string {
 	return append([]string(nil), p.denied...)
 }
 
+// Allows сообщает, разрешено ли имя (пакет, класс или функция вида jexl.math.abs).
+// Шаблон совпадает с самим именем и со всеми вложенными в него именами.
+// Запреты приоритетнее разрешений; пустой список разрешений разрешает всё.
+func (p *Permissions) Allows(name string) bool {
+	if p == nil {
+		return true
+	}
+	for _, pattern := range p.denied {
+		if matchesPermission(pattern, name) {
+			return false
+		}
+	}
+	if len(p.allowed) == 0 {
+		return true
+	}
+	for _, pattern := range p.allowed {
+		if matchesPermission(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPermission проверяет, покрывает ли шаблон имя.
+func matchesPermission(pattern, name string) bool {
+	return name == pattern || strings.HasPrefix(name, pattern+".")
+}
+
 var (
 	// PermissionsRestricted соответствует набору RESTRICTED в Java-версии.
 	PermissionsRestricted = NewPermissions(
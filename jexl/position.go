@@ -0,0 +1,111 @@
+package jexl
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Position - положение узла или токена в исходном тексте.
+// Смещения - в байтах, строки и столбцы нумеруются с 1, столбцы - в символах.
+// Нулевое значение означает, что положение неизвестно (узел создан не парсером).
+type Position struct {
+	Offset    int // смещение начала
+	EndOffset int // смещение конца (не включая)
+	Line      int
+	Column    int
+	EndLine   int
+	EndColumn int
+}
+
+// IsValid сообщает, известно ли положение.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String форматирует положение в виде line:column.
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// span хранит положение узла; встраивается во все узлы AST.
+type span struct {
+	pos Position
+}
+
+// Position возвращает положение узла в исходном тексте.
+func (s *span) Position() Position {
+	return s.pos
+}
+
+// SetPosition устанавливает положение узла. Используется парсером.
+func (s *span) SetPosition(pos Position) {
+	s.pos = pos
+}
+
+// nodeDetail - Detail с диапазоном и исходным текстом узла.
+type nodeDetail struct {
+	start, end int
+	text       string
+}
+
+func (d nodeDetail) Start() int     { return d.start }
+func (d nodeDetail) End() int       { return d.end }
+func (d nodeDetail) String() string { return d.text }
+
+// NodeInfo создаёт Info с положением узла; Detail содержит диапазон смещений
+// и исходный текст узла. Для узла без положения возвращает nil.
+func NodeInfo(name string, node Node) *Info {
+	if node == nil {
+		return nil
+	}
+	return PositionInfo(name, node.Position(), node.SourceText())
+}
+
+// PositionInfo создаёт Info с положением pos; Detail содержит диапазон смещений
+// и текст text. Для неизвестного положения возвращает nil.
+func PositionInfo(name string, pos Position, text string) *Info {
+	if !pos.IsValid() {
+		return nil
+	}
+	return NewInfoAt(name, pos.Line, pos.Column).WithDetail(nodeDetail{
+		start: pos.Offset,
+		end:   pos.EndOffset,
+		text:  text,
+	})
+}
+
+// AttachInfo оборачивает ошибку выполнения в Error с тем же текстом и заданным Info,
+// если в цепочке ошибок ещё нет Info с Detail. Сама ошибка не изменяется:
+// ошибки движка могут быть общими (ErrUnsupportedOperation и т.п.).
+func AttachInfo(err error, info *Info) error {
+	if err == nil || info == nil {
+		return err
+	}
+	if current := ErrorInfo(err); current != nil && current.Detail() != nil {
+		return err
+	}
+	return &Error{cause: err, info: info}
+}
+
+// ErrorInfo возвращает Info из цепочки ошибок err: первый с Detail,
+// а если такого нет - первый заданный.
+func ErrorInfo(err error) *Info {
+	var first *Info
+	for err != nil {
+		if holder, ok := err.(interface{ Info() *Info }); ok {
+			if info := holder.Info(); info != nil {
+				if info.Detail() != nil {
+					return info
+				}
+				if first == nil {
+					first = info
+				}
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return first
+}
@@ -0,0 +1,223 @@
+package jexl
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+)
+
+// Range - ленивый целочисленный диапазон first .. last с шагом step.
+// Элементы вычисляются по требованию, поэтому 1 .. 1000000000 не занимает памяти.
+// Шаг отрицателен для убывающего диапазона; last включается, если попадает в шаг.
+type Range struct {
+	first int64
+	last  int64
+	step  int64
+}
+
+// NewRange создаёт диапазон от first до last включительно с шагом 1 или -1 по направлению.
+func NewRange(first, last int64) *Range {
+	step := int64(1)
+	if first > last {
+		step = -1
+	}
+	return &Range{first: first, last: last, step: step}
+}
+
+// WithStep возвращает диапазон с тем же началом и концом и шагом заданной величины.
+// Величина шага должна быть положительной; направление определяется границами.
+func (r *Range) WithStep(step int64) (*Range, error) {
+	if step <= 0 {
+		return nil, NewError(fmt.Sprintf("range step must be positive, got %d", step))
+	}
+	if r.step < 0 {
+		step = -step
+	}
+	return &Range{first: r.first, last: r.last, step: step}, nil
+}
+
+// First возвращает первый элемент (начальную границу).
+func (r *Range) First() int64 {
+	return r.first
+}
+
+// Last возвращает конечную границу.
+func (r *Range) Last() int64 {
+	return r.last
+}
+
+// Step возвращает шаг (отрицательный для убывающего диапазона).
+func (r *Range) Step() int64 {
+	return r.step
+}
+
+// MaxRangeElements ограничивает число элементов диапазона, который
+// материализуется в список (JSON, конкатенация, функции и методы коллекций).
+// Более длинный диапазон можно только обходить.
+const MaxRangeElements = 1 << 24
+
+// Size возвращает число элементов без материализации; больше math.MaxInt64 не бывает.
+func (r *Range) Size() int64 {
+	steps, ok := r.steps()
+	if !ok {
+		return 0
+	}
+	// Полный диапазон int64 содержит 2^64 элементов: число ограничивается MaxInt64
+	if steps >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(steps) + 1
+}
+
+// steps возвращает число шагов от первого элемента до последнего; false - для
+// пустого диапазона. Считается в uint64, поэтому точно и там, где Size ограничен.
+func (r *Range) steps() (uint64, bool) {
+	if r.step > 0 {
+		if r.last < r.first {
+			return 0, false
+		}
+		return (uint64(r.last) - uint64(r.first)) / uint64(r.step), true
+	}
+	if r.last > r.first {
+		return 0, false
+	}
+	return (uint64(r.first) - uint64(r.last)) / -uint64(r.step), true
+}
+
+// lastElement возвращает последний элемент диапазона из steps шагов.
+// Произведение переполняет uint64 по модулю 2^64, но результат лежит в границах.
+func (r *Range) lastElement(steps uint64) int64 {
+	return int64(uint64(r.first) + steps*uint64(r.step))
+}
+
+// CheckMaterialize возвращает ошибку, если диапазон длиннее MaxRangeElements
+// и не может быть материализован в список.
+func (r *Range) CheckMaterialize() error {
+	if steps, ok := r.steps(); ok && steps >= MaxRangeElements {
+		return NewError(fmt.Sprintf("range %s is too large to materialize", r))
+	}
+	return nil
+}
+
+// IsEmpty сообщает, пуст ли диапазон.
+func (r *Range) IsEmpty() bool {
+	return r.Size() == 0
+}
+
+// Get возвращает элемент по индексу.
+func (r *Range) Get(index int64) (int64, bool) {
+	if index < 0 || index >= r.Size() {
+		return 0, false
+	}
+	return r.first + index*r.step, true
+}
+
+// Contains сообщает, является ли значение элементом диапазона.
+// Значение должно быть целым числом любого числового типа.
+func (r *Range) Contains(value any) bool {
+	rat, ok := toBig(value)
+	if _, isBool := value.(bool); isBool || !ok || !rat.IsInt() || !rat.Num().IsInt64() {
+		return false
+	}
+	n := rat.Num().Int64()
+	steps, ok := r.steps()
+	if !ok {
+		return false
+	}
+	lo, hi := r.first, r.lastElement(steps)
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if n < lo || n > hi {
+		return false
+	}
+	diff := uint64(n - r.first)
+	if r.step < 0 {
+		diff = uint64(r.first - n)
+		return diff%uint64(-r.step) == 0
+	}
+	return diff%uint64(r.step) == 0
+}
+
+// Reversed возвращает диапазон с теми же элементами в обратном порядке.
+func (r *Range) Reversed() *Range {
+	steps, ok := r.steps()
+	if !ok {
+		return &Range{first: r.last, last: r.first, step: -r.step}
+	}
+	return &Range{first: r.lastElement(steps), last: r.first, step: -r.step}
+}
+
+// Iterator возвращает итератор элементов.
+func (r *Range) Iterator() iter.Seq[int64] {
+	return func(yield func(int64) bool) {
+		steps, ok := r.steps()
+		if !ok {
+			return
+		}
+		n := r.first
+		for i := uint64(0); ; i++ {
+			if !yield(n) || i == steps {
+				return
+			}
+			n += r.step
+		}
+	}
+}
+
+// ToSlice материализует диапазон целиком; длинный диапазон обходят через Iterator.
+func (r *Range) ToSlice() []int64 {
+	result := make([]int64, 0, r.Size())
+	for n := range r.Iterator() {
+		result = append(result, n)
+	}
+	return result
+}
+
+// String возвращает представление диапазона в синтаксисе JEXL.
+func (r *Range) String() string {
+	if r.step == 1 || r.step == -1 {
+		return fmt.Sprintf("%d .. %d", r.first, r.last)
+	}
+	step := r.step
+	if step < 0 {
+		step = -step
+	}
+	return fmt.Sprintf("%d .. %d step %d", r.first, r.last, step)
+}
+
+// MarshalJSON сериализует диапазон как JSON-массив.
+// Слишком длинный диапазон не сериализуется.
+func (r *Range) MarshalJSON() ([]byte, error) {
+	if err := r.CheckMaterialize(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(r.ToSlice())
+}
+
+// rangeEquals сравнивает диапазон с другим диапазоном или слайсом поэлементно.
+func rangeEquals(r *Range, other any) bool {
+	if o, ok := other.(*Range); ok {
+		steps, ok := r.steps()
+		if other, otherOk := o.steps(); ok != otherOk || steps != other {
+			return false
+		}
+		return !ok || (r.first == o.first && (steps == 0 || r.step == o.step))
+	}
+	items := reflect.ValueOf(other)
+	if items.Kind() != reflect.Slice && items.Kind() != reflect.Array {
+		return false
+	}
+	if int64(items.Len()) != r.Size() {
+		return false
+	}
+	for i := 0; i < items.Len(); i++ {
+		n, _ := r.Get(int64(i))
+		if !equals(n, items.Index(i).Interface()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,114 @@
+package jexl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+// regexCacheLimit ограничивает число шаблонов в кэше, чтобы динамически
+// построенные строки не приводили к неограниченному росту памяти.
+const regexCacheLimit = 1024
+
+var (
+	regexCache     sync.Map
+	regexCacheSize atomic.Int64
+)
+
+// CompileRegex компилирует строковый шаблон, переиспользуя ранее скомпилированные выражения.
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	if regexCacheSize.Load() < regexCacheLimit {
+		if _, loaded := regexCache.LoadOrStore(pattern, re); !loaded {
+			regexCacheSize.Add(1)
+		}
+	}
+	return re, nil
+}
+
+// ToRegex приводит значение к регулярному выражению.
+// Принимает *regexp.Regexp как есть, строки компилирует через кэш.
+func ToRegex(value any) (*regexp.Regexp, error) {
+	switch v := value.(type) {
+	case *regexp.Regexp:
+		if v == nil {
+			return nil, NewError("regex must not be null")
+		}
+		return v, nil
+	case string:
+		return CompileRegex(v)
+	case nil:
+		return nil, NewError("regex must not be null")
+	default:
+		return CompileRegex(fmt.Sprintf("%v", v))
+	}
+}
+
+// RegexGroups возвращает группы первого совпадения: индексные ("0" - всё совпадение)
+// и именованные. Возвращает nil, если совпадения нет.
+func RegexGroups(re *regexp.Regexp, s string) map[string]any {
+	match := re.FindStringSubmatchIndex(s)
+	if match == nil {
+		return nil
+	}
+	groups := make(map[string]any, len(match))
+	names := re.SubexpNames()
+	for i := 0; i < len(match)/2; i++ {
+		var value any
+		if match[2*i] >= 0 {
+			value = s[match[2*i]:match[2*i+1]]
+		}
+		groups[fmt.Sprint(i)] = value
+		if names[i] != "" {
+			groups[names[i]] = value
+		}
+	}
+	return groups
+}
+
+// RegexReplacement переводит строку замены в стиле Java ($1, \$) в синтаксис Go regexp (${1}, $$).
+func RegexReplacement(repl string) string {
+	var sb strings.Builder
+	runes := []rune(repl)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] == '$' {
+				sb.WriteString("$$")
+			} else {
+				sb.WriteRune(runes[i])
+			}
+		case c == '$' && i+1 < len(runes) && runes[i+1] == '{':
+			end := i + 2
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			sb.WriteString(string(runes[i:min(end+1, len(runes))]))
+			i = end
+		case c == '$':
+			j := i + 1
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			if j == i+1 {
+				sb.WriteString("$$")
+				continue
+			}
+			sb.WriteString("${" + string(runes[i+1:j]) + "}")
+			i = j - 1
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
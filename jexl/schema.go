@@ -0,0 +1,318 @@
+package jexl
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// TypeKind - вид статического типа значения.
+type TypeKind int
+
+const (
+	TypeAny TypeKind = iota // тип неизвестен, проверки не выполняются
+	TypeNull
+	TypeBoolean
+	TypeInteger
+	TypeNumber // дробное число; целые совместимы с ним
+	TypeString
+	TypeList
+	TypeMap
+	TypeObject
+	TypeFunction
+)
+
+var typeKindNames = [...]string{"any", "null", "boolean", "integer", "number", "string", "list", "map", "object", "function"}
+
+// String возвращает название вида.
+func (k TypeKind) String() string {
+	if k >= 0 && int(k) < len(typeKindNames) {
+		return typeKindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Type - статический тип значения для проверки типов.
+// Свойства и методы типа Go (GoType) разрешаются по правилам Uberspect.
+type Type struct {
+	Kind       TypeKind
+	Nullable   bool             // значение может быть null
+	Elem       *Type            // элементы списка или значения мапы; nil - any
+	Properties map[string]*Type // свойства объекта, описанного схемой
+	Open       bool             // объект допускает свойства, не перечисленные в Properties
+	Params     []*Type          // параметры функции
+	Variadic   bool             // последний параметр функции принимает любое число аргументов
+	Result     *Type            // результат функции; nil - any
+	GoType     reflect.Type     // тип Go, из которого построен тип
+}
+
+// AnyType возвращает тип без проверок.
+func AnyType() *Type {
+	return &Type{Kind: TypeAny}
+}
+
+// ListOf возвращает тип списка с элементами elem.
+func ListOf(elem *Type) *Type {
+	return &Type{Kind: TypeList, Elem: elem}
+}
+
+// MapOf возвращает тип мапы со значениями elem и произвольными ключами.
+func MapOf(elem *Type) *Type {
+	return &Type{Kind: TypeMap, Elem: elem}
+}
+
+// ObjectOf возвращает тип объекта с заданным набором свойств.
+func ObjectOf(properties map[string]*Type) *Type {
+	return &Type{Kind: TypeObject, Properties: properties}
+}
+
+// FunctionOf возвращает тип функции с параметрами params и результатом result.
+func FunctionOf(result *Type, params ...*Type) *Type {
+	return &Type{Kind: TypeFunction, Params: params, Result: result}
+}
+
+// OrNull возвращает копию типа, допускающую null.
+func (t *Type) OrNull() *Type {
+	nullable := *t
+	nullable.Nullable = true
+	return &nullable
+}
+
+// String возвращает запись типа: integer, list<string>, map<any>, *pkg.Order?
+func (t *Type) String() string {
+	if t == nil {
+		return "any"
+	}
+	var s string
+	switch {
+	case t.GoType != nil && (t.Kind == TypeObject || t.Kind == TypeFunction || t.GoType.Name() != ""):
+		// Именованные типы Go печатаются по имени: так печать не зацикливается на рекурсивных типах
+		s = t.GoType.String()
+	case t.Kind == TypeList || t.Kind == TypeMap:
+		s = t.Kind.String() + "<" + t.Elem.String() + ">"
+	default:
+		s = t.Kind.String()
+	}
+	if t.Nullable && t.Kind != TypeNull && t.Kind != TypeAny {
+		s += "?"
+	}
+	return s
+}
+
+var (
+	mapType    = reflect.TypeOf((*Map)(nil))
+	setType    = reflect.TypeOf((*Set)(nil))
+	rangeType  = reflect.TypeOf((*Range)(nil))
+	scriptType = reflect.TypeOf((*Script)(nil)).Elem()
+)
+
+// TypeOf возвращает статический тип значений типа Go. Структуры становятся
+// объектами, свойства которых разрешаются по правилам Uberspect. Указатели не
+// считаются null, чтобы обход графа объектов не давал предупреждений: поле,
+// которое может быть nil, помечается тегом jexl:"nullable", тип - через OrNull.
+// Рекурсивные типы (type Tree map[string]Tree) дают циклический Type.
+func TypeOf(typ reflect.Type) *Type {
+	return typeOf(typ, make(map[reflect.Type]*Type))
+}
+
+// typeOf строит тип; seen хранит уже построенные и строящиеся типы.
+func typeOf(typ reflect.Type, seen map[reflect.Type]*Type) *Type {
+	if typ == nil {
+		return AnyType()
+	}
+	if t, ok := seen[typ]; ok {
+		return t
+	}
+	switch typ {
+	case mapType:
+		return &Type{Kind: TypeMap, GoType: typ}
+	case setType, rangeType:
+		return &Type{Kind: TypeList, GoType: typ}
+	}
+	if typ.Implements(scriptType) {
+		return &Type{Kind: TypeFunction, Variadic: true}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &Type{Kind: TypeBoolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Type{Kind: TypeInteger}
+	case reflect.Float32, reflect.Float64:
+		return &Type{Kind: TypeNumber}
+	case reflect.String:
+		return &Type{Kind: TypeString}
+	case reflect.Struct:
+		return &Type{Kind: TypeObject, GoType: typ}
+	}
+	// Составной тип регистрируется до построения частей, которые могут ссылаться на него
+	t := &Type{Kind: TypeAny}
+	seen[typ] = t
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		t.Kind, t.GoType = TypeList, typ
+		t.Elem = typeOf(typ.Elem(), seen)
+	case reflect.Map:
+		t.Kind, t.GoType = TypeMap, typ
+		t.Elem = typeOf(typ.Elem(), seen)
+	case reflect.Func:
+		t.Kind, t.Variadic, t.GoType = TypeFunction, typ.IsVariadic(), typ
+		for i := 0; i < typ.NumIn(); i++ {
+			t.Params = append(t.Params, typeOf(typ.In(i), seen))
+		}
+		if typ.NumOut() > 0 {
+			t.Result = typeOf(typ.Out(0), seen)
+		}
+	case reflect.Ptr:
+		elem := typeOf(typ.Elem(), seen)
+		if elem != t && elem.Kind != TypeAny {
+			*t = *elem
+			t.GoType = typ
+		}
+	}
+	// Интерфейсы и прочие типы остаются any: значение определяется при выполнении
+	return t
+}
+
+// Schema описывает переменные контекста и пространства имён для проверки типов.
+type Schema struct {
+	// Context - тип контекста; его свойства - переменные. Для схемы из типа Go
+	// имена переменных разрешаются так же, как ObjectContext.
+	Context *Type
+	// Namespaces - пространства имён: объекты, свойства или методы которых - функции.
+	// Не описанные здесь пространства берутся из опций движка.
+	Namespaces map[string]*Type
+}
+
+// NewSchema создаёт схему с заданными переменными.
+func NewSchema(variables map[string]*Type) *Schema {
+	return &Schema{Context: ObjectOf(variables)}
+}
+
+// SchemaOf создаёт схему из типа значения Go, как для ObjectContext: переменные -
+// поля и геттеры структуры или значения мапы.
+func SchemaOf(value any) *Schema {
+	return &Schema{Context: TypeOf(reflect.TypeOf(value))}
+}
+
+// ParseSchema разбирает схему в формате, похожем на JSON Schema:
+//
+//	{"properties": {"order": {"type": "object", "properties": {"total": {"type": "number"}}}},
+//	 "namespaces": {"util": {"properties": {"f": {"type": "function", "parameters": [{"type": "string"}]}}}}}
+//
+// Типы: any, null, boolean, integer, number, string, array, object, function;
+// "type": ["string", "null"] или "nullable": true допускают null. В отличие от
+// JSON Schema объект со списком properties закрыт, если не задано
+// "additionalProperties": true; объект без properties - мапа значений additionalProperties.
+func ParseSchema(data []byte) (*Schema, error) {
+	var root struct {
+		jsonType
+		Namespaces map[string]*jsonType `json:"namespaces"`
+	}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	context, err := root.jsonType.toType()
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{Context: context, Namespaces: make(map[string]*Type)}
+	for name, ns := range root.Namespaces {
+		if schema.Namespaces[name], err = ns.toType(); err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", name, err)
+		}
+	}
+	return schema, nil
+}
+
+// jsonType - описание типа в схеме ParseSchema.
+type jsonType struct {
+	Type                 json.RawMessage      `json:"type"`
+	Nullable             bool                 `json:"nullable"`
+	Items                *jsonType            `json:"items"`
+	Properties           map[string]*jsonType `json:"properties"`
+	AdditionalProperties json.RawMessage      `json:"additionalProperties"`
+	Parameters           []*jsonType          `json:"parameters"`
+	Variadic             bool                 `json:"variadic"`
+	Returns              *jsonType            `json:"returns"`
+}
+
+// toType преобразует описание в Type.
+func (j *jsonType) toType() (*Type, error) {
+	if j == nil {
+		return AnyType(), nil
+	}
+	var names []string
+	if len(j.Type) > 0 {
+		var name string
+		if err := json.Unmarshal(j.Type, &name); err == nil {
+			names = []string{name}
+		} else if err := json.Unmarshal(j.Type, &names); err != nil {
+			return nil, fmt.Errorf("invalid type %s", j.Type)
+		}
+	}
+	t := &Type{Kind: TypeAny, Nullable: j.Nullable}
+	for _, name := range names {
+		switch strings.ToLower(name) {
+		case "null":
+			t.Nullable = true
+			if len(names) == 1 {
+				t.Kind = TypeNull
+			}
+		case "any":
+		case "boolean":
+			t.Kind = TypeBoolean
+		case "integer":
+			t.Kind = TypeInteger
+		case "number":
+			t.Kind = TypeNumber
+		case "string":
+			t.Kind = TypeString
+		case "array", "list":
+			t.Kind = TypeList
+		case "object", "map":
+			t.Kind = TypeObject
+		case "function":
+			t.Kind = TypeFunction
+		default:
+			return nil, fmt.Errorf("unknown type %q", name)
+		}
+	}
+	var err error
+	switch t.Kind {
+	case TypeList:
+		t.Elem, err = j.Items.toType()
+	case TypeObject, TypeAny:
+		if j.Properties != nil {
+			t.Kind = TypeObject
+			t.Properties = make(map[string]*Type, len(j.Properties))
+			for name, property := range j.Properties {
+				if t.Properties[name], err = property.toType(); err != nil {
+					return nil, fmt.Errorf("property %s: %w", name, err)
+				}
+			}
+			t.Open = string(j.AdditionalProperties) == "true"
+		} else if t.Kind == TypeObject {
+			// Объект без свойств - мапа значений additionalProperties
+			t.Kind = TypeMap
+			var additional jsonType
+			if len(j.AdditionalProperties) > 0 && json.Unmarshal(j.AdditionalProperties, &additional) == nil {
+				t.Elem, err = additional.toType()
+			}
+		}
+	case TypeFunction:
+		t.Variadic = j.Variadic
+		for _, param := range j.Parameters {
+			p, err := param.toType()
+			if err != nil {
+				return nil, err
+			}
+			t.Params = append(t.Params, p)
+		}
+		if j.Returns != nil {
+			t.Result, err = j.Returns.toType()
+		}
+	}
+	return t, err
+}
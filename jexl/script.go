@@ -6,9 +6,19 @@ package jexl
 type Script interface {
 	Expression // Script включает все методы Expression
 	
+	// AST возвращает корневой узел скрипта. Узлы не изменяются: для преобразований
+	// используйте Rewrite и Engine.CreateScriptFromAST.
+	AST() *ScriptNode
 	CallableWithArgs(ctx Context, args ...any) func() (any, error)
 	Curry(args ...any) Script
+	// Dependencies возвращает зависимости скрипта от контекста с учётом Builder.CollectMode.
+	Dependencies() []Dependency
 	Execute(ctx Context, args ...any) (any, error)
+	ExecuteNamed(ctx Context, named map[string]any) (any, error)
+	// Function возвращает функцию, объявленную на верхнем уровне скрипта; остальные
+	// имена, которые она использует, берутся из ctx. ctx не изменяется.
+	Function(ctx Context, name string) (Script, error)
+	Functions() []string
 	LocalVariables() []string
 	Parameters() []string
 	ParsedTextWithIndent(indent int) string
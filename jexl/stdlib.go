@@ -0,0 +1,465 @@
+package jexl
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Имена стандартных пространств имён (см. Builder.StandardNamespaces).
+const (
+	NamespaceMath   = "math"
+	NamespaceStr    = "str"
+	NamespaceTime   = "time"
+	NamespaceJSON   = "json"
+	NamespaceRegex  = "regex"
+	NamespaceUUID   = "uuid"
+	NamespaceBase64 = "base64"
+	NamespaceHash   = "hash"
+)
+
+// NamespaceFuncs - пространство имён из именованных функций, вызываемых как ns:name(args).
+type NamespaceFuncs map[string]func(args ...any) (any, error)
+
+// stdFunc - функция стандартной библиотеки с допустимым числом аргументов
+// (max < 0 - без ограничения).
+type stdFunc struct {
+	min int
+	max int
+	fn  func(args []any) (any, error)
+}
+
+// standardNamespaces - функции стандартных пространств имён.
+var standardNamespaces = map[string]map[string]stdFunc{
+	NamespaceMath:   mathFuncs,
+	NamespaceStr:    strFuncs,
+	NamespaceTime:   timeFuncs,
+	NamespaceJSON:   jsonFuncs,
+	NamespaceRegex:  regexFuncs,
+	NamespaceUUID:   uuidFuncs,
+	NamespaceBase64: base64Funcs,
+	NamespaceHash:   hashFuncs,
+}
+
+// StandardNamespaceNames возвращает отсортированные имена стандартных пространств имён.
+func StandardNamespaceNames() []string {
+	names := make([]string, 0, len(standardNamespaces))
+	for name := range standardNamespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StandardNamespace возвращает функции стандартного пространства имён.
+// Функция ns:name включается, только если permissions разрешают имя jexl.ns.name,
+// поэтому запрет "jexl.time" отключает всё пространство, а "jexl.time.now" - одну функцию.
+func StandardNamespace(name string, permissions *Permissions) (NamespaceFuncs, error) {
+	funcs, ok := standardNamespaces[name]
+	if !ok {
+		return nil, NewError("unknown standard namespace: " + name)
+	}
+	result := NamespaceFuncs{}
+	for fname, f := range funcs {
+		if permissions.Allows("jexl." + name + "." + fname) {
+			result[fname] = f.bind(name + ":" + fname)
+		}
+	}
+	return result, nil
+}
+
+// StandardFuncArity возвращает допустимое число аргументов функции ns:name
+// стандартного пространства имён (max < 0 - без ограничения).
+func StandardFuncArity(namespace, name string) (min, max int, ok bool) {
+	f, ok := standardNamespaces[namespace][name]
+	return f.min, f.max, ok
+}
+
+// bind оборачивает функцию проверкой числа аргументов.
+func (f stdFunc) bind(qualified string) func(args ...any) (any, error) {
+	return func(args ...any) (any, error) {
+		if len(args) < f.min || (f.max >= 0 && len(args) > f.max) {
+			switch {
+			case f.max == 0:
+				return nil, NewError(qualified + "() takes no arguments")
+			case f.min == f.max:
+				return nil, NewError(fmt.Sprintf("%s() takes %d argument(s)", qualified, f.min))
+			case f.max < 0:
+				return nil, NewError(fmt.Sprintf("%s() takes at least %d argument(s)", qualified, f.min))
+			default:
+				return nil, NewError(fmt.Sprintf("%s() takes %d to %d arguments", qualified, f.min, f.max))
+			}
+		}
+		result, err := f.fn(args)
+		if err != nil {
+			return nil, NewError(qualified + ": " + err.Error())
+		}
+		return result, nil
+	}
+}
+
+// stdNumber приводит аргумент к числу.
+func stdNumber(value any) (*big.Rat, error) {
+	if _, isBool := value.(bool); !isBool {
+		if _, isString := value.(string); !isString {
+			if r, ok := toBig(value); ok {
+				return r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("expected number, got %T", value)
+}
+
+// stdInt приводит аргумент к целому числу.
+func stdInt(value any) (int64, error) {
+	r, err := stdNumber(value)
+	if err != nil {
+		return 0, err
+	}
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, fmt.Errorf("expected integer, got %s", r.RatString())
+	}
+	return r.Num().Int64(), nil
+}
+
+// stdString приводит аргумент к строке; nil становится пустой строкой.
+func stdString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// numberResult возвращает целое как int64, остальные числа - как float64.
+func numberResult(r *big.Rat) any {
+	if r.IsInt() && r.Num().IsInt64() {
+		return r.Num().Int64()
+	}
+	f, _ := r.Float64()
+	return f
+}
+
+// floatResultValue возвращает float64, а целое значение - как int64.
+func floatResultValue(f float64) any {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return int64(f)
+	}
+	return f
+}
+
+// stdElements возвращает элементы коллекции: слайса, массива, Set, Map (значения) или Range.
+// Второй результат false, если значение не коллекция; ошибка - если диапазон
+// слишком длинный, чтобы его материализовать.
+func stdElements(value any) ([]any, bool, error) {
+	switch v := value.(type) {
+	case []any:
+		return v, true, nil
+	case *Set:
+		return v.Values(), true, nil
+	case *Map:
+		return v.Values(), true, nil
+	case *Range:
+		if err := v.CheckMaterialize(); err != nil {
+			return nil, true, err
+		}
+		result := make([]any, 0, v.Size())
+		for n := range v.Iterator() {
+			result = append(result, n)
+		}
+		return result, true, nil
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false, nil
+	}
+	result := make([]any, rv.Len())
+	for i := range result {
+		result[i] = rv.Index(i).Interface()
+	}
+	return result, true, nil
+}
+
+// mathFuncs - пространство имён math.
+var mathFuncs = map[string]stdFunc{
+	"abs": {1, 1, func(a []any) (any, error) {
+		x, err := stdNumber(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return numberResult(x.Abs(x)), nil
+	}},
+	"round": {1, 2, func(a []any) (any, error) {
+		x, err := stdNumber(a[0])
+		if err != nil {
+			return nil, err
+		}
+		scale := int64(0)
+		if len(a) > 1 {
+			if scale, err = stdInt(a[1]); err != nil {
+				return nil, err
+			}
+		}
+		return numberResult(roundHalfUp(x, scale)), nil
+	}},
+	"pow": {2, 2, func(a []any) (any, error) {
+		x, err := stdNumber(a[0])
+		if err != nil {
+			return nil, err
+		}
+		y, err := stdNumber(a[1])
+		if err != nil {
+			return nil, err
+		}
+		// Целая степень считается точно
+		if y.IsInt() && y.Num().IsInt64() && math.Abs(float64(y.Num().Int64())) <= 1024 {
+			n := y.Num().Int64()
+			if n < 0 && x.Sign() == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			exp := big.NewInt(n)
+			exp.Abs(exp)
+			num := new(big.Int).Exp(x.Num(), exp, nil)
+			den := new(big.Int).Exp(x.Denom(), exp, nil)
+			if n < 0 {
+				num, den = den, num
+			}
+			return numberResult(new(big.Rat).SetFrac(num, den)), nil
+		}
+		xf, _ := x.Float64()
+		yf, _ := y.Float64()
+		return floatResultValue(math.Pow(xf, yf)), nil
+	}},
+	"sqrt": {1, 1, func(a []any) (any, error) {
+		x, err := stdNumber(a[0])
+		if err != nil {
+			return nil, err
+		}
+		if x.Sign() < 0 {
+			return nil, fmt.Errorf("square root of negative number")
+		}
+		f, _ := x.Float64()
+		return floatResultValue(math.Sqrt(f)), nil
+	}},
+	"min": {1, -1, func(a []any) (any, error) {
+		return extremum(a, -1)
+	}},
+	"max": {1, -1, func(a []any) (any, error) {
+		return extremum(a, 1)
+	}},
+	"floor": {1, 1, func(a []any) (any, error) {
+		x, err := stdNumber(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return numberResult(floorRat(x)), nil
+	}},
+	"ceil": {1, 1, func(a []any) (any, error) {
+		x, err := stdNumber(a[0])
+		if err != nil {
+			return nil, err
+		}
+		x.Neg(x)
+		x = floorRat(x)
+		return numberResult(x.Neg(x)), nil
+	}},
+}
+
+// floorRat округляет число вниз до целого.
+func floorRat(x *big.Rat) *big.Rat {
+	q := new(big.Int)
+	m := new(big.Int)
+	q.DivMod(x.Num(), x.Denom(), m) // Евклидово деление: остаток неотрицателен
+	return new(big.Rat).SetInt(q)
+}
+
+// roundHalfUp округляет число до scale знаков после запятой, половину - от нуля.
+// Отрицательный scale округляет до десятков, сотен и т.д.
+func roundHalfUp(x *big.Rat, scale int64) *big.Rat {
+	shift := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(abs64(scale)), nil))
+	v := new(big.Rat).Set(x)
+	if scale >= 0 {
+		v.Mul(v, shift)
+	} else {
+		v.Quo(v, shift)
+	}
+	sign := v.Sign()
+	v.Abs(v)
+	v.Add(v, big.NewRat(1, 2))
+	v = floorRat(v)
+	if sign < 0 {
+		v.Neg(v)
+	}
+	if scale >= 0 {
+		return v.Quo(v, shift)
+	}
+	return v.Mul(v, shift)
+}
+
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// extremum находит минимум (dir < 0) или максимум (dir > 0) аргументов
+// или элементов единственного аргумента-коллекции.
+func extremum(args []any, dir int) (any, error) {
+	if len(args) == 1 {
+		items, ok, err := stdElements(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			args = items
+		}
+	}
+	var best *big.Rat
+	for _, arg := range args {
+		x, err := stdNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || x.Cmp(best)*dir > 0 {
+			best = x
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return numberResult(best), nil
+}
+
+// strFuncs - пространство имён str. Длины и отступы считаются в символах.
+var strFuncs = map[string]stdFunc{
+	"length": {1, 1, func(a []any) (any, error) {
+		return int64(utf8.RuneCountInString(stdString(a[0]))), nil
+	}},
+	"upper": {1, 1, func(a []any) (any, error) {
+		return strings.ToUpper(stdString(a[0])), nil
+	}},
+	"lower": {1, 1, func(a []any) (any, error) {
+		return strings.ToLower(stdString(a[0])), nil
+	}},
+	"trim": {1, 1, func(a []any) (any, error) {
+		return strings.TrimSpace(stdString(a[0])), nil
+	}},
+	"isBlank": {1, 1, func(a []any) (any, error) {
+		return strings.TrimSpace(stdString(a[0])) == "", nil
+	}},
+	"capitalize": {1, 1, func(a []any) (any, error) {
+		s := stdString(a[0])
+		r, size := utf8.DecodeRuneInString(s)
+		if size == 0 {
+			return s, nil
+		}
+		return string(unicode.ToUpper(r)) + s[size:], nil
+	}},
+	"reverse": {1, 1, func(a []any) (any, error) {
+		runes := []rune(stdString(a[0]))
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}},
+	"contains": {2, 2, func(a []any) (any, error) {
+		return strings.Contains(stdString(a[0]), stdString(a[1])), nil
+	}},
+	"startsWith": {2, 2, func(a []any) (any, error) {
+		return strings.HasPrefix(stdString(a[0]), stdString(a[1])), nil
+	}},
+	"endsWith": {2, 2, func(a []any) (any, error) {
+		return strings.HasSuffix(stdString(a[0]), stdString(a[1])), nil
+	}},
+	"replace": {3, 3, func(a []any) (any, error) {
+		return strings.ReplaceAll(stdString(a[0]), stdString(a[1]), stdString(a[2])), nil
+	}},
+	"repeat": {2, 2, func(a []any) (any, error) {
+		n, err := stdInt(a[1])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("negative count %d", n)
+		}
+		return strings.Repeat(stdString(a[0]), int(n)), nil
+	}},
+	"split": {2, 2, func(a []any) (any, error) {
+		parts := strings.Split(stdString(a[0]), stdString(a[1]))
+		result := make([]any, len(parts))
+		for i, part := range parts {
+			result[i] = part
+		}
+		return result, nil
+	}},
+	"join": {1, 2, func(a []any) (any, error) {
+		items, ok, err := stdElements(a[0])
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("expected collection, got %T", a[0])
+		}
+		separator := ","
+		if len(a) > 1 {
+			separator = stdString(a[1])
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = stdString(item)
+		}
+		return strings.Join(parts, separator), nil
+	}},
+	"padStart": {2, 3, func(a []any) (any, error) {
+		pad, err := stdPadding(a)
+		if err != nil {
+			return nil, err
+		}
+		return pad + stdString(a[0]), nil
+	}},
+	"padEnd": {2, 3, func(a []any) (any, error) {
+		pad, err := stdPadding(a)
+		if err != nil {
+			return nil, err
+		}
+		return stdString(a[0]) + pad, nil
+	}},
+}
+
+// stdPadding вычисляет дополнение строки a[0] до длины a[1] символами a[2] (по умолчанию пробел).
+func stdPadding(a []any) (string, error) {
+	length, err := stdInt(a[1])
+	if err != nil {
+		return "", err
+	}
+	pad := " "
+	if len(a) > 2 {
+		pad = stdString(a[2])
+	}
+	return Padding(stdString(a[0]), int(length), pad), nil
+}
+
+// Padding возвращает дополнение строки s до length символов повторением pad.
+// Используется padStart и padEnd стандартной библиотеки и строковых методов.
+func Padding(s string, length int, pad string) string {
+	missing := length - utf8.RuneCountInString(s)
+	if missing <= 0 || pad == "" {
+		return ""
+	}
+	padRunes := []rune(pad)
+	result := make([]rune, missing)
+	for i := range result {
+		result[i] = padRunes[i%len(padRunes)]
+	}
+	return string(result)
+}
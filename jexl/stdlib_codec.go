@@ -0,0 +1,262 @@
+package jexl
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonFuncs - пространство имён json.
+var jsonFuncs = map[string]stdFunc{
+	"parse": {1, 1, func(a []any) (any, error) {
+		dec := json.NewDecoder(strings.NewReader(stdString(a[0])))
+		dec.UseNumber()
+		value, err := decodeJSON(dec)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return nil, fmt.Errorf("unexpected data after JSON value")
+		}
+		return value, nil
+	}},
+	"stringify": {1, 2, func(a []any) (any, error) {
+		value := jsonReady(a[0])
+		if len(a) == 1 {
+			data, err := json.Marshal(value)
+			return string(data), err
+		}
+		indent := stdString(a[1])
+		if n, err := stdInt(a[1]); err == nil {
+			indent = strings.Repeat(" ", int(n))
+		}
+		data, err := json.MarshalIndent(value, "", indent)
+		return string(data), err
+	}},
+}
+
+// decodeJSON читает JSON-значение, сохраняя порядок ключей объектов в *Map.
+// Целые числа становятся int64, дробные - float64.
+func decodeJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			list := []any{}
+			for dec.More() {
+				item, err := decodeJSON(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, item)
+			}
+			_, err := dec.Token() // ']'
+			return list, err
+		}
+		m := NewMap()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.Put(key, value)
+		}
+		_, err := dec.Token() // '}'
+		return m, err
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, nil
+		}
+		return t.Float64()
+	}
+	return tok, nil
+}
+
+// jsonReady подготавливает значение к сериализации: числа арифметики становятся
+// JSON-числами, множества - массивами.
+func jsonReady(value any) any {
+	switch v := value.(type) {
+	case *big.Rat, *floatResult:
+		r, _ := toBig(v)
+		if r.IsInt() {
+			return json.Number(r.Num().String())
+		}
+		f, _ := r.Float64()
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64))
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = jsonReady(item)
+		}
+		return result
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			result[key] = jsonReady(item)
+		}
+		return result
+	case *Map:
+		result := NewMap()
+		for key, item := range v.Iterator() {
+			result.Put(key, jsonReady(item))
+		}
+		return result
+	case *Set:
+		return jsonReady(v.Values())
+	}
+	return value
+}
+
+// regexFuncs - пространство имён regex. Шаблон - строка или регулярное выражение ~/.../.
+var regexFuncs = map[string]stdFunc{
+	"matches": {2, 2, func(a []any) (any, error) {
+		re, err := ToRegex(a[1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(stdString(a[0])), nil
+	}},
+	"find": {2, 2, func(a []any) (any, error) {
+		re, err := ToRegex(a[1])
+		if err != nil {
+			return nil, err
+		}
+		loc := re.FindStringIndex(stdString(a[0]))
+		if loc == nil {
+			return nil, nil
+		}
+		return stdString(a[0])[loc[0]:loc[1]], nil
+	}},
+	"findAll": {2, 2, func(a []any) (any, error) {
+		re, err := ToRegex(a[1])
+		if err != nil {
+			return nil, err
+		}
+		result := []any{}
+		for _, match := range re.FindAllString(stdString(a[0]), -1) {
+			result = append(result, match)
+		}
+		return result, nil
+	}},
+	"groups": {2, 2, func(a []any) (any, error) {
+		re, err := ToRegex(a[1])
+		if err != nil {
+			return nil, err
+		}
+		if groups := RegexGroups(re, stdString(a[0])); groups != nil {
+			return groups, nil
+		}
+		return nil, nil
+	}},
+	"replace": {3, 3, func(a []any) (any, error) {
+		re, err := ToRegex(a[1])
+		if err != nil {
+			return nil, err
+		}
+		return re.ReplaceAllString(stdString(a[0]), RegexReplacement(stdString(a[2]))), nil
+	}},
+	"split": {2, 2, func(a []any) (any, error) {
+		re, err := ToRegex(a[1])
+		if err != nil {
+			return nil, err
+		}
+		parts := re.Split(stdString(a[0]), -1)
+		result := make([]any, len(parts))
+		for i, part := range parts {
+			result[i] = part
+		}
+		return result, nil
+	}},
+	"quote": {1, 1, func(a []any) (any, error) {
+		return regexp.QuoteMeta(stdString(a[0])), nil
+	}},
+}
+
+// base64Funcs - пространство имён base64. Декодирование возвращает строку.
+var base64Funcs = map[string]stdFunc{
+	"encode": {1, 1, func(a []any) (any, error) {
+		return base64.StdEncoding.EncodeToString([]byte(stdString(a[0]))), nil
+	}},
+	"decode": {1, 1, func(a []any) (any, error) {
+		data, err := base64.StdEncoding.DecodeString(stdString(a[0]))
+		return string(data), err
+	}},
+	"urlEncode": {1, 1, func(a []any) (any, error) {
+		return base64.RawURLEncoding.EncodeToString([]byte(stdString(a[0]))), nil
+	}},
+	"urlDecode": {1, 1, func(a []any) (any, error) {
+		data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(stdString(a[0]), "="))
+		return string(data), err
+	}},
+}
+
+// hashFuncs - пространство имён hash. Дайджесты возвращаются в шестнадцатеричном виде.
+var hashFuncs = map[string]stdFunc{
+	"md5": {1, 1, func(a []any) (any, error) {
+		sum := md5.Sum([]byte(stdString(a[0])))
+		return hex.EncodeToString(sum[:]), nil
+	}},
+	"sha256": {1, 1, func(a []any) (any, error) {
+		sum := sha256.Sum256([]byte(stdString(a[0])))
+		return hex.EncodeToString(sum[:]), nil
+	}},
+	"crc32": {1, 1, func(a []any) (any, error) {
+		return int64(crc32.ChecksumIEEE([]byte(stdString(a[0])))), nil
+	}},
+}
+
+// uuidFuncs - пространство имён uuid (RFC 9562).
+var uuidFuncs = map[string]stdFunc{
+	"v4": {0, 0, func([]any) (any, error) {
+		var u [16]byte
+		if _, err := rand.Read(u[:]); err != nil {
+			return nil, err
+		}
+		return formatUUID(u, 4), nil
+	}},
+	"v7": {0, 0, func([]any) (any, error) {
+		// 48 бит - миллисекунды Unix-времени, остальное - случайные биты
+		var u [16]byte
+		if _, err := rand.Read(u[6:]); err != nil {
+			return nil, err
+		}
+		var ts [8]byte
+		binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+		copy(u[:6], ts[2:])
+		return formatUUID(u, 7), nil
+	}},
+}
+
+// formatUUID проставляет версию и вариант и форматирует UUID.
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+	var buf bytes.Buffer
+	for i, b := range u {
+		if i == 4 || i == 6 || i == 8 || i == 10 {
+			buf.WriteByte('-')
+		}
+		fmt.Fprintf(&buf, "%02x", b)
+	}
+	return buf.String()
+}
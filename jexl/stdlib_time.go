@@ -0,0 +1,288 @@
+package jexl
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// timeUnit - единица измерения времени для time:add, time:diff и time:truncate.
+type timeUnit int
+
+const (
+	unitNanos timeUnit = iota
+	unitMicros
+	unitMillis
+	unitSeconds
+	unitMinutes
+	unitHours
+	unitDays
+	unitWeeks
+	unitMonths
+	unitYears
+)
+
+// timeUnitNames сопоставляет имена единиц (в том числе краткие) с единицами.
+var timeUnitNames = map[string]timeUnit{
+	"nanos": unitNanos, "nano": unitNanos, "ns": unitNanos,
+	"micros": unitMicros, "micro": unitMicros, "us": unitMicros,
+	"millis": unitMillis, "milli": unitMillis, "ms": unitMillis,
+	"seconds": unitSeconds, "second": unitSeconds, "s": unitSeconds,
+	"minutes": unitMinutes, "minute": unitMinutes, "min": unitMinutes,
+	"hours": unitHours, "hour": unitHours, "h": unitHours,
+	"days": unitDays, "day": unitDays, "d": unitDays,
+	"weeks": unitWeeks, "week": unitWeeks, "w": unitWeeks,
+	"months": unitMonths, "month": unitMonths, "mo": unitMonths,
+	"years": unitYears, "year": unitYears, "y": unitYears,
+}
+
+// unitDurations - длительность единиц фиксированной длины.
+var unitDurations = map[timeUnit]time.Duration{
+	unitNanos:   time.Nanosecond,
+	unitMicros:  time.Microsecond,
+	unitMillis:  time.Millisecond,
+	unitSeconds: time.Second,
+	unitMinutes: time.Minute,
+	unitHours:   time.Hour,
+	unitDays:    24 * time.Hour,
+	unitWeeks:   7 * 24 * time.Hour,
+}
+
+// defaultTimeLayouts - форматы, которые time:parse пробует без явного шаблона.
+var defaultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// timeFuncs - пространство имён time. Значения - time.Time; строки разбираются
+// в формате RFC 3339, числа считаются миллисекундами Unix-времени.
+var timeFuncs = map[string]stdFunc{
+	"now": {0, 0, func([]any) (any, error) {
+		return time.Now(), nil
+	}},
+	"parse": {1, 2, func(a []any) (any, error) {
+		s := stdString(a[0])
+		if len(a) > 1 {
+			return time.Parse(TimeLayout(stdString(a[1])), s)
+		}
+		return parseTime(s)
+	}},
+	"format": {1, 2, func(a []any) (any, error) {
+		t, err := toTime(a[0])
+		if err != nil {
+			return nil, err
+		}
+		layout := time.RFC3339
+		if len(a) > 1 {
+			layout = TimeLayout(stdString(a[1]))
+		}
+		return t.Format(layout), nil
+	}},
+	"duration": {1, 1, func(a []any) (any, error) {
+		return ToDuration(a[0])
+	}},
+	"add": {2, 3, func(a []any) (any, error) {
+		t, err := toTime(a[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(a) == 2 {
+			d, err := ToDuration(a[1])
+			if err != nil {
+				return nil, err
+			}
+			return t.Add(d), nil
+		}
+		amount, err := stdInt(a[1])
+		if err != nil {
+			return nil, err
+		}
+		unit, err := toTimeUnit(a[2])
+		if err != nil {
+			return nil, err
+		}
+		switch unit {
+		case unitMonths:
+			return t.AddDate(0, int(amount), 0), nil
+		case unitYears:
+			return t.AddDate(int(amount), 0, 0), nil
+		case unitDays, unitWeeks:
+			// Календарные дни сохраняют время суток при переходе на летнее время
+			return t.AddDate(0, 0, int(amount*int64(unitDurations[unit]/unitDurations[unitDays]))), nil
+		}
+		d, err := scaleDuration(unitDurations[unit], big.NewRat(amount, 1))
+		if err != nil {
+			return nil, err
+		}
+		return t.Add(d), nil
+	}},
+	"diff": {2, 3, func(a []any) (any, error) {
+		from, err := toTime(a[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := toTime(a[1])
+		if err != nil {
+			return nil, err
+		}
+		unit := unitMillis
+		if len(a) > 2 {
+			if unit, err = toTimeUnit(a[2]); err != nil {
+				return nil, err
+			}
+		}
+		switch unit {
+		case unitMonths:
+			return monthsBetween(from, to), nil
+		case unitYears:
+			return monthsBetween(from, to) / 12, nil
+		}
+		return int64(to.Sub(from) / unitDurations[unit]), nil
+	}},
+	"truncate": {2, 2, func(a []any) (any, error) {
+		t, err := toTime(a[0])
+		if err != nil {
+			return nil, err
+		}
+		unit, err := toTimeUnit(a[1])
+		if err != nil {
+			return nil, err
+		}
+		return truncateTime(t, unit), nil
+	}},
+}
+
+// toTime приводит значение к time.Time.
+func toTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		return parseTime(v)
+	case nil:
+	default:
+		if millis, err := stdInt(v); err == nil {
+			return time.UnixMilli(millis), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected time, got %T", value)
+}
+
+// parseTime разбирает строку в одном из форматов defaultTimeLayouts.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range defaultTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", s)
+}
+
+// toTimeUnit разбирает имя единицы времени без учёта регистра.
+func toTimeUnit(value any) (timeUnit, error) {
+	name := stdString(value)
+	if unit, ok := timeUnitNames[strings.ToLower(name)]; ok {
+		return unit, nil
+	}
+	return 0, fmt.Errorf("unknown time unit %q", name)
+}
+
+// monthsBetween возвращает число полных месяцев от from до to (отрицательное, если to раньше).
+func monthsBetween(from, to time.Time) int64 {
+	to = to.In(from.Location())
+	months := int64(to.Year()-from.Year())*12 + int64(to.Month()-from.Month())
+	shifted := from.AddDate(0, int(months), 0)
+	if months > 0 && shifted.After(to) {
+		months--
+	} else if months < 0 && shifted.Before(to) {
+		months++
+	}
+	return months
+}
+
+// truncateTime отбрасывает части времени меньше единицы в часовом поясе значения.
+// Неделя начинается с понедельника.
+func truncateTime(t time.Time, unit timeUnit) time.Time {
+	year, month, day := t.Date()
+	switch unit {
+	case unitYears:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, t.Location())
+	case unitMonths:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	case unitWeeks:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, t.Location())
+	case unitDays:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	case unitHours:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case unitMinutes:
+		return time.Date(year, month, day, t.Hour(), t.Minute(), 0, 0, t.Location())
+	case unitSeconds:
+		return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	}
+	unitNs := int(unitDurations[unit])
+	return time.Date(year, month, day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/unitNs*unitNs, t.Location())
+}
+
+// javaLayoutElements сопоставляет элементы шаблона java.time.format.DateTimeFormatter
+// с элементами шаблона Go.
+var javaLayoutElements = map[string]string{
+	"yyyy": "2006", "yy": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"dd": "02", "d": "2",
+	"EEEE": "Monday", "EEE": "Mon", "E": "Mon",
+	"HH": "15", "hh": "03", "h": "3",
+	"mm": "04", "m": "4",
+	"ss": "05", "s": "5",
+	"SSS": "000", "SSSSSS": "000000", "SSSSSSSSS": "000000000",
+	"a":   "PM",
+	"XXX": "Z07:00", "XX": "Z0700", "X": "Z07",
+	"Z": "-0700", "z": "MST",
+}
+
+// TimeLayout переводит шаблон в стиле Java (yyyy-MM-dd HH:mm:ss) в шаблон Go.
+// Шаблон Go (с элементами 2006, 15:04 и т.п.) возвращается без изменений;
+// текст в одинарных кавычках выводится как есть.
+func TimeLayout(pattern string) string {
+	if strings.Contains(pattern, "2006") || strings.Contains(pattern, "15:04") {
+		return pattern
+	}
+	var sb strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		if c == '\'' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == i+1 && end < len(runes) {
+				sb.WriteRune('\'') // '' - экранированная кавычка
+			} else {
+				sb.WriteString(string(runes[i+1 : min(end, len(runes))]))
+			}
+			i = end + 1
+			continue
+		}
+		j := i
+		for j < len(runes) && runes[j] == c {
+			j++
+		}
+		element := string(runes[i:j])
+		if layout, ok := javaLayoutElements[element]; ok {
+			sb.WriteString(layout)
+		} else {
+			sb.WriteString(element)
+		}
+		i = j
+	}
+	return sb.String()
+}
@@ -0,0 +1,299 @@
+package jexl
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// asTime возвращает значение как time.Time (принимает time.Time и ненулевой *time.Time).
+func asTime(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// asDuration возвращает значение как time.Duration.
+func asDuration(value any) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case *time.Duration:
+		if v != nil {
+			return *v, true
+		}
+	}
+	return 0, false
+}
+
+// addTemporal складывает время с длительностью и длительности между собой.
+// Второй результат false, если операнды не временные.
+func addTemporal(lhs, rhs any) (any, bool, error) {
+	if t, ok := asTime(lhs); ok {
+		if d, ok := asDuration(rhs); ok {
+			return t.Add(d), true, nil
+		}
+		return nil, false, nil
+	}
+	if d, ok := asDuration(lhs); ok {
+		if t, ok := asTime(rhs); ok {
+			return t.Add(d), true, nil
+		}
+		if d2, ok := asDuration(rhs); ok {
+			sum, err := ratDuration(new(big.Rat).Add(big.NewRat(int64(d), 1), big.NewRat(int64(d2), 1)))
+			return sum, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// subtractTemporal вычитает длительность из времени или длительности,
+// а разность двух моментов времени возвращает как длительность.
+func subtractTemporal(lhs, rhs any) (any, bool, error) {
+	if t, ok := asTime(lhs); ok {
+		if d, ok := asDuration(rhs); ok {
+			return t.Add(-d), true, nil
+		}
+		if t2, ok := asTime(rhs); ok {
+			return t.Sub(t2), true, nil
+		}
+		return nil, false, nil
+	}
+	if d, ok := asDuration(lhs); ok {
+		if d2, ok := asDuration(rhs); ok {
+			diff, err := ratDuration(new(big.Rat).Sub(big.NewRat(int64(d), 1), big.NewRat(int64(d2), 1)))
+			return diff, true, err
+		}
+	}
+	return nil, false, nil
+}
+
+// multiplyTemporal умножает длительность на число (с округлением до наносекунд).
+func multiplyTemporal(lhs, rhs any) (any, bool, error) {
+	d, ok := asDuration(lhs)
+	factor := rhs
+	if !ok {
+		if d, ok = asDuration(rhs); !ok {
+			return nil, false, nil
+		}
+		factor = lhs
+	}
+	r, ok := temporalScalar(factor)
+	if !ok {
+		return nil, false, nil
+	}
+	product, err := scaleDuration(d, r)
+	return product, true, err
+}
+
+// divideTemporal делит длительность на число или на длительность (результат - число).
+func divideTemporal(lhs, rhs any) (any, bool, error) {
+	d, ok := asDuration(lhs)
+	if !ok {
+		return nil, false, nil
+	}
+	if d2, ok := asDuration(rhs); ok {
+		if d2 == 0 {
+			return nil, true, NewError("division by zero")
+		}
+		return big.NewRat(int64(d), int64(d2)), true, nil
+	}
+	r, ok := temporalScalar(rhs)
+	if !ok {
+		return nil, false, nil
+	}
+	if r.Sign() == 0 {
+		return nil, true, NewError("division by zero")
+	}
+	quotient, err := scaleDuration(d, r.Inv(r))
+	return quotient, true, err
+}
+
+// compareTemporal сравнивает два момента времени или две длительности.
+func compareTemporal(lhs, rhs any) (int, bool) {
+	if t, ok := asTime(lhs); ok {
+		if t2, ok := asTime(rhs); ok {
+			return t.Compare(t2), true
+		}
+		return 0, false
+	}
+	if d, ok := asDuration(lhs); ok {
+		if d2, ok := asDuration(rhs); ok {
+			switch {
+			case d < d2:
+				return -1, true
+			case d > d2:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// temporalScalar приводит множитель длительности к числу; булевы и строки не допускаются.
+func temporalScalar(value any) (*big.Rat, bool) {
+	switch value.(type) {
+	case bool, string, time.Duration, time.Time:
+		return nil, false
+	}
+	return toBig(value)
+}
+
+// scaleDuration умножает длительность на коэффициент с округлением до наносекунд.
+func scaleDuration(d time.Duration, factor *big.Rat) (time.Duration, error) {
+	return ratDuration(new(big.Rat).Mul(big.NewRat(int64(d), 1), factor))
+}
+
+// ratDuration округляет число наносекунд до целого. Значения вне диапазона
+// time.Duration (около ±292 лет) - ошибка, а не переполнение.
+func ratDuration(nanos *big.Rat) (time.Duration, error) {
+	n := roundHalfUp(nanos, 0).Num()
+	if !n.IsInt64() {
+		return 0, NewError("duration out of range")
+	}
+	return time.Duration(n.Int64()), nil
+}
+
+// ToDuration приводит значение к time.Duration: длительность возвращается как есть,
+// строка разбирается как ISO-8601 (P3DT4H) или в формате Go (1h30m),
+// число считается миллисекундами.
+func ToDuration(value any) (time.Duration, error) {
+	if d, ok := asDuration(value); ok {
+		return d, nil
+	}
+	switch v := value.(type) {
+	case string:
+		d, err := ParseDuration(v)
+		if err != nil {
+			if goDuration, goErr := time.ParseDuration(v); goErr == nil {
+				return goDuration, nil
+			}
+		}
+		return d, err
+	case bool, nil:
+	default:
+		if r, ok := toBig(v); ok {
+			return scaleDuration(time.Millisecond, r)
+		}
+	}
+	return 0, NewError(fmt.Sprintf("cannot convert %T to duration", value))
+}
+
+// isoDurationUnits - единицы ISO-8601 длительности в порядке следования:
+// до T допустимы недели и дни, после T - часы, минуты и секунды.
+var isoDurationUnits = []struct {
+	designator byte
+	timePart   bool
+	unit       time.Duration
+}{
+	{'W', false, 7 * 24 * time.Hour},
+	{'D', false, 24 * time.Hour},
+	{'H', true, time.Hour},
+	{'M', true, time.Minute},
+	{'S', true, time.Second},
+}
+
+// ParseDuration разбирает длительность ISO-8601 вида [-]PnWnDTnHnMn.nS.
+// Как и java.time.Duration, день считается равным 24 часам; годы и месяцы
+// не поддерживаются, так как их длина зависит от даты (см. time:add).
+func ParseDuration(s string) (time.Duration, error) {
+	src := s
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+	if len(s) < 2 || (s[0] != 'P' && s[0] != 'p') {
+		return 0, NewError(fmt.Sprintf("invalid ISO-8601 duration %q", src))
+	}
+	s = strings.ToUpper(s[1:])
+
+	total := new(big.Rat)
+	timePart := false
+	next := 0 // индекс следующей допустимой единицы в isoDurationUnits
+	components := 0
+	for len(s) > 0 {
+		if s[0] == 'T' {
+			if timePart || len(s) == 1 {
+				return 0, NewError(fmt.Sprintf("invalid ISO-8601 duration %q", src))
+			}
+			timePart = true
+			s = s[1:]
+			continue
+		}
+		end := 0
+		for end < len(s) && (s[end] == '-' || s[end] == '.' || s[end] == ',' || (s[end] >= '0' && s[end] <= '9')) {
+			end++
+		}
+		if end == 0 || end == len(s) {
+			return 0, NewError(fmt.Sprintf("invalid ISO-8601 duration %q", src))
+		}
+		number, ok := new(big.Rat).SetString(strings.Replace(s[:end], ",", ".", 1))
+		if !ok {
+			return 0, NewError(fmt.Sprintf("invalid ISO-8601 duration %q", src))
+		}
+		found := false
+		for next < len(isoDurationUnits) {
+			u := isoDurationUnits[next]
+			next++
+			if u.designator == s[end] && u.timePart == timePart {
+				total.Add(total, number.Mul(number, big.NewRat(int64(u.unit), 1)))
+				found = true
+				break
+			}
+		}
+		if !found {
+			if s[end] == 'Y' || (s[end] == 'M' && !timePart) {
+				return 0, NewError(fmt.Sprintf("ISO-8601 duration %q: years and months have no fixed length", src))
+			}
+			return 0, NewError(fmt.Sprintf("invalid ISO-8601 duration %q", src))
+		}
+		components++
+		s = s[end+1:]
+	}
+	if components == 0 {
+		return 0, NewError(fmt.Sprintf("invalid ISO-8601 duration %q", src))
+	}
+	if negative {
+		total.Neg(total)
+	}
+	d, err := ratDuration(total)
+	if err != nil {
+		return 0, NewError(fmt.Sprintf("ISO-8601 duration %q is out of range", src))
+	}
+	return d, nil
+}
+
+// FormatDuration форматирует длительность в ISO-8601, как java.time.Duration.toString: PT26H3M4.5S.
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "PT0S"
+	}
+	var sb strings.Builder
+	if d < 0 {
+		sb.WriteByte('-')
+		d = -d
+	}
+	sb.WriteString("PT")
+	if hours := d / time.Hour; hours > 0 {
+		sb.WriteString(strconv.FormatInt(int64(hours), 10) + "H")
+		d -= hours * time.Hour
+	}
+	if minutes := d / time.Minute; minutes > 0 {
+		sb.WriteString(strconv.FormatInt(int64(minutes), 10) + "M")
+		d -= minutes * time.Minute
+	}
+	if d > 0 {
+		sb.WriteString(strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S")
+	}
+	return sb.String()
+}
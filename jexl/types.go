@@ -0,0 +1,193 @@
+package jexl
+
+import (
+	"fmt"
+	"maps"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// builtinTypes - встроенные имена типов для оператора instanceof.
+// Имена сравниваются без учёта регистра; поддерживаются и Java-имена.
+var builtinTypes = map[string]func(value any) bool{
+	"object": func(any) bool { return true },
+	"string": func(v any) bool {
+		_, ok := v.(string)
+		return ok
+	},
+	"number":  isNumberValue,
+	"integer": isIntegerValue,
+	"long":    isIntegerValue,
+	"int":     isIntegerValue,
+	"float": func(v any) bool {
+		return isNumberValue(v) && !isIntegerValue(v)
+	},
+	"double": func(v any) bool {
+		return isNumberValue(v) && !isIntegerValue(v)
+	},
+	"boolean": func(v any) bool {
+		_, ok := v.(bool)
+		return ok
+	},
+	"list": func(v any) bool {
+		return kindOf(v) == kindList
+	},
+	"array": func(v any) bool {
+		return kindOf(v) == kindList
+	},
+	"set": func(v any) bool {
+		return kindOf(v) == kindSet
+	},
+	"map": func(v any) bool {
+		return kindOf(v) == kindMap
+	},
+	"range": func(v any) bool {
+		_, ok := v.(*Range)
+		return ok
+	},
+	"time": func(v any) bool {
+		_, ok := asTime(v)
+		return ok
+	},
+	"duration": func(v any) bool {
+		_, ok := asDuration(v)
+		return ok
+	},
+	"regex": func(v any) bool {
+		_, ok := v.(*regexp.Regexp)
+		return ok
+	},
+	"function": func(v any) bool {
+		if _, ok := v.(Script); ok {
+			return true
+		}
+		return reflect.ValueOf(v).Kind() == reflect.Func
+	},
+}
+
+// builtinTypeAliases сопоставляет Java-имена типов со встроенными.
+var builtinTypeAliases = map[string]string{
+	"java.lang.object":        "object",
+	"java.lang.string":        "string",
+	"java.lang.number":        "number",
+	"java.lang.integer":       "integer",
+	"java.lang.long":          "long",
+	"java.lang.double":        "double",
+	"java.lang.float":         "float",
+	"java.lang.boolean":       "boolean",
+	"java.util.list":          "list",
+	"java.util.set":           "set",
+	"java.util.map":           "map",
+	"java.time.instant":       "time",
+	"java.time.duration":      "duration",
+	"java.util.regex.pattern": "regex",
+	"bigdecimal":              "number",
+	"biginteger":              "integer",
+}
+
+// isNumberValue проверяет, является ли значение числом (bool и строки не числа).
+func isNumberValue(value any) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, *big.Rat, *big.Int, *big.Float, *floatResult:
+		return true
+	}
+	return false
+}
+
+// isIntegerValue проверяет, является ли значение целым числом.
+func isIntegerValue(value any) bool {
+	switch v := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, *big.Int:
+		return true
+	case *big.Rat:
+		return v.IsInt()
+	}
+	return false
+}
+
+// BuiltinTypeNames возвращает встроенные имена типов оператора instanceof.
+func BuiltinTypeNames() []string {
+	return slices.Sorted(maps.Keys(builtinTypes))
+}
+
+// InstanceOf проверяет, является ли значение экземпляром типа (оператор instanceof).
+// Тип - reflect.Type (значение должно быть присваиваемо ему) или встроенное имя типа.
+// null не является экземпляром никакого типа.
+func (a *BaseArithmetic) InstanceOf(value, typ any) (bool, error) {
+	switch t := typ.(type) {
+	case reflect.Type:
+		if value == nil {
+			return false, nil
+		}
+		vt := reflect.TypeOf(value)
+		if vt.AssignableTo(t) {
+			return true, nil
+		}
+		// Указатель на структуру считается экземпляром самой структуры
+		return vt.Kind() == reflect.Pointer && vt.Elem() == t, nil
+	case string:
+		name := strings.ToLower(t)
+		if alias, ok := builtinTypeAliases[name]; ok {
+			name = alias
+		}
+		check, ok := builtinTypes[name]
+		if !ok {
+			return false, NewOperatorError("instanceof", nil, fmt.Errorf("unknown type %q", t))
+		}
+		return value != nil && check(value), nil
+	}
+	return false, NewOperatorError("instanceof", nil, fmt.Errorf("not a type: %T", typ))
+}
+
+// In проверяет принадлежность значения контейнеру (оператор in): подстроки строке,
+// элемента списку или множеству, ключа мапе, числа диапазону.
+func (a *BaseArithmetic) In(value, container any) (any, error) {
+	switch c := container.(type) {
+	case nil:
+		if a.strict {
+			return nil, NewOperatorError("in", nil, fmt.Errorf("null container"))
+		}
+		return false, nil
+	case string:
+		if value == nil {
+			return false, nil
+		}
+		return strings.Contains(c, toMembershipString(value)), nil
+	case *Set, *Map, *Range:
+		return a.Contains(container, value)
+	}
+	switch kindOf(container) {
+	case kindList:
+		items, _, err := stdElements(container)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if equals(item, value) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case kindMap:
+		return a.Contains(container, value)
+	}
+	return nil, NewOperatorError("in", nil, fmt.Errorf("cannot test membership in %T", container))
+}
+
+// toMembershipString приводит значение к строке для поиска подстроки.
+func toMembershipString(value any) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	if d, ok := asDuration(value); ok {
+		return FormatDuration(d)
+	}
+	if r, ok := value.(*big.Rat); ok && r.IsInt() {
+		return r.Num().String()
+	}
+	return fmt.Sprint(value)
+}
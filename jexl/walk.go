@@ -0,0 +1,702 @@
+package jexl
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Visitor посещает узлы AST при обходе Walk. Для каждого узла вызывается
+// метод Visit* его типа; false пропускает потомков узла.
+// Встройте BaseVisitor, чтобы реализовать только нужные методы.
+type Visitor interface {
+	VisitScript(node *ScriptNode) bool
+	VisitLiteral(node *LiteralNode) bool
+	VisitIdentifier(node *IdentifierNode) bool
+	VisitBinaryOp(node *BinaryOpNode) bool
+	VisitUnaryOp(node *UnaryOpNode) bool
+	VisitPropertyAccess(node *PropertyAccessNode) bool
+	VisitIndexAccess(node *IndexAccessNode) bool
+	VisitMethodCall(node *MethodCallNode) bool
+	VisitAssignment(node *AssignmentNode) bool
+	VisitTernary(node *TernaryNode) bool
+	VisitRange(node *RangeNode) bool
+	VisitElvis(node *ElvisNode) bool
+	VisitPipe(node *PipeNode) bool
+	VisitArrayLiteral(node *ArrayLiteralNode) bool
+	VisitMapLiteral(node *MapLiteralNode) bool
+	VisitSetLiteral(node *SetLiteralNode) bool
+	VisitIf(node *IfNode) bool
+	VisitFor(node *ForNode) bool
+	VisitForeach(node *ForeachNode) bool
+	VisitWhile(node *WhileNode) bool
+	VisitDoWhile(node *DoWhileNode) bool
+	VisitBlock(node *BlockNode) bool
+	VisitBreak(node *BreakNode) bool
+	VisitContinue(node *ContinueNode) bool
+	VisitReturn(node *ReturnNode) bool
+	VisitVar(node *VarNode) bool
+	VisitArrayPattern(node *ArrayPatternNode) bool
+	VisitMapPattern(node *MapPatternNode) bool
+	VisitDestructuring(node *DestructuringNode) bool
+	VisitLambda(node *LambdaNode) bool
+	VisitFunction(node *FunctionNode) bool
+	VisitSwitch(node *SwitchNode) bool
+	VisitCase(node *CaseNode) bool
+	VisitTry(node *TryNode) bool
+}
+
+// BaseVisitor - Visitor, посещающий все узлы и ничего не делающий.
+type BaseVisitor struct{}
+
+func (BaseVisitor) VisitScript(*ScriptNode) bool                 { return true }
+func (BaseVisitor) VisitLiteral(*LiteralNode) bool               { return true }
+func (BaseVisitor) VisitIdentifier(*IdentifierNode) bool         { return true }
+func (BaseVisitor) VisitBinaryOp(*BinaryOpNode) bool             { return true }
+func (BaseVisitor) VisitUnaryOp(*UnaryOpNode) bool               { return true }
+func (BaseVisitor) VisitPropertyAccess(*PropertyAccessNode) bool { return true }
+func (BaseVisitor) VisitIndexAccess(*IndexAccessNode) bool       { return true }
+func (BaseVisitor) VisitMethodCall(*MethodCallNode) bool         { return true }
+func (BaseVisitor) VisitAssignment(*AssignmentNode) bool         { return true }
+func (BaseVisitor) VisitTernary(*TernaryNode) bool               { return true }
+func (BaseVisitor) VisitRange(*RangeNode) bool                   { return true }
+func (BaseVisitor) VisitElvis(*ElvisNode) bool                   { return true }
+func (BaseVisitor) VisitPipe(*PipeNode) bool                     { return true }
+func (BaseVisitor) VisitArrayLiteral(*ArrayLiteralNode) bool     { return true }
+func (BaseVisitor) VisitMapLiteral(*MapLiteralNode) bool         { return true }
+func (BaseVisitor) VisitSetLiteral(*SetLiteralNode) bool         { return true }
+func (BaseVisitor) VisitIf(*IfNode) bool                         { return true }
+func (BaseVisitor) VisitFor(*ForNode) bool                       { return true }
+func (BaseVisitor) VisitForeach(*ForeachNode) bool               { return true }
+func (BaseVisitor) VisitWhile(*WhileNode) bool                   { return true }
+func (BaseVisitor) VisitDoWhile(*DoWhileNode) bool               { return true }
+func (BaseVisitor) VisitBlock(*BlockNode) bool                   { return true }
+func (BaseVisitor) VisitBreak(*BreakNode) bool                   { return true }
+func (BaseVisitor) VisitContinue(*ContinueNode) bool             { return true }
+func (BaseVisitor) VisitReturn(*ReturnNode) bool                 { return true }
+func (BaseVisitor) VisitVar(*VarNode) bool                       { return true }
+func (BaseVisitor) VisitArrayPattern(*ArrayPatternNode) bool     { return true }
+func (BaseVisitor) VisitMapPattern(*MapPatternNode) bool         { return true }
+func (BaseVisitor) VisitDestructuring(*DestructuringNode) bool   { return true }
+func (BaseVisitor) VisitLambda(*LambdaNode) bool                 { return true }
+func (BaseVisitor) VisitFunction(*FunctionNode) bool             { return true }
+func (BaseVisitor) VisitSwitch(*SwitchNode) bool                 { return true }
+func (BaseVisitor) VisitCase(*CaseNode) bool                     { return true }
+func (BaseVisitor) VisitTry(*TryNode) bool                       { return true }
+
+// Walk обходит AST в глубину в порядке Children, вызывая для каждого узла
+// метод Visit* его типа. Потомки узлов других типов обходятся без вызова Visitor.
+func Walk(node Node, v Visitor) {
+	if node == nil || !visit(node, v) {
+		return
+	}
+	for _, child := range node.Children() {
+		Walk(child, v)
+	}
+}
+
+// Inspect обходит AST в глубину, вызывая f для каждого узла; false пропускает потомков узла.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+	for _, child := range node.Children() {
+		Inspect(child, f)
+	}
+}
+
+// visit вызывает метод Visitor для типа узла.
+func visit(node Node, v Visitor) bool {
+	switch n := node.(type) {
+	case *ScriptNode:
+		return v.VisitScript(n)
+	case *LiteralNode:
+		return v.VisitLiteral(n)
+	case *IdentifierNode:
+		return v.VisitIdentifier(n)
+	case *BinaryOpNode:
+		return v.VisitBinaryOp(n)
+	case *UnaryOpNode:
+		return v.VisitUnaryOp(n)
+	case *PropertyAccessNode:
+		return v.VisitPropertyAccess(n)
+	case *IndexAccessNode:
+		return v.VisitIndexAccess(n)
+	case *MethodCallNode:
+		return v.VisitMethodCall(n)
+	case *AssignmentNode:
+		return v.VisitAssignment(n)
+	case *TernaryNode:
+		return v.VisitTernary(n)
+	case *RangeNode:
+		return v.VisitRange(n)
+	case *ElvisNode:
+		return v.VisitElvis(n)
+	case *PipeNode:
+		return v.VisitPipe(n)
+	case *ArrayLiteralNode:
+		return v.VisitArrayLiteral(n)
+	case *MapLiteralNode:
+		return v.VisitMapLiteral(n)
+	case *SetLiteralNode:
+		return v.VisitSetLiteral(n)
+	case *IfNode:
+		return v.VisitIf(n)
+	case *ForNode:
+		return v.VisitFor(n)
+	case *ForeachNode:
+		return v.VisitForeach(n)
+	case *WhileNode:
+		return v.VisitWhile(n)
+	case *DoWhileNode:
+		return v.VisitDoWhile(n)
+	case *BlockNode:
+		return v.VisitBlock(n)
+	case *BreakNode:
+		return v.VisitBreak(n)
+	case *ContinueNode:
+		return v.VisitContinue(n)
+	case *ReturnNode:
+		return v.VisitReturn(n)
+	case *VarNode:
+		return v.VisitVar(n)
+	case *ArrayPatternNode:
+		return v.VisitArrayPattern(n)
+	case *MapPatternNode:
+		return v.VisitMapPattern(n)
+	case *DestructuringNode:
+		return v.VisitDestructuring(n)
+	case *LambdaNode:
+		return v.VisitLambda(n)
+	case *FunctionNode:
+		return v.VisitFunction(n)
+	case *SwitchNode:
+		return v.VisitSwitch(n)
+	case *CaseNode:
+		return v.VisitCase(n)
+	case *TryNode:
+		return v.VisitTry(n)
+	}
+	return true
+}
+
+// Rewrite перестраивает AST снизу вверх: сначала переписываются потомки узла,
+// затем f получает узел (копию, если изменились потомки) и возвращает замену
+// или сам узел. Исходное дерево не изменяется, неизменённые поддеревья
+// переиспользуются. Если f возвращает nil, инструкция удаляется из блока или
+// скрипта; в остальных позициях nil означает отсутствие необязательной части
+// (ветви else, шага range). Имя переменной, параметры lambda и case заменяются
+// только узлом того же типа, иначе Rewrite возвращает ошибку.
+// Исходный текст копии собирается из текста узла, в котором тексты изменённых
+// потомков заменены текстами замен; у скрипта пересчитываются локальные переменные.
+func Rewrite(node Node, f func(Node) Node) (Node, error) {
+	if node == nil {
+		return nil, nil
+	}
+	r := &rewriter{f: f}
+	result := r.rewrite(node)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return result, nil
+}
+
+// rewriter выполняет Rewrite.
+type rewriter struct {
+	f   func(Node) Node
+	err error // первая замена недопустимого типа
+}
+
+func (r *rewriter) rewrite(node Node) Node {
+	if node == nil || r.err != nil {
+		return node
+	}
+	return r.f(r.children(node))
+}
+
+// children возвращает копию узла с переписанными потомками или сам узел,
+// если потомки не изменились.
+func (r *rewriter) children(node Node) Node {
+	switch n := node.(type) {
+	case *ScriptNode:
+		children, replaced, childrenChanged := r.statements(n.children)
+		defaults, defaultsChanged := r.list(n.defaults)
+		if childrenChanged || defaultsChanged {
+			c := *n
+			c.children, c.defaults, c.pragmas = children, defaults, maps.Clone(n.pragmas)
+			// Тексты инструкций нормализованы парсером, поэтому в исходном тексте
+			// скрипта они ищутся по положению, пока текст соответствует положениям
+			base := -1
+			if n.pos.IsValid() && n.pos.EndOffset-n.pos.Offset == len(n.source) {
+				base = n.pos.Offset
+			}
+			respell(&c.source, base, n.children, replaced)
+			c.pos = Position{}
+			if n.variables != nil {
+				c.variables = localVariables(&c)
+			}
+			return &c
+		}
+	case *BinaryOpNode:
+		left, right := r.rewrite(n.left), r.rewrite(n.right)
+		if left != n.left || right != n.right {
+			c := *n
+			c.left, c.right = left, right
+			respell(&c.source, -1, []Node{n.left, n.right}, []Node{left, right})
+			return &c
+		}
+	case *UnaryOpNode:
+		if operand := r.rewrite(n.operand); operand != n.operand {
+			c := *n
+			c.operand = operand
+			respell(&c.source, -1, []Node{n.operand}, []Node{operand})
+			return &c
+		}
+	case *PropertyAccessNode:
+		object, property := r.rewrite(n.object), r.rewrite(n.property)
+		if object != n.object || property != n.property {
+			c := *n
+			c.object, c.property = object, property
+			respell(&c.source, -1, []Node{n.object, n.property}, []Node{object, property})
+			return &c
+		}
+	case *IndexAccessNode:
+		object, index := r.rewrite(n.object), r.rewrite(n.index)
+		if object != n.object || index != n.index {
+			c := *n
+			c.object, c.index = object, index
+			respell(&c.source, -1, []Node{n.object, n.index}, []Node{object, index})
+			return &c
+		}
+	case *MethodCallNode:
+		target, method := r.rewrite(n.target), r.rewrite(n.method)
+		args, changed := r.list(n.args)
+		if changed || target != n.target || method != n.method {
+			c := *n
+			c.target, c.method, c.args = target, method, args
+			respell(&c.source, -1, append([]Node{n.target, n.method}, n.args...), append([]Node{target, method}, args...))
+			return &c
+		}
+	case *AssignmentNode:
+		target, value := r.rewrite(n.target), r.rewrite(n.value)
+		if target != n.target || value != n.value {
+			c := *n
+			c.target, c.value = target, value
+			respell(&c.source, -1, []Node{n.target, n.value}, []Node{target, value})
+			return &c
+		}
+	case *TernaryNode:
+		condition, trueExpr, falseExpr := r.rewrite(n.condition), r.rewrite(n.trueExpr), r.rewrite(n.falseExpr)
+		if condition != n.condition || trueExpr != n.trueExpr || falseExpr != n.falseExpr {
+			c := *n
+			c.condition, c.trueExpr, c.falseExpr = condition, trueExpr, falseExpr
+			respell(&c.source, -1, []Node{n.condition, n.trueExpr, n.falseExpr}, []Node{condition, trueExpr, falseExpr})
+			return &c
+		}
+	case *RangeNode:
+		left, right, step := r.rewrite(n.left), r.rewrite(n.right), r.rewrite(n.step)
+		if left != n.left || right != n.right || step != n.step {
+			c := *n
+			c.left, c.right, c.step = left, right, step
+			respell(&c.source, -1, []Node{n.left, n.right, n.step}, []Node{left, right, step})
+			return &c
+		}
+	case *ElvisNode:
+		expr, defaultExpr := r.rewrite(n.expr), r.rewrite(n.defaultExpr)
+		if expr != n.expr || defaultExpr != n.defaultExpr {
+			c := *n
+			c.expr, c.defaultExpr = expr, defaultExpr
+			respell(&c.source, -1, []Node{n.expr, n.defaultExpr}, []Node{expr, defaultExpr})
+			return &c
+		}
+	case *PipeNode:
+		value, target := r.rewrite(n.value), r.rewrite(n.target)
+		if value != n.value || target != n.target {
+			c := *n
+			c.value, c.target = value, target
+			respell(&c.source, -1, []Node{n.value, n.target}, []Node{value, target})
+			return &c
+		}
+	case *ArrayLiteralNode:
+		if elements, changed := r.list(n.elements); changed {
+			c := *n
+			c.elements = elements
+			respell(&c.source, -1, n.elements, elements)
+			return &c
+		}
+	case *SetLiteralNode:
+		if elements, changed := r.list(n.elements); changed {
+			c := *n
+			c.elements = elements
+			respell(&c.source, -1, n.elements, elements)
+			return &c
+		}
+	case *MapLiteralNode:
+		entries := make([]MapEntry, len(n.entries))
+		var before, after []Node
+		changed := false
+		for k, entry := range n.entries {
+			entries[k] = MapEntry{Key: r.rewrite(entry.Key), Value: r.rewrite(entry.Value)}
+			changed = changed || entries[k] != entry
+			before = append(before, entry.Key, entry.Value)
+			after = append(after, entries[k].Key, entries[k].Value)
+		}
+		if changed {
+			c := *n
+			c.entries = entries
+			respell(&c.source, -1, before, after)
+			return &c
+		}
+	case *IfNode:
+		condition, thenBranch, elseBranch := r.rewrite(n.condition), r.rewrite(n.thenBranch), r.rewrite(n.elseBranch)
+		if condition != n.condition || thenBranch != n.thenBranch || elseBranch != n.elseBranch {
+			c := *n
+			c.condition, c.thenBranch, c.elseBranch = condition, thenBranch, elseBranch
+			respell(&c.source, -1, []Node{n.condition, n.thenBranch, n.elseBranch}, []Node{condition, thenBranch, elseBranch})
+			return &c
+		}
+	case *ForNode:
+		init, condition, step, body := r.rewrite(n.init), r.rewrite(n.condition), r.rewrite(n.step), r.rewrite(n.body)
+		if init != n.init || condition != n.condition || step != n.step || body != n.body {
+			c := *n
+			c.init, c.condition, c.step, c.body = init, condition, step, body
+			respell(&c.source, -1, []Node{n.init, n.condition, n.step, n.body}, []Node{init, condition, step, body})
+			return &c
+		}
+	case *ForeachNode:
+		key, variable, items, body := r.rewrite(n.key), r.rewrite(n.variable), r.rewrite(n.items), r.rewrite(n.body)
+		if key != n.key || variable != n.variable || items != n.items || body != n.body {
+			c := *n
+			c.key, c.variable, c.items, c.body = key, variable, items, body
+			respell(&c.source, -1, []Node{n.key, n.variable, n.items, n.body}, []Node{key, variable, items, body})
+			return &c
+		}
+	case *WhileNode:
+		condition, body := r.rewrite(n.condition), r.rewrite(n.body)
+		if condition != n.condition || body != n.body {
+			c := *n
+			c.condition, c.body = condition, body
+			respell(&c.source, -1, []Node{n.condition, n.body}, []Node{condition, body})
+			return &c
+		}
+	case *DoWhileNode:
+		condition, body := r.rewrite(n.condition), r.rewrite(n.body)
+		if condition != n.condition || body != n.body {
+			c := *n
+			c.condition, c.body = condition, body
+			respell(&c.source, -1, []Node{n.body, n.condition}, []Node{body, condition})
+			return &c
+		}
+	case *BlockNode:
+		if statements, replaced, changed := r.statements(n.statements); changed {
+			c := *n
+			c.statements = statements
+			respell(&c.source, -1, n.statements, replaced)
+			return &c
+		}
+	case *ReturnNode:
+		if value := r.rewrite(n.value); value != n.value {
+			c := *n
+			c.value = value
+			respell(&c.source, -1, []Node{n.value}, []Node{value})
+			return &c
+		}
+	case *VarNode:
+		name, value := rewriteAs(r, n.name), r.rewrite(n.value)
+		if name != n.name || value != n.value {
+			c := *n
+			c.name, c.value = name, value
+			respell(&c.source, -1, []Node{n.name, n.value}, []Node{asNode(name), value})
+			return &c
+		}
+	case *ArrayPatternNode:
+		elements, elementsChanged := r.list(n.elements)
+		defaults, defaultsChanged := r.list(n.defaults)
+		rest := rewriteAs(r, n.rest)
+		if elementsChanged || defaultsChanged || rest != n.rest {
+			c := *n
+			c.elements, c.defaults, c.rest = elements, defaults, rest
+			before, after := interleave(n.elements, n.defaults), interleave(elements, defaults)
+			respell(&c.source, -1, append(before, asNode(n.rest)), append(after, asNode(rest)))
+			return &c
+		}
+	case *MapPatternNode:
+		targets, targetsChanged := r.list(n.targets)
+		defaults, defaultsChanged := r.list(n.defaults)
+		rest := rewriteAs(r, n.rest)
+		if targetsChanged || defaultsChanged || rest != n.rest {
+			c := *n
+			c.targets, c.defaults, c.rest = targets, defaults, rest
+			before, after := interleave(n.targets, n.defaults), interleave(targets, defaults)
+			respell(&c.source, -1, append(before, asNode(n.rest)), append(after, asNode(rest)))
+			return &c
+		}
+	case *DestructuringNode:
+		pattern, value := r.rewrite(n.pattern), r.rewrite(n.value)
+		if pattern != n.pattern || value != n.value {
+			c := *n
+			c.pattern, c.value = pattern, value
+			respell(&c.source, -1, []Node{n.pattern, n.value}, []Node{pattern, value})
+			return &c
+		}
+	case *LambdaNode:
+		parameters := make([]*IdentifierNode, len(n.parameters))
+		before, after := make([]Node, len(n.parameters)), make([]Node, len(n.parameters))
+		changed := false
+		for k, param := range n.parameters {
+			parameters[k] = rewriteAs(r, param)
+			changed = changed || parameters[k] != param
+			before[k], after[k] = param, asNode(parameters[k])
+		}
+		defaults, defaultsChanged := r.list(n.defaults)
+		body := r.rewrite(n.body)
+		if changed || defaultsChanged || body != n.body {
+			c := *n
+			c.parameters, c.defaults, c.body = parameters, defaults, body
+			before, after = interleave(before, n.defaults), interleave(after, defaults)
+			respell(&c.source, -1, append(before, n.body), append(after, body))
+			return &c
+		}
+	case *FunctionNode:
+		name, lambda := rewriteAs(r, n.name), rewriteAs(r, n.lambda)
+		if name != n.name || lambda != n.lambda {
+			c := *n
+			c.name, c.lambda = name, lambda
+			// Текст lambda объявленной функции включает "function name"
+			respell(&c.source, -1, []Node{n.lambda}, []Node{asNode(lambda)})
+			respell(&c.source, -1, []Node{n.name}, []Node{asNode(name)})
+			return &c
+		}
+	case *SwitchNode:
+		expression := r.rewrite(n.expression)
+		cases := make([]*CaseNode, len(n.cases))
+		before, after := []Node{n.expression}, []Node{expression}
+		changed := expression != n.expression
+		for k, cs := range n.cases {
+			cases[k] = rewriteAs(r, cs)
+			changed = changed || cases[k] != cs
+			before, after = append(before, cs), append(after, asNode(cases[k]))
+		}
+		if changed {
+			c := *n
+			c.expression, c.cases = expression, cases
+			respell(&c.source, -1, before, after)
+			return &c
+		}
+	case *CaseNode:
+		if body := r.rewrite(n.body); body != n.body {
+			c := *n
+			c.body = body
+			respell(&c.source, -1, []Node{n.body}, []Node{body})
+			return &c
+		}
+	case *TryNode:
+		resources, changed := r.list(n.resources)
+		tryBlock, catchBlock, finallyBlock := r.rewrite(n.tryBlock), r.rewrite(n.catchBlock), r.rewrite(n.finallyBlock)
+		if changed || tryBlock != n.tryBlock || catchBlock != n.catchBlock || finallyBlock != n.finallyBlock {
+			c := *n
+			c.resources, c.tryBlock, c.catchBlock, c.finallyBlock = resources, tryBlock, catchBlock, finallyBlock
+			respell(&c.source, -1, append(slices.Clone(n.resources), n.tryBlock, n.catchBlock, n.finallyBlock),
+				append(slices.Clone(resources), tryBlock, catchBlock, finallyBlock))
+			return &c
+		}
+	}
+	return node
+}
+
+// list переписывает список узлов; nil элементы сохраняются.
+func (r *rewriter) list(nodes []Node) ([]Node, bool) {
+	if len(nodes) == 0 {
+		return nodes, false
+	}
+	result := make([]Node, len(nodes))
+	changed := false
+	for k, node := range nodes {
+		result[k] = r.rewrite(node)
+		changed = changed || result[k] != node
+	}
+	return result, changed
+}
+
+// statements переписывает последовательность инструкций; nil удаляет инструкцию.
+// replaced - замены по позициям исходных инструкций, включая nil.
+func (r *rewriter) statements(nodes []Node) (result, replaced []Node, changed bool) {
+	result = make([]Node, 0, len(nodes))
+	replaced = make([]Node, len(nodes))
+	for k, node := range nodes {
+		stmt := r.rewrite(node)
+		changed = changed || stmt != node
+		replaced[k] = stmt
+		if stmt != nil {
+			result = append(result, stmt)
+		}
+	}
+	if !changed {
+		return nodes, nodes, false
+	}
+	return result, replaced, true
+}
+
+// rewriteAs переписывает узел, тип которого задан полем родителя.
+// Замена другого типа запоминается как ошибка Rewrite.
+func rewriteAs[T Node](r *rewriter, node T) T {
+	var none T
+	if Node(node) == Node(none) {
+		return node
+	}
+	result := r.rewrite(node)
+	if result == nil {
+		return none
+	}
+	typed, ok := result.(T)
+	if !ok {
+		if r.err == nil {
+			r.err = NewError(fmt.Sprintf("Rewrite replaced %T with %T", node, result))
+		}
+		return node
+	}
+	return typed
+}
+
+// asNode возвращает узел как Node; nil-указатель становится nil.
+func asNode[T Node](node T) Node {
+	var none T
+	if Node(node) == Node(none) {
+		return nil
+	}
+	return node
+}
+
+// interleave чередует элементы с их значениями по умолчанию в порядке текста: a, a = 1.
+func interleave(nodes, defaults []Node) []Node {
+	result := make([]Node, 0, len(nodes)+len(defaults))
+	for k, node := range nodes {
+		result = append(result, node)
+		if k < len(defaults) {
+			result = append(result, defaults[k])
+		}
+	}
+	return result
+}
+
+// respell заменяет в исходном тексте копии узла тексты изменённых потомков
+// текстами замен. before и after - потомки до и после Rewrite в порядке текста;
+// текст удалённой инструкции удаляется вместе с разделителем ';'. Если base >= 0,
+// потомки с положением находятся по смещению от base, остальные - поиском текста.
+// Потомок, текст которого не найден (или добавленная необязательная часть), не меняет текст.
+func respell(source *string, base int, before, after []Node) {
+	text := *source
+	var b strings.Builder
+	pos := 0
+	for k, old := range before {
+		if old == nil {
+			continue
+		}
+		i, end := -1, 0
+		if p := old.Position(); base >= 0 && p.IsValid() && p.Offset-base >= pos && p.EndOffset-base <= len(text) {
+			i, end = p.Offset-base, p.EndOffset-base
+		} else if i = indexSource(text, old.SourceText(), pos); i >= 0 {
+			end = i + len(old.SourceText())
+		}
+		if i < 0 {
+			continue
+		}
+		if old == after[k] {
+			// Неизменённый потомок только сдвигает поиск, чтобы одинаковые тексты не путались
+			b.WriteString(text[pos:end])
+			pos = end
+			continue
+		}
+		b.WriteString(text[pos:i])
+		if after[k] != nil {
+			b.WriteString(after[k].SourceText())
+		} else {
+			end += len(text[end:]) - len(strings.TrimLeft(text[end:], " \t"))
+			if strings.HasPrefix(text[end:], ";") {
+				end++
+				end += len(text[end:]) - len(strings.TrimLeft(text[end:], " \t\r\n"))
+			}
+		}
+		pos = end
+	}
+	if pos == 0 {
+		return
+	}
+	b.WriteString(text[pos:])
+	*source = b.String()
+}
+
+// indexSource ищет текст потомка в тексте узла начиная с from. Текст,
+// начинающийся или заканчивающийся именем, не должен продолжать соседнее имя:
+// f не находится в "function".
+func indexSource(text, sub string, from int) int {
+	if sub == "" {
+		return -1
+	}
+	first, _ := utf8.DecodeRuneInString(sub)
+	last, _ := utf8.DecodeLastRuneInString(sub)
+	for from <= len(text) {
+		k := strings.Index(text[from:], sub)
+		if k < 0 {
+			return -1
+		}
+		i := from + k
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[i+len(sub):])
+		if !(isWordRune(first) && i > 0 && isWordRune(before)) &&
+			!(isWordRune(last) && i+len(sub) < len(text) && isWordRune(after)) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '$'
+}
+
+// localVariables собирает имена локальных переменных скрипта, объявленных
+// var и деструктуризацией, без переменных вложенных функций.
+func localVariables(script *ScriptNode) []string {
+	var names []string
+	declare := func(ident *IdentifierNode) {
+		if ident != nil && !slices.Contains(names, ident.Name()) {
+			names = append(names, ident.Name())
+		}
+	}
+	var pattern func(node Node)
+	pattern = func(node Node) {
+		switch p := node.(type) {
+		case *IdentifierNode:
+			declare(p)
+		case *ArrayPatternNode:
+			for _, element := range p.elements {
+				pattern(element)
+			}
+			declare(p.rest)
+		case *MapPatternNode:
+			for _, target := range p.targets {
+				pattern(target)
+			}
+			declare(p.rest)
+		}
+	}
+	Inspect(script, func(node Node) bool {
+		switch n := node.(type) {
+		case *LambdaNode, *FunctionNode:
+			return false
+		case *VarNode:
+			declare(n.name)
+		case *DestructuringNode:
+			if n.declaration {
+				pattern(n.pattern)
+			}
+		}
+		return true
+	})
+	return names
+}
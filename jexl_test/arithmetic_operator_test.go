@@ -40,11 +40,12 @@ func TestRangeOperator(t *testing.T) {
 				t.Fatalf("Failed to evaluate: %v", err)
 			}
 
-			// Проверяем, что результат - слайс int64
-			rangeResult, ok := result.([]int64)
+			// Проверяем, что результат - ленивый диапазон
+			r, ok := result.(*jexl.Range)
 			if !ok {
-				t.Fatalf("Expected []int64, got %T", result)
+				t.Fatalf("Expected *jexl.Range, got %T", result)
 			}
+			rangeResult := r.ToSlice()
 
 			if len(rangeResult) != len(tt.expected) {
 				t.Fatalf("Expected length %d, got %d", len(tt.expected), len(rangeResult))
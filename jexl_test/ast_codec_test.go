@@ -0,0 +1,293 @@
+package jexl_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// astCodecScripts - скрипты, покрывающие основные виды узлов.
+var astCodecScripts = []string{
+	"1 + 2 * 3",
+	"x = 10; y = x > 5 ? 'big' : 'small'; y",
+	"var total = 0;\nfor (item : [1, 2, 3, 4]) {\n  total += item;\n}\ntotal",
+	"var s = 0; for (item : [1, 2, 3]) { s = s + item } s",
+	"var m = {'a': 1, 'b': [1, 2.5, null]}; m.a + size(m.b)",
+	"var f = (a, b = 2) -> a * b; f(3) + f(1, 5)",
+	"function sq(n) { return n * n } sq(7)",
+	"var [a, b, ...rest] = [1, 2, 3, 4]; a + b + size(rest)",
+	"var {x, y: z} = {'x': 1, 'y': 2}; x + z",
+	"var n = 0; while (n < 3) { n = n + 1 } do { n = n - 1 } while (n > 1); n",
+	"var v = 2; switch (v) { case 1, 3: { 'odd' } case 2: { 'two' } default: { 'other' } }",
+	"try { throw 'boom' } catch (e) { 'caught' } finally { 1 }",
+	"var r = 0; for (i : 1 .. 4) { if (i == 2) continue; if (i == 4) break; r += i } r",
+	"'abc' =~ ~/a.c/ && !(1 > 2) || empty('')",
+	"null ?: 'fallback'",
+	"var s = {1, 2, 3}; size(s)",
+	"9007199254740993 + 0.1",
+	"'line\\nbreak' + \"quote\\\"s\"",
+}
+
+// TestASTCodecRoundTrip тестирует сохранение AST при сериализации в JSON и двоичный формат
+func TestASTCodecRoundTrip(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	codecs := map[string]func(*jexl.ScriptNode) ([]byte, error){
+		"json":   jexl.MarshalAST,
+		"binary": jexl.MarshalASTBinary,
+	}
+	for _, src := range astCodecScripts {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		want, err := script.Execute(jexl.NewMapContext())
+		if err != nil {
+			t.Fatalf("Failed to execute %q: %v", src, err)
+		}
+		for name, marshal := range codecs {
+			data, err := marshal(script.AST())
+			if err != nil {
+				t.Fatalf("%s: failed to marshal %q: %v", name, src, err)
+			}
+			ast, err := jexl.UnmarshalAST(data)
+			if err != nil {
+				t.Fatalf("%s: failed to unmarshal %q: %v", name, src, err)
+			}
+			if ast.String() != script.AST().String() {
+				t.Errorf("%s: %q restored as %q", name, src, ast.String())
+			}
+			if got, want := astPositions(ast), astPositions(script.AST()); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %q positions differ:\n%v\n%v", name, src, got, want)
+			}
+			again, err := marshal(ast)
+			if err != nil || !bytes.Equal(again, data) {
+				t.Errorf("%s: %q encoding is not stable: %v", name, src, err)
+			}
+
+			loaded, err := engine.LoadScript(data)
+			if err != nil {
+				t.Fatalf("%s: failed to load %q: %v", name, src, err)
+			}
+			got, err := loaded.Execute(jexl.NewMapContext())
+			if err != nil {
+				t.Fatalf("%s: failed to execute loaded %q: %v", name, src, err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("%s: %q = %v, want %v", name, src, got, want)
+			}
+		}
+	}
+}
+
+// astPositions перечисляет виды и положения узлов в порядке обхода.
+func astPositions(ast *jexl.ScriptNode) []string {
+	var result []string
+	jexl.Inspect(ast, func(node jexl.Node) bool {
+		if node != nil {
+			result = append(result, fmt.Sprintf("%T@%+v", node, node.Position()))
+		}
+		return true
+	})
+	return result
+}
+
+// TestASTCodecScriptAttributes тестирует сохранение параметров, pragma и features
+func TestASTCodecScriptAttributes(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	info := engine.CreateInfoAt("pricing.jexl", 3, 7)
+	script, err := engine.CreateScript(nil, info, "price * qty", "price", "qty")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	ast := script.AST()
+	ast.SetPragma("version", int64(2))
+	ast.SetPragma("ratio", big.NewRat(1, 3))
+	ast.SetPragma("tags", []any{"a", 1.5, true, nil})
+	ast.SetPragma("limits", map[string]any{"max": 10})
+
+	for _, marshal := range []func(*jexl.ScriptNode) ([]byte, error){jexl.MarshalAST, jexl.MarshalASTBinary} {
+		data, err := marshal(ast)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		restored, err := jexl.UnmarshalAST(data)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal: %v", err)
+		}
+		if !reflect.DeepEqual(restored.Parameters(), []string{"price", "qty"}) {
+			t.Errorf("Unexpected parameters %v", restored.Parameters())
+		}
+		if !reflect.DeepEqual(restored.Pragmas(), ast.Pragmas()) {
+			t.Errorf("Unexpected pragmas %v", restored.Pragmas())
+		}
+		if restored.Info().Name() != "pricing.jexl" || restored.Info().Line() != 3 || restored.Info().Column() != 7 {
+			t.Errorf("Unexpected info %v", restored.Info())
+		}
+		if !reflect.DeepEqual(restored.Features(), ast.Features()) {
+			t.Errorf("Features differ")
+		}
+
+		loaded, err := engine.LoadScript(data)
+		if err != nil {
+			t.Fatalf("Failed to load script: %v", err)
+		}
+		result, err := loaded.Execute(nil, 6, 7)
+		if err != nil {
+			t.Fatalf("Failed to execute: %v", err)
+		}
+		if asInt64(t, result) != 42 {
+			t.Errorf("Expected 42, got %v", result)
+		}
+	}
+}
+
+// TestASTCodecJSONSchema тестирует стабильную схему JSON
+func TestASTCodecJSONSchema(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, jexl.NewInfoAt("t", 1, 1), "x + 1")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	data, err := jexl.MarshalAST(script.AST())
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+	text := string(data)
+	for _, part := range []string{
+		`{"format":"jexl-ast","version":1,"script":{"kind":"Script","source":"x + 1"`,
+		`{"kind":"BinaryOp","source":"x + 1","op":"+","left":{"kind":"Identifier","source":"x","name":"x","pos":{"offset":0,"end":1,"line":1,"column":1,"endLine":1,"endColumn":2}}`,
+		`"right":{"kind":"Literal","source":"1","value":{"int64":"1"}`,
+	} {
+		if !strings.Contains(text, part) {
+			t.Errorf("JSON %s does not contain %s", text, part)
+		}
+	}
+}
+
+// TestASTCodecErrors тестирует отказ на чужой версии и повреждённых данных
+func TestASTCodecErrors(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "var a = [1, 'x', 2.5]; a[0] + size(a)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	jsonData, _ := jexl.MarshalAST(script.AST())
+	binaryData, _ := jexl.MarshalASTBinary(script.AST())
+
+	newer := bytes.Replace(jsonData, []byte(`"version":1`), []byte(`"version":2`), 1)
+	if _, err := engine.LoadScript(newer); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("Expected version error, got %v", err)
+	}
+	newer = append([]byte("JXAST\x02"), binaryData[6:]...)
+	if _, err := jexl.UnmarshalAST(newer); err == nil || !strings.Contains(err.Error(), "version 2") {
+		t.Errorf("Expected version error, got %v", err)
+	}
+
+	for _, data := range [][]byte{
+		nil,
+		[]byte("{}"),
+		[]byte(`{"format":"jexl-ast","version":1,"script":{"kind":"Nope"}}`),
+		[]byte(`{"format":"jexl-ast","version":1,"script":{"kind":"Literal"}}`),
+		[]byte(`{"format":"jexl-ast","version":1,"script":{"kind":"Script","children":[{"kind":"BinaryOp","op":"+"}]}}`),
+		[]byte(`{"format":"jexl-ast","version":1,"script":{"kind":"Script","children":[{"kind":"Literal","value":{"rat":"1e999999999"}}]}}`),
+		bytes.Replace(jsonData, []byte(`"name":"a"`), []byte(`"name":1`), 1),
+		append(append([]byte{}, binaryData...), 0),
+	} {
+		if _, err := jexl.UnmarshalAST(data); err == nil {
+			t.Errorf("Expected error for %q", data)
+		}
+	}
+	for n := range binaryData {
+		if _, err := jexl.UnmarshalAST(binaryData[:n]); err == nil {
+			t.Errorf("Expected error for binary data truncated to %d bytes", n)
+		}
+	}
+	for n := range jsonData {
+		if _, err := jexl.UnmarshalAST(jsonData[:n]); err == nil {
+			t.Errorf("Expected error for JSON data truncated to %d bytes", n)
+		}
+	}
+}
+
+// FuzzUnmarshalAST проверяет, что декодирование произвольных данных не паникует
+func FuzzUnmarshalAST(f *testing.F) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		f.Fatalf("Failed to build engine: %v", err)
+	}
+	for _, src := range astCodecScripts {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			f.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		jsonData, _ := jexl.MarshalAST(script.AST())
+		binaryData, _ := jexl.MarshalASTBinary(script.AST())
+		f.Add(jsonData)
+		f.Add(binaryData)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ast, err := jexl.UnmarshalAST(data)
+		if err != nil {
+			return
+		}
+		// Восстановленный AST снова сериализуется
+		if _, err := jexl.MarshalASTBinary(ast); err != nil {
+			t.Errorf("Failed to marshal decoded AST: %v", err)
+		}
+	})
+}
+
+// TestASTCodecFeatures тестирует проверку features движка для загруженного AST
+func TestASTCodecFeatures(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	tests := []struct {
+		src     string
+		without jexl.Feature
+		message string
+	}{
+		{"var s = 0; while (s < 3) { s += 1 } s", jexl.FeatureLoop, "loops are not enabled"},
+		{"function f(x) { x } f(1)", jexl.FeatureLambda, "lambda functions are not enabled"},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		data, _ := jexl.MarshalAST(script.AST())
+
+		restricted, err := jexl.NewBuilder().Features(jexl.FeaturesDefault().Without(tt.without)).Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		if _, err := restricted.CreateScript(nil, nil, tt.src); err == nil {
+			t.Errorf("CreateScript(%q) accepted a disabled feature", tt.src)
+		}
+		if _, err := restricted.LoadScript(data); err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("LoadScript(%q) error = %v, want %q", tt.src, err, tt.message)
+		}
+		if _, err := engine.LoadScript(data); err != nil {
+			t.Errorf("LoadScript(%q) failed: %v", tt.src, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package jexl_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestCollectionArithmetic тестирует операторы над списками, множествами и мапами
+// в нестрогой арифметике
+func TestCollectionArithmetic(t *testing.T) {
+	builder := jexl.NewBuilder().Arithmetic(jexl.NewBaseArithmetic(false, nil, 0))
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"[1, 2] + [3]", []any{int64(1), int64(2), int64(3)}},
+		{"[1, 2] + (3 .. 4)", []any{int64(1), int64(2), int64(3), int64(4)}},
+		{"[1] + 2", []any{int64(1), int64(2)}},
+		{"var l = [1]; l += [2]; l", []any{int64(1), int64(2)}},
+		{"[1, 2, 3, 2] - [2]", []any{int64(1), int64(3)}},
+		{"[1, 2, 3] - 1", []any{int64(2), int64(3)}},
+		{"var a = {1, 2}; var b = {2, 3}; (a + b).toSlice()", []any{int64(1), int64(2), int64(3)}},
+		{"var a = {1, 2}; var b = {2, 3}; (a & b).toSlice()", []any{int64(2)}},
+		{"var a = {1, 2}; var b = {2, 3}; (a - b).toSlice()", []any{int64(1)}},
+		{"var a = {1, 2}; (a & [2.0, 5]).toSlice()", []any{int64(2)}},
+		{"var n = {1, 2} & {2, 3}; n.toSlice()", []any{int64(2)}},
+		{"var a = {1, 2}; (a + 3).toSlice()", []any{int64(1), int64(2), int64(3)}},
+		{"var m = {'a': 1, 'b': 2} + {'b': 20, 'c': 3}; m.entries()", []any{[]any{"a", int64(1)}, []any{"b", int64(20)}, []any{"c", int64(3)}}},
+		{"var m = {'a': 1} + goMap; m.keys()", []any{"a", "x"}},
+		{"'x' + [1]", "x[1]"},
+		{"6 & 3", int64(2)},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("goMap", map[string]any{"x": 1})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tt.src, tt.expected, result)
+		}
+	}
+
+	// Операнды исходной коллекции не изменяются
+	ctx := jexl.NewMapContext()
+	list := []any{int64(1)}
+	ctx.Set("list", list)
+	script, err := engine.CreateScript(nil, nil, "list + [2]")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if _, err := script.Execute(ctx); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if len(list) != 1 {
+		t.Errorf("Expected operand to stay unchanged, got %v", list)
+	}
+}
+
+// TestCollectionArithmeticStrict тестирует ошибки смешанных операций в строгом режиме
+func TestCollectionArithmeticStrict(t *testing.T) {
+	builder := jexl.NewBuilder().Arithmetic(jexl.NewBaseArithmetic(true, nil, 0))
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	for _, src := range []string{
+		"[1] + 2",
+		"1 + [2]",
+		"var s = {1}; s + [2]",
+		"var s = {1}; s - [1]",
+		"var s = {1}; s & [1]",
+		"var s = {1}; [1] & s",
+		"var m = {'a': 1}; m + [1]",
+		"[1, 2] - {1}",
+	} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		_, err = script.Execute(nil)
+		var opErr *jexl.OperatorError
+		if !errors.As(err, &opErr) {
+			t.Errorf("%q: expected OperatorError, got %v", src, err)
+		}
+	}
+
+	script, err := engine.CreateScript(nil, nil, "var a = {1, 2}; var b = {2}; size(a - b) + size([1] + [2])")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if asInt64(t, result) != 3 {
+		t.Errorf("Expected 3, got %v", result)
+	}
+}
@@ -0,0 +1,195 @@
+package jexl_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// collectionItem - элемент для проверки методов коллекций
+type collectionItem struct {
+	Sku   string
+	Price int
+	Kind  string
+}
+
+// TestCollectionMethods тестирует встроенные методы коллекций с лямбдами
+func TestCollectionMethods(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	items := []collectionItem{
+		{Sku: "a", Price: 5, Kind: "x"},
+		{Sku: "b", Price: 20, Kind: "y"},
+		{Sku: "c", Price: 15, Kind: "x"},
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"items.filter(x -> x.price > 10).map(x -> x.sku)", []any{"b", "c"}},
+		{"items.map(x -> x.price).reduce((a, b) -> a + b)", int64(40)},
+		{"[1, 2, 3].reduce((acc, x) -> acc + x, 10)", int64(16)},
+		{"[1, 2, 3].reduce(10, (acc, x) -> acc + x)", int64(16)},
+		{"['b', 'a'].reduce('', (acc, x) -> acc + x)", "ba"},
+		{"items.find(x -> x.kind == 'y').sku", "b"},
+		{"items.find(x -> x.price > 100)", nil},
+		{"items.any(x -> x.price > 15)", true},
+		{"items.all(x -> x.price > 15)", false},
+		{"items.sortBy(x -> -x.price).map(x -> x.sku).join('')", "bca"},
+		{"items.sortBy('price').map('sku').join()", "a,c,b"},
+		{"[1, 2, 2, '2', 1].distinct()", []any{int64(1), int64(2), "2"}},
+		{"[[1, 2], [3]].flatMap(x -> x)", []any{int64(1), int64(2), int64(3)}},
+		{"(1 .. 1000000000).take(3)", []any{int64(1), int64(2), int64(3)}},
+		{"[1, 2, 3, 4].skip(2)", []any{int64(3), int64(4)}},
+		{"items.sum(x -> x.price)", int64(40)},
+		{"[4, 1, 3].min()", int64(1)},
+		{"items.max(x -> x.price)", int64(20)},
+		{"[1, 2, 3, 6].avg()", int64(3)},
+		{"[].avg()", nil},
+		{"['a', 'b'].map((x, i) -> x + i).join('-')", "a0-b1"},
+		{"[1, 2].map(x -> x * 2).join(',')", "2,4"},
+		{"[1 / 2, 3].join(2 - 1)", "0.513"},
+		{"(1 .. 10 step 3).filter(x -> x % 2 == 0)", []any{int64(4), int64(10)}},
+		{"var s = {3, 1, 2}; s.sortBy().join()", "1,2,3"},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("items", items)
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestCollectionMethodsOnMaps тестирует методы коллекций на мапах
+func TestCollectionMethodsOnMaps(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"var m = {'a': 1, 'b': 2, 'c': 3}; m.keys()", []any{"a", "b", "c"}},
+		{"var m = {'a': 1, 'b': 2}; m.entries()", []any{[]any{"a", int64(1)}, []any{"b", int64(2)}}},
+		{"var m = {'a': 1, 'b': 2, 'c': 3}; m.filter((v, k) -> k != 'b').keys()", []any{"a", "c"}},
+		{"var m = {'a': 1, 'b': 2}; m.map((v, k) -> k + v)", []any{"a1", "b2"}},
+		{"goMap.values()", []any{int64(10), int64(20)}},
+		{"goMap.entries().map(e -> e[0]).join()", "x,y"},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("goMap", map[string]int64{"y": 20, "x": 10})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tt.src, tt.expected, result)
+		}
+	}
+
+	script, err := engine.CreateScript(nil, nil, "[1, 2, 3, 4].groupBy(x -> x % 2 == 0 ? 'even' : 'odd')")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	groups, ok := result.(*jexl.Map)
+	if !ok {
+		t.Fatalf("Expected *jexl.Map, got %T", result)
+	}
+	if odd, _ := groups.Get("odd"); !reflect.DeepEqual(odd, []any{int64(1), int64(3)}) {
+		t.Errorf("Expected odd [1 3], got %v", odd)
+	}
+	if keys := groups.Keys(); !reflect.DeepEqual(keys, []any{"odd", "even"}) {
+		t.Errorf("Expected group order [odd even], got %v", keys)
+	}
+
+	for _, src := range []string{"[1].take()", "[1, 'a'].max()", "['a', 2].min()"} {
+		bad, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if result, err := bad.Execute(nil); err == nil {
+			t.Errorf("%q: expected error, got %v", src, result)
+		}
+	}
+}
+
+// TestCollectionMethodsOnHugeRange тестирует отказ методов материализовать длинный диапазон
+func TestCollectionMethodsOnHugeRange(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	methods := []string{
+		"r.map(x -> x)", "r.filter(x -> true)", "r.sortBy(x -> -x)", "r.groupBy(x -> x % 2)",
+		"r.distinct()", "[1].flatMap(x -> r)", "r.flatMap(x -> [x])", "r.skip(1)",
+		"r.take(1000000000)", "r.join(',')", "r.keys()", "r.values()", "r.entries()",
+	}
+	for _, src := range methods {
+		script, err := engine.CreateScript(nil, nil, "var r = 1 .. 1000000000000; "+src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		_, err = script.Execute(nil)
+		if err == nil || !strings.Contains(err.Error(), "too large to materialize") {
+			t.Errorf("%q: expected too large to materialize error, got %v", src, err)
+		}
+	}
+
+	// Ленивые методы обходят только нужную часть диапазона
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"r.take(2)", []any{int64(1), int64(2)}},
+		{"r.find(x -> x > 2)", int64(3)},
+		{"r.any(x -> x == 5)", true},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, "var r = 1 .. 1000000000000; "+tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tt.src, tt.expected, result)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package jexl_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestSetKeyIdentity тестирует, что множество сохраняет типы элементов
+func TestSetKeyIdentity(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"size({1, '1'})", int64(2)},
+		{"size({1, 1.0, 2})", int64(2)},
+		{"1 =~ {1, 2}", true},
+		{"'1' =~ {1, 2}", false},
+		{"[1, 2] =~ {1, 2, 3}", true},
+		{"var s = {1, 2}; s =~ {3, 2, 1}", true},
+		{"var s = {1, 2}; s == {2, 1}", true},
+		{"empty({})", true},
+		{"var s = ''; for (var x : {'c', 'a', 'b'}) { s = s + x }; s", "cab"},
+		{"size({ {1}, {1} })", int64(1)},
+		{"size({ {1, 2}, {2, 1}, {1} })", int64(2)},
+		{"size({ {'a': [1]}, {'a': [1]}, {'a': [2]} })", int64(2)},
+		{"size({ [1, 2, 3], 1 .. 3, [3, 2, 1] })", int64(2)},
+		{"size({ [1], 1 .. 1, [1.0] })", int64(1)},
+		{"size({ [], 1 .. 0 step 1, [1, 1] })", int64(3)},
+		{"{ {1}, [2] } == { [2], {1} }", true},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(jexl.NewMapContext())
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestMapKeyIdentity тестирует упорядоченную мапу с сохранением типов ключей
+func TestMapKeyIdentity(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Strict(false).SortedKeys(false).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"var m = {1: 'a'}; m[1]", "a"},
+		{"var m = {1: 'a'}; m['1']", nil},
+		{"var m = {'1': 'b'}; m['1']", "b"},
+		{"size({1: 'a', '1': 'b'})", int64(2)},
+		{"var m = {'x': 1}; m.y = 2; m[3] = 4; m.x + m['y'] + m[3]", int64(7)},
+		{"'x' =~ {'x': 1}", true},
+		{"var s = ''; for (var k, v : {'z': 1, 'a': 2, 'm': 3}) { s = s + k + v }; s", "z1a2m3"},
+		{"var {a, ...others} = {'a': 1, 'b': 2}; size(others)", int64(1)},
+		{"var m = {'a': 1, 'b': 2}; m == {'b': 2, 'a': 1}", true},
+		{"{'a': [1]} == {'a': [1]}", true},
+		{"{'a': [1]} != {'a': [2]}", true},
+		{"{'a': {'b': [1, [2]]}} == {'a': {'b': [1, [2]]}}", true},
+		{"{1} != {2} && !({1} == {2}) && {1} <= {1}", true},
+		{"{'a': 1} == 1 || 1 == {'a': 1}", false},
+		{"var m = {:}; m[{1}] = 1; m[{1}]", int64(1)},
+		{"var m = {:}; m[[1, 2]] = 'l'; m[1 .. 2]", "l"},
+		{"var m = {{'a': 1}: 'x'}; m[{'a': 1}]", "x"},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(jexl.NewMapContext())
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestCollectionOrdering тестирует, что неравные множества и мапы не упорядочены
+func TestCollectionOrdering(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	for _, src := range []string{"{1} > {2}", "{2} < {1}", "{'a': 1} >= {'b': 1}", "1 < {1}"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if result, err := script.Execute(jexl.NewMapContext()); err == nil {
+			t.Errorf("%q: expected ordering error, got %v", src, result)
+		}
+	}
+}
+
+// TestListEquality тестирует операторы == и != для списков и мап Go
+func TestListEquality(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	tests := []struct {
+		src      string
+		expected bool
+	}{
+		{"[1, [2, 'a']] == [1, [2, 'a']]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] != [1]", true},
+		{"list == [1, 2]", true},
+		{"goMap == goMap && goMap != list", true},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("list", []int{1, 2})
+		ctx.Set("goMap", map[string]any{"a": []any{1}})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+	script, err := engine.CreateScript(nil, nil, "[1] < [2]")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if result, err := script.Execute(nil); err == nil {
+		t.Errorf("Expected ordering error, got %v", result)
+	}
+}
+
+// TestCollectionGoConversion тестирует преобразование Set и Map в значения Go
+func TestCollectionGoConversion(t *testing.T) {
+	m := jexl.NewMap()
+	m.Put("b", 1)
+	m.Put(2, jexl.NewSet("x", "y"))
+	m.Put("a", jexl.NewMap())
+
+	if keys := m.Keys(); !reflect.DeepEqual(keys, []any{"b", 2, "a"}) {
+		t.Errorf("Expected insertion order [b 2 a], got %v", keys)
+	}
+	if !m.Remove(2) || m.Has(2) || m.Size() != 2 {
+		t.Errorf("Expected key 2 removed, got %v", m)
+	}
+	m.Put(int64(2), []any{1})
+
+	goMap := m.ToGo()
+	if !reflect.DeepEqual(goMap, map[any]any{"b": 1, "a": map[any]any{}, int64(2): []any{1}}) {
+		t.Errorf("Unexpected Go map %#v", goMap)
+	}
+	if str := m.ToStringMap(); !reflect.DeepEqual(str["2"], []any{1}) {
+		t.Errorf("Expected string key '2', got %#v", str)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Failed to marshal map: %v", err)
+	}
+	if string(data) != `{"b":1,"a":{},"2":[1]}` {
+		t.Errorf("Unexpected JSON %s", data)
+	}
+	m.Put("2", "x")
+	if data, err := json.Marshal(m); err == nil {
+		t.Errorf("Expected duplicate key error, got %s", data)
+	}
+
+	set := jexl.NewSet(1, int64(1), "1", []any{1, 2}, []any{int64(1), 2})
+	if set.Size() != 3 {
+		t.Errorf("Expected 3 distinct elements, got %v", set)
+	}
+	if set.String() != "{1, '1', [1 2]}" {
+		t.Errorf("Unexpected string %s", set)
+	}
+	if !reflect.DeepEqual(jexl.ToGoValue(set), []any{1, "1", []any{1, 2}}) {
+		t.Errorf("Unexpected slice %v", jexl.ToGoValue(set))
+	}
+}
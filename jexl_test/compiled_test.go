@@ -0,0 +1,183 @@
+package jexl_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+type compiledOrder struct {
+	Amount int64
+	Tags   []string
+}
+
+func (o compiledOrder) Total(tax int64) int64 {
+	return o.Amount + tax
+}
+
+type compiledUser struct {
+	Name string
+}
+
+func (u *compiledUser) Greet(greeting string) string {
+	return greeting + ", " + u.Name
+}
+
+// TestCompiledScripts тестирует повторное выполнение скомпилированных скриптов
+// с меняющимися типами получателей
+func TestCompiledScripts(t *testing.T) {
+	engine, err := jexl.NewBuilder().Compiled(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil, "x.amount + x.Total(tax)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	receivers := []any{
+		compiledOrder{Amount: 1},
+		&compiledOrder{Amount: 2},
+		map[string]any{"amount": int64(3), "Total": nil},
+		compiledOrder{Amount: 4},
+	}
+	for _, receiver := range receivers {
+		ctx := jexl.NewMapContext()
+		ctx.Set("x", receiver)
+		ctx.Set("tax", int64(86400))
+		result, err := script.Execute(ctx)
+		if _, isMap := receiver.(map[string]any); isMap {
+			// У мапы нет метода Total
+			if err == nil {
+				t.Errorf("Expected error for map receiver, got %v", result)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Failed to execute script for %T: %v", receiver, err)
+		}
+		var amount int64
+		switch r := receiver.(type) {
+		case compiledOrder:
+			amount = r.Amount
+		case *compiledOrder:
+			amount = r.Amount
+		}
+		if actual := asInt64(t, result); actual != 2*amount+86400 {
+			t.Errorf("%T: expected %d, got %d", receiver, 2*amount+86400, actual)
+		}
+	}
+
+	greet, err := engine.CreateScript(nil, nil, "u.greet(g)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	for _, g := range []string{"Hello", "Hi"} {
+		ctx := jexl.NewMapContext()
+		ctx.Set("u", &compiledUser{Name: "Ann"})
+		ctx.Set("g", g)
+		result, err := greet.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script: %v", err)
+		}
+		if result != g+", Ann" {
+			t.Errorf("Expected %q, got %v", g+", Ann", result)
+		}
+	}
+}
+
+// TestCompiledConstantErrors тестирует, что ошибки в константных выражениях
+// возникают при выполнении, а не при компиляции
+func TestCompiledConstantErrors(t *testing.T) {
+	engine, err := jexl.NewBuilder().Compiled(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "flag ? 1 / 0 : 'ok' + '!'")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	ctx := jexl.NewMapContext()
+	ctx.Set("flag", false)
+	if result, err := script.Execute(ctx); err != nil || result != "ok!" {
+		t.Errorf("Expected 'ok!', got %v (%v)", result, err)
+	}
+	ctx.Set("flag", true)
+	if _, err := script.Execute(ctx); err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("Expected division by zero, got %v", err)
+	}
+}
+
+// TestCompiledConcurrent тестирует параллельное выполнение одного скомпилированного скрипта
+func TestCompiledConcurrent(t *testing.T) {
+	engine, err := jexl.NewBuilder().Compiled(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "var f = (o) -> o.amount * 2; f(x) + size(x.tags)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	var wg sync.WaitGroup
+	for n := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range 100 {
+				ctx := jexl.NewMapContext()
+				var receiver any = compiledOrder{Amount: int64(k), Tags: []string{"a"}}
+				if (n+k)%2 == 0 {
+					receiver = &compiledOrder{Amount: int64(k), Tags: []string{"a"}}
+				}
+				ctx.Set("x", receiver)
+				result, err := script.Execute(ctx)
+				if err != nil {
+					t.Errorf("Failed to execute script: %v", err)
+					return
+				}
+				if actual := asInt64(t, result); actual != int64(2*k+1) {
+					t.Errorf("Expected %d, got %d", 2*k+1, actual)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type compiledItem struct {
+	Price int64
+	Qty   int64
+}
+
+// benchmarkCompiledLoop выполняет цикл с доступом к свойствам и присваиванием
+// с компиляцией в дерево замыканий или интерпретацией AST
+func benchmarkCompiledLoop(b *testing.B, compiled bool) {
+	engine, err := jexl.NewBuilder().Compiled(compiled).Build()
+	if err != nil {
+		b.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "var t = 0; for (var i : items) { if (i.Qty > 0) { t += i.Price * i.Qty } } t")
+	if err != nil {
+		b.Fatalf("Failed to create script: %v", err)
+	}
+	items := make([]compiledItem, 100)
+	for k := range items {
+		items[k] = compiledItem{Price: int64(k), Qty: 2}
+	}
+	ctx := jexl.NewMapContext()
+	ctx.Set("items", items)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := script.Execute(ctx); err != nil {
+			b.Fatalf("Failed to execute script: %v", err)
+		}
+	}
+}
+
+func BenchmarkLoopInterpreted(b *testing.B) { benchmarkCompiledLoop(b, false) }
+
+func BenchmarkLoopCompiled(b *testing.B) { benchmarkCompiledLoop(b, true) }
@@ -0,0 +1,183 @@
+package jexl_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// dependencies разбирает скрипт и возвращает зависимости в виде "путь: доступ".
+func dependencies(t *testing.T, builder *jexl.Builder, src string) []string {
+	t.Helper()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, src)
+	if err != nil {
+		t.Fatalf("Failed to create script %q: %v", src, err)
+	}
+	var result []string
+	for _, dep := range script.Dependencies() {
+		result = append(result, dep.String()+": "+dep.Access.String())
+	}
+	return result
+}
+
+// TestDependencies тестирует пути доступа и виды обращений
+func TestDependencies(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"order.customer.tier == 'gold'", []string{"order.customer.tier: read"}},
+		{"items[i].price", []string{"i: read", "items[*].price: read"}},
+		{"m['a b'][0].x + m.k", []string{"m['a b'][0].x: read", "m.k: read"}},
+		{"total = price * qty", []string{"price: read", "qty: read", "total: write"}},
+		{"count += 1; ++hits; cache.x ??= 0", []string{"count: read|write", "hits: read|write", "cache.x: read|write"}},
+		{"order.items.count() + list.get(0)", []string{"order.items.count: call", "list.get: call"}},
+		{"x = f(y); f2(x)", []string{"f: call", "y: read", "x: read|write", "f2: call"}},
+		{"str:trim(name) + empty(v) + size(w)", []string{"str:trim: call", "name: read", "v: read", "w: read"}},
+		{"s |> str:upper |> fmt", []string{"s: read", "str:upper: call", "fmt: call"}},
+		{"order.items |> size() > 0", []string{"order.items: read"}},
+		{"order.items |> size > 0 || order.tags |> empty", []string{"order.items: read", "order.tags: read"}},
+		{"f().x + (a ?: b)", []string{"f: call", "a: read", "b: read"}},
+		{"now() + duration(d)", []string{"d: read"}},
+	}
+	for _, tt := range tests {
+		if got := dependencies(t, jexl.NewBuilder(), tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Dependencies(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestDependenciesLocals тестирует исключение локальных имён
+func TestDependenciesLocals(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"var x = a; x.y = x.z; y", []string{"a: read", "y: read"}},
+		{"for (item : order.items) { sum += item.price }", []string{"order.items: read", "order.items[*].price: read", "sum: read|write"}},
+		{"for (var k, v : m) { out[k] = v }", []string{"m: read", "m[*]: read", "out[*]: write"}},
+		{"for (var i : items) { i.price }", []string{"items: read", "items[*].price: read"}},
+		{"for (var o : orders) { for (var i : o.lines) { i.qty += 1; i = null } }", []string{"orders: read", "orders[*].lines: read", "orders[*].lines[*].qty: read|write"}},
+		{"var xs = []; for (var x : xs) { x.y }", nil},
+		{"items.map(i -> i.price)", []string{"items: read", "items[*].price: read"}},
+		{"items.filter(i -> i.active).sum(i -> i.price * k)", []string{"items: read", "items[*].active: read", "items[*].price: read", "k: read"}},
+		{"items.skip(1).map(i -> i.x).filter(x -> x.y)", []string{"items.skip: call", "items: read", "items[*].x: read"}},
+		{"order.items.reduce((acc, i) -> acc + i.qty, 0)", []string{"order.items: read", "order.items[*].qty: read"}},
+		{"orders.flatMap(o -> o.lines.map(l -> l.qty))", []string{"orders: read", "orders[*].lines: read", "orders[*].lines[*].qty: read"}},
+		{"var xs = []; xs.map(x -> x.y)", nil},
+		{"items.sortBy() + items.map(f)", []string{"items.sortBy: call", "items.map: call", "f: read"}},
+		{"function sq(n) { return n * n } sq(base)", []string{"base: read"}},
+		{"f(order.total); function f(a) { a * 2 }", []string{"order.total: read"}},
+		{"if (c) { g(1); function g(a) { a } }", []string{"c: read"}},
+		{"var f = (a, b = limit) -> a + b + c; f(1)", []string{"limit: read", "c: read"}},
+		{"var [a, , b] = pair; var {x, y: z = d} = point; a + b + x + z", []string{"pair: read", "point: read", "d: read"}},
+		{"[q, r] = pair", []string{"pair: read", "q: write", "r: write"}},
+		{"try (var r = open(path)) { r.read() } catch (e) { log(e) }", []string{"open: call", "path: read", "log: call"}},
+		{"if (c) { var t = 1 } t", []string{"c: read"}},
+	}
+	for _, tt := range tests {
+		if got := dependencies(t, jexl.NewBuilder(), tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Dependencies(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "a + b", "a")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if deps := script.Dependencies(); len(deps) != 1 || deps[0].Root() != "b" {
+		t.Errorf("Parameter reported as dependency: %v", deps)
+	}
+}
+
+// TestDependenciesCollectMode тестирует обрыв путей на индексах в строгом режиме
+func TestDependenciesCollectMode(t *testing.T) {
+	src := "items[0].price + items[i].qty + m.k"
+	want := []string{"items: read", "i: read", "m.k: read"}
+	if got := dependencies(t, jexl.NewBuilder().CollectMode(0), src); !reflect.DeepEqual(got, want) {
+		t.Errorf("Strict dependencies = %q, want %q", got, want)
+	}
+	for _, loop := range []string{"for (var i : items) { i.price }", "items.map(i -> i.price)"} {
+		if got := dependencies(t, jexl.NewBuilder().CollectMode(0), loop); !reflect.DeepEqual(got, []string{"items: read"}) {
+			t.Errorf("Strict loop dependencies of %q = %q, want [items: read]", loop, got)
+		}
+	}
+	want = []string{"items[0].price: read", "i: read", "items[*].qty: read", "m.k: read"}
+	if got := dependencies(t, jexl.NewBuilder().CollectAll(true), src); !reflect.DeepEqual(got, want) {
+		t.Errorf("Extended dependencies = %q, want %q", got, want)
+	}
+}
+
+// TestDependencyKinds тестирует виды зависимостей и AnalyzeDependencies
+func TestDependencyKinds(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "math:max(a.b, fn(c))")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	want := []jexl.Dependency{
+		{Kind: jexl.DependencyNamespace, Namespace: "math", Path: []string{"max"}, Access: jexl.AccessCall},
+		{Kind: jexl.DependencyVariable, Path: []string{"a", "b"}, Access: jexl.AccessRead},
+		{Kind: jexl.DependencyFunction, Path: []string{"fn"}, Access: jexl.AccessCall},
+		{Kind: jexl.DependencyVariable, Path: []string{"c"}, Access: jexl.AccessRead},
+	}
+	if got := jexl.AnalyzeDependencies(script.AST(), 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("AnalyzeDependencies = %+v, want %+v", got, want)
+	}
+}
+
+// TestBuiltins тестирует таблицу встроенных функций и их переопределение контекстом
+func TestBuiltins(t *testing.T) {
+	tests := []struct {
+		name        string
+		overridable bool
+		ok          bool
+	}{
+		{"size", false, true},
+		{"empty", false, true},
+		{"now", true, true},
+		{"matches", true, true},
+		{"fn", false, false},
+	}
+	for _, tt := range tests {
+		if overridable, ok := jexl.Builtin(tt.name); overridable != tt.overridable || ok != tt.ok {
+			t.Errorf("Builtin(%q) = %v, %v, want %v, %v", tt.name, overridable, ok, tt.overridable, tt.ok)
+		}
+	}
+
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "now() + size('ab')")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	// Встроенные функции не входят в зависимости, даже переопределяемые
+	if got := jexl.AnalyzeDependencies(script.AST(), 1); len(got) != 0 {
+		t.Errorf("AnalyzeDependencies = %+v, want none", got)
+	}
+	ctx := jexl.NewMapContext()
+	ctx.Set("now", func(args ...any) (any, error) { return int64(40), nil })
+	ctx.Set("size", func(args ...any) (any, error) { return int64(100), nil })
+	result, err := script.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute: %v", err)
+	}
+	if asInt64(t, result) != 42 {
+		t.Errorf("now() + size('ab') = %v, want 42", result)
+	}
+}
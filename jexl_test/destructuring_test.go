@@ -0,0 +1,263 @@
+package jexl_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// destructuringPerson - структура для проверки деструктуризации через Uberspect
+type destructuringPerson struct {
+	Name string
+	Age  int
+	City string
+}
+
+// TestDestructuringArrayDeclaration тестирует var [a, b] = ...
+func TestDestructuringArrayDeclaration(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"var [lo, hi] = bounds; hi - lo", int64(9)},
+		{"var [a, , c] = [1, 2, 3]; c", int64(3)},
+		{"var [a, b = 5] = [1]; b", int64(5)},
+		{"var [first, ...rest] = [1, 2, 3]; rest", []any{int64(2), int64(3)}},
+		{"var [x, [y, z]] = [1, [2, 3]]; z", int64(3)},
+		{"var [a, b] = 1 .. 1000000000; a + b", int64(3)},
+		{"var [a, ...rest] = 1 .. 3; rest", []any{int64(2), int64(3)}},
+		{"var [a, b, c = 7] = {1, 2}; a + b + c", int64(10)},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("bounds", []int64{1, 10})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if _, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != tt.expected {
+				t.Errorf("%q: expected %v, got %d", tt.src, tt.expected, actual)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestDestructuringMapDeclaration тестирует var {name, age} = ... для мап и структур
+func TestDestructuringMapDeclaration(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	sources := map[string]any{
+		"map":    map[string]any{"name": "Ann", "age": 30, "city": "Oslo"},
+		"struct": &destructuringPerson{Name: "Ann", Age: 30, City: "Oslo"},
+	}
+	for kind, person := range sources {
+		ctx := jexl.NewMapContext()
+		ctx.Set("person", person)
+
+		script, err := engine.CreateScript(nil, nil,
+			"var {name, age: years, zip = 'none', ...others} = person; name + ':' + years + ':' + zip")
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("%s: failed to execute script: %v", kind, err)
+		}
+		if result != "Ann:30:none" {
+			t.Errorf("%s: expected Ann:30:none, got %v", kind, result)
+		}
+
+		others, ok := ctx.Get("others").(*jexl.Map)
+		if !ok || others.Size() != 1 {
+			t.Fatalf("%s: expected single remaining property, got %v", kind, ctx.Get("others"))
+		}
+		for _, v := range others.Values() {
+			if v != "Oslo" {
+				t.Errorf("%s: expected Oslo in rest, got %v", kind, v)
+			}
+		}
+	}
+}
+
+// TestDestructuringMapRest тестирует, что остаток мапы сохраняет ключи любого типа и их порядок
+func TestDestructuringMapRest(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	tests := []struct {
+		src  string
+		keys []any
+	}{
+		{"var {a, ...rest} = {'z': 0, 2: 'two', 'a': 1, 1: 'one'}; rest", []any{"z", int64(2), int64(1)}},
+		{"var {...rest} = ints; rest", []any{1, 2}},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("ints", map[int]string{2: "two", 1: "one"})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		rest, ok := result.(*jexl.Map)
+		if !ok || !reflect.DeepEqual(rest.Keys(), tt.keys) {
+			t.Errorf("%q: expected map with keys %v, got %v", tt.src, tt.keys, result)
+		}
+	}
+}
+
+// TestDestructuringAssignment тестирует присваивание с деструктуризацией
+func TestDestructuringAssignment(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"var a = 1; var b = 2; [a, b] = [b, a]; a + ',' + b", "2,1"},
+		{"var n; var c; {name: n, city: c} = person; n + '@' + c", "Bob@Rome"},
+		{"{name} = person; name", "Bob"},
+		{"if (true) { ({city} = person); city }", "Rome"},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("person", map[string]any{"name": "Bob", "city": "Rome"})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestDestructuringForeach тестирует деструктуризацию в заголовке foreach
+func TestDestructuringForeach(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	ctx := jexl.NewMapContext()
+	ctx.Set("people", []any{
+		&destructuringPerson{Name: "Ann", Age: 30},
+		map[string]any{"name": "Bob", "age": 12},
+	})
+
+	script, err := engine.CreateScript(nil, nil,
+		"var s = ''; for (var [k, v] : [['a', 1], ['b', 2]]) { s = s + k + v }; for (var {name, age} : people) { s = s + name + age }; s")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != "a1b2Ann30Bob12" {
+		t.Errorf("Expected a1b2Ann30Bob12, got %v", result)
+	}
+}
+
+// TestDestructuringErrors тестирует ошибки разбора и строгий режим
+func TestDestructuringErrors(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	invalid := []string{
+		"var [a, b]",
+		"var [...rest, a] = x",
+		"var [] = x",
+		"var {'quoted'} = x",
+		"var [1] = x",
+	}
+	for _, src := range invalid {
+		if _, err := engine.CreateScript(nil, nil, src); err == nil {
+			t.Errorf("Expected parsing error for %q", src)
+		}
+	}
+
+	script, err := engine.CreateScript(nil, nil, "var [a, b] = 42")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if _, err := script.Execute(nil); err == nil {
+		t.Error("Expected error destructuring a number as array")
+	}
+
+	// Остаток слишком длинного диапазона не материализуется
+	huge, err := engine.CreateScript(nil, nil, "var [a, ...b] = 1 .. 1000000000000; a")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if _, err := huge.Execute(nil); err == nil || !strings.Contains(err.Error(), "too large to materialize") {
+		t.Errorf("Expected too large to materialize error, got %v", err)
+	}
+
+	strictEngine, err := jexl.NewBuilder().Strict(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	strict, err := strictEngine.CreateScript(nil, nil, "var {a} = null")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if _, err := strict.Execute(nil); err == nil {
+		t.Error("Expected error destructuring null in strict mode")
+	}
+
+	// В нестрогом режиме null даёт отсутствующие значения
+	lenientEngine, err := jexl.NewBuilder().Strict(false).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	lenient, err := lenientEngine.CreateScript(nil, nil, "var [p, q = 7] = null; var {r} = null; q")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := lenient.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 7 {
+		t.Errorf("Expected 7, got %d", actual)
+	}
+}
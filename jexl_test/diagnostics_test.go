@@ -0,0 +1,172 @@
+package jexl_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestParseDiagnostics тестирует сбор нескольких ошибок с восстановлением на границах инструкций
+func TestParseDiagnostics(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	src := "var a = 1;\nb = (2 + ;\nc = a d;\nif (a) { e = ; f = 2 }\n@;\ng(1, 2"
+	diagnostics, ast := engine.ParseDiagnostics(src, nil)
+
+	expected := []struct {
+		position string
+		message  string
+		expected []string
+		fix      string
+	}{
+		{"2:10", "expected expression, found ';'", []string{"expression"}, ""},
+		{"3:7", "expected ';' between expressions, found identifier 'd'", []string{"';'"}, "insert ';'"},
+		{"4:14", "expected expression, found ';'", []string{"expression"}, ""},
+		{"5:1", "unexpected character '@'", nil, "remove '@'"},
+		{"6:7", "expected ',' or ')', found end of input", []string{"','", "')'"}, "insert ')'"},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(expected), diagnostics)
+	}
+	for k, tt := range expected {
+		d := diagnostics[k]
+		if d.Severity != jexl.SeverityError || d.Range.String() != tt.position || d.Message != tt.message {
+			t.Errorf("Diagnostic %d: expected %s %q, got %v", k, tt.position, tt.message, d)
+		}
+		if !slices.Equal(d.Expected, tt.expected) {
+			t.Errorf("Diagnostic %d: expected tokens %v, got %v", k, tt.expected, d.Expected)
+		}
+		if (d.Fix == nil) != (tt.fix == "") || d.Fix != nil && d.Fix.Message != tt.fix {
+			t.Errorf("Diagnostic %d: expected fix %q, got %+v", k, tt.fix, d.Fix)
+		}
+	}
+
+	// Частичный AST содержит успешно разобранные инструкции
+	var statements []string
+	for _, child := range ast.Children() {
+		statements = append(statements, child.SourceText())
+	}
+	for _, stmt := range []string{"var a = 1", "c = a", "d", "if (a) {f = 2}"} {
+		if !slices.Contains(statements, stmt) {
+			t.Errorf("Expected statement %q in partial AST %q", stmt, statements)
+		}
+	}
+	if ast.Children()[1].Position().Line != 3 {
+		t.Errorf("Expected partial AST positions, got %v", ast.Children()[1].Position())
+	}
+}
+
+// TestParseDiagnosticsFixes тестирует, что предложенные исправления устраняют ошибку
+func TestParseDiagnosticsFixes(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	tests := []struct {
+		src   string
+		fixed string
+	}{
+		{"x = [1, 2", "x = [1, 2]"},
+		{"f(1, 2", "f(1, 2)"},
+		{"if (a { b }", "if (a) { b }"},
+		{"var x = 1\nvar y = 2 x + y", "var x = 1\nvar y = 2; x + y"},
+		{"a = 1; # comment\n` a", "a = 1; # comment\n a"},
+	}
+	for _, tt := range tests {
+		diagnostics, _ := engine.ParseDiagnostics(tt.src, nil)
+		if len(diagnostics) != 1 || diagnostics[0].Fix == nil {
+			t.Fatalf("%q: expected one diagnostic with fix, got %v", tt.src, diagnostics)
+		}
+		fixed := diagnostics[0].Fix.Apply(tt.src)
+		if fixed != tt.fixed {
+			t.Errorf("%q: expected %q after fix, got %q", tt.src, tt.fixed, fixed)
+		}
+		if diagnostics, _ := engine.ParseDiagnostics(fixed, nil); len(diagnostics) != 0 {
+			t.Errorf("%q: unexpected diagnostics after fix: %v", fixed, diagnostics)
+		}
+	}
+}
+
+// TestParseDiagnosticsValid тестирует, что корректный скрипт разбирается без диагностики
+func TestParseDiagnosticsValid(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	src := "var total = 0; for (item : items) { total += item.price } total"
+	diagnostics, ast := engine.ParseDiagnostics(src, nil)
+	if len(diagnostics) != 0 {
+		t.Fatalf("Unexpected diagnostics: %v", diagnostics)
+	}
+	script, err := engine.CreateScript(nil, nil, src)
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if ast.String() != script.AST().String() {
+		t.Errorf("Expected %q, got %q", script.AST().String(), ast.String())
+	}
+}
+
+// TestParsingErrorPosition тестирует положение и текст ошибки разбора
+func TestParsingErrorPosition(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	_, err = engine.CreateScript(nil, nil, "x = 1;\ny = (x + 1;")
+	if err == nil {
+		t.Fatalf("Expected parsing error")
+	}
+	if err.Error() != "expected ')', found ';'" {
+		t.Errorf("Unexpected message %q", err.Error())
+	}
+	info := jexl.ErrorInfo(err)
+	if info == nil || info.Line() != 2 || info.Column() != 11 || info.Detail().String() != ";" {
+		t.Errorf("Unexpected info %v", info)
+	}
+}
+
+// TestParseDiagnosticsUnicode тестирует имена и недопустимые символы за пределами ASCII
+func TestParseDiagnosticsUnicode(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "var привет = 2; var ответ = привет; ответ")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if result, err := script.Execute(nil); err != nil || result != int64(2) {
+		t.Errorf("Expected 2, got %v (%v)", result, err)
+	}
+
+	if diagnostics, _ := engine.ParseDiagnostics("привет + 1", nil); len(diagnostics) != 0 {
+		t.Errorf("Unexpected diagnostics: %v", diagnostics)
+	}
+
+	src := "имя ← 1"
+	diagnostics, _ := engine.ParseDiagnostics(src, nil)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Range.String() != "1:5" || d.Message != "unexpected character '←'" || d.Fix == nil || d.Fix.Message != "remove '←'" {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+	if fixed := d.Fix.Apply(src); fixed != "имя  1" {
+		t.Errorf("Expected %q after fix, got %q", "имя  1", fixed)
+	}
+
+	diagnostics, _ = engine.ParseDiagnostics("var = 3", nil)
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected one diagnostic, got %v", diagnostics)
+	}
+	d = diagnostics[0]
+	if d.Range.String() != "1:5" || d.Message != "expected identifier after 'var', found '='" || !slices.Equal(d.Expected, []string{"identifier"}) {
+		t.Errorf("Unexpected diagnostic %v, expected tokens %v", d, d.Expected)
+	}
+}
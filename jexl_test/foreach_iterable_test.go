@@ -0,0 +1,164 @@
+package jexl_test
+
+import (
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// iterableUser - элемент типизированного слайса
+type iterableUser struct {
+	Name string
+}
+
+// javaIterator - итератор в стиле Java с методами HasNext/Next
+type javaIterator struct {
+	items []string
+	pos   int
+}
+
+func (it *javaIterator) HasNext() bool {
+	return it.pos < len(it.items)
+}
+
+func (it *javaIterator) Next() string {
+	it.pos++
+	return it.items[it.pos-1]
+}
+
+// javaIterable - тип с методом Iterator(), возвращающим итератор
+type javaIterable struct {
+	items []string
+}
+
+func (j *javaIterable) Iterator() *javaIterator {
+	return &javaIterator{items: j.items}
+}
+
+// seqIterable - тип с методом Iterator(), возвращающим iter.Seq
+type seqIterable struct {
+	items []string
+}
+
+func (s seqIterable) Iterator() iter.Seq[string] {
+	return slices.Values(s.items)
+}
+
+// runForeach выполняет скрипт накопления и возвращает результат
+func runForeach(t *testing.T, engine jexl.Engine, src string, vars map[string]any) any {
+	t.Helper()
+	ctx := jexl.NewMapContext()
+	for k, v := range vars {
+		ctx.Set(k, v)
+	}
+	script, err := engine.CreateScript(nil, nil, src)
+	if err != nil {
+		t.Fatalf("Failed to create script %q: %v", src, err)
+	}
+	result, err := script.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute script %q: %v", src, err)
+	}
+	return result
+}
+
+// TestForeachTypedCollections тестирует итерацию по слайсам, мапам, структурам и каналам любого типа
+func TestForeachTypedCollections(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	ch := make(chan int, 3)
+	ch <- 1
+	ch <- 2
+	ch <- 3
+	close(ch)
+
+	tests := []struct {
+		name     string
+		items    any
+		expected string
+	}{
+		{"float slice", []float64{1.5, 2.5}, ":1.5:2.5"},
+		{"pointer slice", []*iterableUser{{Name: "a"}, {Name: "b"}}, ":a:b"},
+		{"array", [2]string{"x", "y"}, ":x:y"},
+		{"int map", map[int]string{1: "one"}, ":one"},
+		{"struct", iterableUser{Name: "n"}, ":n"},
+		{"channel", (<-chan int)(ch), ":1:2:3"},
+		{"seq", slices.Values([]string{"p", "q"}), ":p:q"},
+		{"iterator", &javaIterable{items: []string{"i", "j"}}, ":i:j"},
+		{"seq iterator", seqIterable{items: []string{"s", "t"}}, ":s:t"},
+	}
+	for _, tt := range tests {
+		result := runForeach(t, engine, "var s = ''; for (var x : items) { s = s + ':' + (x.name ?: x) }; s",
+			map[string]any{"items": tt.items})
+		if result != tt.expected {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.expected, result)
+		}
+	}
+
+	result := runForeach(t, engine, "var s = 0; for (var x : 1..4) { s = s + x }; s", nil)
+	if actual := asInt64(t, result); actual != 10 {
+		t.Errorf("range: expected 10, got %d", actual)
+	}
+}
+
+// TestForeachKeyValue тестирует форму for (var k, v : items)
+func TestForeachKeyValue(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.SortedKeys(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		src      string
+		items    any
+		expected string
+	}{
+		{"sorted map", "for (var k, v : items) { s = s + k + '=' + v + ';' }", map[string]int{"b": 2, "a": 1, "c": 3}, "a=1;b=2;c=3;"},
+		{"numeric keys", "for (var k, v : items) { s = s + k + v }", map[int]string{10: "x", 2: "y", 1: "z"}, "1z2y10x"},
+		{"slice index", "for (k, v : items) { s = s + k + v }", []string{"a", "b"}, "0a1b"},
+		{"struct fields", "for (var k, v : items) { s = s + k + '=' + v }", iterableUser{Name: "n"}, "Name=n"},
+		{"seq2", "for (var k, v : items) { s = s + k + v }", slices.All([]string{"a", "b"}), "0a1b"},
+		{"break", "for (var k, v : items) { if (k == 1) break; s = s + v }", slices.All([]string{"a", "b", "c"}), "a"},
+		{"continue", "for (var k, v : items) { if (k == 1) continue; s = s + v }", []string{"a", "b", "c"}, "ac"},
+	}
+	for _, tt := range tests {
+		result := runForeach(t, engine, "var s = ''; "+tt.src+"; s", map[string]any{"items": tt.items})
+		if result != tt.expected {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.expected, result)
+		}
+	}
+}
+
+// TestForeachNotIterable тестирует ошибки итерации
+func TestForeachNotIterable(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	for _, items := range []any{42, make(chan<- int)} {
+		ctx := jexl.NewMapContext()
+		ctx.Set("items", items)
+		script, err := engine.CreateScript(nil, nil, "for (var x : items) { x }")
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		if _, err := script.Execute(ctx); err == nil {
+			t.Errorf("Expected error iterating %T", items)
+		}
+	}
+
+	if _, err := engine.CreateScript(nil, nil, "for (var k, k : items) { k }"); err == nil {
+		t.Error("Expected parsing error for duplicate key/value names")
+	}
+}
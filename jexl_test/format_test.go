@@ -0,0 +1,286 @@
+package jexl_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/format"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestFormatStyle тестирует канонический вид операторов, блоков и инструкций
+func TestFormatStyle(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"1+2*3", "1 + 2 * 3\n"},
+		{"x=10;y=x>5?'big':'small';y", "x = 10;\ny = x > 5 ? 'big' : 'small';\ny\n"},
+		{"for(item:[1,2,3]){total+=item}", "for (item : [1, 2, 3]) {\n    total += item;\n}\n"},
+		{"for(var k,v:m){}", "for (var k, v : m) {}\n"},
+		{"if(a){b}else if(c)d;else{e}", "if (a) {\n    b;\n} else if (c) d; else {\n    e;\n}\n"},
+		{"while(n<3)n=n+1", "while (n < 3) n = n + 1\n"},
+		{"do{--n}while(n>0);n", "do {\n    --n;\n} while (n > 0);\nn\n"},
+		{"function sq(n){return n*n} sq(7)", "function sq(n) {\n    return n * n;\n}\nsq(7)\n"},
+		{"var f=(a,b=2)=>a*b", "var f = (a, b = 2) => a * b\n"},
+		{"var g=(x)->{x+1}", "var g = x -> {\n    x + 1;\n}\n"},
+		{"var h=function(x){x}", "var h = function(x) {\n    x;\n}\n"},
+		{"var [a,,b,...r]=l; var {x,'y z':y=1}=m", "var [a, , b, ...r] = l;\nvar {x, 'y z': y = 1} = m\n"},
+		{"{'a':1,'b':{1,2}}", "{'a': 1, 'b': {1, 2}}\n"},
+		{"a??b??c; (a??b)??c; -a??b; (-a)??b", "a ?? b ?? c;\n(a ?? b) ?? c;\n-(a ?? b);\n(-a) ?? b\n"},
+		{"not empty x; - -x; ! ~/a/; ++i; x**=2", "not empty(x);\n- -x;\n! ~/a/;\n++i;\nx **= 2\n"},
+		{"7 div(2+1); 7 div 2*3; a div-b", "7 div (2 + 1);\n7 div 2 * 3;\na div -b\n"},
+		{"-2**2; (-2)**2; 2**-1; (2**3)**2", "-2 ** 2;\n(-2) ** 2;\n2 ** -1;\n(2 ** 3) ** 2\n"},
+		{"x instanceof java.util.List and y !in z", "x instanceof java.util.List and y !in z\n"},
+		{"list|>str:trim|>(s->s+1); 1..10 step 2", "list |> str:trim |> (s -> s + 1);\n1 .. 10 step 2\n"},
+		{"list|>size; list|>empty", "list |> size();\nlist |> empty()\n"},
+		{"switch(v){case 1,2:'a' default:{'b'}}", "switch (v) {\n    case 1, 2: 'a'\n    default: {\n        'b';\n    }\n}\n"},
+		{"try(var r=open();q){r.read()}catch{1}finally{close()}",
+			"try (var r = open(); q) {\n    r.read();\n} catch {\n    1;\n} finally {\n    close();\n}\n"},
+	}
+	for _, tt := range tests {
+		got, err := format.Source(tt.src, nil)
+		if err != nil {
+			t.Fatalf("Failed to format %q: %v", tt.src, err)
+		}
+		if got != tt.want {
+			t.Errorf("format(%q) =\n%s\nwant\n%s", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestFormatParens тестирует сохранение и удаление скобок
+func TestFormatParens(t *testing.T) {
+	src := "x = ((a + b)) * c; y = (a * b) + (c); z = a - (b - c)"
+	got, _ := format.Source(src, nil)
+	if want := "x = (a + b) * c;\ny = (a * b) + (c);\nz = a - (b - c)\n"; got != want {
+		t.Errorf("Kept parens:\n%s\nwant\n%s", got, want)
+	}
+	got, _ = format.Source(src, &format.Options{MinimizeParens: true})
+	if want := "x = (a + b) * c;\ny = a * b + c;\nz = a - (b - c)\n"; got != want {
+		t.Errorf("Minimized parens:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestFormatOptions тестирует отступы и расположение скобок
+func TestFormatOptions(t *testing.T) {
+	src := "if (a) { b } else { c } try { d } catch (e) { f }"
+	got, _ := format.Source(src, &format.Options{UseTabs: true, Braces: format.BraceNextLine})
+	want := "if (a)\n{\n\tb;\n}\nelse\n{\n\tc;\n}\ntry\n{\n\td;\n}\ncatch (e)\n{\n\tf;\n}\n"
+	if got != want {
+		t.Errorf("Next line braces:\n%s\nwant\n%s", got, want)
+	}
+	got, _ = format.Source("list.map(x -> { x })", &format.Options{IndentWidth: 2, Braces: format.BraceNextLine})
+	if want := "list.map(x -> {\n  x;\n})\n"; got != want {
+		t.Errorf("Lambda braces:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestFormatComments тестирует сохранение комментариев и пустых строк
+func TestFormatComments(t *testing.T) {
+	src := `// header
+var a = 1;   # hash
+
+
+/* block */
+if (a) {
+  // inside
+  b = f(a, /* inner */ 2) // trailing
+  // last
+}
+'//not a comment' + ~/\/\/x/ // end
+`
+	want := `// header
+var a = 1; # hash
+
+/* block */
+if (a) {
+    // inside
+    b = f(a, 2); /* inner */ // trailing
+    // last
+}
+'//not a comment' + ~/\/\/x/ // end
+`
+	got, err := format.Source(src, nil)
+	if err != nil {
+		t.Fatalf("Failed to format: %v", err)
+	}
+	if got != want {
+		t.Errorf("Comments:\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestFormatNode тестирует печать AST без исходного текста
+func TestFormatNode(t *testing.T) {
+	body := jexl.NewBinaryOpNode("*", jexl.NewIdentifierNode("x", ""), jexl.NewLiteralNode(2.0, ""), "")
+	call := jexl.NewMethodCallNode(jexl.NewIdentifierNode("list", ""), jexl.NewIdentifierNode("map", ""),
+		[]jexl.Node{jexl.NewLambdaNode([]*jexl.IdentifierNode{jexl.NewIdentifierNode("x", "")}, body, "")}, "")
+	if got, want := format.Node(call, nil), "list.map(x -> x * 2.0)"; got != want {
+		t.Errorf("Node = %q, want %q", got, want)
+	}
+	if got, want := format.Node(jexl.NewLiteralNode("it's", ""), nil), `'it\'s'`; got != want {
+		t.Errorf("Node = %q, want %q", got, want)
+	}
+}
+
+// TestFormatRoundTrip форматирует все скрипты из тестов пакета и проверяет,
+// что форматирование идемпотентно и не меняет AST
+func TestFormatRoundTrip(t *testing.T) {
+	scripts := testScripts(t)
+	styles := []*format.Options{
+		nil,
+		{MinimizeParens: true},
+		{UseTabs: true, Braces: format.BraceNextLine},
+	}
+	formatted := 0
+	for _, src := range scripts {
+		want, err := structure(src)
+		if err != nil {
+			continue
+		}
+		for _, style := range styles {
+			once, err := format.Source(src, style)
+			if err != nil {
+				t.Errorf("Failed to format %q: %v", src, err)
+				continue
+			}
+			twice, err := format.Source(once, style)
+			if err != nil {
+				t.Errorf("Formatted %q does not parse: %v\n%s", src, err, once)
+				continue
+			}
+			if twice != once {
+				t.Errorf("Formatting %q is not idempotent:\n%s\n%s", src, once, twice)
+			}
+			if got, _ := structure(once); !reflect.DeepEqual(got, want) {
+				t.Errorf("Formatting %q changed AST:\n%s", src, once)
+			}
+		}
+		formatted++
+	}
+	if formatted < 500 {
+		t.Errorf("Only %d scripts formatted", formatted)
+	}
+}
+
+// testScripts собирает строковые литералы из тестов пакета.
+func testScripts(t testing.TB) []string {
+	files, err := filepath.Glob("*_test.go")
+	if err != nil {
+		t.Fatalf("Failed to list tests: %v", err)
+	}
+	seen := make(map[string]bool)
+	var scripts []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", file, err)
+		}
+		ast.Inspect(f, func(node ast.Node) bool {
+			if lit, ok := node.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if s, err := strconv.Unquote(lit.Value); err == nil && !seen[s] {
+					seen[s] = true
+					scripts = append(scripts, s)
+				}
+			}
+			return true
+		})
+	}
+	return scripts
+}
+
+// structure разбирает скрипт и возвращает AST без исходного текста и положений.
+func structure(src string) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic %v", r)
+		}
+	}()
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		return nil, err
+	}
+	diagnostics, ast := engine.ParseDiagnostics(src, nil)
+	if len(diagnostics) > 0 {
+		return nil, errors.New(diagnostics[0].String())
+	}
+	data, err := jexl.MarshalAST(ast)
+	if err != nil {
+		return nil, err
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	return stripSource(tree), nil
+}
+
+// stripSource удаляет исходный текст и положения узлов.
+func stripSource(tree any) any {
+	switch v := tree.(type) {
+	case map[string]any:
+		delete(v, "source")
+		delete(v, "pos")
+		for key, value := range v {
+			v[key] = stripSource(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = stripSource(value)
+		}
+	}
+	return tree
+}
+
+// FuzzFormatSource проверяет, что форматирование любого разбираемого скрипта
+// сохраняет AST и идемпотентно
+func FuzzFormatSource(f *testing.F) {
+	for _, src := range testScripts(f) {
+		f.Add(src)
+	}
+	f.Add("a ?? b ?? c; -a ?? b; not empty x; x **= 2 ** 3 ** 2")
+	f.Add("switch (v) { case 1, 2: 'a' default: { 'b' } } // c")
+	f.Add("(0).0 .0(); if (0) ({a} = m); ! $")
+	f.Fuzz(func(t *testing.T, src string) {
+		want, err := structure(src)
+		if err != nil || emptyTopBlock(want) {
+			return
+		}
+		once, err := format.Source(src, nil)
+		if err != nil {
+			t.Fatalf("Failed to format %q: %v", src, err)
+		}
+		got, err := structure(once)
+		if err != nil {
+			t.Fatalf("Formatted %q does not parse: %v\n%s", src, err, once)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Formatting %q changed AST:\n%s", src, once)
+		}
+		if twice, _ := format.Source(once, nil); twice != once {
+			t.Fatalf("Formatting %q is not idempotent:\n%s\n%s", src, once, twice)
+		}
+	})
+}
+
+// emptyTopBlock сообщает, есть ли в скрипте пустой блок верхнего уровня:
+// в исходном тексте он читается как пустой литерал {} и не воспроизводим.
+func emptyTopBlock(tree any) bool {
+	script, _ := tree.(map[string]any)["script"].(map[string]any)
+	children, _ := script["children"].([]any)
+	for _, child := range children {
+		if node, ok := child.(map[string]any); ok && node["kind"] == "Block" && node["statements"] == nil {
+			return true
+		}
+	}
+	return false
+}
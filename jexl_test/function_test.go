@@ -0,0 +1,246 @@
+package jexl_test
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// asInt64 приводит числовой результат скрипта к int64
+func asInt64(t *testing.T, value any) int64 {
+	t.Helper()
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	case *big.Rat:
+		return v.Num().Int64()
+	default:
+		t.Fatalf("Unexpected result type: %T, value: %v", value, value)
+		return 0
+	}
+}
+
+// TestFunctionDeclaration тестирует объявление и вызов именованной функции
+func TestFunctionDeclaration(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil, "function add(a, b) { a + b } add(40, 2)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	result, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 42 {
+		t.Errorf("Expected 42, got %d", actual)
+	}
+}
+
+// TestFunctionHoisting тестирует вызов функции до её объявления
+func TestFunctionHoisting(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	sources := []string{
+		"var r = twice(21); function twice(x) { return x * 2; } r",
+		"if (true) { var r = inc(41); function inc(x) { x + 1 } r } else { 0 }",
+	}
+	for _, src := range sources {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", src, err)
+		}
+		if actual := asInt64(t, result); actual != 42 {
+			t.Errorf("%q: expected 42, got %d", src, actual)
+		}
+	}
+}
+
+// TestFunctionRecursion тестирует рекурсивные и взаимно рекурсивные функции
+func TestFunctionRecursion(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected int64
+	}{
+		{"function fact(n) { if (n <= 1) { return 1; } return n * fact(n - 1); } fact(5)", 120},
+		{"function fib(n) { n < 2 ? n : fib(n - 1) + fib(n - 2) } fib(10)", 55},
+		{"function even(n) { n == 0 ? 1 : odd(n - 1) } function odd(n) { n == 0 ? 0 : even(n - 1) } even(10)", 1},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if actual := asInt64(t, result); actual != tt.expected {
+			t.Errorf("%q: expected %d, got %d", tt.src, tt.expected, actual)
+		}
+	}
+}
+
+// TestFunctionsFromGo тестирует получение объявленных функций и их вызов из Go
+func TestFunctionsFromGo(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	library, err := engine.CreateScript(nil, nil,
+		"var unit = 10; function scale(x) { x * unit } function sum(n) { n == 0 ? 0 : n + sum(n - 1) }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if names := library.Functions(); !reflect.DeepEqual(names, []string{"scale", "sum"}) {
+		t.Errorf("Expected [scale sum], got %v", names)
+	}
+
+	ctx := jexl.NewMapContext()
+	if _, err := library.Execute(ctx); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if ctx.Has("scale") || ctx.Has("sum") {
+		t.Error("Expected declared functions to stay out of the host context")
+	}
+
+	scale, err := library.Function(ctx, "scale")
+	if err != nil {
+		t.Fatalf("Failed to get function: %v", err)
+	}
+	if params := scale.Parameters(); !reflect.DeepEqual(params, []string{"x"}) {
+		t.Errorf("Expected [x], got %v", params)
+	}
+	result, err := scale.Execute(nil, 4)
+	if err != nil {
+		t.Fatalf("Failed to execute function: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 40 {
+		t.Errorf("Expected 40, got %d", actual)
+	}
+
+	sum, err := library.Function(ctx, "sum")
+	if err != nil {
+		t.Fatalf("Failed to get function: %v", err)
+	}
+	result, err = sum.Execute(nil, 4)
+	if err != nil {
+		t.Fatalf("Failed to execute function: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 10 {
+		t.Errorf("Expected 10, got %d", actual)
+	}
+	if _, err := library.Function(ctx, "unit"); err == nil {
+		t.Error("Expected error for a name that is not a declared function")
+	}
+}
+
+// TestFunctionScope тестирует видимость функций в пределах блока или скрипта
+func TestFunctionScope(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected int64
+	}{
+		{"var r = 0; if (true) { function f() { 5 } r = f() } r", 5},
+		{"function f() { 1 } if (true) { function f() { 5 } }; f()", 1},
+		{"function outer() { function inner() { 2 } inner() } var r = outer(); r", 2},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if actual := asInt64(t, result); actual != tt.expected {
+			t.Errorf("%q: expected %d, got %d", tt.src, tt.expected, actual)
+		}
+	}
+
+	for _, src := range []string{"if (true) { function f() { 5 } } f()", "function outer() { function inner() { 2 } 0 } outer(); inner()"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if _, err := script.Execute(nil); err == nil {
+			t.Errorf("%q: expected error for a function outside its block", src)
+		}
+	}
+
+	ctx := jexl.NewMapContext()
+	script, err := engine.CreateScript(nil, nil, "function f(x) { x + 1 } f(1)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if _, err := script.Execute(ctx); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if ctx.Has("f") {
+		t.Error("Expected host context to stay unchanged")
+	}
+}
+
+// TestFunctionParsing тестирует ошибки разбора объявлений функций
+func TestFunctionParsing(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	invalid := []string{
+		"function f(x) x + 1",
+		"function f(1) { 1 }",
+		"function f(x { x }",
+		"while (true) { function f() { break } }",
+	}
+	for _, src := range invalid {
+		if _, err := engine.CreateScript(nil, nil, src); err == nil {
+			t.Errorf("Expected parsing error for %q", src)
+		}
+	}
+
+	features := jexl.FeaturesDefault()
+	features.Enable(jexl.FeatureLambda, false)
+	restricted, err := jexl.NewBuilder().Features(features).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	if _, err := restricted.CreateScript(nil, nil, "function f(x) { x }"); err == nil {
+		t.Error("Expected error when lambdas are disabled")
+	}
+}
@@ -352,14 +352,13 @@ func TestSetLiteral(t *testing.T) {
 		t.Fatalf("Failed to evaluate expression: %v", err)
 	}
 
-	// В JEXL множества реализованы как мапы, где ключи - это элементы множества
-	m, ok := result.(map[string]any)
+	set, ok := result.(*jexl.Set)
 	if !ok {
-		t.Fatalf("Expected map (set), got %T", result)
+		t.Fatalf("Expected *jexl.Set, got %T", result)
 	}
 
-	if len(m) != 5 {
-		t.Fatalf("Expected set size 5, got %d", len(m))
+	if set.Size() != 5 {
+		t.Fatalf("Expected set size 5, got %d", set.Size())
 	}
 }
 
@@ -384,14 +383,15 @@ func TestNestedLiterals(t *testing.T) {
 		t.Fatalf("Failed to evaluate expression: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	arr, ok := m["foo"].([]any)
+	foo, _ := m.Get("foo")
+	arr, ok := foo.([]any)
 	if !ok {
-		t.Fatalf("Expected array in map, got %T", m["foo"])
+		t.Fatalf("Expected array in map, got %T", foo)
 	}
 
 	if len(arr) != 3 {
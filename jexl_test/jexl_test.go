@@ -345,12 +345,12 @@ func TestMapLiteral(t *testing.T) {
 		t.Fatalf("Failed to evaluate expression: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	if len(m) != 3 {
-		t.Fatalf("Expected map size 3, got %d", len(m))
+	if m.Size() != 3 {
+		t.Fatalf("Expected map size 3, got %d", m.Size())
 	}
 }
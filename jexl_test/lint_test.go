@@ -0,0 +1,172 @@
+package jexl_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	"github.com/mentatxx/jexl-golang/jexl/lint"
+)
+
+// lintMessages проверяет исходный текст и возвращает сообщения в виде строк.
+func lintMessages(src string, config *lint.Config) []string {
+	var result []string
+	for _, issue := range lint.Source(src, config) {
+		result = append(result, issue.String())
+	}
+	return result
+}
+
+// TestLintRules тестирует встроенные правила
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"var a = 1; var b = a; b", nil},
+		{"var unused = 1; function helper() { 1 } var _skip = 2; x", []string{
+			"1:5: warning: local variable unused is never read [unused-local]",
+			"1:26: warning: function helper is never called [unused-local]",
+		}},
+		{"var tier = 1; order.tier + f(2)", []string{"1:5: warning: local variable tier is never read [unused-local]"}},
+		{"var n = 0; n += 1; var o = {}; o.x = 1; n", nil},
+		{"if (x = 1) { y }", []string{
+			"1:5: warning: assignment in condition; did you mean ==? (replace '=' with '==') [assign-in-condition]",
+		}},
+		{"while (x += 1) { y }", nil},
+		{"var x = 1; var f = (x) -> x * 2; f(x)", []string{"1:21: warning: x shadows the declaration at 1:5 [shadow]"}},
+		{"function g() { return 1; x = 2; y } g()", []string{"1:26: warning: unreachable code [unreachable]"}},
+		{"for (i : list) { if (i) { break } else { continue } log(i) }", []string{
+			"1:53: warning: unreachable code [unreachable]",
+		}},
+		{"if (1 > 2) { a } while (true) { b }; c ? 1 : 2; !false ? 1 : 2", []string{
+			"1:5: warning: condition is constant [constant-condition]",
+			"1:49: warning: condition is constant [constant-condition]",
+		}},
+		{"try { f() } catch (e) { } try { g() } catch (e) { /* expected */ }", []string{
+			"1:23: warning: empty catch block ignores the error [empty-catch]",
+		}},
+		{"x == '1' || x == 1 || '1' == 1 || [1] != 1 || (a < b) == 'yes' || y != null", []string{
+			"1:23: warning: == compares string with number [incompatible-equality]",
+			"1:35: warning: != compares array with number [incompatible-equality]",
+			"1:47: warning: == compares boolean with string [incompatible-equality]",
+		}},
+	}
+	for _, tt := range tests {
+		if got := lintMessages(tt.src, nil); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lint(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestLintPolicy тестирует запрет конструкций политикой
+func TestLintPolicy(t *testing.T) {
+	config := &lint.Config{
+		Policy: jexl.FeaturesDefault().Without(jexl.FeatureLoop, jexl.FeatureSideEffectGlobal, jexl.FeatureStructuredLiteral),
+	}
+	src := "var total = 0; for (i : items) { total += i } result = total; [total]"
+	want := []string{
+		"1:16: error: loops are disabled by policy [disabled-feature]",
+		"1:47: error: assignments to global variables are disabled by policy [disabled-feature]",
+		"1:63: error: structured literals are disabled by policy [disabled-feature]",
+	}
+	if got := lintMessages(src, config); !reflect.DeepEqual(got, want) {
+		t.Errorf("lint with policy =\n%q\nwant\n%q", got, want)
+	}
+
+	config.Policy = jexl.FeaturesDefault().Without(jexl.FeatureLambda, jexl.FeatureMethodCall)
+	src = "function f(a) { var g = b -> b; g(a) } f(s.trim())"
+	want = []string{
+		"1:1: error: functions are disabled by policy [disabled-feature]",
+		"1:25: error: lambdas are disabled by policy [disabled-feature]",
+		"1:42: error: method calls are disabled by policy [disabled-feature]",
+	}
+	if got := lintMessages(src, config); !reflect.DeepEqual(got, want) {
+		t.Errorf("lint with policy =\n%q\nwant\n%q", got, want)
+	}
+}
+
+// TestLintSuppression тестирует подавление сообщений комментариями и pragma
+func TestLintSuppression(t *testing.T) {
+	src := "var a = 1; // lint:ignore unused-local\n" +
+		"// lint:ignore\n" +
+		"var b = 1;\n" +
+		"var c = 1; # lint:ignore shadow\n" +
+		"var d = 1"
+	want := []string{
+		"4:5: warning: local variable c is never read [unused-local]",
+		"5:5: warning: local variable d is never read [unused-local]",
+	}
+	if got := lintMessages(src, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("lint =\n%q\nwant\n%q", got, want)
+	}
+
+	src = "#pragma lint.disable unused-local, constant-condition\nvar a = 1; if (true) { b }; x == 'a' + 1"
+	if got := lintMessages(src, nil); got != nil {
+		t.Errorf("lint with pragma = %q", got)
+	}
+	if got := lintMessages("var a = 1", &lint.Config{Disabled: []string{"unused-local"}}); got != nil {
+		t.Errorf("lint with disabled rule = %q", got)
+	}
+
+	config := &lint.Config{Severity: map[string]jexl.Severity{"unused-local": jexl.SeverityError}}
+	want = []string{"1:5: error: local variable a is never read [unused-local]"}
+	if got := lintMessages("var a = 1", config); !reflect.DeepEqual(got, want) {
+		t.Errorf("lint with severity = %q, want %q", got, want)
+	}
+}
+
+// TestLintSyntaxAndJSON тестирует ошибки разбора, пользовательские правила и вывод JSON
+func TestLintSyntaxAndJSON(t *testing.T) {
+	issues := lint.Source("var = 1", nil)
+	if len(issues) == 0 || issues[0].Rule != lint.SyntaxRule || issues[0].Severity != jexl.SeverityError {
+		t.Fatalf("Syntax error not reported: %v", issues)
+	}
+
+	noCalls := &lint.Rule{ID: "no-calls", Severity: jexl.SeverityInfo, Check: func(p *lint.Pass) {
+		jexl.Walk(p.Script, &callVisitor{pass: p})
+	}}
+	issues = lint.Source("if (a = 1) { log(a) }", &lint.Config{Rules: append(lint.DefaultRules(), noCalls)})
+	for i := range issues {
+		issues[i].File = "rule.jexl"
+	}
+	var buf bytes.Buffer
+	if err := lint.WriteJSON(&buf, issues); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON %s: %v", buf.String(), err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("JSON issues = %s", buf.String())
+	}
+	if got[0]["rule"] != "assign-in-condition" || got[0]["file"] != "rule.jexl" || got[0]["line"] != 1.0 || got[0]["column"] != 5.0 {
+		t.Errorf("First issue = %v", got[0])
+	}
+	fix, _ := got[0]["fix"].(map[string]any)
+	if fix["offset"] != 6.0 || fix["endOffset"] != 7.0 || fix["newText"] != "==" {
+		t.Errorf("Fix = %v", fix)
+	}
+	if got[1]["rule"] != "no-calls" || got[1]["severity"] != "info" || got[1]["message"] != "call of log" {
+		t.Errorf("Custom rule issue = %v", got[1])
+	}
+
+	buf.Reset()
+	if err := lint.WriteJSON(&buf, nil); err != nil || buf.String() != "[]\n" {
+		t.Errorf("WriteJSON(nil) = %q, %v", buf.String(), err)
+	}
+}
+
+// callVisitor сообщает о вызовах функций.
+type callVisitor struct {
+	jexl.BaseVisitor
+	pass *lint.Pass
+}
+
+func (v *callVisitor) VisitMethodCall(node *jexl.MethodCallNode) bool {
+	v.pass.Reportf(node, "call of %v", node.Method())
+	return true
+}
@@ -0,0 +1,20 @@
+package jexl_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+)
+
+// TestMain прогоняет все тесты трижды: с интерпретацией AST, с компиляцией
+// скриптов в дерево замыканий (Options.Compiled) и с оптимизацией AST после
+// разбора (Options.Optimize). Семантика режимов должна совпадать.
+func TestMain(m *testing.M) {
+	interpreted := m.Run()
+	jexl.SetDefaultFlags("+compiled")
+	compiled := m.Run()
+	jexl.SetDefaultFlags("-compiled", "+optimize")
+	optimized := m.Run()
+	os.Exit(max(interpreted, compiled, optimized))
+}
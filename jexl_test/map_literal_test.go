@@ -28,13 +28,13 @@ func TestEmptyMapLiteral(t *testing.T) {
 		t.Fatalf("Failed to evaluate: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	if len(m) != 0 {
-		t.Errorf("Expected empty map, got size %d", len(m))
+	if m.Size() != 0 {
+		t.Errorf("Expected empty map, got size %d", m.Size())
 	}
 }
 
@@ -58,17 +58,17 @@ func TestMapLiteralWithStrings(t *testing.T) {
 		t.Fatalf("Failed to evaluate: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	if len(m) != 1 {
-		t.Fatalf("Expected map size 1, got %d", len(m))
+	if m.Size() != 1 {
+		t.Fatalf("Expected map size 1, got %d", m.Size())
 	}
 
-	if m["foo"] != "bar" {
-		t.Errorf("Expected m['foo'] to be 'bar', got %v", m["foo"])
+	if foo, _ := m.Get("foo"); foo != "bar" {
+		t.Errorf("Expected m['foo'] to be 'bar', got %v", foo)
 	}
 }
 
@@ -92,21 +92,21 @@ func TestMapLiteralWithMultipleEntries(t *testing.T) {
 		t.Fatalf("Failed to evaluate: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	if len(m) != 2 {
-		t.Fatalf("Expected map size 2, got %d", len(m))
+	if m.Size() != 2 {
+		t.Fatalf("Expected map size 2, got %d", m.Size())
 	}
 
-	if m["foo"] != "bar" {
-		t.Errorf("Expected m['foo'] to be 'bar', got %v", m["foo"])
+	if foo, _ := m.Get("foo"); foo != "bar" {
+		t.Errorf("Expected m['foo'] to be 'bar', got %v", foo)
 	}
 
-	if m["eat"] != "food" {
-		t.Errorf("Expected m['eat'] to be 'food', got %v", m["eat"])
+	if eat, _ := m.Get("eat"); eat != "food" {
+		t.Errorf("Expected m['eat'] to be 'food', got %v", eat)
 	}
 }
 
@@ -130,13 +130,16 @@ func TestMapLiteralWithNumbers(t *testing.T) {
 		t.Fatalf("Failed to evaluate: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	// Ключи преобразуются в строки
-	val := m["5"]
+	// Ключи сохраняют исходный тип: числовой ключ не совпадает со строкой '5'
+	if m.Has("5") {
+		t.Errorf("Expected no string key '5'")
+	}
+	val, _ := m.Get(5)
 	var expected int64 = 10
 	var actual int64
 	switch v := val.(type) {
@@ -180,14 +183,15 @@ func TestMapArrayLiteral(t *testing.T) {
 		t.Fatalf("Failed to evaluate: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	arr, ok := m["foo"].([]any)
+	foo, _ := m.Get("foo")
+	arr, ok := foo.([]any)
 	if !ok {
-		t.Fatalf("Expected []any in map, got %T", m["foo"])
+		t.Fatalf("Expected []any in map, got %T", foo)
 	}
 
 	if len(arr) != 3 {
@@ -215,18 +219,19 @@ func TestMapMapLiteral(t *testing.T) {
 		t.Fatalf("Failed to evaluate: %v", err)
 	}
 
-	m, ok := result.(map[string]any)
+	m, ok := result.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any, got %T", result)
+		t.Fatalf("Expected *jexl.Map, got %T", result)
 	}
 
-	inner, ok := m["foo"].(map[string]any)
+	foo, _ := m.Get("foo")
+	inner, ok := foo.(*jexl.Map)
 	if !ok {
-		t.Fatalf("Expected map[string]any in map, got %T", m["foo"])
+		t.Fatalf("Expected *jexl.Map in map, got %T", foo)
 	}
 
-	if inner["inner"] != "bar" {
-		t.Errorf("Expected inner['inner'] to be 'bar', got %v", inner["inner"])
+	if value, _ := inner.Get("inner"); value != "bar" {
+		t.Errorf("Expected inner['inner'] to be 'bar', got %v", value)
 	}
 }
 
@@ -0,0 +1,208 @@
+package jexl_test
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+type operatorPerson struct {
+	Name string
+}
+
+// TestExtraOperators тестирует **, div, ??=, составные побитовые присваивания, in и instanceof
+func TestExtraOperators(t *testing.T) {
+	builder := jexl.NewBuilder().Types(map[string]reflect.Type{
+		"Person": reflect.TypeOf(operatorPerson{}),
+	})
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"2 ** 10", int64(1024)},
+		{"2 ** 3 ** 2", int64(512)},
+		{"2 * 3 ** 2", int64(18)},
+		{"2 ** 100 / 2 ** 98", int64(4)},
+		{"4 ** 0.5", int64(2)},
+		{"-2 ** 2", int64(-4)},
+		{"(-2) ** 2", int64(4)},
+		{"2 ** -1 * 4", int64(2)},
+		{"-x ** 2 + 1", int64(-8)},
+		{"var a = 3; a **= 2; a", int64(9)},
+		{"7 div 2", int64(3)},
+		{"-7 div 2", int64(-3)},
+		{"7.5 div 0.5", int64(15)},
+		{"7 div 2 * 2 + 7 % 2", int64(7)},
+		{"1 + x div 2", int64(2)},
+		{"var div = 9; div div 2", int64(4)},
+		{"var x = null; x ??= 5; x", int64(5)},
+		{"var x = 1; x ??= 5; x", int64(1)},
+		{"var m = {'a': null}; m.a ??= 'z'; m.a", "z"},
+		{"var x = 6; x &= 3; x", int64(2)},
+		{"var x = 6; x |= 1; x", int64(7)},
+		{"var x = 6; x ^= 3; x", int64(5)},
+		{"var x = 1; x <<= 4; x", int64(16)},
+		{"var x = 16; x >>= 2; x", int64(4)},
+		{"var x = 16; x >>>= 3; x", int64(2)},
+		{"1 in [1, 2]", true},
+		{"3 in [1, 2]", false},
+		{"3 !in [1, 2]", true},
+		{"true && 3 !in [1, 2]", true},
+		{"var in = [1]; 1 in in", true},
+		{"'ell' in 'hello'", true},
+		{"'x' in ['xy']", false},
+		{"[1] in [[1]]", true},
+		{"[1] !in [[1], [2, 3]]", false},
+		{"[2] in [[1], {'a': [2]}]", false},
+		{"{'a': [2]} in [[1], {'a': [2]}]", true},
+		{"'a' in {'a': 1}", true},
+		{"2 in {1, 2}", true},
+		{"5 in 1 .. 10", true},
+		{"1 + 1 in [2]", true},
+		{"p instanceof Person", true},
+		{"pp instanceof Person", true},
+		{"'s' instanceof Person", false},
+		{"'s' instanceof string", true},
+		{"1 instanceof number && 1 instanceof integer", true},
+		{"1.5 instanceof integer", false},
+		{"[1] instanceof list", true},
+		{"{1} instanceof set", true},
+		{"null instanceof object", false},
+		{"'s' !instanceof number", true},
+		{"'s' instanceof java.lang.String", true},
+		{"var f = (x) -> x; f instanceof function", true},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("p", operatorPerson{Name: "Ann"})
+		ctx.Set("pp", &operatorPerson{Name: "Bob"})
+		ctx.Set("x", int64(3))
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestPowerPrecision тестирует точность целых степеней
+func TestPowerPrecision(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "2 ** 100")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	expected := new(big.Int).Lsh(big.NewInt(1), 100)
+	r, ok := result.(*big.Rat)
+	if !ok || !r.IsInt() || r.Num().Cmp(expected) != 0 {
+		t.Errorf("Expected %s, got %v", expected, result)
+	}
+
+	for _, src := range []string{"0 ** -1", "(-8) ** 0.5", "var b = 10 ** 1000; b ** 65536"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if _, err := script.Execute(nil); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
+
+// TestExtraOperatorErrors тестирует ошибки операторов in, instanceof и div
+func TestExtraOperatorErrors(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	for _, src := range []string{"1 instanceof Unknown", "1 in 2"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		_, err = script.Execute(nil)
+		var opErr *jexl.OperatorError
+		if !errors.As(err, &opErr) {
+			t.Errorf("%q: expected operator error, got %v", src, err)
+		}
+	}
+	// div, как и /, возвращает ошибку арифметики
+	for _, src := range []string{"1 div 0", "'a' div 2"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if _, err := script.Execute(nil); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
+
+// TestExtraOperatorsParsedText тестирует восстановление исходного текста новых операторов
+func TestExtraOperatorsParsedText(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	sources := []string{
+		"a ** 2",
+		"a **= 2",
+		"a div 2",
+		"x ??= 1",
+		"x &= 1",
+		"x |= 1",
+		"x ^= 1",
+		"x <<= 1",
+		"x >>= 1",
+		"x >>>= 1",
+		"1 in [1, 2]",
+		"1 !in [1, 2]",
+		"x instanceof Person",
+		"x !instanceof java.lang.String",
+	}
+	for _, src := range sources {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if text := script.ParsedText(); text != src {
+			t.Errorf("ParsedText: expected %q, got %q", src, text)
+		}
+		// Восстановленный текст разбирается повторно в тот же текст
+		again, err := engine.CreateScript(nil, nil, script.ParsedText())
+		if err != nil {
+			t.Fatalf("Failed to reparse %q: %v", script.ParsedText(), err)
+		}
+		if again.ParsedText() != src {
+			t.Errorf("Round-trip: expected %q, got %q", src, again.ParsedText())
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package jexl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// countingArithmetic считает вызовы умножения и сложения
+type countingArithmetic struct {
+	*jexl.BaseArithmetic
+	calls int
+}
+
+func (a *countingArithmetic) Multiply(left, right any) (any, error) {
+	a.calls++
+	return a.BaseArithmetic.Multiply(left, right)
+}
+
+func (a *countingArithmetic) Add(left, right any) (any, error) {
+	a.calls++
+	return a.BaseArithmetic.Add(left, right)
+}
+
+// TestOptimizer тестирует, что оптимизация AST не меняет результатов скриптов
+func TestOptimizer(t *testing.T) {
+	optimized, err := jexl.NewBuilder().Optimize(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	plain, err := jexl.NewBuilder().Optimize(false).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"60 * 60 * 24 * days", int64(172800)},
+		{"'prefix-' + 'x' + id", "prefix-x7"},
+		{"-(2 + 3) * days", int64(-10)},
+		{"true && days > 1", true},
+		{"true && days", true},
+		{"days && true", true},
+		{"false || days == 3", false},
+		{"1 == 1 && days", true},
+		{"if (false) { 1 } else { 2 }", int64(2)},
+		{"if (1 > 2) { 1 }", nil},
+		{"if (true) { var a = 1; } a + 1", int64(2)},
+		{"var s = 0; if (days > 0) { s = s + 1; { s = s + 2; } } s", int64(3)},
+		{"if (days > 0) { days; {} }", nil},
+		{"function f() { 1 } if (true) { function f() { 5 } }; f()", int64(1)},
+		{"var f = (x = 2 * 3) -> x + 0; f()", int64(6)},
+		{"var r = 0; while (r < 3) { { r = r + 1 } } r", int64(3)},
+	}
+	for _, tt := range tests {
+		for _, engine := range []jexl.Engine{optimized, plain} {
+			ctx := jexl.NewMapContext()
+			ctx.Set("days", int64(2))
+			ctx.Set("id", int64(7))
+			script, err := engine.CreateScript(nil, nil, tt.src)
+			if err != nil {
+				t.Fatalf("Failed to create script %q: %v", tt.src, err)
+			}
+			result, err := script.Execute(ctx)
+			if err != nil {
+				t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+			}
+			if n, ok := tt.expected.(int64); ok {
+				if actual := asInt64(t, result); actual != n {
+					t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+				}
+				continue
+			}
+			if result != tt.expected {
+				t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+			}
+		}
+	}
+}
+
+// TestOptimizerFolding тестирует, что операции над литералами выполняются при разборе
+func TestOptimizerFolding(t *testing.T) {
+	for _, optimize := range []bool{true, false} {
+		arithmetic := &countingArithmetic{BaseArithmetic: jexl.NewBaseArithmetic(true, nil, 0)}
+		engine, err := jexl.NewBuilder().Arithmetic(arithmetic).Optimize(optimize).Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		script, err := engine.CreateScript(nil, nil, "60 * 60 * 24 * days + ('a' + 'b' == 'ab' ? 1 : 0)")
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		parsed := arithmetic.calls
+		ctx := jexl.NewMapContext()
+		ctx.Set("days", int64(1))
+		if _, err := script.Execute(ctx); err != nil {
+			t.Fatalf("Failed to execute script: %v", err)
+		}
+		executed := arithmetic.calls - parsed
+		if optimize && (parsed != 3 || executed != 2) {
+			t.Errorf("Optimized: expected 3 folded and 2 executed operations, got %d and %d", parsed, executed)
+		}
+		if !optimize && (parsed != 0 || executed != 5) {
+			t.Errorf("Plain: expected 0 folded and 5 executed operations, got %d and %d", parsed, executed)
+		}
+	}
+}
+
+// TestOptimizerErrors тестирует, что ошибки в константных выражениях возникают при выполнении
+func TestOptimizerErrors(t *testing.T) {
+	engine, err := jexl.NewBuilder().Optimize(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "flag ? 1 / 0 : 'ok'")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	ctx := jexl.NewMapContext()
+	ctx.Set("flag", false)
+	if result, err := script.Execute(ctx); err != nil || result != "ok" {
+		t.Errorf("Expected 'ok', got %v (%v)", result, err)
+	}
+	ctx.Set("flag", true)
+	if _, err := script.Execute(ctx); err == nil || !strings.Contains(err.Error(), "division by zero") {
+		t.Errorf("Expected division by zero, got %v", err)
+	}
+}
+
+// TestOptimizerParsedText тестирует, что ParsedText показывает исходный, а не оптимизированный текст
+func TestOptimizerParsedText(t *testing.T) {
+	engine, err := jexl.NewBuilder().Optimize(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	for _, src := range []string{"60 * 60 * 24 * days", "true && x", "if (false) { x } else { y }"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if text := script.ParsedText(); text != src {
+			t.Errorf("ParsedText: expected %q, got %q", src, text)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package jexl_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestLambdaDefaultParameters тестирует значения параметров по умолчанию
+func TestLambdaDefaultParameters(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		args     []any
+		expected int64
+	}{
+		{"(a, b = 10) -> a + b", []any{1}, 11},
+		{"(a, b = 10) -> a + b", []any{1, 2}, 3},
+		{"(a, b = a * 2) -> a + b", []any{5}, 15},
+		{"function f(a = 1, b = 2) { a * 10 + b } f()", nil, 12},
+		{"var f = (x, y = 40) => x + y; f(2)", nil, 42},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil, tt.args...)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if actual := asInt64(t, result); actual != tt.expected {
+			t.Errorf("%q %v: expected %d, got %d", tt.src, tt.args, tt.expected, actual)
+		}
+	}
+}
+
+// TestLambdaRestParameter тестирует параметр ...rest
+func TestLambdaRestParameter(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil, "(first, ...rest) -> rest")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	result, err := script.Execute(nil, 1, 2, 3)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if !reflect.DeepEqual(result, []any{2, 3}) {
+		t.Errorf("Expected [2 3], got %v", result)
+	}
+
+	result, err = script.Execute(nil, 1)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if !reflect.DeepEqual(result, []any{}) {
+		t.Errorf("Expected empty rest, got %#v", result)
+	}
+
+	count, err := engine.CreateScript(nil, nil, "function count(...items) { size(items) } count(1, 2, 3, 4)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err = count.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 4 {
+		t.Errorf("Expected 4, got %d", actual)
+	}
+}
+
+// TestScriptParameterNames тестирует значения по умолчанию и rest в именах параметров скрипта
+func TestScriptParameterNames(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil, "a + b + size(rest)", "a", "b = 10", "...rest")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if params := script.Parameters(); !reflect.DeepEqual(params, []string{"a", "b", "rest"}) {
+		t.Errorf("Expected [a b rest], got %v", params)
+	}
+
+	result, err := script.Execute(nil, 1)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 11 {
+		t.Errorf("Expected 11, got %d", actual)
+	}
+
+	result, err = script.Execute(nil, 1, 2, "x", "y")
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 5 {
+		t.Errorf("Expected 5, got %d", actual)
+	}
+
+	invalid := [][]string{
+		{"a = 1", "b"},
+		{"...rest", "a"},
+		{"a", "a"},
+	}
+	for _, names := range invalid {
+		if _, err := engine.CreateScript(nil, nil, "1", names...); err == nil {
+			t.Errorf("Expected error for parameters %v", names)
+		}
+	}
+}
+
+// TestScriptExecuteNamed тестирует вызов с именованными аргументами
+func TestScriptExecuteNamed(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil, "x * 100 + y * 10 + z", "x", "y = 5", "z = 7")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	result, err := script.ExecuteNamed(nil, map[string]any{"x": 1, "z": 2})
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 152 {
+		t.Errorf("Expected 152, got %d", actual)
+	}
+
+	if _, err := script.ExecuteNamed(nil, map[string]any{"w": 1}); err == nil {
+		t.Error("Expected error for unknown parameter")
+	}
+
+	curried := script.Curry(3)
+	if _, err := curried.ExecuteNamed(nil, map[string]any{"x": 1}); err == nil {
+		t.Error("Expected error for already bound parameter")
+	}
+	result, err = curried.ExecuteNamed(nil, map[string]any{"y": 0})
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 307 {
+		t.Errorf("Expected 307, got %d", actual)
+	}
+
+	lambda, err := engine.CreateScript(nil, nil, "(a, b = 2) -> a - b")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err = lambda.ExecuteNamed(nil, map[string]any{"a": 10})
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 8 {
+		t.Errorf("Expected 8, got %d", actual)
+	}
+}
+
+// TestCurryWithDefaults тестирует Curry и UnboundParameters с параметрами по умолчанию
+func TestCurryWithDefaults(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	base, err := engine.CreateScript(nil, nil, "var k = 1000; (a, b, c = 3, ...rest) -> k + a * 100 + b * 10 + c")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := base.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	fn := result.(jexl.Script)
+
+	if unbound := fn.UnboundParameters(); !reflect.DeepEqual(unbound, []string{"a", "b"}) {
+		t.Errorf("Expected [a b], got %v", unbound)
+	}
+
+	curried := fn.Curry(1)
+	if unbound := curried.UnboundParameters(); !reflect.DeepEqual(unbound, []string{"b"}) {
+		t.Errorf("Expected [b], got %v", unbound)
+	}
+
+	curried = curried.Curry(2)
+	if unbound := curried.UnboundParameters(); len(unbound) != 0 {
+		t.Errorf("Expected no unbound parameters, got %v", unbound)
+	}
+
+	result, err = curried.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 1123 {
+		t.Errorf("Expected 1123, got %d", actual)
+	}
+
+	result, err = curried.Execute(nil, 9)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 1129 {
+		t.Errorf("Expected 1129, got %d", actual)
+	}
+}
@@ -0,0 +1,108 @@
+package jexl_test
+
+import (
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestPipeline тестирует оператор конвейера |>
+func TestPipeline(t *testing.T) {
+	builder := jexl.NewBuilder().StandardNamespaces(jexl.NamespaceMath, jexl.NamespaceStr)
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"x.amount |> math:round(1)", 12.3},
+		{"x.amount |> math:round |> str:length", int64(2)},
+		{"'  Hello ' |> str:trim |> str:lower", "hello"},
+		{"'abc' |> str:upper |> str:padStart(5, '-')", "--ABC"},
+		{"3 |> (n) -> n * 2", int64(6)},
+		{"var twice = (n) -> n * 2; 3 |> twice |> twice", int64(12)},
+		{"5 |> add(10)", int64(15)},
+		{"1 + 2 |> add(10)", int64(13)},
+		{"2 * 3 |> twice == 12", true},
+		{"' Hi ' |> trim |> toUpperCase", "HI"},
+		{"'hello' |> substring(1, 3)", "el"},
+		{"'a,b' |> split(',') |> str:join('-')", "a-b"},
+		{"[1, 2] |> size", int64(2)},
+		{"[1, 2] |> size()", int64(2)},
+		{"[1, 2] |> size == 2", true},
+		{"'abc' |> size |> twice", int64(6)},
+		{"[] |> empty", true},
+		{"[1] |> empty()", false},
+		{"null |> empty && true", true},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("x", map[string]any{"amount": 12.345})
+		ctx.Set("add", func(args ...any) (any, error) {
+			return asInt64(t, args[0]) + asInt64(t, args[1]), nil
+		})
+		twice, err := engine.CreateScript(nil, nil, "(n) -> n * 2")
+		if err != nil {
+			t.Fatalf("Failed to create lambda: %v", err)
+		}
+		twiceFn, err := twice.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to evaluate lambda: %v", err)
+		}
+		ctx.Set("twice", twiceFn)
+
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v (%T), got %v (%T)", tt.src, tt.expected, tt.expected, result, result)
+		}
+	}
+}
+
+// TestPipelineParsedText тестирует восстановление исходного текста конвейера
+func TestPipelineParsedText(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	sources := []string{
+		"x |> f",
+		"x |> size",
+		"x |> empty()",
+		"x.amount |> math:round(2) |> str:trim",
+		"a + 1 |> f(2, 3)",
+		"x |> (v) -> v + 1",
+	}
+	for _, src := range sources {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if text := script.ParsedText(); text != src {
+			t.Errorf("ParsedText: expected %q, got %q", src, text)
+		}
+		again, err := engine.CreateScript(nil, nil, script.ParsedText())
+		if err != nil {
+			t.Fatalf("Failed to reparse %q: %v", script.ParsedText(), err)
+		}
+		if again.ParsedText() != src {
+			t.Errorf("Round-trip: expected %q, got %q", src, again.ParsedText())
+		}
+	}
+}
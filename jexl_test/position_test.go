@@ -0,0 +1,127 @@
+package jexl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestNodePositions тестирует положения узлов многострочного скрипта
+func TestNodePositions(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	src := "var total = 0;\nfor (item : items) {\n  total = total + item.price * 2;\n}\ntotal"
+	script, err := engine.CreateScript(nil, nil, src)
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	root := script.AST().Position()
+	if root.Offset != 0 || root.EndOffset != len(src) || root.Line != 1 || root.EndLine != 5 {
+		t.Errorf("Unexpected script position %+v", root)
+	}
+	jexl.Inspect(script.AST(), func(node jexl.Node) bool {
+		if node != nil && !node.Position().IsValid() {
+			t.Errorf("%T %q has no position", node, node.SourceText())
+		}
+		return true
+	})
+
+	var product *jexl.BinaryOpNode
+	jexl.Inspect(script.AST(), func(node jexl.Node) bool {
+		if binary, ok := node.(*jexl.BinaryOpNode); ok && binary.Op() == "*" {
+			product = binary
+		}
+		return true
+	})
+	if product == nil {
+		t.Fatalf("Multiplication not found")
+	}
+	pos := product.Position()
+	if pos.String() != "3:19" || pos.EndLine != 3 || pos.EndColumn != 33 {
+		t.Errorf("Unexpected position %+v", pos)
+	}
+	if text := src[pos.Offset:pos.EndOffset]; text != "item.price * 2" {
+		t.Errorf("Position covers %q", text)
+	}
+	if price := product.Left().(*jexl.PropertyAccessNode).Property().Position(); price.String() != "3:24" {
+		t.Errorf("Unexpected property position %v", price)
+	}
+
+	// Столбцы считаются в символах, смещения - в байтах
+	script, err = engine.CreateScript(nil, nil, "'ключ' + x")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	x := script.AST().Children()[0].(*jexl.BinaryOpNode).Right().Position()
+	if x.Column != 10 || x.Offset != len("'ключ' + ") {
+		t.Errorf("Unexpected position %+v", x)
+	}
+
+	// Имена и литералы получают положение своего токена
+	script, err = engine.CreateScript(nil, nil, "var a = 1; var va = a")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	children := script.AST().Children()
+	a, va := children[0].(*jexl.VarNode).Name().Position(), children[1].(*jexl.VarNode).Name().Position()
+	if a.String() != "1:5" || va.String() != "1:16" {
+		t.Errorf("Unexpected name positions %v, %v", a, va)
+	}
+	script, err = engine.CreateScript(nil, nil, "a + (b + a)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	inner := script.AST().Children()[0].(*jexl.BinaryOpNode).Right().(*jexl.BinaryOpNode)
+	if pos := inner.Right().Position(); pos.String() != "1:10" {
+		t.Errorf("Unexpected identifier position %v", pos)
+	}
+}
+
+// TestErrorPositions тестирует Info с положением и Detail у ошибок выполнения
+func TestErrorPositions(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	tests := []struct {
+		src    string
+		line   int
+		column int
+		detail string
+	}{
+		{"a.b.c", 1, 1, "a"},
+		{"var s = 0;\nfor (item : items) {\n  s = s + item.price * 2;\n}", 3, 11, "item.price * 2"},
+		{"var f = (v) -> {\n  v / 0\n};\nf(1)", 2, 3, "v / 0"},
+		{"x = 1;\n  x.foo(1, 2)", 2, 3, "x.foo(1, 2)"},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		ctx := jexl.NewMapContext()
+		ctx.Set("items", []any{map[string]any{}})
+		_, err = script.Execute(ctx)
+		if err == nil {
+			t.Fatalf("%q: expected error", tt.src)
+		}
+		info := jexl.ErrorInfo(err)
+		if info == nil || info.Detail() == nil {
+			t.Fatalf("%q: expected info with detail, got %v", tt.src, info)
+		}
+		if info.Line() != tt.line || info.Column() != tt.column || info.Detail().String() != tt.detail {
+			t.Errorf("%q: expected %d:%d %q, got %v", tt.src, tt.line, tt.column, tt.detail, info)
+		}
+		if text := tt.src[info.Detail().Start():info.Detail().End()]; text != tt.detail {
+			t.Errorf("%q: detail range covers %q", tt.src, text)
+		}
+		if strings.Contains(err.Error(), "@") {
+			t.Errorf("%q: error text should not change, got %q", tt.src, err.Error())
+		}
+	}
+}
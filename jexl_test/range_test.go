@@ -0,0 +1,148 @@
+package jexl_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestLazyRange тестирует операции над диапазоном без материализации
+func TestLazyRange(t *testing.T) {
+	builder := jexl.NewBuilder().StandardNamespaces(jexl.NamespaceMath, jexl.NamespaceJSON)
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"size(1 .. 1000000000)", int64(1000000000)},
+		{"500000000 =~ 1 .. 1000000000", true},
+		{"0 =~ 1 .. 10", false},
+		{"1.5 =~ 1 .. 10", false},
+		{"[2, 3] =~ 1 .. 10", true},
+		{"(1 .. 1000000000)[999999999]", int64(1000000000)},
+		{"(10 .. 1)[2]", int64(8)},
+		{"empty(1 .. 1)", false},
+		{"size(1 .. 10 step 3)", int64(4)},
+		{"(1 .. 10 step 3)[3]", int64(10)},
+		{"4 =~ (1 .. 10 step 3)", true},
+		{"5 =~ (1 .. 10 step 3)", false},
+		{"size(10 .. 1 step 4)", int64(3)},
+		{"var s = 0; for (var x : 1 .. 1000000000) { if (x > 3) break; s = s + x }; s", int64(6)},
+		{"var s = ''; for (var x : (1 .. 9 step 4).reversed()) { s = s + x }; s", "951"},
+		{"1 .. 3 == [1, 2, 3]", true},
+		{"[3, 2, 1] == 3 .. 1", true},
+		{"1 .. 3 == [1, 2]", false},
+		{"(1 .. 2) != (3 .. 4) && (1 .. 2) <= (1 .. 2)", true},
+		{"var step = 2; 0 .. 4 step step", nil},
+		{"json:stringify(1 .. 3)", "[1,2,3]"},
+		{"size((1 .. 3) + [0])", int64(4)},
+		{"math:max(1 .. 10)", int64(10)},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(jexl.NewMapContext())
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		switch expected := tt.expected.(type) {
+		case int64:
+			if actual := asInt64(t, result); actual != expected {
+				t.Errorf("%q: expected %d, got %d", tt.src, expected, actual)
+			}
+		case nil:
+			if r, ok := result.(*jexl.Range); !ok || !reflect.DeepEqual(r.ToSlice(), []int64{0, 2, 4}) {
+				t.Errorf("%q: expected 0 .. 4 step 2, got %v", tt.src, result)
+			}
+		default:
+			if result != expected {
+				t.Errorf("%q: expected %v, got %v", tt.src, expected, result)
+			}
+		}
+	}
+}
+
+// TestRangeType тестирует API jexl.Range
+func TestRangeType(t *testing.T) {
+	r, err := jexl.NewRange(1, 10).WithStep(4)
+	if err != nil {
+		t.Fatalf("Failed to set step: %v", err)
+	}
+	if !reflect.DeepEqual(r.ToSlice(), []int64{1, 5, 9}) {
+		t.Errorf("Expected [1 5 9], got %v", r.ToSlice())
+	}
+	if !reflect.DeepEqual(r.Reversed().ToSlice(), []int64{9, 5, 1}) {
+		t.Errorf("Expected [9 5 1], got %v", r.Reversed().ToSlice())
+	}
+	if r.String() != "1 .. 10 step 4" {
+		t.Errorf("Unexpected string %s", r)
+	}
+	if _, err := r.WithStep(0); err == nil {
+		t.Error("Expected error for zero step")
+	}
+
+	if size := jexl.NewRange(math.MinInt64, math.MaxInt64).Size(); size != math.MaxInt64 {
+		t.Errorf("Expected full range size clamped to MaxInt64, got %d", size)
+	}
+	if size := jexl.NewRange(math.MaxInt64, math.MinInt64).Size(); size != math.MaxInt64 {
+		t.Errorf("Expected full descending range size clamped to MaxInt64, got %d", size)
+	}
+	// Элементы полного диапазона считаются точно, хотя Size ограничен
+	full := jexl.NewRange(math.MinInt64, math.MaxInt64)
+	if reversed := full.Reversed(); reversed.First() != math.MaxInt64 || reversed.Last() != math.MinInt64 {
+		t.Errorf("Expected reversed full range, got %v", reversed)
+	}
+	var first []int64
+	for n := range full.Iterator() {
+		if first = append(first, n); len(first) == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(first, []int64{math.MinInt64, math.MinInt64 + 1}) {
+		t.Errorf("Expected iteration from MinInt64, got %v", first)
+	}
+	var last []int64
+	for n := range jexl.NewRange(math.MaxInt64-1, math.MaxInt64).Iterator() {
+		last = append(last, n)
+	}
+	if !reflect.DeepEqual(last, []int64{math.MaxInt64 - 1, math.MaxInt64}) {
+		t.Errorf("Expected iteration up to MaxInt64, got %v", last)
+	}
+	if !full.Contains(int64(math.MaxInt64)) || !full.Contains(int64(math.MinInt64)) {
+		t.Error("Expected full range to contain its bounds")
+	}
+	if jexl.HashKey(full) == jexl.HashKey(jexl.NewRange(math.MinInt64, math.MaxInt64-1)) {
+		t.Error("Expected ranges of different length to differ")
+	}
+	if _, err := jexl.NewRange(1, 1000000000).MarshalJSON(); err == nil {
+		t.Error("Expected error marshaling a huge range")
+	}
+
+	builder := jexl.NewBuilder().StandardNamespaces(jexl.NamespaceMath, jexl.NamespaceJSON)
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	for _, src := range []string{
+		"1 .. 5 step -1", "(1 .. 2) > (3 .. 4)", "(3 .. 4) > (1 .. 2)", "[1] < (1 .. 2)",
+		"json:stringify(1 .. 1000000000)", "(1 .. 1000000000) + [0]", "[0] + (1 .. 1000000000)",
+		"math:max(1 .. 1000000000)",
+	} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if result, err := script.Execute(nil); err == nil {
+			t.Errorf("%q: expected error, got %v", src, result)
+		}
+	}
+}
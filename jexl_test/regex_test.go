@@ -0,0 +1,202 @@
+package jexl_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestRegexLiteral тестирует литералы ~/pattern/flags
+func TestRegexLiteral(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected bool
+	}{
+		{"'Hello' =~ ~/^h/i", true},
+		{"'Hello' =~ ~/^h/", false},
+		{"'a/b' =~ ~/a\\/b/", true},
+		{"'x1' !~ ~/\\d$/", false},
+		{"~/^\\d+$/ =~ '123'", true},
+		{"'line1\nline2' =~ ~/^line2$/m", true},
+		{"null =~ ~/a/", false},
+		{"var r = 1; ~/a/ =~ 'a'", true},
+		{"if (true) ~/a/ =~ 'b'", false},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+
+	script, err := engine.CreateScript(nil, nil, "~/a+/i")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if re, ok := result.(*regexp.Regexp); !ok || re.String() != "(?i)a+" {
+		t.Errorf("Expected compiled (?i)a+, got %v", result)
+	}
+}
+
+// TestRegexLiteralErrors тестирует ошибки разбора литералов
+func TestRegexLiteralErrors(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	// Литерал сразу после операнда - ни сопоставление, ни отдельная инструкция
+	invalid := []string{
+		"x =~ ~/a(b/", "x =~ ~/a/g", "x =~ ~/abc",
+		"a ~/x/", "a\n~/x/", "f() ~/x/", "{ a ~/x/ }", "g(a ~/x/)", "a ~ 1",
+	}
+	for _, src := range invalid {
+		_, err := engine.CreateScript(nil, nil, src)
+		if err == nil {
+			t.Errorf("Expected parsing error for %q", src)
+			continue
+		}
+		var parsingErr *jexl.ParsingError
+		if !errors.As(err, &parsingErr) {
+			t.Errorf("%q: expected ParsingError, got %T: %v", src, err, err)
+		}
+	}
+}
+
+// TestRegexFromContext тестирует *regexp.Regexp из контекста и строковые шаблоны
+func TestRegexFromContext(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	ctx := jexl.NewMapContext()
+	ctx.Set("re", regexp.MustCompile(`^[a-z]+@[a-z]+\.com$`))
+	ctx.Set("email", "bob@example.com")
+
+	tests := []struct {
+		src      string
+		expected bool
+	}{
+		{"email =~ re", true},
+		{"'not an email' =~ re", false},
+		{"matches(email, re)", true},
+		{"matches(email, '^bob')", true},
+		{"matches('alice', ~/^bob/)", false},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestRegexInvalidStringPattern тестирует строковые шаблоны без поиска подстроки
+func TestRegexInvalidStringPattern(t *testing.T) {
+	for _, strict := range []bool{true, false} {
+		engine, err := jexl.NewBuilder().Arithmetic(jexl.NewBaseArithmetic(strict, nil, 0)).Build()
+		if err != nil {
+			t.Fatalf("Failed to build engine: %v", err)
+		}
+		script, err := engine.CreateScript(nil, nil, "'a(b' =~ '('")
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		if _, err := script.Execute(nil); err == nil {
+			t.Errorf("Expected error for invalid pattern (strict=%v)", strict)
+		}
+
+		script, err = engine.CreateScript(nil, nil, "'a+b' =~ 'a+b'")
+		if err != nil {
+			t.Fatalf("Failed to create script: %v", err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script: %v", err)
+		}
+		if result != false {
+			t.Errorf("Expected regex match only, got %v (strict=%v)", result, strict)
+		}
+	}
+}
+
+// TestRegexGroups тестирует groups() с индексными и именованными группами
+func TestRegexGroups(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil,
+		"var g = groups('2024-05-17', ~/(?P<year>\\d{4})-(\\d{2})-(?P<day>\\d{2})/); g.year + '/' + g['2'] + '/' + g.day + ' ' + g['0']")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(jexl.NewMapContext())
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != "2024/05/17 2024-05-17" {
+		t.Errorf("Expected 2024/05/17 2024-05-17, got %v", result)
+	}
+
+	script, err = engine.CreateScript(nil, nil, "groups('abc', ~/\\d+/)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err = script.Execute(jexl.NewMapContext())
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != nil {
+		t.Errorf("Expected nil for no match, got %v", result)
+	}
+
+	// Локальная функция или функция контекста скрывает встроенную
+	for _, src := range []string{"var matches = (a, b) -> a + b; matches(1, 2)", "groups(1, 2)"} {
+		script, err = engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		ctx := jexl.NewMapContext()
+		ctx.Set("groups", func(args ...any) (any, error) { return int64(3), nil })
+		result, err = script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", src, err)
+		}
+		if asInt64(t, result) != 3 {
+			t.Errorf("%q: expected 3, got %v", src, result)
+		}
+	}
+}
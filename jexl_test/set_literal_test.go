@@ -2,7 +2,6 @@ package jexl_test
 
 import (
 	"math/big"
-	"reflect"
 	"testing"
 
 	"github.com/mentatxx/jexl-golang/jexl"
@@ -32,15 +31,14 @@ func TestSetLiteralWithOneEntry(t *testing.T) {
 		}
 
 		// Проверяем, что это множество
-		resultType := reflect.TypeOf(result)
-		if resultType.Kind() != reflect.Map {
-			t.Errorf("Expected map (set), got %v for %s", resultType, src)
+		set, ok := result.(*jexl.Set)
+		if !ok {
+			t.Fatalf("Expected *jexl.Set, got %T for %s", result, src)
 		}
 
 		// Проверяем размер
-		resultValue := reflect.ValueOf(result)
-		if resultValue.Len() != 1 {
-			t.Errorf("Expected set size 1, got %d for %s", resultValue.Len(), src)
+		if set.Size() != 1 {
+			t.Errorf("Expected set size 1, got %d for %s", set.Size(), src)
 		}
 	}
 }
@@ -100,21 +98,17 @@ func TestSetLiteralWithNulls(t *testing.T) {
 			t.Fatalf("Failed to execute script for %s: %v", exprStr, err)
 		}
 
-		// Проверяем, что это множество (map в Go)
-		if result == nil {
-			// Пустое множество может быть nil
-			if exprStr != "{  }" {
-				t.Errorf("Unexpected nil result for %s", exprStr)
+		// Пустые фигурные скобки - литерал пустой мапы
+		if exprStr == "{  }" {
+			if m, ok := result.(*jexl.Map); !ok || m.Size() != 0 {
+				t.Errorf("Expected empty *jexl.Map, got %v for %s", result, exprStr)
 			}
 			continue
 		}
-		resultType := reflect.TypeOf(result)
-		if resultType == nil {
-			t.Errorf("Result type is nil for %s", exprStr)
-			continue
-		}
-		if resultType.Kind() != reflect.Map {
-			t.Errorf("Expected map (set), got %v for %s", resultType, exprStr)
+
+		// Проверяем, что это множество
+		if _, ok := result.(*jexl.Set); !ok {
+			t.Errorf("Expected *jexl.Set, got %T for %s", result, exprStr)
 		}
 	}
 }
@@ -140,15 +134,14 @@ func TestSetLiteralWithNumbers(t *testing.T) {
 	}
 
 	// Проверяем, что это множество
-	resultType := reflect.TypeOf(result)
-	if resultType.Kind() != reflect.Map {
-		t.Errorf("Expected map (set), got %v", resultType)
+	set, ok := result.(*jexl.Set)
+	if !ok {
+		t.Fatalf("Expected *jexl.Set, got %T", result)
 	}
 
 	// Проверяем размер
-	resultValue := reflect.ValueOf(result)
-	if resultValue.Len() != 2 {
-		t.Errorf("Expected set size 2, got %d", resultValue.Len())
+	if set.Size() != 2 {
+		t.Errorf("Expected set size 2, got %d", set.Size())
 	}
 }
 
@@ -173,15 +166,14 @@ func TestSetLiteralWithOneEntryScript(t *testing.T) {
 	}
 
 	// Проверяем, что это множество
-	resultType := reflect.TypeOf(result)
-	if resultType.Kind() != reflect.Map {
-		t.Errorf("Expected map (set), got %v", resultType)
+	set, ok := result.(*jexl.Set)
+	if !ok {
+		t.Fatalf("Expected *jexl.Set, got %T", result)
 	}
 
 	// Проверяем размер
-	resultValue := reflect.ValueOf(result)
-	if resultValue.Len() != 1 {
-		t.Errorf("Expected set size 1, got %d", resultValue.Len())
+	if set.Size() != 1 {
+		t.Errorf("Expected set size 1, got %d", set.Size())
 	}
 }
 
@@ -208,15 +200,14 @@ func TestSetLiteralWithStrings(t *testing.T) {
 		}
 
 		// Проверяем, что это множество
-		resultType := reflect.TypeOf(result)
-		if resultType.Kind() != reflect.Map {
-			t.Errorf("Expected map (set), got %v for %s", resultType, src)
+		set, ok := result.(*jexl.Set)
+		if !ok {
+			t.Fatalf("Expected *jexl.Set, got %T for %s", result, src)
 		}
 
 		// Проверяем размер
-		resultValue := reflect.ValueOf(result)
-		if resultValue.Len() != 2 {
-			t.Errorf("Expected set size 2, got %d for %s", resultValue.Len(), src)
+		if set.Size() != 2 {
+			t.Errorf("Expected set size 2, got %d for %s", set.Size(), src)
 		}
 	}
 
@@ -248,15 +239,14 @@ func TestSetLiteralWithStringsScript(t *testing.T) {
 	}
 
 	// Проверяем, что это множество
-	resultType := reflect.TypeOf(result)
-	if resultType.Kind() != reflect.Map {
-		t.Errorf("Expected map (set), got %v", resultType)
+	set, ok := result.(*jexl.Set)
+	if !ok {
+		t.Fatalf("Expected *jexl.Set, got %T", result)
 	}
 
 	// Проверяем размер
-	resultValue := reflect.ValueOf(result)
-	if resultValue.Len() != 2 {
-		t.Errorf("Expected set size 2, got %d", resultValue.Len())
+	if set.Size() != 2 {
+		t.Errorf("Expected set size 2, got %d", set.Size())
 	}
 }
 
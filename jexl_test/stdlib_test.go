@@ -0,0 +1,225 @@
+package jexl_test
+
+import (
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestStandardNamespaces тестирует функции стандартных пространств имён
+func TestStandardNamespaces(t *testing.T) {
+	builder := jexl.NewBuilder().StandardNamespaces(jexl.StandardNamespaceNames()...)
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"math:abs(-5)", int64(5)},
+		{"math:round(2.345, 2)", 2.35},
+		{"math:round(-2.5)", int64(-3)},
+		{"math:round(1234, -2)", int64(1200)},
+		{"math:pow(2, 10)", int64(1024)},
+		{"math:pow(2, -1)", 0.5},
+		{"math:pow(10, 400)", math.Inf(1)},
+		{"math:sqrt(16)", int64(4)},
+		{"math:min(3, 1, 2)", int64(1)},
+		{"math:max([3, 7, 2])", int64(7)},
+		{"math:floor(-1.5)", int64(-2)},
+		{"math:floor(7 / 2)", int64(3)},
+		{"math:ceil(1.2)", int64(2)},
+		{"math:abs(-1) + math:abs(-2)", int64(3)},
+		{"str:upper('привет')", "ПРИВЕТ"},
+		{"str:capitalize('мир')", "Мир"},
+		{"str:reverse('абв')", "вба"},
+		{"str:join([1, 2, 3], '-')", "1-2-3"},
+		{"str:padStart('7', 3, '0')", "007"},
+		{"str:length('ёж')", int64(2)},
+		{"json:stringify({'b': 1, 'a': [true, null, 'x']})", `{"b":1,"a":[true,null,"x"]}`},
+		{"json:stringify(1 / 4)", "0.25"},
+		{"json:parse('{\"z\": 1, \"a\": {\"n\": 2.5}}').a.n", 2.5},
+		{"json:parse('[1, 2]')", []any{int64(1), int64(2)}},
+		{"regex:matches('abc123', '\\\\d+')", true},
+		{"regex:find('abc123def45', ~/\\d+/)", "123"},
+		{"regex:findAll('a1b22c333', '\\\\d+')", []any{"1", "22", "333"}},
+		{"regex:replace('2024-01-31', '(\\\\d+)-(\\\\d+)-(\\\\d+)', '$3.$2.$1')", "31.01.2024"},
+		{"regex:groups('k=v', '(?P<key>\\\\w)=(\\\\w)').key", "k"},
+		{"base64:encode('привет')", "0L/RgNC40LLQtdGC"},
+		{"base64:decode('0L/RgNC40LLQtdGC')", "привет"},
+		{"hash:md5('abc')", "900150983cd24fb0d6963f7d28e17f72"},
+		{"hash:sha256('abc')", "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"hash:crc32('abc')", int64(891568578)},
+		{"time:format(time:parse('2024-01-31 10:20:30'), 'dd.MM.yyyy HH:mm')", "31.01.2024 10:20"},
+		{"time:format(time:add(time:parse('2024-01-31'), 1, 'months'), 'yyyy-MM-dd')", "2024-03-02"},
+		{"time:diff(time:parse('2024-01-01'), time:parse('2024-03-01'), 'days')", int64(60)},
+		{"time:diff(time:parse('2024-01-31'), time:parse('2024-03-30'), 'months')", int64(1)},
+		{"time:diff('2024-01-01T00:00:00Z', '2024-01-01T00:00:01Z')", int64(1000)},
+		{"time:format(time:truncate(time:parse('2024-05-15T13:45:10'), 'hours'))", "2024-05-15T13:00:00Z"},
+		{"time:format(time:truncate(time:parse('2024-05-15'), 'weeks'), 'EEE yyyy-MM-dd')", "Mon 2024-05-13"},
+		{"time:format(time:parse('31/01/2024', 'dd/MM/yyyy'), 'yyyy-MM-dd')", "2024-01-31"},
+		{"var x = 1; x ? math:abs(-2) : 3", int64(2)},
+		{"var x = 0; var y = 4; x ? y : math:abs(-3)", int64(3)},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tt.src, tt.expected, result)
+		}
+	}
+
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([47])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	for _, src := range []string{"uuid:v4()", "uuid:v7()"} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", src, err)
+		}
+		match := uuidPattern.FindStringSubmatch(result.(string))
+		if match == nil || match[1] != src[6:7] {
+			t.Errorf("%q: unexpected uuid %v", src, result)
+		}
+	}
+
+	script, err := engine.CreateScript(nil, nil, "time:now()")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	now, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if ts, ok := now.(time.Time); !ok || time.Since(ts) > time.Minute {
+		t.Errorf("Unexpected time:now() result %v", now)
+	}
+}
+
+// TestStandardNamespacesOptIn тестирует выборочное подключение и разрешения
+func TestStandardNamespacesOptIn(t *testing.T) {
+	permissions := jexl.NewPermissions(nil, []string{"jexl.time.now", "jexl.hash"})
+	engine, err := jexl.NewBuilder().
+		StandardNamespaces(jexl.NamespaceMath, jexl.NamespaceTime, jexl.NamespaceHash).
+		Permissions(permissions).
+		Strict(true).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	run := func(src string) (any, error) {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		return script.Execute(nil)
+	}
+
+	if result, err := run("math:max(1, 2)"); err != nil || asInt64(t, result) != 2 {
+		t.Errorf("Expected math:max to work, got %v, %v", result, err)
+	}
+	for _, src := range []string{"str:upper('a')", "time:now()", "hash:md5('a')"} {
+		if _, err := run(src); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+	if _, err := run("time:parse('2024-01-01')"); err != nil {
+		t.Errorf("Expected time:parse to be allowed: %v", err)
+	}
+	if _, err := run("math:abs()"); err == nil || !strings.Contains(err.Error(), "math:abs") {
+		t.Errorf("Expected arity error, got %v", err)
+	}
+
+	if _, err := jexl.NewBuilder().StandardNamespaces("nope").Build(); err == nil {
+		t.Error("Expected error for unknown standard namespace")
+	}
+
+	// Пространство имён, заданное явно, имеет приоритет
+	custom, err := jexl.NewBuilder().
+		Namespaces(map[string]any{"math": jexl.NamespaceFuncs{
+			"abs": func(args ...any) (any, error) { return "custom", nil },
+		}}).
+		StandardNamespaces(jexl.NamespaceMath).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := custom.CreateScript(nil, nil, "math:abs(-1)")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if result, err := script.Execute(nil); err != nil || result != "custom" {
+		t.Errorf("Expected custom namespace, got %v, %v", result, err)
+	}
+}
+
+// TestNamespaceCallSyntax тестирует разбор ns:func рядом с тернарным оператором и мапами
+func TestNamespaceCallSyntax(t *testing.T) {
+	engine, err := jexl.NewBuilder().
+		Namespaces(map[string]any{"util": map[string]any{
+			"twice": func(args ...any) (any, error) { return []any{args[0], args[0]}, nil },
+		}}).
+		Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	ctx := jexl.NewMapContext()
+	ctx.Set("f", func(args ...any) (any, error) { return "f", nil })
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"size(util:twice(1))", int64(2)},
+		{"util:twice(1)[0]", int64(1)},
+		{"var a = 1; true ? a : f(2)", int64(1)},
+		{"var a = 1; var m = {a : f(2)}; m[1]", "f"},
+		{"var a = 1; var b = 2; var m = {a:f(1), b:f(2)}; m[1] + m[2]", "ff"},
+		{"var m = {util:twice(1): 'x'}; size(m)", int64(1)},
+		{"size({util:twice(1), 2})", int64(2)},
+		{"size({util:twice(1)[0], 3})", int64(2)},
+		{"var s = {(util:twice(1))}; size(s)", int64(1)},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+}
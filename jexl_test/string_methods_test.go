@@ -0,0 +1,108 @@
+package jexl_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// TestJavaStringMethods тестирует методы строк с семантикой java.lang.String
+func TestJavaStringMethods(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"'привет'.length()", int64(6)},
+		{"size('привет')", int64(6)},
+		{"size 'héllo' == 'héllo'.length()", true},
+		{"'привет'.charAt(1)", "р"},
+		{"'привет'.substring(2, 4)", "ив"},
+		{"'привет мир'.indexOf('мир')", int64(7)},
+		{"'абаб'.indexOf('б', 2)", int64(3)},
+		{"'абаб'.lastIndexOf('б')", int64(3)},
+		{"'абаб'.lastIndexOf('б', 2)", int64(1)},
+		{"'abc'.indexOf('z')", int64(-1)},
+		{"'  a b  '.trim()", "a b"},
+		{"'　a　'.strip()", "a"},
+		{"'  a  '.stripLeading()", "a  "},
+		{"'a,b;;c,,'.split('[,;]')", []any{"a", "b", "", "c"}},
+		{"'a,b,c'.split(',', 2)", []any{"a", "b,c"}},
+		{"'a1b22c'.split(~/\\d+/)", []any{"a", "b", "c"}},
+		{"'a.b.c'.replace('.', '-')", "a-b-c"},
+		{"'2024-01-31'.replaceAll('(\\\\d+)-(\\\\d+)-(\\\\d+)', '$3.$2.$1')", "31.01.2024"},
+		{"'aaa'.replaceFirst('a', 'b')", "baa"},
+		{"'cost: 5'.replaceAll('\\\\d', '\\\\$')", "cost: $"},
+		{"'привет'.startsWith('при')", true},
+		{"'привет'.startsWith('ив', 2)", true},
+		{"'привет'.endsWith('вет')", true},
+		{"'привет'.contains('иве')", true},
+		{"'abc123'.matches('[a-z]+\\\\d+')", true},
+		{"'abc123x'.matches('[a-z]+\\\\d+')", false},
+		{"''.isEmpty()", true},
+		{"' \t'.isBlank()", true},
+		{"'ab'.repeat(3)", "ababab"},
+		{"'%s = %d'.format('x', 42)", "x = 42"},
+		{"'%.2f'.format(1.5)", "1.50"},
+		{"'ая'.chars()", []any{int64('а'), int64('я')}},
+		{"'Привет'.equalsIgnoreCase('пРИВЕТ')", true},
+		{"'b'.compareTo('a')", int64(1)},
+		{"'ab'.compareTo('abcd')", int64(-2)},
+		{"'Б'.compareToIgnoreCase('б')", int64(0)},
+		{"'5'.padStart(3, '0')", "005"},
+		{"'я'.padEnd(4, 'аб')", "яаба"},
+		{"'abc'.padStart(2)", "abc"},
+		{"'Ab'.toUpperCase() + 'Ab'.toLowerCase()", "ABab"},
+	}
+	for _, tt := range tests {
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(nil)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if n, ok := tt.expected.(int64); ok {
+			if actual := asInt64(t, result); actual != n {
+				t.Errorf("%q: expected %d, got %d", tt.src, n, actual)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tt.src, tt.expected, result)
+		}
+	}
+}
+
+// TestStringMethodErrors тестирует ошибки методов строк
+func TestStringMethodErrors(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	for _, src := range []string{
+		"'abc'.charAt(3)",
+		"'abc'.substring(2, 1)",
+		"'abc'.repeat(-1)",
+		"'abc'.length(1)",
+		"'abc'.noSuchMethod()",
+	} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if _, err := script.Execute(nil); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package jexl_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// temporalOrder - объект с датой для проверки арифметики времени
+type temporalOrder struct {
+	Created time.Time
+}
+
+// TestTemporalArithmetic тестирует арифметику времени и длительностей
+func TestTemporalArithmetic(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	created := time.Date(2024, 1, 31, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		src      string
+		expected any
+	}{
+		{"order.created + duration('P3D')", created.Add(72 * time.Hour)},
+		{"duration('PT1H') + order.created", created.Add(time.Hour)},
+		{"order.created - duration('PT30M')", created.Add(-30 * time.Minute)},
+		{"order.created + duration('P3D') < now()", true},
+		{"(order.created + duration('P1D')) - order.created", 24 * time.Hour},
+		{"duration('PT1H') + duration('PT30M')", 90 * time.Minute},
+		{"duration('PT1H') * 2.5", 150 * time.Minute},
+		{"3 * duration('PT10S')", 30 * time.Second},
+		{"duration('PT1H') / 4", 15 * time.Minute},
+		{"-duration('PT1S')", -time.Second},
+		{"duration('PT1M') > duration('PT59S')", true},
+		{"duration('PT60S') == duration('PT1M')", true},
+		{"order.created == later - duration('P1W')", true},
+		{"order.created < later", true},
+		{"duration('P1W2DT3H4M5.5S')", 9*24*time.Hour + 3*time.Hour + 4*time.Minute + 5500*time.Millisecond},
+		{"duration('-PT1.5S')", -1500 * time.Millisecond},
+		{"duration('1h30m')", 90 * time.Minute},
+		{"duration(250)", 250 * time.Millisecond},
+		{"empty(zero)", true},
+		{"zero ? 'set' : 'unset'", "unset"},
+		{"!duration('PT0S')", true},
+		{"empty(order.created)", false},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("order", temporalOrder{Created: created})
+		ctx.Set("later", created.Add(7*24*time.Hour).In(time.FixedZone("MSK", 3*3600)))
+		ctx.Set("zero", time.Time{})
+		script, err := engine.CreateScript(nil, nil, tt.src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", tt.src, err)
+		}
+		result, err := script.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script %q: %v", tt.src, err)
+		}
+		if expected, ok := tt.expected.(time.Time); ok {
+			if actual, ok := result.(time.Time); !ok || !actual.Equal(expected) {
+				t.Errorf("%q: expected %v, got %v", tt.src, expected, result)
+			}
+			continue
+		}
+		if result != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.src, tt.expected, result)
+		}
+	}
+
+	script, err := engine.CreateScript(nil, nil, "duration('PT1H') / duration('PT15M')")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if asInt64(t, result) != 4 {
+		t.Errorf("Expected 4, got %v", result)
+	}
+
+	for _, src := range []string{
+		"duration('P1M')", "duration('P1X')", "duration('PT')", "now() + 1",
+		"duration('P200000D')", "duration('P3D') * 100000", "duration('P100000D') + duration('P100000D')",
+		"duration('-P100000D') - duration('P100000D')", "duration('PT1S') / 0.000000000000001",
+	} {
+		script, err := engine.CreateScript(nil, nil, src)
+		if err != nil {
+			t.Fatalf("Failed to create script %q: %v", src, err)
+		}
+		if _, err := script.Execute(nil); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+}
+
+// TestFormatDuration тестирует разбор и форматирование ISO-8601
+func TestFormatDuration(t *testing.T) {
+	for _, src := range []string{"PT0S", "PT26H3M4.5S", "-PT1M", "PT0.001S"} {
+		d, err := jexl.ParseDuration(src)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", src, err)
+		}
+		if formatted := jexl.FormatDuration(d); formatted != src {
+			t.Errorf("Expected %s, got %s", src, formatted)
+		}
+	}
+}
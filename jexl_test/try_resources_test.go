@@ -0,0 +1,257 @@
+package jexl_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// trackedResource - ресурс, реализующий io.Closer и записывающий порядок закрытия
+type trackedResource struct {
+	name     string
+	log      *[]string
+	closeErr error
+}
+
+func (r *trackedResource) Close() error {
+	*r.log = append(*r.log, r.name)
+	return r.closeErr
+}
+
+// Name возвращает имя ресурса (для доступа из скрипта)
+func (r *trackedResource) Name() string {
+	return r.name
+}
+
+// plainResource - ресурс без io.Closer, закрываемый через метод Close() без результата
+type plainResource struct {
+	closed bool
+}
+
+func (r *plainResource) Close() {
+	r.closed = true
+}
+
+// TestTryResourcesCloseOrder тестирует закрытие ресурсов в обратном порядке
+func TestTryResourcesCloseOrder(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	var log []string
+	ctx := jexl.NewMapContext()
+	ctx.Set("open", func(args ...any) (any, error) {
+		return &trackedResource{name: args[0].(string), log: &log}, nil
+	})
+
+	script, err := engine.CreateScript(nil, nil,
+		"try (var a = open('a'); var b = open('b')) { a.name() + b.name() }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	result, err := script.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != "ab" {
+		t.Errorf("Expected 'ab', got %v", result)
+	}
+	if strings.Join(log, ",") != "b,a" {
+		t.Errorf("Expected close order b,a, got %v", log)
+	}
+}
+
+// TestTryResourcesClosedBeforeCatch тестирует, что ресурсы закрываются до catch и finally
+func TestTryResourcesClosedBeforeCatch(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	var log []string
+	ctx := jexl.NewMapContext()
+	ctx.Set("res", &trackedResource{name: "res", log: &log})
+	ctx.Set("mark", func(args ...any) (any, error) {
+		log = append(log, args[0].(string))
+		return nil, nil
+	})
+
+	script, err := engine.CreateScript(nil, nil,
+		"try (var r = res) { r.missing(); } catch (e) { mark('catch'); 1 } finally { mark('finally') }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	if _, err := script.Execute(ctx); err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if strings.Join(log, ",") != "res,catch,finally" {
+		t.Errorf("Expected res,catch,finally, got %v", log)
+	}
+}
+
+// TestTryResourcesSuppressedError тестирует присоединение ошибки закрытия к основной ошибке
+func TestTryResourcesSuppressedError(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	var log []string
+	closeErr := errors.New("close failed")
+	ctx := jexl.NewMapContext()
+	ctx.Set("res", &trackedResource{name: "res", log: &log, closeErr: closeErr})
+
+	script, err := engine.CreateScript(nil, nil, "try (var r = res) { r.missing() }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	_, err = script.Execute(ctx)
+	if err == nil {
+		t.Fatal("Expected error from try block")
+	}
+	var suppressed *jexl.SuppressedError
+	if !errors.As(err, &suppressed) {
+		t.Fatalf("Expected SuppressedError, got %T: %v", err, err)
+	}
+	if len(suppressed.Suppressed()) != 1 || suppressed.Suppressed()[0] != closeErr {
+		t.Errorf("Expected close error to be suppressed, got %v", suppressed.Suppressed())
+	}
+	if !strings.Contains(suppressed.Unwrap().Error(), "missing") {
+		t.Errorf("Expected primary error about missing method, got %v", suppressed.Unwrap())
+	}
+}
+
+// TestTryResourcesCloseErrorIsPrimary тестирует, что ошибка закрытия становится основной при успешном try
+func TestTryResourcesCloseErrorIsPrimary(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	var log []string
+	closeErr := errors.New("close failed")
+	ctx := jexl.NewMapContext()
+	ctx.Set("res", &trackedResource{name: "res", log: &log, closeErr: closeErr})
+
+	script, err := engine.CreateScript(nil, nil, "try (res) { return 1 }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	_, err = script.Execute(ctx)
+	if !errors.Is(err, closeErr) {
+		t.Errorf("Expected close error, got %v", err)
+	}
+
+	caught, err := engine.CreateScript(nil, nil, "try (res) { 1 } catch (e) { 'caught: ' + e }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := caught.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != "caught: close failed" {
+		t.Errorf("Expected close error to be caught, got %v", result)
+	}
+}
+
+// TestTryResourcesReturn тестирует return внутри try с ресурсами
+func TestTryResourcesReturn(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	res := &plainResource{}
+	ctx := jexl.NewMapContext()
+	ctx.Set("res", res)
+
+	script, err := engine.CreateScript(nil, nil,
+		"try (var r = res; var n = null) { return 42 } catch (e) { return -1 }; 0")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	result, err := script.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != int64(42) {
+		t.Errorf("Expected 42, got %v (%T)", result, result)
+	}
+	if !res.closed {
+		t.Error("Expected resource to be closed through Close() method")
+	}
+}
+
+// TestTryResourcesParsing тестирует разбор и восстановление текста try с ресурсами
+func TestTryResourcesParsing(t *testing.T) {
+	builder := jexl.NewBuilder()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	script, err := engine.CreateScript(nil, nil, "try (var a = x; b;) { a } finally { 0 }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	if text := script.ParsedText(); !strings.HasPrefix(text, "try (var a = x; b") {
+		t.Errorf("Expected resources in parsed text, got %q", text)
+	}
+
+	invalid := []string{
+		"try () { 1 }",
+		"try (var a) { 1 }",
+		"try (1 + 2) { 1 }",
+	}
+	for _, src := range invalid {
+		if _, err := engine.CreateScript(nil, nil, src); err == nil {
+			t.Errorf("Expected parsing error for %q", src)
+		}
+	}
+}
+
+// TestTryResourcesScope тестирует пропуск незакрываемых значений и область видимости ресурсов
+func TestTryResourcesScope(t *testing.T) {
+	engine, err := jexl.NewBuilder().Strict(true).Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+
+	res := &plainResource{}
+	ctx := jexl.NewMapContext()
+	ctx.Set("res", res)
+	ctx.Set("n", int64(1))
+
+	script, err := engine.CreateScript(nil, nil, "try (var r = res; var v = 'text'; n) { v + n }")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	result, err := script.Execute(ctx)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if result != "text1" {
+		t.Errorf("Expected text1, got %v (%T)", result, result)
+	}
+	if !res.closed {
+		t.Error("Expected resource to be closed")
+	}
+	if ctx.Has("r") || ctx.Has("v") {
+		t.Error("Expected resource variables to be removed after try")
+	}
+}
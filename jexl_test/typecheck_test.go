@@ -0,0 +1,222 @@
+package jexl_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+type checkCustomer struct {
+	Name string
+	Tier string
+}
+
+func (c *checkCustomer) Discount(rate float64) float64 { return rate }
+
+type checkItem struct {
+	Price float64
+	Qty   int
+}
+
+type checkOrder struct {
+	Total    float64
+	Customer *checkCustomer `jexl:"nullable"`
+	Billing  *checkCustomer
+	Items    []checkItem
+	Tags     map[string]string
+}
+
+func (o checkOrder) GetCount() int { return len(o.Items) }
+
+type checkContext struct {
+	Order checkOrder
+	Limit int
+	Log   func(msg string)
+}
+
+// checkTypes разбирает скрипт и возвращает сообщения проверки типов в виде "line:column: message".
+func checkTypes(t *testing.T, builder *jexl.Builder, schema *jexl.Schema, src string) []string {
+	t.Helper()
+	engine, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, src)
+	if err != nil {
+		t.Fatalf("Failed to create script %q: %v", src, err)
+	}
+	var result []string
+	for _, d := range engine.CheckTypes(script.AST(), schema) {
+		result = append(result, d.String())
+	}
+	return result
+}
+
+// TestCheckTypesStruct тестирует проверку по схеме из типов Go
+func TestCheckTypesStruct(t *testing.T) {
+	schema := jexl.SchemaOf(checkContext{})
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"order.total > limit && order.count < 10 && order.items[0].qty == 1", nil},
+		{"order.total =~ 'abc'", []string{"1:1: error: operator =~ expects a string operand, got number"}},
+		{"order.totl + order.items[0].cost", []string{
+			"1:7: error: unknown property totl of main.checkOrder",
+			"1:29: error: unknown property cost of main.checkItem",
+		}},
+		{"order.customer.name", []string{"1:1: warning: possibly null dereference: order.customer may be null"}},
+		{"order.customer != null ? order.customer.name : ''", nil},
+		{"order.billing.name + order.tags.vip; order.billing = null", nil},
+		{"if (order.customer) { order.customer.discount(0.1, 2) }", []string{
+			"1:38: error: discount() takes 1 argument(s), got 2",
+		}},
+		{"log('a'); log(); order.tags.vip.length() + limit.foo()", []string{
+			"1:11: error: log() takes 1 argument(s), got 0",
+			"1:50: error: unknown method foo of integer",
+		}},
+		{"unknown + 1; unknown", []string{"1:1: error: unknown variable unknown"}},
+		{"order.items.filter(i -> i.qty > 0).sum('price') - order.items.take().sum('qty')", []string{
+			"1:63: error: take() takes 1 argument(s), got 0",
+		}},
+		{"limit = 'x'; order.total - 'a'", []string{
+			"1:1: error: cannot assign string to limit of type integer",
+			"1:14: error: invalid operands for -: number and string",
+		}},
+		{"limit = order.total div 2; order.total div 'a'", []string{
+			"1:28: error: invalid operands for div: number and string",
+		}},
+		{"var c = order.customer; c.tier", []string{"1:25: warning: possibly null dereference: c may be null"}},
+		{"for (item : order.items) { total = item.price * item.qty } total", nil},
+	}
+	for _, tt := range tests {
+		got := checkTypes(t, jexl.NewBuilder(), schema, tt.src)
+		for i := range got {
+			got[i] = strings.ReplaceAll(got[i], "jexl_test.", "main.")
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CheckTypes(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+		}
+	}
+}
+
+type checkTree map[string]checkTree
+
+type checkList []checkList
+
+type checkNode struct {
+	Name     string
+	Children []*checkNode
+}
+
+// TestCheckTypesRecursive тестирует схемы из рекурсивных типов Go
+func TestCheckTypesRecursive(t *testing.T) {
+	tree := jexl.TypeOf(reflect.TypeOf(checkTree{}))
+	if tree.Elem != tree {
+		t.Errorf("TypeOf(checkTree).Elem = %v, want the type itself", tree.Elem)
+	}
+	list := jexl.TypeOf(reflect.TypeOf(checkList{}))
+	if list.Elem != list || !strings.HasSuffix(list.String(), "checkList") {
+		t.Errorf("TypeOf(checkList) = %v", list)
+	}
+	schema := jexl.NewSchema(map[string]*jexl.Type{
+		"tree": tree,
+		"list": list,
+		"node": jexl.TypeOf(reflect.TypeOf(&checkNode{})),
+	})
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"tree.a.b.c; list[0][1][2]", nil},
+		// Указатели без явной пометки не дают предупреждений о null
+		{"node.children[0].children[1].name", nil},
+		{"node != null ? node.nam : ''", []string{"1:21: error: unknown property nam of *main.checkNode"}},
+	}
+	for _, tt := range tests {
+		got := checkTypes(t, jexl.NewBuilder(), schema, tt.src)
+		for i := range got {
+			got[i] = strings.ReplaceAll(got[i], "jexl_test.", "main.")
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CheckTypes(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestCheckTypesJSONSchema тестирует проверку по схеме в формате JSON
+func TestCheckTypesJSONSchema(t *testing.T) {
+	schema, err := jexl.ParseSchema([]byte(`{
+		"properties": {
+			"user": {"type": "object", "properties": {
+				"name": {"type": "string"},
+				"age": {"type": ["integer", "null"]},
+				"roles": {"type": "array", "items": {"type": "string"}}
+			}},
+			"attrs": {"type": "object", "additionalProperties": {"type": "number"}},
+			"notify": {"type": "function", "parameters": [{"type": "string"}], "returns": {"type": "boolean"}}
+		},
+		"namespaces": {
+			"geo": {"properties": {"distance": {"type": "function", "parameters": [{}, {}]}}}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	tests := []struct {
+		src  string
+		want []string
+	}{
+		{"user.name.toUpperCase() + attrs.score * 2", nil},
+		{"user.age + 1 > 18 and 'admin' in user.roles", nil},
+		{"user.email", []string{"1:6: error: unknown property email of object"}},
+		{"user.name.trim(1); user.roles.join(',', 2)", []string{
+			"1:11: error: trim() takes 0 argument(s), got 1",
+			"1:31: error: join() takes 0 to 1 argument(s), got 2",
+		}},
+		{"notify('x') && notify('x', 'y')", []string{"1:16: error: notify() takes 1 argument(s), got 2"}},
+		{"geo:distance(1, 2) + geo:area(1)", []string{"1:26: error: unknown function geo:area"}},
+		{"user.age.hashCode(); user.age.toString()", []string{
+			"1:1: warning: possibly null dereference: user.age may be null",
+			"1:22: warning: possibly null dereference: user.age may be null",
+			"1:31: error: unknown method toString of integer",
+		}},
+		{"user.name < 3 || -user.name || user.roles[user.name]", []string{
+			"1:1: error: cannot compare string and integer",
+			"1:18: error: invalid operand for unary -: string",
+			"1:43: error: list index must be integer, got string",
+		}},
+	}
+	for _, tt := range tests {
+		if got := checkTypes(t, jexl.NewBuilder(), schema, tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("CheckTypes(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// TestCheckTypesNamespaces тестирует пространства имён движка и локальные имена
+func TestCheckTypesNamespaces(t *testing.T) {
+	builder := jexl.NewBuilder().Namespaces(map[string]any{
+		"util": map[string]any{"twice": func(x int) int { return x * 2 }},
+	})
+	schema := jexl.NewSchema(map[string]*jexl.Type{"n": {Kind: jexl.TypeInteger}})
+	src := "function sq(x) { x * x } var f = y -> y; util:twice(n) + util:twice() + sq(n) + f(1, 2) + nope:f()"
+	want := []string{
+		"1:58: error: util:twice() takes 1 argument(s), got 0",
+		"1:91: error: unknown namespace nope",
+	}
+	if got := checkTypes(t, builder, schema, src); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckTypes =\n%q\nwant\n%q", got, want)
+	}
+	std := jexl.NewBuilder().StandardNamespaces(jexl.NamespaceMath)
+	want = []string{"1:17: error: math:round() takes 1 to 2 argument(s), got 4", "1:47: error: unknown function math:nope"}
+	if got := checkTypes(t, std, nil, "math:round(1) + math:round(1, 2, 3, 4) + math:nope()"); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckTypes =\n%q\nwant\n%q", got, want)
+	}
+	if got := checkTypes(t, builder, nil, "a.b.c(1) + x[0]"); got != nil {
+		t.Errorf("CheckTypes without schema = %q", got)
+	}
+}
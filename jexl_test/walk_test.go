@@ -0,0 +1,224 @@
+package jexl_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mentatxx/jexl-golang/jexl"
+	_ "github.com/mentatxx/jexl-golang/jexl/impl"
+)
+
+// identifierCollector собирает идентификаторы, не заходя в тела lambda
+type identifierCollector struct {
+	jexl.BaseVisitor
+	names   []string
+	lambdas int
+}
+
+func (c *identifierCollector) VisitIdentifier(node *jexl.IdentifierNode) bool {
+	c.names = append(c.names, node.Name())
+	return true
+}
+
+func (c *identifierCollector) VisitLambda(*jexl.LambdaNode) bool {
+	c.lambdas++
+	return false
+}
+
+// TestWalk тестирует обход AST с типизированным Visitor
+func TestWalk(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "var f = (v) -> v + hidden; a.b + f(c) + [d, 1]")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	collector := &identifierCollector{}
+	jexl.Walk(script.AST(), collector)
+	if collector.lambdas != 1 {
+		t.Errorf("Expected 1 lambda, got %d", collector.lambdas)
+	}
+	if slices.Contains(collector.names, "hidden") {
+		t.Errorf("Lambda body should be skipped, got %v", collector.names)
+	}
+	for _, name := range []string{"f", "a", "b", "c", "d"} {
+		if !slices.Contains(collector.names, name) {
+			t.Errorf("Expected identifier %q in %v", name, collector.names)
+		}
+	}
+
+	literals := 0
+	jexl.Inspect(script.AST(), func(node jexl.Node) bool {
+		if _, ok := node.(*jexl.LiteralNode); ok {
+			literals++
+		}
+		return true
+	})
+	if literals != 1 {
+		t.Errorf("Expected 1 literal, got %d", literals)
+	}
+}
+
+// TestRewrite тестирует переименование переменных и внедрение выражений через Rewrite
+func TestRewrite(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "order.total > 100")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	original := script.AST()
+	condition := original.Children()[0]
+
+	// Переименование order -> o и внедрение фильтра по tenant
+	rewrittenNode, err := jexl.Rewrite(original, func(node jexl.Node) jexl.Node {
+		if ident, ok := node.(*jexl.IdentifierNode); ok && ident.Name() == "order" {
+			return jexl.NewIdentifierNode("o", "o")
+		}
+		if binary, ok := node.(*jexl.BinaryOpNode); ok && binary.Op() == ">" {
+			tenant := jexl.NewBinaryOpNode("==",
+				jexl.NewPropertyAccessNode(jexl.NewIdentifierNode("o", "o"), jexl.NewIdentifierNode("tenant", "tenant"), "o.tenant"),
+				jexl.NewLiteralNode("acme", "'acme'"), "o.tenant == 'acme'")
+			return jexl.NewBinaryOpNode("&&", binary, tenant, binary.SourceText()+" && o.tenant == 'acme'")
+		}
+		return node
+	})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	rewritten := rewrittenNode.(*jexl.ScriptNode)
+
+	if original.Children()[0] != condition {
+		t.Errorf("Rewrite must not modify the original AST")
+	}
+	filtered, err := engine.CreateScriptFromAST(rewritten)
+	if err != nil {
+		t.Fatalf("Failed to create script from AST: %v", err)
+	}
+	const want = "o.total > 100 && o.tenant == 'acme'"
+	if filtered.ParsedText() != want || filtered.SourceText() != want {
+		t.Errorf("Rewritten text = %q, %q, want %q", filtered.ParsedText(), filtered.SourceText(), want)
+	}
+	if original.SourceText() != "order.total > 100" {
+		t.Errorf("Original text changed to %q", original.SourceText())
+	}
+	tests := []struct {
+		order    map[string]any
+		expected bool
+	}{
+		{map[string]any{"total": int64(150), "tenant": "acme"}, true},
+		{map[string]any{"total": int64(150), "tenant": "other"}, false},
+		{map[string]any{"total": int64(50), "tenant": "acme"}, false},
+	}
+	for _, tt := range tests {
+		ctx := jexl.NewMapContext()
+		ctx.Set("o", tt.order)
+		result, err := filtered.Execute(ctx)
+		if err != nil {
+			t.Fatalf("Failed to execute script: %v", err)
+		}
+		if result != tt.expected {
+			t.Errorf("%v: expected %v, got %v", tt.order, tt.expected, result)
+		}
+	}
+
+	// Неизменённое дерево переиспользуется целиком
+	if same, _ := jexl.Rewrite(original, func(node jexl.Node) jexl.Node { return node }); same != original {
+		t.Errorf("Identity rewrite should return the original node")
+	}
+}
+
+// TestRewriteStatements тестирует удаление инструкций и проверку типов замен
+func TestRewriteStatements(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "var x = 1; x = x + 10; x * 2")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	rewrittenNode, err := jexl.Rewrite(script.AST(), func(node jexl.Node) jexl.Node {
+		if _, ok := node.(*jexl.AssignmentNode); ok {
+			return nil
+		}
+		return node
+	})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	rewritten := rewrittenNode.(*jexl.ScriptNode)
+	if len(rewritten.Children()) != 2 {
+		t.Fatalf("Expected 2 statements, got %d", len(rewritten.Children()))
+	}
+	if text := rewritten.SourceText(); text != "var x = 1; x * 2" {
+		t.Errorf("Expected removed statement dropped from text, got %q", text)
+	}
+	updated, err := engine.CreateScriptFromAST(rewritten)
+	if err != nil {
+		t.Fatalf("Failed to create script from AST: %v", err)
+	}
+	result, err := updated.Execute(nil)
+	if err != nil {
+		t.Fatalf("Failed to execute script: %v", err)
+	}
+	if actual := asInt64(t, result); actual != 2 {
+		t.Errorf("Expected 2, got %d", actual)
+	}
+
+	_, err = jexl.Rewrite(script.AST(), func(node jexl.Node) jexl.Node {
+		if _, ok := node.(*jexl.IdentifierNode); ok {
+			return jexl.NewLiteralNode(int64(1), "1")
+		}
+		return node
+	})
+	if err == nil {
+		t.Errorf("Expected error when replacing a variable name with a literal")
+	}
+}
+
+// TestRewriteSource тестирует пересборку исходного текста и локальных переменных
+func TestRewriteSource(t *testing.T) {
+	engine, err := jexl.NewBuilder().Build()
+	if err != nil {
+		t.Fatalf("Failed to build engine: %v", err)
+	}
+	script, err := engine.CreateScript(nil, nil, "function f(a) { a + fa } var x = f(1); var [fx, y] = [x, f]; fx")
+	if err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+	ast := script.AST()
+	ast.SetVariables([]string{"x", "fx", "y"})
+	renamed, err := jexl.Rewrite(ast, func(node jexl.Node) jexl.Node {
+		if ident, ok := node.(*jexl.IdentifierNode); ok {
+			switch ident.Name() {
+			case "f":
+				return jexl.NewIdentifierNode("g", "g")
+			case "x":
+				return jexl.NewIdentifierNode("z", "z")
+			}
+		}
+		return node
+	})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+	rewritten := renamed.(*jexl.ScriptNode)
+	// Изменённые инструкции получают текст, нормализованный парсером
+	want := "function g(a) {a + fa} var z = g(1); var [fx, y] = [z, g]; fx"
+	if rewritten.SourceText() != want {
+		t.Errorf("Rewritten text = %q, want %q", rewritten.SourceText(), want)
+	}
+	if vars := rewritten.Variables(); !slices.Equal(vars, []string{"z", "fx", "y"}) {
+		t.Errorf("Rewritten variables = %v", vars)
+	}
+	if reparsed, err := engine.CreateScript(nil, nil, rewritten.SourceText()); err != nil {
+		t.Errorf("Rewritten text does not parse: %v", err)
+	} else if result, err := reparsed.Execute(jexl.NewMapContextWithMap(map[string]any{"fa": int64(0)})); err != nil || asInt64(t, result) != 1 {
+		t.Errorf("Rewritten script = %v, %v", result, err)
+	}
+}